@@ -0,0 +1,121 @@
+package backend
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SQL for the account a record is inserted into, the first account if the record's AccId is 0
+const defaultAccount = "COALESCE(NULLIF(?, 0), (SELECT MIN(acc_id) FROM account))"
+
+func dbRowsToAccounts(rows *sql.Rows) ([]DataRow, error) {
+	var accounts []DataRow
+	for rows.Next() {
+		var acc Account
+		if err := rows.Scan(&acc.Id, &acc.Name, &acc.Type, &acc.Opening, &acc.Desc, &acc.Balance); err != nil {
+			return nil, dbError(err)
+		}
+		accounts = append(accounts, acc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err)
+	}
+	return accounts, nil
+}
+
+/* Returns all accounts with their current balance, including future dated records */
+func (s *Store) GetAccounts() ([]DataRow, error) {
+	end, _ := makeDate(3000, 1, 1)
+	return s.GetAccountBalances(end)
+}
+
+/* Returns all accounts with their balance at a point in time, i.e. including records before date */
+func (s *Store) GetAccountBalances(date time.Time) ([]DataRow, error) {
+	rows, err := s.db.Query(`SELECT acc_id, acc_name, acc_type, acc_opening, acc_desc,
+                                  acc_opening + IFNULL((SELECT SUM(rec_amt) FROM record
+                                                        WHERE record.acc_id = account.acc_id AND rec_date < ?), 0)
+                           FROM account
+                           ORDER BY acc_id`, date)
+	if err != nil {
+		return nil, dbError(err)
+	}
+	defer rows.Close()
+
+	return dbRowsToAccounts(rows)
+}
+
+/*
+Returns a page of an account's records, newest first, each with the account's
+balance after it.
+*/
+func (s *Store) GetAccountLedger(accId, page int) ([]DataRow, error) {
+	// the balance is summed oldest first, before the page is taken
+	rows, err := s.db.Query(`SELECT * FROM (
+                             SELECT rec_id, rec_date, rec_desc, rec_amt, cat_id, cat_name, acc_id, acc_name, rec_xfer_id, `+recordTags+`,
+                                    acc_opening + SUM(rec_amt) OVER (ORDER BY rec_date, rec_id) AS balance
+                             FROM record LEFT JOIN category USING (cat_id) JOIN account USING (acc_id)
+                             WHERE acc_id = ?)
+                           ORDER BY rec_date DESC, rec_id DESC
+                           LIMIT ?, ?`, accId, page*s.PageRows, s.PageRows)
+	if err != nil {
+		return nil, dbError(err)
+	}
+	defer rows.Close()
+
+	var ledger []DataRow
+	for rows.Next() {
+		var lr LedgerRow
+		if lr.Record, err = scanRecord(rows, &lr.Balance); err != nil {
+			return nil, err
+		}
+		ledger = append(ledger, lr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err)
+	}
+	return s.attachSplits(ledger)
+}
+
+func (s *Store) GetAccountLedgerMaxPage(accId int) (int, error) {
+	var n int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM record WHERE acc_id = ?", accId).Scan(&n); err != nil {
+		return 0, dbError(err)
+	}
+	return s.maxPage(n), nil
+}
+
+func (s *Store) GetAccountIdFromName(accName string) (int, error) {
+	var res int
+	if err := s.db.QueryRow("SELECT acc_id FROM account WHERE acc_name = ?", accName).Scan(&res); err != nil {
+		return 0, fmt.Errorf("account %q: %w", accName, dbError(err))
+	}
+	return res, nil
+}
+
+func (s *Store) InsertAccount(acc Account) error {
+	_, name, accType, opening, desc := acc.Spread()
+	_, err := s.db.Exec("INSERT INTO account (acc_name, acc_type, acc_opening, acc_desc) VALUES (?,?,?,?)", name, accType, opening, desc)
+	if err != nil {
+		return fmt.Errorf("failed to insert account: %w", dbError(err))
+	}
+	return nil
+}
+
+func (s *Store) UpdateAccount(id int, acc Account) error {
+	_, name, accType, opening, desc := acc.Spread()
+	err := checkAffected(s.db.Exec("UPDATE account SET acc_name = ?, acc_type = ?, acc_opening = ?, acc_desc = ? WHERE acc_id = ?",
+		name, accType, opening, desc, id))
+	if err != nil {
+		return fmt.Errorf("failed to update account %d: %w", id, err)
+	}
+	return nil
+}
+
+/* Deletes an account, fails with ErrConstraint if it has any records */
+func (s *Store) DeleteAccount(id int) error {
+	if err := checkAffected(s.db.Exec("DELETE FROM account WHERE acc_id = ?", id)); err != nil {
+		return fmt.Errorf("failed to delete account %d: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package backend
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+/* The fixture store with a credit card account (id 2) holding two more records */
+func newAccountsStore(t *testing.T) *Store {
+	t.Helper()
+	s := newFixtureStore(t)
+	mustNil(t, s.InsertAccount(Account{Name: "Visa", Type: "Card", Opening: -10000}))
+	mustNil(t, s.InsertRecord(Record{Date: date(t, "2024-01-20"), Desc: "dinner", Amt: -5000, CatId: 2, AccId: 2}))
+	mustNil(t, s.InsertRecord(Record{Date: date(t, "2024-02-20"), Desc: "lunch", Amt: -2000, CatId: 2, AccId: 2}))
+	return s
+}
+
+func accountBalances(t *testing.T, rows []DataRow) map[string]int {
+	t.Helper()
+	res := map[string]int{}
+	for _, r := range rows {
+		acc := r.(Account)
+		res[acc.Name] = acc.Balance
+	}
+	return res
+}
+
+func TestRecordsUseDefaultAccount(t *testing.T) {
+	s := newAccountsStore(t)
+
+	recs, err := s.GetRecordsRecent(0)
+	mustNil(t, err)
+	for _, r := range recs {
+		rec := r.(Record)
+		want := "Everyday"
+		if rec.Desc == "dinner" || rec.Desc == "lunch" {
+			want = "Visa"
+		}
+		if rec.AccName != want {
+			t.Errorf("%s: got account %q, want %q", rec.Desc, rec.AccName, want)
+		}
+	}
+
+	// updating without an account keeps the record's account
+	mustNil(t, s.UpdateRecord(8, Record{Date: date(t, "2024-01-20"), Desc: "dinner out", Amt: -5000, CatId: 2}))
+	recs, err = s.GetRecordsFilter(NewFilterOpts().WithAccId([]int{2}))
+	mustNil(t, err)
+	if len(recs) != 2 || recs[0].(Record).Desc != "dinner out" {
+		t.Errorf("unexpected card records %+v", recs)
+	}
+}
+
+func TestAccountBalances(t *testing.T) {
+	s := newAccountsStore(t)
+
+	rows, err := s.GetAccounts()
+	mustNil(t, err)
+	got := accountBalances(t, rows)
+	if got["Everyday"] != 443750 || got["Visa"] != -17000 {
+		t.Errorf("unexpected balances %v", got)
+	}
+
+	// at the end of January
+	rows, err = s.GetAccountBalances(date(t, "2024-02-01"))
+	mustNil(t, err)
+	got = accountBalances(t, rows)
+	if got["Everyday"] != 300000-4250-150000-1000 || got["Visa"] != -15000 {
+		t.Errorf("unexpected balances at end of January %v", got)
+	}
+}
+
+func TestGetAccountLedger(t *testing.T) {
+	s := newAccountsStore(t)
+	s.PageRows = 2
+
+	var got []int
+	for page := 0; page < 4; page++ {
+		rows, err := s.GetAccountLedger(1, page)
+		mustNil(t, err)
+		for _, r := range rows {
+			got = append(got, r.(LedgerRow).Balance)
+		}
+	}
+	// newest first, each balance is after the record
+	want := []int{443750, 438750, 444750, 144750, 294750, 299000, -1000}
+	if !equalInts(got, want) {
+		t.Errorf("got balances %v, want %v", got, want)
+	}
+
+	maxPage, err := s.GetAccountLedgerMaxPage(1)
+	mustNil(t, err)
+	if maxPage != 3 {
+		t.Errorf("got max page %d, want 3", maxPage)
+	}
+}
+
+func TestDeleteAccount(t *testing.T) {
+	s := newAccountsStore(t)
+
+	if err := s.DeleteAccount(2); !errors.Is(err, ErrConstraint) {
+		t.Errorf("deleting an account with records: got %v, want ErrConstraint", err)
+	}
+	mustNil(t, s.InsertAccount(Account{Name: "Cash", Type: "Cash"}))
+	mustNil(t, s.DeleteAccount(3))
+	if err := s.DeleteAccount(3); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+	if err := s.InsertAccount(Account{Name: "Visa", Type: "Card"}); !errors.Is(err, ErrConstraint) {
+		t.Errorf("duplicate name: got %v, want ErrConstraint", err)
+	}
+}
+
+func TestMigrateMovesRecordsToDefaultAccount(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	// a database from before accounts were added
+	db, err := sql.Open("sqlite3", path)
+	mustNil(t, err)
+	saved := migrations
+	migrations = migrations[:3]
+	err = migrate(db)
+	migrations = saved
+	mustNil(t, err)
+	_, err = db.Exec("INSERT INTO record (rec_date, rec_desc, rec_amt) VALUES ('2024-01-01', 'old', 100)")
+	mustNil(t, err)
+	db.Close()
+
+	s, err := SetupDb(path)
+	mustNil(t, err)
+	defer s.Close()
+	recs, err := s.GetRecordsRecent(0)
+	mustNil(t, err)
+	if len(recs) != 1 || recs[0].(Record).AccId != 1 || recs[0].(Record).AccName != "Everyday" {
+		t.Errorf("unexpected records after migrating %+v", recs)
+	}
+}
@@ -0,0 +1,160 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+)
+
+func checkAction(a CorporateAction) error {
+	switch {
+	case strings.TrimSpace(a.Code) == "":
+		return fmt.Errorf("%w: a corporate action needs a stock code", ErrConstraint)
+	case a.Type == ActionSplit && (a.Old <= 0 || a.New <= a.Old):
+		return fmt.Errorf("%w: a split needs more units after than before, e.g. 2 for 1", ErrConstraint)
+	case a.Type == ActionConsolidation && (a.New <= 0 || a.New >= a.Old):
+		return fmt.Errorf("%w: a consolidation needs fewer units after than before, e.g. 1 for 10", ErrConstraint)
+	case a.Type == ActionRename && (strings.TrimSpace(a.NewCode) == "" || strings.EqualFold(a.NewCode, a.Code)):
+		return fmt.Errorf("%w: a code change needs a different new code", ErrConstraint)
+	case a.Type != ActionSplit && a.Type != ActionConsolidation && a.Type != ActionRename:
+		return fmt.Errorf("%w: unknown corporate action %q, want split, consolidation or rename", ErrConstraint, a.Type)
+	}
+	return nil
+}
+
+/* Returns the action type named by s, either as stored (e.g. "rename") or as shown (e.g. "Code Change") */
+func ParseActionType(s string) (ActionType, error) {
+	for _, t := range ActionTypes {
+		if strings.EqualFold(s, string(t)) || strings.EqualFold(s, t.String()) {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("%w: unknown corporate action %q, want split, consolidation or rename", ErrConstraint, s)
+}
+
+/* The columns stored for an action, renames are 1 for 1 and only renames have a new code */
+func actionColumns(a CorporateAction) (newUnits, oldUnits int, newCode string) {
+	if a.Type == ActionRename {
+		return 1, 1, a.NewCode
+	}
+	return a.New, a.Old, ""
+}
+
+/* Returns every corporate action, newest first */
+func (s *Store) GetCorporateActions() ([]DataRow, error) {
+	actions, err := readActions(s.db, "")
+	if err != nil {
+		return nil, err
+	}
+	res := make([]DataRow, len(actions))
+	for i, a := range actions {
+		res[len(actions)-1-i] = a
+	}
+	return res, nil
+}
+
+func (s *Store) GetCorporateAction(id int) (CorporateAction, error) {
+	actions, err := readActions(s.db, "WHERE ca_id = ?", id)
+	if err != nil {
+		return CorporateAction{}, err
+	} else if len(actions) == 0 {
+		return CorporateAction{}, fmt.Errorf("corporate action %d: %w", id, ErrNotFound)
+	}
+	return actions[0], nil
+}
+
+/* Reads the corporate actions matching where, in the order matchLots needs: by date, then in the order they were added */
+func readActions(q querier, where string, args ...any) ([]CorporateAction, error) {
+	rows, err := q.Query(`SELECT ca_id, ca_date, ca_type, ca_code, ca_new, ca_old, IFNULL(ca_new_code, '')
+                        FROM corporate_action
+                        `+where+`
+                        ORDER BY ca_date, ca_id`, args...)
+	if err != nil {
+		return nil, dbError(err)
+	}
+	defer rows.Close()
+
+	var actions []CorporateAction
+	for rows.Next() {
+		var a CorporateAction
+		if err := rows.Scan(&a.Id, &a.Date, &a.Type, &a.Code, &a.New, &a.Old, &a.NewCode); err != nil {
+			return nil, dbError(err)
+		}
+		actions = append(actions, a)
+	}
+	return actions, dbError(rows.Err())
+}
+
+/*
+Returns the code a holding traded as code on a day is known by now, after the
+renames effective since. actions must be in date order.
+*/
+func currentCode(actions []CorporateAction, code, day string) string {
+	for _, a := range actions {
+		if a.Type == ActionRename && a.Code == code && dayOf(a.Date) > day {
+			code = a.NewCode
+		}
+	}
+	return code
+}
+
+/* Inserts a corporate action, failing with ErrConstraint if it leaves a sell of units which aren't held */
+func (s *Store) InsertCorporateAction(a CorporateAction) error {
+	if err := checkAction(a); err != nil {
+		return fmt.Errorf("failed to insert corporate action: %w", err)
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return dbError(err)
+	}
+	newUnits, oldUnits, newCode := actionColumns(a)
+	_, err = tx.Exec("INSERT INTO corporate_action (ca_date, ca_type, ca_code, ca_new, ca_old, ca_new_code) VALUES (?,?,?,?,?,NULLIF(?, ''))",
+		a.Date, a.Type, a.Code, newUnits, oldUnits, newCode)
+	err = dbError(err)
+	if err == nil {
+		err = checkLots(tx)
+	}
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to insert corporate action: %w", err)
+	}
+	return dbError(tx.Commit())
+}
+
+/* Updates a corporate action, failing with ErrConstraint if it leaves a sell of units which aren't held */
+func (s *Store) UpdateCorporateAction(id int, a CorporateAction) error {
+	if err := checkAction(a); err != nil {
+		return fmt.Errorf("failed to update corporate action %d: %w", id, err)
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return dbError(err)
+	}
+	newUnits, oldUnits, newCode := actionColumns(a)
+	err = checkAffected(tx.Exec("UPDATE corporate_action SET ca_date = ?, ca_type = ?, ca_code = ?, ca_new = ?, ca_old = ?, ca_new_code = NULLIF(?, '') WHERE ca_id = ?",
+		a.Date, a.Type, a.Code, newUnits, oldUnits, newCode, id))
+	if err == nil {
+		err = checkLots(tx)
+	}
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to update corporate action %d: %w", id, err)
+	}
+	return dbError(tx.Commit())
+}
+
+/* Deletes a corporate action, failing with ErrConstraint if a later sell is of the units or code it gave */
+func (s *Store) DeleteCorporateAction(id int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return dbError(err)
+	}
+	err = checkAffected(tx.Exec("DELETE FROM corporate_action WHERE ca_id = ?", id))
+	if err == nil {
+		err = checkLots(tx)
+	}
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete corporate action %d: %w", id, err)
+	}
+	return dbError(tx.Commit())
+}
@@ -0,0 +1,78 @@
+package backend
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCorporateActions(t *testing.T) {
+	s := newLotStore(t)
+	mustNil(t, s.InsertCorporateAction(CorporateAction{Date: date(t, "2024-04-01"), Type: ActionSplit, Code: "IVV", New: 2, Old: 1}))
+	// lots of 4 at $50, 4 at $65 and 8 at $80 after the split
+	mustNil(t, s.InsertInvestment(Investment{Date: date(t, "2024-05-01"), Code: "IVV", Unitprice: 10000, Qty: -5}))
+	mustNil(t, s.InsertCorporateAction(CorporateAction{Date: date(t, "2024-06-01"), Type: ActionRename, Code: "IVV", NewCode: "IVVN"}))
+
+	_, disposals, err := s.GetLots()
+	mustNil(t, err)
+	if len(disposals) != 2 || disposals[0].Qty != 4 || disposals[0].Cost != 20000 || disposals[1].Cost != 6500 ||
+		!disposals[0].Acquired.Equal(date(t, "2024-01-01")) {
+		t.Errorf("unexpected disposals %+v", disposals)
+	}
+	// the trades keep the units they were made in
+	if inv, err := s.GetInvestment(1); err != nil || inv.Qty != 2 || inv.Unitprice != 10000 {
+		t.Errorf("got investment %+v (%v), want it unchanged", inv, err)
+	}
+
+	s.FetchPrice = func(code string) (float32, error) { return 100, nil }
+	rows, err := s.GetInvestmentSummary(SortOpts{})
+	mustNil(t, err)
+	if r := rows[0].(InvSummaryRow); len(rows) != 3 || r.code != "IVVN" || r.qty != 11 || r.avgBuy != 7591 || r.realised != 23500 {
+		t.Errorf("got summary row %+v, want 11 IVVN at $75.91 with $235 realised", r)
+	}
+
+	// sells after the rename are of the new code
+	if err := s.InsertInvestment(Investment{Date: date(t, "2024-07-01"), Code: "IVV", Unitprice: 10000, Qty: -1}); !errors.Is(err, ErrConstraint) {
+		t.Errorf("selling the old code: got %v, want ErrConstraint", err)
+	}
+	mustNil(t, s.InsertCorporateAction(CorporateAction{Date: date(t, "2024-07-01"), Type: ActionConsolidation, Code: "IVVN", New: 1, Old: 2}))
+	if err := s.InsertInvestment(Investment{Date: date(t, "2024-08-01"), Code: "IVVN", Unitprice: 20000, Qty: -6}); !errors.Is(err, ErrConstraint) {
+		t.Errorf("selling more than the 5.5 units left after the consolidation: got %v, want ErrConstraint", err)
+	}
+	mustNil(t, s.InsertInvestment(Investment{Date: date(t, "2024-08-01"), Code: "IVVN", Unitprice: 20000, Qty: -5}))
+	if err := s.DeleteCorporateAction(2); !errors.Is(err, ErrConstraint) {
+		t.Errorf("deleting the rename of sold units: got %v, want ErrConstraint", err)
+	}
+
+	// fees before the rename are counted under the new code
+	mustNil(t, s.InsertInvestment(Investment{Date: date(t, "2024-06-15"), Code: "IVVN", Unitprice: 10000, Qty: 1, Fee: 500}))
+	mustNil(t, s.UpdateInvestment(1, Investment{Date: date(t, "2024-01-01"), Code: "IVV", Unitprice: 10000, Qty: 2, Fee: 1000}))
+	if fees, err := s.GetFees(2024); err != nil || len(fees) != 1 || fees[0] != (FeeTotal{Code: "IVVN", Trades: 2, Buys: 1500}) {
+		t.Errorf("got 2023-24 fees %+v (%v), want $15 on IVVN", fees, err)
+	}
+
+	actions, err := s.GetCorporateActions()
+	mustNil(t, err)
+	if len(actions) != 3 || actions[0].(CorporateAction).Type != ActionConsolidation {
+		t.Errorf("got actions %+v, want 3, newest first", actions)
+	}
+}
+
+func TestInsertCorporateActionInvalid(t *testing.T) {
+	s := newTestStore(t)
+	tests := []struct {
+		name string
+		a    CorporateAction
+	}{
+		{"no code", CorporateAction{Type: ActionSplit, New: 2, Old: 1}},
+		{"split to fewer units", CorporateAction{Type: ActionSplit, Code: "IVV", New: 1, Old: 2}},
+		{"consolidation to more units", CorporateAction{Type: ActionConsolidation, Code: "IVV", New: 3, Old: 1}},
+		{"rename to the same code", CorporateAction{Type: ActionRename, Code: "IVV", NewCode: "ivv"}},
+		{"unknown type", CorporateAction{Type: "merger", Code: "IVV", New: 1, Old: 1}},
+	}
+	for _, tt := range tests {
+		tt.a.Date = date(t, "2024-01-01")
+		if err := s.InsertCorporateAction(tt.a); !errors.Is(err, ErrConstraint) {
+			t.Errorf("%s: got %v, want ErrConstraint", tt.name, err)
+		}
+	}
+}
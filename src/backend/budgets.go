@@ -0,0 +1,135 @@
+package backend
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+/* Returns times one second before the start and end of the budget period containing t */
+func budgetPeriod(t time.Time, yearly bool) (time.Time, time.Time) {
+	if !yearly {
+		return getMonthStartAndEnd(t)
+	}
+	loc := t.Location()
+	return time.Date(t.Year(), 1, 1, 0, 0, -1, 0, loc), time.Date(t.Year()+1, 1, 1, 0, 0, -1, 0, loc)
+}
+
+/* Returns ErrConstraint if a budget can't be saved */
+func (s *Store) checkBudget(b Budget) error {
+	if b.Amt <= 0 {
+		return fmt.Errorf("%w: a budget must be positive", ErrConstraint)
+	}
+	var isIncome bool
+	if err := s.db.QueryRow("SELECT cat_isincome FROM category WHERE cat_id = ?", b.CatId).Scan(&isIncome); err != nil {
+		return fmt.Errorf("category %d: %w", b.CatId, dbError(err))
+	}
+	if isIncome {
+		return fmt.Errorf("%w: budgets are only for expenditure categories", ErrConstraint)
+	}
+	return nil
+}
+
+/* Returns all budgets, ordered by category */
+func (s *Store) getBudgets() ([]Budget, error) {
+	rows, err := s.db.Query(`SELECT bud_id, cat_id, cat_name, bud_amt, bud_yearly, bud_rollover, bud_start
+                           FROM budget JOIN category USING (cat_id)
+                           ORDER BY cat_id`)
+	if err != nil {
+		return nil, dbError(err)
+	}
+	defer rows.Close()
+
+	var budgets []Budget
+	for rows.Next() {
+		var b Budget
+		if err := rows.Scan(&b.Id, &b.CatId, &b.CatName, &b.Amt, &b.Yearly, &b.Rollover, &b.Start); err != nil {
+			return nil, dbError(err)
+		}
+		budgets = append(budgets, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err)
+	}
+	return budgets, nil
+}
+
+/*
+Returns the progress of every budget through the period (month or year)
+containing date. Spending in subcategories counts towards a budget on their
+parent. Budgets with rollover add what was left over from each previous
+period since the budget started, or subtract any overspending.
+*/
+func (s *Store) GetBudgets(date time.Time) ([]DataRow, error) {
+	budgets, err := s.getBudgets()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]DataRow, len(budgets))
+	for i, b := range budgets {
+		pStart, pEnd := budgetPeriod(date, b.Yearly)
+		sum, err := s.GetCategoryTreeSum(b.CatId, pStart, pEnd)
+		if err != nil {
+			return nil, err
+		}
+		row := BudgetRow{Budget: b, Available: b.Amt, Spent: -int(math.Round(float64(sum)))}
+
+		if b.Rollover {
+			// number of whole periods between the budget starting and this period
+			periods := date.Year() - b.Start.Year()
+			if !b.Yearly {
+				periods = 12*periods + int(date.Month()-b.Start.Month())
+			}
+			if periods > 0 {
+				first, _ := budgetPeriod(time.Date(b.Start.Year(), b.Start.Month(), 1, 0, 0, 0, 0, date.Location()), b.Yearly)
+				before, err := s.GetCategoryTreeSum(b.CatId, first, pStart)
+				if err != nil {
+					return nil, err
+				}
+				row.Available += periods*b.Amt + int(math.Round(float64(before)))
+			}
+		}
+		res[i] = row
+	}
+	return res, nil
+}
+
+func (s *Store) InsertBudget(b Budget) error {
+	if err := s.checkBudget(b); err != nil {
+		return fmt.Errorf("failed to insert budget: %w", err)
+	}
+	if b.Start.IsZero() {
+		now := time.Now()
+		b.Start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		if b.Yearly {
+			b.Start = time.Date(now.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+		}
+	}
+	_, err := s.db.Exec("INSERT INTO budget (cat_id, bud_amt, bud_yearly, bud_rollover, bud_start) VALUES (?,?,?,?,?)",
+		b.CatId, b.Amt, b.Yearly, b.Rollover, b.Start)
+	if err != nil {
+		return fmt.Errorf("failed to insert budget: %w", dbError(err))
+	}
+	return nil
+}
+
+/* Updates a budget's category, amount and options, keeping its start */
+func (s *Store) UpdateBudget(id int, b Budget) error {
+	if err := s.checkBudget(b); err != nil {
+		return fmt.Errorf("failed to update budget %d: %w", id, err)
+	}
+	err := checkAffected(s.db.Exec("UPDATE budget SET cat_id = ?, bud_amt = ?, bud_yearly = ?, bud_rollover = ? WHERE bud_id = ?",
+		b.CatId, b.Amt, b.Yearly, b.Rollover, id))
+	if err != nil {
+		return fmt.Errorf("failed to update budget %d: %w", id, err)
+	}
+	return nil
+}
+
+func (s *Store) DeleteBudget(id int) error {
+	if err := checkAffected(s.db.Exec("DELETE FROM budget WHERE bud_id = ?", id)); err != nil {
+		return fmt.Errorf("failed to delete budget %d: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package backend
+
+import (
+	"errors"
+	"testing"
+)
+
+/* Returns the budget rows for the period containing month, keyed by category name */
+func budgetRows(t *testing.T, s *Store, month string) map[string]BudgetRow {
+	t.Helper()
+	rows, err := s.GetBudgets(date(t, month))
+	mustNil(t, err)
+	res := map[string]BudgetRow{}
+	for _, r := range rows {
+		br := r.(BudgetRow)
+		res[br.CatName] = br
+	}
+	return res
+}
+
+func TestGetBudgets(t *testing.T) {
+	s := newFixtureStore(t)
+	mustNil(t, s.InsertBudget(Budget{CatId: 2, Amt: 5000, Rollover: true, Start: date(t, "2024-01-01")}))
+	mustNil(t, s.InsertBudget(Budget{CatId: 3, Amt: 100000, Yearly: true, Start: date(t, "2024-01-01")}))
+
+	tests := []struct {
+		month     string
+		category  string
+		available int
+		spent     int
+	}{
+		{"2024-01-10", "Groceries", 5000, 4250},
+		{"2024-02-10", "Groceries", 5000 + 750, 6000}, // $7.50 left over from January
+		{"2024-03-10", "Groceries", 5000 + 750 - 1000, 0},
+		{"2023-12-10", "Groceries", 5000, 1000}, // before the budget started, nothing rolls over
+		{"2024-02-10", "Rent", 100000, 150000},  // spending so far this year
+	}
+	for _, tt := range tests {
+		t.Run(tt.month+" "+tt.category, func(t *testing.T) {
+			br := budgetRows(t, s, tt.month)[tt.category]
+			if br.Available != tt.available || br.Spent != tt.spent {
+				t.Errorf("got available %d spent %d, want %d %d", br.Available, br.Spent, tt.available, tt.spent)
+			}
+		})
+	}
+
+	if br := budgetRows(t, s, "2024-02-10")["Groceries"]; br.Remaining() != -250 || br.SpreadToStrings()[7] != "   $-2.50" {
+		t.Errorf("unexpected remaining %d %q", br.Remaining(), br.SpreadToStrings()[7])
+	}
+}
+
+func TestParentCategoryBudget(t *testing.T) {
+	s := newFixtureStore(t)
+	mustNil(t, s.InsertCategory(Category{Name: "Supermarket", ParentId: 2})) // 5
+	mustNil(t, s.InsertCategory(Category{Name: "Specials", ParentId: 5}))    // 6
+	mustNil(t, s.InsertRecord(Record{Date: date(t, "2024-01-20"), Desc: "aldi", Amt: -2000, CatId: 5}))
+	mustNil(t, s.InsertRecord(Record{Date: date(t, "2024-01-25"), Desc: "half price", Amt: -500, CatId: 6}))
+	mustNil(t, s.InsertBudget(Budget{CatId: 2, Amt: 5000, Rollover: true, Start: date(t, "2024-01-01")}))
+	mustNil(t, s.InsertBudget(Budget{CatId: 5, Amt: 1000, Start: date(t, "2024-01-01")}))
+
+	// spending in subcategories, at any depth, counts towards the parent's budget
+	rows := budgetRows(t, s, "2024-01-10")
+	if br := rows["Groceries"]; br.Spent != 4250+2000+500 || br.Remaining() != -1750 {
+		t.Errorf("got Groceries spent %d remaining %d, want 6750 and -1750", br.Spent, br.Remaining())
+	}
+	if br := rows["Supermarket"]; br.Spent != 2500 {
+		t.Errorf("got Supermarket spent %d, want 2500", br.Spent)
+	}
+	// and so does the overspending rolled over
+	if br := budgetRows(t, s, "2024-02-10")["Groceries"]; br.Available != 5000-1750 {
+		t.Errorf("got Groceries available %d in February, want 3250", br.Available)
+	}
+}
+
+func TestBudgetConstraints(t *testing.T) {
+	s := newFixtureStore(t)
+	mustNil(t, s.InsertBudget(Budget{CatId: 2, Amt: 5000}))
+
+	for name, b := range map[string]Budget{
+		"income category":    {CatId: 1, Amt: 5000},
+		"duplicate category": {CatId: 2, Amt: 100},
+		"negative amount":    {CatId: 3, Amt: -100},
+	} {
+		if err := s.InsertBudget(b); !errors.Is(err, ErrConstraint) {
+			t.Errorf("%s: got %v, want ErrConstraint", name, err)
+		}
+	}
+
+	mustNil(t, s.UpdateBudget(1, Budget{CatId: 3, Amt: 200000, Yearly: true}))
+	if br, ok := budgetRows(t, s, "2024-01-10")["Rent"]; !ok || br.Amt != 200000 || !br.Yearly {
+		t.Errorf("budget not updated %+v", br)
+	}
+
+	// deleting the category deletes its budget
+	mustNil(t, s.DeleteCategory(3))
+	if rows := budgetRows(t, s, "2024-01-10"); len(rows) != 0 {
+		t.Errorf("budget left after deleting its category %+v", rows)
+	}
+	if err := s.DeleteBudget(1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,161 @@
+package backend
+
+import (
+	"fmt"
+	"time"
+)
+
+/*
+Checks a category's parent (if any) exists, has the same type, and isn't the
+category itself or one of its subcategories. id is 0 for a new category.
+*/
+func (s *Store) checkCategory(id int, cat Category) error {
+	if id != 0 {
+		// a category with subcategories can't change type, they'd be left under the wrong parent
+		var mixed bool
+		err := s.db.QueryRow("SELECT EXISTS (SELECT 1 FROM category WHERE cat_parent_id = ? AND cat_isincome != ?)", id, cat.IsIncome).Scan(&mixed)
+		if err != nil {
+			return dbError(err)
+		}
+		if mixed {
+			return fmt.Errorf("%w: a category with subcategories can't change between income and expenditure", ErrConstraint)
+		}
+	}
+	if cat.ParentId == 0 {
+		return nil
+	}
+
+	parent, err := s.GetCategory(cat.ParentId)
+	if err != nil {
+		return err
+	}
+	if parent.IsIncome != cat.IsIncome {
+		return fmt.Errorf("%w: a subcategory must be the same type as its parent %q", ErrConstraint, parent.Name)
+	}
+
+	// walk up from the new parent, a cycle would lead back to this category
+	var cycle bool
+	err = s.db.QueryRow(`WITH RECURSIVE ancestor (cat_id) AS (
+                         SELECT ?
+                         UNION
+                         SELECT cat_parent_id FROM category JOIN ancestor USING (cat_id) WHERE cat_parent_id IS NOT NULL
+                       )
+                       SELECT EXISTS (SELECT 1 FROM ancestor WHERE cat_id = ?)`, cat.ParentId, id).Scan(&cycle)
+	if err != nil {
+		return dbError(err)
+	}
+	if cycle {
+		return fmt.Errorf("%w: a category can't be a subcategory of itself or its subcategories", ErrConstraint)
+	}
+	return nil
+}
+
+func (s *Store) GetCategory(id int) (Category, error) {
+	cats, err := s.GetCategories(0)
+	if err != nil {
+		return Category{}, err
+	}
+	for _, row := range cats {
+		if cat := row.(Category); cat.Id == id {
+			return cat, nil
+		}
+	}
+	return Category{}, fmt.Errorf("category %d: %w", id, ErrNotFound)
+}
+
+/* A category in the tree, with its sums for each period */
+type categorySums struct {
+	CategoryNode
+	sums []int
+}
+
+/*
+Returns the categories of one type in tree order, each with the sums of its
+own and its subcategories' records over n periods. sumsQuery returns rows of
+(cat_id, period, sum) with periods numbered from 1. Categories with no records
+in their subtree are left out.
+*/
+func (s *Store) categoryTree(isIncome bool, n int, sumsQuery string, args ...any) ([]categorySums, error) {
+	cats, err := s.GetCategories(0)
+	if err != nil {
+		return nil, err
+	}
+	var tree []categorySums
+	index := map[int]int{} // category id to position in tree
+	for _, row := range cats {
+		if cat := row.(Category); cat.IsIncome == isIncome {
+			index[cat.Id] = len(tree)
+			tree = append(tree, categorySums{
+				CategoryNode: CategoryNode{CatId: cat.Id, Name: cat.Name, ParentId: cat.ParentId, Depth: cat.Depth},
+				sums:         make([]int, n),
+			})
+		}
+	}
+
+	rows, err := s.db.Query(sumsQuery, args...)
+	if err != nil {
+		return nil, dbError(err)
+	}
+	defer rows.Close()
+	used := make([]bool, len(tree))
+	for rows.Next() {
+		var catId, period, sum int
+		if err := rows.Scan(&catId, &period, &sum); err != nil {
+			return nil, dbError(err)
+		}
+		if i, ok := index[catId]; ok {
+			tree[i].sums[period-1] += sum
+			used[i] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err)
+	}
+
+	// subcategories come after their parent, so working backwards adds each one's subtotal before its parent's is used
+	for i := len(tree) - 1; i >= 0; i-- {
+		p, ok := index[tree[i].ParentId]
+		if !ok || !used[i] {
+			continue
+		}
+		for j, sum := range tree[i].sums {
+			tree[p].sums[j] += sum
+		}
+		used[p] = true
+		tree[p].HasChildren = true
+	}
+
+	var res []categorySums
+	for i, node := range tree {
+		if used[i] {
+			res = append(res, node)
+		}
+	}
+	return res, nil
+}
+
+/*
+Returns the total of each income category then each expenditure category for
+the month containing date, with a divider row between. A parent category's
+total includes its subcategories'.
+*/
+func (s *Store) GetMonthCategoryTotals(date time.Time) ([]DataRow, error) {
+	mStart, mEnd := getMonthStartAndEnd(date)
+	var res []DataRow
+	for _, isIncome := range []bool{true, false} {
+		tree, err := s.categoryTree(isIncome, 1, `SELECT cat_id, 1, SUM(rec_amt)
+                                              FROM record_line
+                                              WHERE cat_id IS NOT NULL AND rec_date BETWEEN ? AND ?
+                                              GROUP BY cat_id`, mStart, mEnd)
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range tree {
+			res = append(res, &CategoryTotal{CategoryNode: node.CategoryNode, Sum: node.sums[0]})
+		}
+		if isIncome {
+			res = append(res, &CategoryTotal{CategoryNode: CategoryNode{CatId: -2}}) // divider row
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,140 @@
+package backend
+
+import (
+	"errors"
+	"testing"
+)
+
+/* Adds Food (5) as the parent of Groceries, and Eating out (6) under Food */
+func newCategoryTreeStore(t *testing.T) *Store {
+	t.Helper()
+	s := newFixtureStore(t)
+	mustNil(t, s.InsertCategory(Category{Name: "Food", Desc: "all food"}))
+	mustNil(t, s.UpdateCategory(2, Category{Name: "Groceries", Desc: "food", ParentId: 5}))
+	mustNil(t, s.InsertCategory(Category{Name: "Eating out", Desc: "restaurants", ParentId: 5}))
+	mustNil(t, s.InsertRecord(Record{Date: date(t, "2024-02-14"), Desc: "dinner", Amt: -8000, CatId: 6}))
+	return s
+}
+
+func TestCategoryTreeOrder(t *testing.T) {
+	s := newCategoryTreeStore(t)
+
+	rows, err := s.GetCategories(0)
+	mustNil(t, err)
+	var ids []int
+	for _, r := range rows {
+		ids = append(ids, r.(Category).Id)
+	}
+	if !equalInts(ids, []int{1, 3, 4, 5, 2, 6}) {
+		t.Errorf("got categories %v, want subcategories after their parent", ids)
+	}
+
+	cat, err := s.GetCategory(6)
+	mustNil(t, err)
+	if cat.ParentName != "Food" || cat.Depth != 1 || cat.SpreadToStrings()[1] != "└ Eating out" {
+		t.Errorf("unexpected category %+v", cat)
+	}
+
+	// deleting a parent moves its subcategories to the top level
+	mustNil(t, s.DeleteCategory(5))
+	cat, err = s.GetCategory(6)
+	mustNil(t, err)
+	if cat.ParentId != 0 || cat.Depth != 0 {
+		t.Errorf("subcategory kept its deleted parent %+v", cat)
+	}
+}
+
+func TestCategoriesSorted(t *testing.T) {
+	s := newCategoryTreeStore(t)
+
+	tests := []struct {
+		name string
+		sort SortOpts
+		want []int
+	}{
+		{"default", SortOpts{}, []int{1, 3, 4, 5, 2, 6}},
+		{"name", SortBy(1, false), []int{5, 6, 2, 4, 3, 1}},
+		{"name descending", SortBy(1, true), []int{1, 3, 4, 5, 2, 6}},
+		{"type", SortBy(2, false), []int{3, 5, 2, 6, 1, 4}},
+	}
+	for _, tt := range tests {
+		rows, err := s.GetCategoriesSorted(tt.sort)
+		mustNil(t, err)
+		var ids []int
+		for _, r := range rows {
+			ids = append(ids, r.(Category).Id)
+		}
+		if !equalInts(ids, tt.want) {
+			t.Errorf("%s: got categories %v, want %v", tt.name, ids, tt.want)
+		}
+	}
+}
+
+func TestCategoryParentChecks(t *testing.T) {
+	s := newCategoryTreeStore(t)
+
+	tests := []struct {
+		name string
+		id   int
+		cat  Category
+		want error
+	}{
+		{"own parent", 5, Category{Name: "Food", ParentId: 5}, ErrConstraint},
+		{"cycle", 5, Category{Name: "Food", ParentId: 6}, ErrConstraint},
+		{"income under expenditure", 4, Category{Name: "Other income", IsIncome: true, ParentId: 5}, ErrConstraint},
+		{"parent changing type", 5, Category{Name: "Food", IsIncome: true}, ErrConstraint},
+		{"missing parent", 3, Category{Name: "Rent", ParentId: 99}, ErrNotFound},
+		{"deeper tree", 3, Category{Name: "Rent", ParentId: 6}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.UpdateCategory(tt.id, tt.cat); !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCategoryTreeTotals(t *testing.T) {
+	s := newCategoryTreeStore(t)
+
+	rows, err := s.GetYearSummary(2024)
+	mustNil(t, err)
+	type want struct {
+		catId, depth int
+		hasChildren  bool
+		feb          int
+	}
+	wants := []want{
+		{3, 0, false, 0},       // Rent
+		{5, 0, true, -14000},   // Food
+		{2, 1, false, -6000},   // Groceries
+		{6, 1, false, -8000},   // Eating out
+		{-2, 0, false, 0},      // divider
+		{-4, 0, false, -14000}, // total expenditure
+	}
+	for i, w := range wants {
+		cy := rows[i+5].(*CategoryYear) // after the income rows
+		if cy.CatId != w.catId || cy.Depth != w.depth || cy.HasChildren != w.hasChildren || cy.MonthSums[1] != w.feb {
+			t.Errorf("row %d: got %+v, want %+v", i, *cy, w)
+		}
+	}
+
+	rows, err = s.GetMonthCategoryTotals(date(t, "2024-02-10"))
+	mustNil(t, err)
+	var got []string
+	for _, r := range rows {
+		got = append(got, r.SpreadToStrings()...)
+	}
+	wantCells := []string{"Work", "   $3000", "Other income", "     $50", "-----------------", "--------",
+		"Food ▾", "   $-140", "  Groceries", "    $-60", "  Eating out", "    $-80"}
+	if len(got) != len(wantCells) {
+		t.Fatalf("got %q, wantCells %q", got, wantCells)
+	}
+	for i := range wantCells {
+		if got[i] != wantCells[i] {
+			t.Errorf("got %q, wantCells %q", got, wantCells)
+			break
+		}
+	}
+}
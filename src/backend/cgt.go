@@ -0,0 +1,118 @@
+package backend
+
+import (
+	"fmt"
+	"time"
+)
+
+/* A disposal in a capital gains report */
+type CGTEvent struct {
+	Disposal
+	Discounted bool // the lot was held for more than 12 months, so a gain gets the 50% CGT discount
+}
+
+func (e CGTEvent) SpreadToStrings() []string {
+	discount := ""
+	if e.Discounted {
+		discount = "Yes"
+	}
+	return []string{
+		fmt.Sprint(e.SellId),
+		e.Sold.Format("2006-01-02"),
+		e.Code,
+		rightAlign(float32(e.Qty), 1, 6, ""),
+		e.Acquired.Format("2006-01-02"),
+		"#" + rightAlign(float32(e.Cost)/100, 2, 10, "$"),
+		"#" + rightAlign(float32(e.Proceeds)/100, 2, 10, "$"),
+		rightAlign(float32(e.Gain())/100, 2, 10, "$"),
+		discount,
+	}
+}
+
+/*
+The capital gains and losses from disposals in an Australian financial year,
+and the net capital gain after losses (this year's, then those carried forward
+from earlier years) and the 50% discount. Amounts are in cents, and losses are
+positive.
+*/
+type CGTReport struct {
+	Year          int // the financial year ending 30 June Year
+	Events        []CGTEvent
+	DiscountGains int // gains on lots held for more than 12 months
+	OtherGains    int
+	Losses        int // this year's capital losses
+	CarriedIn     int // net capital losses carried forward from earlier years
+	Discount      int // half of the discountable gains left after losses
+	NetGain       int // net capital gain, 0 if the losses are more than the gains
+	CarriedOut    int // net capital loss carried forward to the next year
+}
+
+/*
+Returns true if an asset acquired and disposed of on these dates qualifies for
+the CGT discount: it's held for at least 12 months not counting either day,
+so an asset bought on 1 January can be sold with the discount from 2 January
+the next year.
+*/
+func CGTDiscounted(acquired, sold time.Time) bool {
+	day := func(t time.Time) time.Time { return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC) }
+	return day(sold).After(day(acquired).AddDate(1, 0, 0))
+}
+
+/*
+Applies the losses and discount to the gains. Losses are taken from the gains
+which don't get the discount first, as that leaves the smallest net gain.
+*/
+func (r *CGTReport) net() {
+	losses := r.Losses + r.CarriedIn
+	other := max(0, r.OtherGains-losses)
+	losses -= r.OtherGains - other
+	discountable := max(0, r.DiscountGains-losses)
+	losses -= r.DiscountGains - discountable
+
+	r.Discount = discountable / 2
+	r.NetGain = other + discountable - r.Discount
+	r.CarriedOut = losses
+}
+
+/* Builds the report for the financial year ending in year from every disposal, oldest first */
+func capitalGains(disposals []Disposal, year int) CGTReport {
+	r := CGTReport{Year: year}
+	if len(disposals) == 0 {
+		return r
+	}
+
+	// losses carry forward from the year of the first disposal
+	for fy := FinancialYearOf(disposals[0].Sold); fy <= year; fy++ {
+		r = CGTReport{Year: fy, CarriedIn: r.CarriedOut}
+		start, end := FinancialYear(fy)
+		for _, d := range disposals {
+			if d.Sold.Before(start) || !d.Sold.Before(end) {
+				continue
+			}
+			e := CGTEvent{Disposal: d, Discounted: CGTDiscounted(d.Acquired, d.Sold)}
+			r.Events = append(r.Events, e)
+			switch {
+			case d.Gain() < 0:
+				r.Losses -= d.Gain()
+			case e.Discounted:
+				r.DiscountGains += d.Gain()
+			default:
+				r.OtherGains += d.Gain()
+			}
+		}
+		r.net()
+	}
+	return r
+}
+
+/*
+Returns the capital gains report for the financial year ending 30 June year,
+with the losses carried forward from the disposals of earlier years
+*/
+func (s *Store) GetCapitalGains(year int) (CGTReport, error) {
+	_, disposals, err := s.GetLots()
+	if err != nil {
+		return CGTReport{}, fmt.Errorf("failed to get capital gains: %w", err)
+	}
+	return capitalGains(disposals, year), nil
+}
@@ -0,0 +1,62 @@
+package backend
+
+import "testing"
+
+func TestCGTDiscounted(t *testing.T) {
+	tests := []struct {
+		acquired, sold string
+		want           bool
+	}{
+		{"2024-01-01", "2024-12-31", false},
+		{"2024-01-01", "2025-01-01", false}, // 12 months counting the day it was bought
+		{"2024-01-01", "2025-01-02", true},
+		{"2023-07-01", "2025-06-30", true},
+	}
+	for _, tt := range tests {
+		if got := CGTDiscounted(date(t, tt.acquired), date(t, tt.sold)); got != tt.want {
+			t.Errorf("CGTDiscounted(%s, %s) = %v, want %v", tt.acquired, tt.sold, got, tt.want)
+		}
+	}
+}
+
+func TestGetCapitalGains(t *testing.T) {
+	s := newTestStore(t)
+	for _, inv := range []Investment{
+		{Date: date(t, "2022-07-01"), Code: "IVV", Unitprice: 10000, Qty: 10},
+		{Date: date(t, "2023-08-01"), Code: "VGS.AX", Unitprice: 5000, Qty: 10},
+		{Date: date(t, "2024-01-15"), Code: "VGS.AX", Unitprice: 3000, Qty: -10}, // $200 loss in 2023-24
+		{Date: date(t, "2024-08-01"), Code: "IVV", Unitprice: 15000, Qty: -4},    // $200 discountable gain in 2024-25
+		{Date: date(t, "2024-09-01"), Code: "VAS.AX", Unitprice: 1000, Qty: 10},
+		{Date: date(t, "2025-01-01"), Code: "VAS.AX", Unitprice: 1500, Qty: -10}, // $50 gain without the discount
+	} {
+		mustNil(t, s.InsertInvestment(inv))
+	}
+
+	r, err := s.GetCapitalGains(2024)
+	mustNil(t, err)
+	if len(r.Events) != 1 || r.Losses != 20000 || r.NetGain != 0 || r.CarriedOut != 20000 {
+		t.Errorf("unexpected 2023-24 report %+v", r)
+	}
+
+	// the loss carried in is taken from the $50 gain first, then the discountable gain, leaving $50 to halve
+	r, err = s.GetCapitalGains(2025)
+	mustNil(t, err)
+	if len(r.Events) != 2 || !r.Events[0].Discounted || r.Events[1].Discounted {
+		t.Errorf("unexpected 2024-25 disposals %+v", r.Events)
+	}
+	if r.DiscountGains != 20000 || r.OtherGains != 5000 || r.CarriedIn != 20000 || r.Discount != 2500 || r.NetGain != 2500 || r.CarriedOut != 0 {
+		t.Errorf("unexpected 2024-25 report %+v", r)
+	}
+
+	// nothing sold, or carried forward
+	r, err = s.GetCapitalGains(2026)
+	mustNil(t, err)
+	if r.Year != 2026 || len(r.Events) != 0 || r.NetGain != 0 || r.CarriedIn != 0 {
+		t.Errorf("unexpected 2025-26 report %+v", r)
+	}
+	r, err = s.GetCapitalGains(2020)
+	mustNil(t, err)
+	if r.Year != 2020 || len(r.Events) != 0 {
+		t.Errorf("unexpected 2019-20 report %+v", r)
+	}
+}
@@ -1,184 +1,184 @@
 package backend
 
 import (
+	"cmp"
 	"database/sql"
 	"fmt"
 	_ "github.com/mattn/go-sqlite3"
-	"log"
+	"maps"
 	"math"
 	"math/rand"
+	"slices"
 	"strings"
 	"time"
 )
 
-var db *sql.DB
+/*
+Store owns a connection to a finance tracker database and its prepared statements.
+Create one using SetupDb, multiple stores may be open at once.
+*/
+type Store struct {
+	db *sql.DB
 
-var PAGE_ROWS = 15
+	// number of rows per page for paginated queries
+	PageRows int
 
-// prepared statements
-var insInvStmt *sql.Stmt
-var insRecStmt *sql.Stmt
-var insCatStmt *sql.Stmt
+	// fetches the current price of a stock, defaults to GetCurrentStockPrice
+	FetchPrice func(code string) (float32, error)
 
-var getInvRecStmt *sql.Stmt
-var getInvFilStmt *sql.Stmt
-var getRecRecStmt *sql.Stmt
-var getCategoriesStmt *sql.Stmt
+	// whether record searches use the FTS5 index, see setupSearch
+	fts bool
 
-var getIncomeSumStmt *sql.Stmt
-var getExpenditureSumStmt *sql.Stmt
-var getCategorySumStmt *sql.Stmt
+	// prepared statements
+	insInvStmt *sql.Stmt
+	insRecStmt *sql.Stmt
+	insCatStmt *sql.Stmt
 
-/* Connects to the database, then creates tables and prepared statements */
-func SetupDb(path string) {
+	getInvRecStmt     *sql.Stmt
+	getInvFilStmt     *sql.Stmt
+	getRecRecStmt     *sql.Stmt
+	getCategoriesStmt *sql.Stmt
 
-	createTables := func() {
+	getIncomeSumStmt      *sql.Stmt
+	getExpenditureSumStmt *sql.Stmt
+	getCategorySumStmt    *sql.Stmt
+	getCatTreeSumStmt     *sql.Stmt
+}
+
+/*
+Connects to the database, upgrades the schema to the latest version, then creates prepared statements.
+Use ":memory:" as the path for a temporary in-memory database.
+*/
+func SetupDb(path string) (*Store, error) {
+	s := &Store{PageRows: 15, FetchPrice: GetCurrentStockPrice}
+
+	createPreparedStmts := func() error {
 		var err error
-		sql := `
-    PRAGMA foreign_keys = ON; -- enforce referential integrity
-
-    CREATE TABLE IF NOT EXISTS category (
-      cat_id       INTEGER     NOT NULL PRIMARY KEY,
-      cat_name     VARCHAR(20) NOT NULL,
-      cat_isincome BOOL        NOT NULL,
-      cat_desc     VARCHAR(40)
-    );
-
-    CREATE TABLE IF NOT EXISTS record (
-      rec_id   INTEGER     NOT NULL  PRIMARY KEY,
-      rec_date DATE        NOT NULL,
-      rec_desc VARCHAR(50) NOT NULL,
-      rec_amt  NUMBER(9)   NOT NULL,
-      cat_id   INTEGER,
-      CONSTRAINT category_record_fk FOREIGN KEY (cat_id) REFERENCES category (cat_id) ON UPDATE CASCADE ON DELETE SET NULL
-    );
-
-    CREATE TABLE IF NOT EXISTS investment (
-      inv_id        INTEGER     NOT NULL  PRIMARY KEY,
-      inv_date      DATE        NOT NULL,
-      inv_code      VARCHAR(10) NOT NULL,
-      inv_qty       NUMBER(7,2) NOT NULL,
-      inv_unitprice NUMBER(8)   NOT NULL
-    );
-
-    CREATE TABLE IF NOT EXISTS stock (
-      st_code         VARCHAR(10) NOT NULL PRIMARY KEY,
-      st_unitprice    NUMBER(8,2) NOT NULL,
-      st_last_updated DATE        NOT NULL
-    );
-    `
-		if _, err = db.Exec(sql); err != nil {
-			log.Printf("%q: %s\n", err, sql)
+		prepare := func(stmt **sql.Stmt, name, query string) {
+			if err != nil {
+				return
+			}
+			if *stmt, err = s.db.Prepare(query); err != nil {
+				err = fmt.Errorf("failed initialising %s: %w", name, err)
+			}
 		}
-	}
 
-	createPreparedStmts := func() {
-		var err error
 		// insertion statements
-		insInvStmt, err = db.Prepare("INSERT INTO investment (inv_date, inv_code, inv_unitprice, inv_qty) VALUES (?,?,?,?)")
-		if err != nil {
-			log.Println("Failed initialising insInvStmt: ", err)
-		}
-		insRecStmt, err = db.Prepare("INSERT INTO record (rec_date, rec_desc, rec_amt, cat_id) VALUES (?,?,?,?)")
-		if err != nil {
-			log.Println("Failed initialising insRecStmt: ", err)
-		}
-		insCatStmt, err = db.Prepare("INSERT INTO category (cat_name, cat_isincome, cat_desc) VALUES (?,?,?)")
-		if err != nil {
-			log.Println("Failed initialising insCatStmt: ", err)
-		}
+		prepare(&s.insInvStmt, "insInvStmt", "INSERT INTO investment (inv_date, inv_code, inv_unitprice, inv_qty, inv_fee, inv_method, inv_lot_id, inv_ext_id) VALUES (?,?,?,?,?,?,NULLIF(?, 0),NULLIF(?, ''))")
+		prepare(&s.insRecStmt, "insRecStmt", "INSERT INTO record (rec_date, rec_desc, rec_amt, cat_id, acc_id, rec_ext_id) VALUES (?,?,?,?,"+defaultAccount+",NULLIF(?, ''))")
+		prepare(&s.insCatStmt, "insCatStmt", "INSERT INTO category (cat_name, cat_isincome, cat_desc, cat_parent_id) VALUES (?,?,?,NULLIF(?, 0))")
 
 		// query statements
-		getInvRecStmt, err = db.Prepare(`SELECT inv_id, inv_date, inv_code, inv_unitprice, inv_qty
-                                     FROM investment
-                                     ORDER BY inv_date DESC
-                                     LIMIT ?, ?`)
-		if err != nil {
-			log.Println("Failed initialising getInvRecStmt: ", err)
-		}
-		getInvFilStmt, err = db.Prepare(`SELECT inv_id, inv_date, inv_code, inv_unitprice, inv_qty
-                                     FROM investment
-                                     WHERE inv_qty*inv_unitprice BETWEEN ? AND ?
-                                       AND inv_date BETWEEN ? AND ?
-                                       AND inv_code LIKE ?
-                                     ORDER BY inv_date DESC`)
-		if err != nil {
-			log.Println("Failed initialising getInvFilStmt: ", err)
-		}
-		getRecRecStmt, err = db.Prepare(`SELECT rec_id, rec_date, rec_desc, rec_amt, cat_id
-                                     FROM record
-                                     ORDER BY rec_date DESC
-                                     LIMIT ?, ?`)
-		if err != nil {
-			log.Println("Failed initialising getRecRecStmt: ", err)
-		}
-		getCategoriesStmt, err = db.Prepare(`SELECT cat_id, cat_name, cat_desc, cat_isincome FROM category`)
-		if err != nil {
-			log.Println("Failed initialising getRecRecStmt: ", err)
-		}
-
-		getIncomeSumStmt, err = db.Prepare(`SELECT IFNULL(SUM(rec_amt), 0)
-                                        FROM record
-                                        WHERE cat_id IN (SELECT cat_id FROM category WHERE cat_isincome = true)
-                                          AND rec_date BETWEEN ? AND ?`)
-		if err != nil {
-			log.Println("Failed initialising getRecRecStmt: ", err)
-		}
-		getExpenditureSumStmt, err = db.Prepare(`SELECT IFNULL(SUM(rec_amt), 0)
-                                             FROM record
-                                             WHERE cat_id IN (SELECT cat_id FROM category WHERE cat_isincome = false)
-                                               AND rec_date BETWEEN ? AND ?`)
-		if err != nil {
-			log.Println("Failed initialising getRecRecStmt: ", err)
-		}
-		getCategorySumStmt, err = db.Prepare(`SELECT IFNULL(SUM(rec_amt), 0)
-                                          FROM record
-                                          WHERE cat_id = ? AND rec_date BETWEEN ? AND ?`)
-		if err != nil {
-			log.Println("Failed initialising getRecRecStmt: ", err)
-		}
+		prepare(&s.getInvRecStmt, "getInvRecStmt", `SELECT `+investmentCols+`
+                                              FROM investment
+                                              ORDER BY inv_date DESC
+                                              LIMIT ?, ?`)
+		prepare(&s.getInvFilStmt, "getInvFilStmt", `SELECT `+investmentCols+`
+                                              FROM investment
+                                              WHERE `+investmentFilter+`
+                                              ORDER BY inv_date DESC`)
+		prepare(&s.getRecRecStmt, "getRecRecStmt", `SELECT rec_id, rec_date, rec_desc, rec_amt, cat_id, cat_name, acc_id, acc_name, rec_xfer_id, `+recordTags+`
+                                              FROM record LEFT JOIN category USING (cat_id) LEFT JOIN account USING (acc_id)
+                                              ORDER BY `+recentOrder+`
+                                              LIMIT ?, ?`)
+		prepare(&s.getCategoriesStmt, "getCategoriesStmt", categoriesQuery("cat_id"))
+
+		prepare(&s.getIncomeSumStmt, "getIncomeSumStmt", `SELECT IFNULL(SUM(rec_amt), 0)
+                                                    FROM record_line
+                                                    WHERE cat_id IN (SELECT cat_id FROM category WHERE cat_isincome = true)
+                                                      AND rec_date BETWEEN ? AND ?`)
+		prepare(&s.getExpenditureSumStmt, "getExpenditureSumStmt", `SELECT IFNULL(SUM(rec_amt), 0)
+                                                              FROM record_line
+                                                              WHERE cat_id IN (SELECT cat_id FROM category WHERE cat_isincome = false)
+                                                                AND rec_date BETWEEN ? AND ?`)
+		prepare(&s.getCategorySumStmt, "getCategorySumStmt", `SELECT IFNULL(SUM(rec_amt), 0)
+                                                        FROM record_line
+                                                        WHERE cat_id = ? AND rec_date BETWEEN ? AND ?`)
+		prepare(&s.getCatTreeSumStmt, "getCatTreeSumStmt", `WITH RECURSIVE descendant (cat_id) AS (
+                                                      SELECT ?
+                                                      UNION
+                                                      SELECT c.cat_id FROM category c JOIN descendant d ON c.cat_parent_id = d.cat_id
+                                                    )
+                                                    SELECT IFNULL(SUM(rec_amt), 0)
+                                                    FROM record_line
+                                                    WHERE cat_id IN descendant AND rec_date BETWEEN ? AND ?`)
+		return err
 	}
 
 	// open connection to db
 	var err error
-	db, err = sql.Open("sqlite3", path)
-	db.SetMaxOpenConns(1)
+	s.db, err = sql.Open("sqlite3", path)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
+	// a single connection, so pragmas apply to every query and in-memory databases persist
+	s.db.SetMaxOpenConns(1)
 
-	createTables()
-	createPreparedStmts()
+	fail := func(err error) (*Store, error) {
+		s.db.Close()
+		return nil, err
+	}
+
+	// enforce referential integrity
+	if _, err = s.db.Exec("PRAGMA foreign_keys = ON"); err != nil {
+		return fail(err)
+	}
+	if err = migrate(s.db); err != nil {
+		return fail(err)
+	}
+	if err = s.setupSearch(); err != nil {
+		return fail(err)
+	}
+	if err = createPreparedStmts(); err != nil {
+		return fail(err)
+	}
+	return s, nil
+}
+
+/* Closes the database connection, the store can't be used afterwards */
+func (s *Store) Close() error {
+	return s.db.Close()
 }
 
-func CreateDummyData() {
+/*
+Fills the database with randomly generated categories, records and investments.
+The same seed always generates the same data.
+*/
+func (s *Store) CreateDummyData(seed int64) error {
+	r := rand.New(rand.NewSource(seed))
 	startDate, _ := makeDate(2023, 01, 1)
 
 	// investments
+	investments := []Investment{}
 	for range 5 {
-		InsertInvestment(Investment{
-			Date:      startDate.AddDate(rand.Intn(2), rand.Intn(13), rand.Intn(32)),
+		investments = append(investments, Investment{
+			Date:      startDate.AddDate(r.Intn(2), r.Intn(13), r.Intn(32)),
 			Code:      "IVV",
-			Qty:       float32(rand.Intn(100)),
-			Unitprice: rand.Intn(70000),
+			Qty:       float32(r.Intn(100)),
+			Unitprice: r.Intn(70000),
 		})
 	}
 	for range 5 {
-		InsertInvestment(Investment{
-			Date:      startDate.AddDate(rand.Intn(2), rand.Intn(13), rand.Intn(32)),
+		investments = append(investments, Investment{
+			Date:      startDate.AddDate(r.Intn(2), r.Intn(13), r.Intn(32)),
 			Code:      "VGS.AX",
-			Qty:       float32(rand.Intn(100)),
-			Unitprice: rand.Intn(30000),
+			Qty:       float32(r.Intn(100)),
+			Unitprice: r.Intn(30000),
 		})
 	}
 	for range 5 {
-		InsertInvestment(Investment{
-			Date:      startDate.AddDate(rand.Intn(2), rand.Intn(13), rand.Intn(32)),
+		investments = append(investments, Investment{
+			Date:      startDate.AddDate(r.Intn(2), r.Intn(13), r.Intn(32)),
 			Code:      "NDQ.AX",
-			Qty:       float32(rand.Intn(100)),
-			Unitprice: rand.Intn(20000),
+			Qty:       float32(r.Intn(100)),
+			Unitprice: r.Intn(20000),
 		})
 	}
+	for _, inv := range investments {
+		if err := s.InsertInvestment(inv); err != nil {
+			return err
+		}
+	}
 
 	// categories
 	categories := [...]Category{
@@ -192,60 +192,207 @@ func CreateDummyData() {
 		{Name: "Other expenditure", IsIncome: false, Desc: "other spending"},
 	}
 	for _, cat := range categories {
-		InsertCategory(cat)
+		if err := s.InsertCategory(cat); err != nil {
+			return err
+		}
+	}
+
+	// accounts, the default account is created by the migrations
+	if err := s.InsertAccount(Account{Name: "Credit Card", Type: "Card", Desc: "rewards card"}); err != nil {
+		return err
 	}
 
 	// records
+	records := []Record{}
 	for i := range 120 { // income
-		InsertRecord(Record{
-			Date:  startDate.AddDate(rand.Intn(2), rand.Intn(13), rand.Intn(32)),
+		records = append(records, Record{
+			Date:  startDate.AddDate(r.Intn(2), r.Intn(13), r.Intn(32)),
 			Desc:  "dummy income record " + fmt.Sprint(i),
-			Amt:   600 + rand.Intn(70000),
-			CatId: rand.Intn(2) + 1,
+			Amt:   600 + r.Intn(70000),
+			CatId: r.Intn(2) + 1,
 		})
 	}
 	for i := range 500 { // expenditure
-		InsertRecord(Record{
-			Date:  startDate.AddDate(rand.Intn(2), rand.Intn(13), rand.Intn(32)),
+		records = append(records, Record{
+			Date:  startDate.AddDate(r.Intn(2), r.Intn(13), r.Intn(32)),
 			Desc:  "dummy expenditure record " + fmt.Sprint(i),
-			Amt:   -rand.Intn(20000),
-			CatId: rand.Intn(6) + 3,
+			Amt:   -r.Intn(20000),
+			CatId: r.Intn(6) + 3,
+			AccId: 1 + i%4/3, // every 4th on the card
+		})
+	}
+	for _, rec := range records {
+		if err := s.InsertRecord(rec); err != nil {
+			return err
+		}
+	}
+
+	// monthly credit card repayments
+	for i := range 24 {
+		err := s.InsertTransfer(Transfer{
+			Date:      startDate.AddDate(0, i, 14),
+			FromAccId: 1,
+			ToAccId:   2,
+			Desc:      "card repayment",
+			Amt:       50000 + r.Intn(50000),
 		})
+		if err != nil {
+			return err
+		}
 	}
 
-	fmt.Println("Inserted dummy data")
+	return nil
 }
 
 // Inserting Rows
 
-func InsertRecord(rec Record) {
+/* Inserts a record with its tags and split lines */
+func (s *Store) InsertRecord(rec Record) error {
+	if err := checkSplits(rec); err != nil {
+		return fmt.Errorf("failed to insert record: %w", err)
+	}
 	_, date, desc, amt, cat_id := rec.Spread()
-	if _, err := insRecStmt.Exec(date, desc, amt, cat_id); err != nil {
-		log.Fatal("Failed to insert into category: ", err.Error())
+	tx, err := s.db.Begin()
+	if err != nil {
+		return dbError(err)
+	}
+	res, err := tx.Stmt(s.insRecStmt).Exec(date, desc, amt, cat_id, rec.AccId, rec.ExtId)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to insert record: %w", dbError(err))
+	}
+	id, _ := res.LastInsertId()
+	err = setRecordTags(tx, id, rec.Tags)
+	if err == nil {
+		err = setRecordSplits(tx, id, rec.Splits)
 	}
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to insert record: %w", err)
+	}
+	return dbError(tx.Commit())
 }
 
-func InsertCategory(cat Category) {
+/*
+Inserts many records in a single transaction, either all of them are inserted or none are.
+Records with an ExtId that is already in the database are skipped, returns the number inserted.
+*/
+func (s *Store) InsertRecords(recs []Record) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, dbError(err)
+	}
+	inserted := 0
+	for i, rec := range recs {
+		_, date, desc, amt, catId := rec.Spread()
+		if err := checkSplits(rec); err != nil {
+			tx.Rollback()
+			return 0, fmt.Errorf("failed to insert record %d (%s): %w", i+1, desc, err)
+		}
+		res, err := tx.Exec(`INSERT INTO record (rec_date, rec_desc, rec_amt, cat_id, acc_id, rec_ext_id)
+                         VALUES (?,?,?,?,`+defaultAccount+`,NULLIF(?, ''))
+                         ON CONFLICT (rec_ext_id) WHERE rec_ext_id IS NOT NULL DO NOTHING`,
+			date, desc, amt, catId, rec.AccId, rec.ExtId)
+		if err != nil {
+			tx.Rollback()
+			return 0, fmt.Errorf("failed to insert record %d (%s): %w", i+1, desc, dbError(err))
+		}
+		n, _ := res.RowsAffected()
+		if n > 0 {
+			id, _ := res.LastInsertId()
+			err := setRecordTags(tx, id, rec.Tags)
+			if err == nil {
+				err = setRecordSplits(tx, id, rec.Splits)
+			}
+			if err != nil {
+				tx.Rollback()
+				return 0, fmt.Errorf("failed to insert record %d (%s): %w", i+1, desc, err)
+			}
+		}
+		inserted += int(n)
+	}
+	return inserted, dbError(tx.Commit())
+}
+
+/* Returns whether a record imported from a statement with the given transaction id exists */
+func (s *Store) RecordExtIdExists(extId string) (bool, error) {
+	var exists bool
+	err := s.db.QueryRow("SELECT EXISTS (SELECT 1 FROM record WHERE rec_ext_id = ?)", extId).Scan(&exists)
+	return exists, dbError(err)
+}
+
+func (s *Store) InsertCategory(cat Category) error {
+	if err := s.checkCategory(0, cat); err != nil {
+		return fmt.Errorf("failed to insert category: %w", err)
+	}
 	_, name, isIncome, desc := cat.Spread()
-	if _, err := insCatStmt.Exec(name, isIncome, desc); err != nil {
-		log.Fatal("Failed to insert into category: ", err.Error())
+	if _, err := s.insCatStmt.Exec(name, isIncome, desc, cat.ParentId); err != nil {
+		return fmt.Errorf("failed to insert category: %w", dbError(err))
 	}
+	return nil
 }
 
-func InsertInvestment(inv Investment) {
+/* Inserts an investment, failing with ErrConstraint if it's a sell of units which aren't held */
+func (s *Store) InsertInvestment(inv Investment) error {
+	if err := checkInvestment(inv); err != nil {
+		return fmt.Errorf("failed to insert investment: %w", err)
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return dbError(err)
+	}
 	_, date, code, unitprice, qty := inv.Spread()
-	if _, err := insInvStmt.Exec(date, code, unitprice, qty); err != nil {
-		log.Fatal("Failed to insert into investment: ", err.Error())
+	method, lotId := lotColumns(inv)
+	if _, err := tx.Stmt(s.insInvStmt).Exec(date, code, unitprice, qty, inv.Fee, method, lotId, inv.ExtId); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to insert investment: %w", dbError(err))
+	}
+	if err := checkLots(tx); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to insert investment: %w", err)
 	}
+	return dbError(tx.Commit())
+}
+
+/* Like InsertRecords, for investments. Fails without inserting any if a sell is of units which aren't held */
+func (s *Store) InsertInvestments(invs []Investment) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, dbError(err)
+	}
+	inserted := 0
+	for i, inv := range invs {
+		_, date, code, unitprice, qty := inv.Spread()
+		method, lotId := lotColumns(inv)
+		if err := checkInvestment(inv); err != nil {
+			tx.Rollback()
+			return 0, fmt.Errorf("failed to insert investment %d (%s): %w", i+1, code, err)
+		}
+		res, err := tx.Exec(`INSERT INTO investment (inv_date, inv_code, inv_unitprice, inv_qty, inv_fee, inv_method, inv_lot_id, inv_ext_id)
+                         VALUES (?,?,?,?,?,?,NULLIF(?, 0),NULLIF(?, ''))
+                         ON CONFLICT (inv_ext_id) WHERE inv_ext_id IS NOT NULL DO NOTHING`,
+			date, code, unitprice, qty, inv.Fee, method, lotId, inv.ExtId)
+		if err != nil {
+			tx.Rollback()
+			return 0, fmt.Errorf("failed to insert investment %d (%s): %w", i+1, code, dbError(err))
+		}
+		n, _ := res.RowsAffected()
+		inserted += int(n)
+	}
+	if err := checkLots(tx); err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to insert investments: %w", err)
+	}
+	return inserted, dbError(tx.Commit())
 }
 
 // Reading Rows
 
 /* Returns investments made during within a date range */
-func GetInvestmentsRecent(page int) []DataRow {
-	rows, err := getInvRecStmt.Query(page*PAGE_ROWS, PAGE_ROWS)
+func (s *Store) GetInvestmentsRecent(page int) ([]DataRow, error) {
+	rows, err := s.getInvRecStmt.Query(page*s.PageRows, s.PageRows)
 	if err != nil {
-		panic(err)
+		return nil, dbError(err)
 	}
 	defer rows.Close()
 
@@ -253,70 +400,208 @@ func GetInvestmentsRecent(page int) []DataRow {
 }
 
 /* Returns investments matching a specified filter */
-func GetInvestmentsFilter(opts FilterOpts) []DataRow {
-	rows, err := getInvFilStmt.Query(opts.minCost, opts.maxCost, opts.startDate, opts.endDate, "%"+opts.code+"%")
+func (s *Store) GetInvestmentsFilter(opts FilterOpts) ([]DataRow, error) {
+	rows, err := s.getInvFilStmt.Query(opts.minCost, opts.maxCost, opts.startDate, opts.endDate, "%"+opts.code+"%")
 	if err != nil {
-		panic(err)
+		return nil, dbError(err)
 	}
 	defer rows.Close()
 
 	return dbRowsToInvestments(rows)
 }
 
+// columns read by dbRowsToInvestments
+const investmentCols = "inv_id, inv_date, inv_code, inv_unitprice, inv_qty, inv_method, IFNULL(inv_lot_id, 0), inv_fee"
+
+// conditions of an investment filter, taking the arguments of investmentFilterArgs. Amounts are the value bought or sold
+const investmentFilter = `ABS(inv_qty)*inv_unitprice BETWEEN ? AND ?
+                          AND inv_date >= ? AND inv_date < ?
+                          AND inv_code LIKE ?`
+
+func investmentFilterArgs(opts FilterOpts) []any {
+	return []any{opts.minCost, opts.maxCost, opts.startDate, opts.endDate, "%" + opts.code + "%"}
+}
+
+// columns of the investments table (ID, Date, Type, Code, Unitprice, Qty, Total, Fee), buys before sells
+var investmentSortCols = []string{"inv_id", "inv_date", "inv_qty < 0", "inv_code", "inv_unitprice", "ABS(inv_qty)", "ABS(inv_qty)*inv_unitprice", "inv_fee"}
+
+/* Returns a page of the investments matching a filter, sorted by a column or newest first like GetInvestmentsRecent */
+func (s *Store) GetInvestmentsFilterPage(opts FilterOpts, sort SortOpts, page int) ([]DataRow, error) {
+	rows, err := s.db.Query(`SELECT `+investmentCols+`
+                           FROM investment
+                           WHERE `+investmentFilter+`
+                           ORDER BY `+sort.orderBy(investmentSortCols, "inv_date DESC, inv_id")+`
+                           LIMIT ?, ?`, append(investmentFilterArgs(opts), page*s.PageRows, s.PageRows)...)
+	if err != nil {
+		return nil, dbError(err)
+	}
+	defer rows.Close()
+
+	return dbRowsToInvestments(rows)
+}
+
+func (s *Store) GetInvestmentsFilterMaxPage(opts FilterOpts) (int, error) {
+	var n int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM investment WHERE "+investmentFilter, investmentFilterArgs(opts)...).Scan(&n); err != nil {
+		return 0, dbError(err)
+	}
+	return s.maxPage(n), nil
+}
+
 /* Returns records from within a date range */
-func GetRecordsRecent(page int) []DataRow {
-	rows, err := getRecRecStmt.Query(page*PAGE_ROWS, PAGE_ROWS)
+func (s *Store) GetRecordsRecent(page int) ([]DataRow, error) {
+	rows, err := s.getRecRecStmt.Query(page*s.PageRows, s.PageRows)
 	if err != nil {
-		panic(err)
+		return nil, dbError(err)
 	}
 	defer rows.Close()
 
-	return dbRowsToRecords(rows)
+	recs, err := dbRowsToRecords(rows)
+	if err != nil {
+		return nil, err
+	}
+	return s.attachSplits(recs)
 }
 
-/* Returns records matching a specified filter */
-func GetRecordsFilter(opts FilterOpts) []DataRow {
-	cmd := `SELECT rec_id, rec_date, rec_desc, rec_amt, cat_id
-          FROM record
-          WHERE rec_amt BETWEEN ? AND ?
-            AND rec_date >= ? AND rec_date < ?
-          ORDER BY rec_date ASC`
+// order of GetRecordsRecent, newest first, with records on the same day in the order they were added
+const recentOrder = "rec_date DESC, rec_id"
+
+// columns of the records table (ID, Date, Category, Account, Description, Amount, Tags), split records sort by their first line's category
+var recordSortCols = []string{"rec_id", "rec_date", "cat_name COLLATE NOCASE", "acc_name COLLATE NOCASE", "rec_desc COLLATE NOCASE", "rec_amt", recordTags}
+
+/* Returns the conditions on the record table for a filter, and their arguments */
+func (s *Store) recordFilter(opts FilterOpts) (string, []any) {
+	cond := "rec_amt BETWEEN ? AND ? AND rec_date >= ? AND rec_date < ?"
 	args := []any{opts.minCost, opts.maxCost, opts.startDate, opts.endDate}
 
-	// filter by category if some are selected
+	// filter by category if some are selected, split records match if any line does
 	if len(opts.catIds) > 0 {
-		cmd += "AND cat_id IN (?" + strings.Repeat(", ?", len(opts.catIds)-1) + ")"
+		cond += " AND rec_id IN (SELECT rec_id FROM record_line WHERE cat_id IN (?" + strings.Repeat(", ?", len(opts.catIds)-1) + "))"
 		for _, c := range opts.catIds {
 			args = append(args, c)
 		}
 	}
+	if len(opts.accIds) > 0 {
+		cond += " AND acc_id IN (?" + strings.Repeat(", ?", len(opts.accIds)-1) + ")"
+		for _, a := range opts.accIds {
+			args = append(args, a)
+		}
+	}
+	if len(opts.tags) > 0 {
+		cond += ` AND rec_id IN (SELECT rec_id FROM record_tag JOIN tag USING (tag_id)
+                             WHERE tag_name IN (?` + strings.Repeat(", ?", len(opts.tags)-1) + "))"
+		for _, t := range opts.tags {
+			args = append(args, normaliseTag(t))
+		}
+	}
+	if text, textArgs := s.searchCondition(opts.text); text != "" {
+		cond += " AND " + text
+		args = append(args, textArgs...)
+	}
+	return cond, args
+}
 
-	rows, err := db.Query(cmd, args...)
+func (s *Store) queryRecords(cmd string, args ...any) ([]DataRow, error) {
+	rows, err := s.db.Query(cmd, args...)
 	if err != nil {
-		panic(err)
+		return nil, dbError(err)
 	}
 	defer rows.Close()
 
-	return dbRowsToRecords(rows)
+	recs, err := dbRowsToRecords(rows)
+	if err != nil {
+		return nil, err
+	}
+	return s.attachSplits(recs)
+}
+
+/* Returns records matching a specified filter, oldest first */
+func (s *Store) GetRecordsFilter(opts FilterOpts) ([]DataRow, error) {
+	cond, args := s.recordFilter(opts)
+	return s.queryRecords(`SELECT rec_id, rec_date, rec_desc, rec_amt, cat_id, cat_name, acc_id, acc_name, rec_xfer_id, `+recordTags+`
+                         FROM record LEFT JOIN category USING (cat_id) LEFT JOIN account USING (acc_id)
+                         WHERE `+cond+`
+                         ORDER BY rec_date ASC`, args...)
+}
+
+/* Returns a page of the records matching a filter, sorted by a column or newest first like GetRecordsRecent */
+func (s *Store) GetRecordsFilterPage(opts FilterOpts, sort SortOpts, page int) ([]DataRow, error) {
+	cond, args := s.recordFilter(opts)
+	return s.queryRecords(`SELECT rec_id, rec_date, rec_desc, rec_amt, cat_id, cat_name, acc_id, acc_name, rec_xfer_id, `+recordTags+`
+                         FROM record LEFT JOIN category USING (cat_id) LEFT JOIN account USING (acc_id)
+                         WHERE `+cond+`
+                         ORDER BY `+sort.orderBy(recordSortCols, recentOrder)+`
+                         LIMIT ?, ?`, append(args, page*s.PageRows, s.PageRows)...)
+}
+
+func (s *Store) GetRecordsFilterMaxPage(opts FilterOpts) (int, error) {
+	cond, args := s.recordFilter(opts)
+	var n int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM record WHERE "+cond, args...).Scan(&n); err != nil {
+		return 0, dbError(err)
+	}
+	return s.maxPage(n), nil
 }
 
 /* Returns a list of records, the total income and total expenditure */
-func GetMonthInfo(date time.Time) ([]DataRow, float32, float32) {
+func (s *Store) GetMonthInfo(date time.Time) ([]DataRow, float32, float32, error) {
 	mStart, mEnd := getMonthStartAndEnd(date)
-	recs := GetRecordsFilter(
+	recs, err := s.GetRecordsFilter(
 		NewFilterOpts().
 			WithStartDate(mStart).
 			WithEndDate(mEnd))
-	income := GetIncomeSum(mStart, mEnd)
-	expenditure := GetExpenditureSum(mStart, mEnd)
-	return recs, income, expenditure
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	income, err := s.GetIncomeSum(mStart, mEnd)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	expenditure, err := s.GetExpenditureSum(mStart, mEnd)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return recs, income, expenditure, nil
+}
+
+/* Returns a slice containing all of the categories, each followed by its subcategories */
+func (s *Store) GetCategories(page int) ([]DataRow, error) {
+	rows, err := s.getCategoriesStmt.Query()
+	if err != nil {
+		return nil, dbError(err)
+	}
+	defer rows.Close()
+
+	return dbRowsToCategories(rows)
+}
+
+/*
+Returns the query of the categories as a tree, depth first so each category is
+followed by its subcategories, with siblings in the given order
+*/
+func categoriesQuery(order string) string {
+	return `WITH RECURSIVE sibling (cat_id, n) AS (
+            SELECT cat_id, ROW_NUMBER() OVER (PARTITION BY cat_parent_id ORDER BY ` + order + `) FROM category
+          ), tree (cat_id, depth, path) AS (
+            SELECT cat_id, 0, printf('%08d', n) FROM category JOIN sibling USING (cat_id) WHERE cat_parent_id IS NULL
+            UNION ALL
+            SELECT c.cat_id, depth + 1, path || printf('%08d', n)
+            FROM category c JOIN sibling USING (cat_id) JOIN tree t ON c.cat_parent_id = t.cat_id
+          )
+          SELECT c.cat_id, c.cat_name, c.cat_desc, c.cat_isincome,
+                 IFNULL(c.cat_parent_id, 0), IFNULL(p.cat_name, ''), depth
+          FROM tree JOIN category c USING (cat_id) LEFT JOIN category p ON p.cat_id = c.cat_parent_id
+          ORDER BY path`
 }
 
-/* Returns a slice containing all of the categories */
-func GetCategories(page int) []DataRow {
-	rows, err := getCategoriesStmt.Query()
+// columns of the categories table (ID, Name, Type, Description), income after expenditure as in the table
+var categorySortCols = []string{"cat_id", "cat_name COLLATE NOCASE", "cat_isincome", "cat_desc COLLATE NOCASE"}
+
+/* Returns the categories as in GetCategories, with each category's subcategories sorted by a column */
+func (s *Store) GetCategoriesSorted(sort SortOpts) ([]DataRow, error) {
+	rows, err := s.db.Query(categoriesQuery(sort.orderBy(categorySortCols, "cat_id")))
 	if err != nil {
-		panic(err)
+		return nil, dbError(err)
 	}
 	defer rows.Close()
 
@@ -324,160 +609,247 @@ func GetCategories(page int) []DataRow {
 }
 
 /* Returns the total income over a date range (inclusive) */
-func GetIncomeSum(startDate, endDate time.Time) float32 {
+func (s *Store) GetIncomeSum(startDate, endDate time.Time) (float32, error) {
 	var sum float32
-	if err := getIncomeSumStmt.QueryRow(startDate, endDate).Scan(&sum); err != nil {
-		panic(err)
+	if err := s.getIncomeSumStmt.QueryRow(startDate, endDate).Scan(&sum); err != nil {
+		return 0, dbError(err)
 	}
-	return sum
+	return sum, nil
 }
 
 /* Returns the total expenditure over a date range (inclusive), flips sign (expenditure > 0) */
-func GetExpenditureSum(startDate, endDate time.Time) float32 {
+func (s *Store) GetExpenditureSum(startDate, endDate time.Time) (float32, error) {
 	var sum float32
-	if err := getExpenditureSumStmt.QueryRow(startDate, endDate).Scan(&sum); err != nil {
-		panic(err)
+	if err := s.getExpenditureSumStmt.QueryRow(startDate, endDate).Scan(&sum); err != nil {
+		return 0, dbError(err)
 	}
-	return -sum
+	return 0 - sum, nil // not -sum, which gives -0 when there are no records
 }
 
 /* Returns the total money in/out for a given category over a date range */
-func GetCategorySum(catId int, startDate, endDate time.Time) (float32, error) {
+func (s *Store) GetCategorySum(catId int, startDate, endDate time.Time) (float32, error) {
 	var sum float32
-	if err := getCategorySumStmt.QueryRow(catId, startDate, endDate).Scan(&sum); err != nil {
-		return 0, err
+	if err := s.getCategorySumStmt.QueryRow(catId, startDate, endDate).Scan(&sum); err != nil {
+		return 0, dbError(err)
+	}
+	return sum, nil
+}
+
+/* Returns the total money in/out for a category and all of its subcategories over a date range */
+func (s *Store) GetCategoryTreeSum(catId int, startDate, endDate time.Time) (float32, error) {
+	var sum float32
+	if err := s.getCatTreeSumStmt.QueryRow(catId, startDate, endDate).Scan(&sum); err != nil {
+		return 0, dbError(err)
 	}
 	return sum, nil
 }
 
 /* Returns rows of [catName, [sum(Month)]] */
-func GetYearSummary(year int) []DataRow {
+func (s *Store) GetYearSummary(year int) ([]DataRow, error) {
 
-	dataRowFromRows := func(rows *sql.Rows) []DataRow {
+	dataRowFromRows := func(rows *sql.Rows) ([]DataRow, error) {
+		defer rows.Close()
 		var res = []DataRow{}
 		var c *CategoryYear
 		for rows.Next() {
 			var cid, month, amt int
-			if err := rows.Scan(&cid, &month, &amt); err != nil {
-				panic(err)
+			var name string
+			if err := rows.Scan(&cid, &name, &month, &amt); err != nil {
+				return nil, err
 			}
 			if c == nil || cid != c.CatId {
-				c = &CategoryYear{CatId: cid}
+				c = &CategoryYear{CategoryNode: CategoryNode{CatId: cid, Name: name}}
 				res = append(res, c)
 			}
 			c.MonthSums[month-1] = amt
 		}
-		return res
+		return res, rows.Err()
+	}
+
+	var res []DataRow
+	appendQuery := func(sql string, args ...any) error {
+		rows, err := s.db.Query(sql, args...)
+		if err != nil {
+			return dbError(err)
+		}
+		catRows, err := dataRowFromRows(rows)
+		if err != nil {
+			return dbError(err)
+		}
+		res = append(res, catRows...)
+		return nil
+	}
+
+	// sums for each income/expenditure category, including its subcategories
+	appendCategories := func(isIncome bool) error {
+		tree, err := s.categoryTree(isIncome, 12, `SELECT cat_id, SUBSTR(rec_date, 6, 2), SUM(rec_amt)
+                                               FROM record_line
+                                               WHERE cat_id IS NOT NULL AND SUBSTR(rec_date, 1, 4) = ?
+                                               GROUP BY cat_id, SUBSTR(rec_date, 6, 2)`, fmt.Sprint(year))
+		if err != nil {
+			return err
+		}
+		for _, node := range tree {
+			cy := &CategoryYear{CategoryNode: node.CategoryNode}
+			copy(cy.MonthSums[:], node.sums)
+			res = append(res, cy)
+		}
+		return nil
+	}
+
+	// a single row of sums over categories with any of the given types, labelled with a special category id
+	appendTotal := func(specialId int, types ...bool) error {
+		return appendQuery(`SELECT ?, '', SUBSTR(rec_date, 6, 2), SUM(rec_amt)
+                        FROM record_line NATURAL JOIN category
+                        WHERE SUBSTR(rec_date, 1, 4) = ? AND cat_isincome IN (?, ?)
+                        GROUP BY SUBSTR(rec_date, 6, 2)
+                        ORDER BY SUBSTR(rec_date, 6, 2) ASC`, specialId, fmt.Sprint(year), types[0], types[len(types)-1])
 	}
 
 	// income categories
-	sql := `SELECT cat_id, SUBSTR(rec_date, 6, 2), SUM(rec_amt)
-          FROM record NATURAL JOIN category
-          WHERE SUBSTR(rec_date, 1, 4) = ? AND cat_isincome
-          GROUP BY cat_id, SUBSTR(rec_date, 6, 2)
-          ORDER BY cat_id, SUBSTR(rec_date, 6, 2) ASC;`
-	rows, err := db.Query(sql, fmt.Sprint(year))
-	if err != nil {
-		panic(err)
+	if err := appendCategories(true); err != nil {
+		return nil, err
 	}
-	defer rows.Close()
-	var res = dataRowFromRows(rows)
-	res = append(res, &CategoryYear{CatId: -2}) // divider row
+	res = append(res, &CategoryYear{CategoryNode: CategoryNode{CatId: -2}}) // divider row
 
 	// total income
-	sql_totals := `SELECT -3, SUBSTR(rec_date, 6, 2), SUM(rec_amt)
-         FROM record NATURAL JOIN category
-         WHERE SUBSTR(rec_date, 1, 4) = ? AND cat_isincome
-         GROUP BY SUBSTR(rec_date, 6, 2)
-         ORDER BY SUBSTR(rec_date, 6, 2) ASC;`
-	rows, err = db.Query(sql_totals, fmt.Sprint(year))
-	if err != nil {
-		panic(err)
+	if err := appendTotal(-3, true); err != nil {
+		return nil, err
 	}
-	defer rows.Close()
-	res = append(res, dataRowFromRows(rows)...)
-	res = append(res, &CategoryYear{CatId: -2}) // divider row
+	res = append(res, &CategoryYear{CategoryNode: CategoryNode{CatId: -2}}) // divider row
 
 	// expenditure categories
-	sql = strings.Replace(sql, "cat_isincome", "NOT cat_isincome", 1)
-	rows, err = db.Query(sql, fmt.Sprint(year))
-	if err != nil {
-		panic(err)
+	if err := appendCategories(false); err != nil {
+		return nil, err
 	}
-	defer rows.Close()
-	res = append(res, dataRowFromRows(rows)...)
-	res = append(res, &CategoryYear{CatId: -2}) // divider row
+	res = append(res, &CategoryYear{CategoryNode: CategoryNode{CatId: -2}}) // divider row
 
 	// total expenditure
-	sql_totals = strings.Replace(sql_totals, "-3", "-4", 1)
-	sql_totals = strings.Replace(sql_totals, "cat_isincome", "NOT cat_isincome", 1)
-	rows, err = db.Query(sql_totals, fmt.Sprint(year))
-	if err != nil {
-		panic(err)
+	if err := appendTotal(-4, false); err != nil {
+		return nil, err
 	}
-	defer rows.Close()
-	res = append(res, dataRowFromRows(rows)...)
-
-	res = append(res, &CategoryYear{CatId: -2}) // divider row
+	res = append(res, &CategoryYear{CategoryNode: CategoryNode{CatId: -2}}) // divider row
 
 	// net change
-	sql_totals = strings.Replace(sql_totals, "-4", "0", 1)
-	sql_totals = strings.Replace(sql_totals, "AND NOT cat_isincome", "", 1)
-	rows, err = db.Query(sql_totals, fmt.Sprint(year))
-	if err != nil {
-		panic(err)
+	if err := appendTotal(0, true, false); err != nil {
+		return nil, err
 	}
-	defer rows.Close()
-	res = append(res, dataRowFromRows(rows)...)
 
-	return res
+	return res, nil
 }
 
-func GetInvestmentSummary() []DataRow {
-	sql := `SELECT inv_code, SUM(inv_qty), SUM(inv_qty * inv_unitprice) / SUM(inv_qty)
-          FROM investment
-          GROUP BY inv_code
-          ORDER BY inv_code`
+/*
+Returns the current price of a stock, using the price cached in the database
+if it was updated in the last day. Otherwise fetches the price from yahoo
+finance and caches it, falling back to a stale cached price if the request
+fails.
+*/
+func (s *Store) getStockPrice(code string) (float32, error) {
+	var price float32
+	var updated time.Time
+	err := s.db.QueryRow("SELECT st_unitprice, st_last_updated FROM stock WHERE st_code = ?", code).Scan(&price, &updated)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, dbError(err)
+	}
+	haveCached := err == nil
 
-	rows, err := db.Query(sql)
-	if err != nil {
-		panic(err)
+	// only update once per day maximum
+	if haveCached && updated.After(time.Now().AddDate(0, 0, -1)) {
+		return price, nil
 	}
 
-	var invRows []InvSummaryRow
-	for rows.Next() {
-		var row InvSummaryRow
-		var avgBuyF float64
-		if err := rows.Scan(&row.code, &row.qty, &avgBuyF); err != nil {
-			panic(err)
+	newPrice, fetchErr := s.FetchPrice(code)
+	if fetchErr != nil {
+		if haveCached {
+			return price, nil
 		}
-		row.avgBuy = int(avgBuyF)
-		invRows = append(invRows, row)
+		return 0, fetchErr
 	}
-	rows.Close()
 
-	var totalValue float32 = 0
-	var totalBuy int = 0
-	for i := range len(invRows) {
+	_, err = s.db.Exec("INSERT OR REPLACE INTO stock (st_code, st_unitprice, st_last_updated) VALUES (?,?,?)", code, newPrice, time.Now())
+	if err != nil {
+		return 0, dbError(err)
+	}
+	return newPrice, nil
+}
 
-		// only update once per day maximum
-		r := db.QueryRow("SELECT st_unitprice FROM stock WHERE st_code = ? AND st_last_updated >= ?", invRows[i].code, time.Now().AddDate(0, 0, -1))
+/*
+Returns a row for each code ever held, with the units held and their unrealised
+P/L at the current price, the realised P/L of the units sold, the dividends
+paid and the brokerage fees paid, followed by a separator and the totals. Fees
+are included in the cost of the units held and the P/L of the units sold.
+Holdings are in the units and codes they have after any corporate actions.
+*/
+func (s *Store) GetInvestmentSummary(sort SortOpts) ([]DataRow, error) {
+	invs, err := readInvestments(s.db)
+	if err != nil {
+		return nil, err
+	}
+	actions, err := readActions(s.db, "")
+	if err != nil {
+		return nil, err
+	}
+	lots, disposals, err := matchLots(invs, actions)
+	if err != nil {
+		return nil, err
+	}
 
-		// try to use unitprice from db, if not exists get from yahoo finance and update db
-		if err := r.Scan(&invRows[i].curPrice); err != nil {
-			invRows[i].curPrice, err = GetCurrentStockPrice(invRows[i].code)
-			if err != nil {
-				panic(err)
-			}
+	// what's held of each code, and what it cost
+	byCode := map[string]*InvSummaryRow{}
+	costs := map[string]float64{}
+	row := func(code string) *InvSummaryRow {
+		if byCode[code] == nil {
+			byCode[code] = &InvSummaryRow{code: code}
+		}
+		return byCode[code]
+	}
+	for _, l := range lots {
+		row(l.Code).qty += float32(l.Held)
+		costs[l.Code] += l.Held * l.UnitCost
+	}
+	// gains, fees and dividends are counted under the code the holding has now
+	for _, d := range disposals {
+		row(currentCode(actions, d.Code, dayOf(d.Sold))).realised += d.Gain()
+	}
+	for _, inv := range invs {
+		row(currentCode(actions, inv.Code, dayOf(inv.Date))).fees += inv.Fee
+	}
+	divs, err := s.getDividends("")
+	if err != nil {
+		return nil, err
+	}
+	yearAgo := time.Now().AddDate(-1, 0, 0)
+	for _, d := range divs {
+		r := row(currentCode(actions, d.Code, dayOf(d.Date)))
+		r.dividends += d.Gross()
+		if d.Date.After(yearAgo) {
+			r.yearDividends += d.Gross()
+		}
+	}
 
-			_, err := db.Exec("INSERT OR REPLACE INTO stock (st_code, st_unitprice, st_last_updated) VALUES (?,?,?)", invRows[i].code, invRows[i].curPrice, time.Now())
-			if err != nil {
-				panic(err)
+	var invRows []InvSummaryRow
+	var totalValue float32 = 0
+	var totalBuy, totalRealised, totalDividends, totalYearDividends, totalFees int
+	for _, code := range slices.Sorted(maps.Keys(byCode)) {
+		r := byCode[code]
+		// prices aren't needed for codes which have been sold
+		if r.qty > qtyEpsilon {
+			r.avgBuy = int(math.Round(costs[code] / float64(r.qty)))
+			if r.curPrice, err = s.getStockPrice(code); err != nil {
+				return nil, fmt.Errorf("getting price for %s: %w", code, err)
 			}
+		} else {
+			r.qty = 0
 		}
-		totalValue += invRows[i].curPrice * invRows[i].qty
-		totalBuy += invRows[i].avgBuy * int(invRows[i].qty)
+		totalValue += r.curPrice * r.qty
+		totalBuy += int(math.Round(costs[code]))
+		totalRealised += r.realised
+		totalDividends += r.dividends
+		totalYearDividends += r.yearDividends
+		totalFees += r.fees
+		invRows = append(invRows, *r)
 	}
+	sortInvSummary(invRows, sort)
 
 	dRows := make([]DataRow, len(invRows))
 	for i, v := range invRows {
@@ -486,98 +858,247 @@ func GetInvestmentSummary() []DataRow {
 
 	// add total row if any investments made
 	dRows = append(dRows, InvSummaryRow{code: "separator"})
-	dRows = append(dRows, InvSummaryRow{code: "total", curPrice: totalValue, avgBuy: totalBuy})
+	dRows = append(dRows, InvSummaryRow{code: "total", curPrice: totalValue, avgBuy: totalBuy, realised: totalRealised,
+		dividends: totalDividends, yearDividends: totalYearDividends, fees: totalFees})
+
+	return dRows, nil
+}
 
-	return dRows
+/*
+Sorts the holdings by a column of the summary (Code, Qty, Avg Buy Price,
+Current Price, Total In, Current Value, P/L, %P/L, Realised P/L, Dividends,
+Yield, Total Return, Fees), keeping them in code order for the default
+order. Unlike the other tables this is done after the query, as the current
+prices aren't in the database.
+*/
+func sortInvSummary(rows []InvSummaryRow, opts SortOpts) {
+	col := opts.Col()
+	if col < 0 || col > 12 {
+		return
+	}
+	value := func(r InvSummaryRow) float32 {
+		totalIn, curVal := float32(r.avgBuy)/100*r.qty, r.curPrice*r.qty
+		switch col {
+		case 1:
+			return r.qty
+		case 2:
+			return float32(r.avgBuy)
+		case 3:
+			return r.curPrice
+		case 4:
+			return totalIn
+		case 5:
+			return curVal
+		case 6:
+			return curVal - totalIn
+		case 7:
+			return (curVal - totalIn) / totalIn
+		case 8:
+			return float32(r.realised)
+		case 9:
+			return float32(r.dividends)
+		case 10:
+			return r.yield()
+		case 12:
+			return float32(r.fees)
+		default:
+			return r.totalReturn()
+		}
+	}
+	slices.SortStableFunc(rows, func(a, b InvSummaryRow) int {
+		if opts.desc {
+			a, b = b, a
+		}
+		if col == 0 {
+			return strings.Compare(a.code, b.code)
+		}
+		return cmp.Compare(value(a), value(b))
+	})
 }
 
 // Frontend Helper Functions
 
-func GetInvestmentsMaxPage() int {
-	var res float64
-	db.QueryRow("SELECT (COUNT(*) / ?) - 1 FROM investment", float32(PAGE_ROWS)).Scan(&res)
-	return int(math.Ceil(res))
+/* Returns the index of the last page needed to show n rows, at least 0 */
+func (s *Store) maxPage(n int) int {
+	if n == 0 || s.PageRows <= 0 {
+		return 0
+	}
+	return (n - 1) / s.PageRows
 }
 
-func GetRecordsMaxPage() int {
-	var res float64
-	db.QueryRow("SELECT (COUNT(*) / ?) - 1 FROM record", float32(PAGE_ROWS)).Scan(&res)
-	return int(math.Ceil(res))
+func (s *Store) GetInvestmentsMaxPage() (int, error) {
+	var n int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM investment").Scan(&n); err != nil {
+		return 0, dbError(err)
+	}
+	return s.maxPage(n), nil
 }
 
-func GetCategoryNameFromId(catId int) string {
-	switch catId {
-	case 0:
-		return "[orange::b:]Net Change"
-	case -1:
-		return "(deleted)"
-	case -2:
-		return ""
-	case -3:
-		return "[orange]Total Income"
-	case -4:
-		return "[orange]Total Expenditure"
-	default:
-		var res string
-		db.QueryRow("SELECT cat_name FROM category WHERE cat_id = ?", catId).Scan(&res)
-		return res
+func (s *Store) GetRecordsMaxPage() (int, error) {
+	var n int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM record").Scan(&n); err != nil {
+		return 0, dbError(err)
 	}
+	return s.maxPage(n), nil
 }
 
-func GetCategoryIdFromName(catName string) int {
+func (s *Store) GetCategoryNameFromId(catId int) (string, error) {
+	if catId <= 0 {
+		return categoryLabel(catId, ""), nil
+	}
+	var res string
+	if err := s.db.QueryRow("SELECT cat_name FROM category WHERE cat_id = ?", catId).Scan(&res); err != nil {
+		return "", dbError(err)
+	}
+	return res, nil
+}
+
+func (s *Store) GetCategoryIdFromName(catName string) (int, error) {
 	var res int
-	db.QueryRow("SELECT cat_id FROM category WHERE cat_name = ?", catName).Scan(&res)
-	return res
+	if err := s.db.QueryRow("SELECT cat_id FROM category WHERE cat_name = ?", catName).Scan(&res); err != nil {
+		return 0, fmt.Errorf("category %q: %w", catName, dbError(err))
+	}
+	return res, nil
 }
 
 // Updating Rows
 
-func UpdateRecord(id int, rec Record) {
+/*
+Updates a record, keeping its account if rec.AccId is 0 and replacing its tags
+and split lines. If the record is a leg of a transfer its category is ignored,
+and the other leg is given the same date and description with the opposite
+amount. Moving a leg to the other leg's account fails with ErrConstraint.
+*/
+func (s *Store) UpdateRecord(id int, rec Record) error {
+	if err := checkSplits(rec); err != nil {
+		return fmt.Errorf("failed to update record %d: %w", id, err)
+	}
 	_, date, desc, amt, catId := rec.Spread()
-	_, err := db.Exec("UPDATE record SET rec_date = ?, rec_desc = ?, rec_amt = ?, cat_id = ? WHERE rec_id = ?", date, desc, amt, catId, id)
+	tx, err := s.db.Begin()
+	if err != nil {
+		return dbError(err)
+	}
+	var sameAcc bool
+	err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM record WHERE rec_xfer_id = ? AND acc_id = ?)", id, rec.AccId).Scan(&sameAcc)
 	if err != nil {
-		log.Fatal("Failed to insert into investment: ", err.Error())
+		tx.Rollback()
+		return fmt.Errorf("failed to update record %d: %w", id, dbError(err))
+	}
+	if sameAcc {
+		tx.Rollback()
+		return fmt.Errorf("failed to update record %d: %w: a transfer must be between two different accounts", id, ErrConstraint)
 	}
+	err = checkAffected(tx.Exec(`UPDATE record SET rec_date = ?, rec_desc = ?, rec_amt = ?,
+                                        cat_id = CASE WHEN rec_xfer_id IS NULL THEN ? END,
+                                        acc_id = COALESCE(NULLIF(?, 0), acc_id)
+                               WHERE rec_id = ?`, date, desc, amt, catId, rec.AccId, id))
+	if err == nil {
+		_, err = tx.Exec("UPDATE record SET rec_date = ?, rec_desc = ?, rec_amt = ? WHERE rec_xfer_id = ?", date, desc, -amt, id)
+		err = dbError(err)
+	}
+	if err == nil {
+		err = setRecordTags(tx, int64(id), rec.Tags)
+	}
+	if err == nil {
+		err = setRecordSplits(tx, int64(id), rec.Splits)
+	}
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to update record %d: %w", id, err)
+	}
+	return dbError(tx.Commit())
 }
 
-func UpdateCategory(id int, cat Category) {
+func (s *Store) UpdateCategory(id int, cat Category) error {
+	if err := s.checkCategory(id, cat); err != nil {
+		return fmt.Errorf("failed to update category %d: %w", id, err)
+	}
 	_, name, isIncome, desc := cat.Spread()
-	_, err := db.Exec("UPDATE category SET cat_name = ?, cat_isincome = ?, cat_desc = ? WHERE cat_id = ?", name, isIncome, desc, id)
+	err := checkAffected(s.db.Exec("UPDATE category SET cat_name = ?, cat_isincome = ?, cat_desc = ?, cat_parent_id = NULLIF(?, 0) WHERE cat_id = ?",
+		name, isIncome, desc, cat.ParentId, id))
 	if err != nil {
-		log.Fatal("Failed to insert into investment: ", err.Error())
+		return fmt.Errorf("failed to update category %d: %w", id, err)
 	}
+	return nil
 }
 
-func UpdateInvestment(id int, inv Investment) {
+/*
+Updates an investment, failing with ErrConstraint if any sell is then of units
+which aren't held, or it's the lot of a reinvested dividend
+*/
+func (s *Store) UpdateInvestment(id int, inv Investment) error {
+	if err := checkInvestment(inv); err != nil {
+		return fmt.Errorf("failed to update investment %d: %w", id, err)
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return dbError(err)
+	}
 	_, date, code, unitprice, qty := inv.Spread()
-	_, err := db.Exec("UPDATE investment SET inv_date = ?, inv_code = ?, inv_qty = ?, inv_unitprice = ? WHERE inv_id = ?", date, code, qty, unitprice, id)
+	method, lotId := lotColumns(inv)
+	err = checkNotDrpLot(tx, id)
+	if err == nil {
+		err = checkAffected(tx.Exec("UPDATE investment SET inv_date = ?, inv_code = ?, inv_qty = ?, inv_unitprice = ?, inv_fee = ?, inv_method = ?, inv_lot_id = NULLIF(?, 0) WHERE inv_id = ?",
+			date, code, qty, unitprice, inv.Fee, method, lotId, id))
+	}
+	if err == nil {
+		err = checkLots(tx)
+	}
 	if err != nil {
-		log.Fatal("Failed to insert into investment: ", err.Error())
+		tx.Rollback()
+		return fmt.Errorf("failed to update investment %d: %w", id, err)
 	}
+	return dbError(tx.Commit())
 }
 
 // Deleting Rows
 
-func DeleteRecord(id int) error {
-	_, err := db.Exec("DELETE FROM record WHERE rec_id = ?", id)
-	if err != nil {
-		return err
+/* Deletes a record, or both legs if it's part of a transfer */
+func (s *Store) DeleteRecord(id int) error {
+	if err := checkAffected(s.db.Exec("DELETE FROM record WHERE rec_id = ? OR rec_xfer_id = ?", id, id)); err != nil {
+		return fmt.Errorf("failed to delete record %d: %w", id, err)
 	}
 	return nil
 }
 
-func DeleteCategory(id int) error {
-	_, err := db.Exec("DELETE FROM category WHERE cat_id = ?", id)
-	if err != nil {
-		return err
+func (s *Store) DeleteCategory(id int) error {
+	if err := checkAffected(s.db.Exec("DELETE FROM category WHERE cat_id = ?", id)); err != nil {
+		return fmt.Errorf("failed to delete category %d: %w", id, err)
 	}
 	return nil
 }
 
-func DeleteInvestment(id int) error {
-	_, err := db.Exec("DELETE FROM investment WHERE inv_id = ?", id)
+/*
+Deletes an investment, failing with ErrConstraint if it's a buy whose units
+have been sold, or the lot of a reinvested dividend
+*/
+func (s *Store) DeleteInvestment(id int) error {
+	tx, err := s.db.Begin()
 	if err != nil {
-		return err
+		return dbError(err)
 	}
-	return nil
+	err = checkNotDrpLot(tx, id)
+	if err == nil {
+		err = deleteInvestment(tx, id)
+	}
+	if err == nil {
+		err = checkLots(tx)
+	}
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete investment %d: %w", id, dbError(err))
+	}
+	return dbError(tx.Commit())
+}
+
+/* Deletes an investment in tx unless a sell is from it as a specific lot, the caller checks the other sells */
+func deleteInvestment(tx *sql.Tx, id int) error {
+	var sellId int
+	err := tx.QueryRow("SELECT inv_id FROM investment WHERE inv_lot_id = ?", id).Scan(&sellId)
+	if err == nil {
+		return fmt.Errorf("%w: it's the lot sold by investment %d", ErrConstraint, sellId)
+	} else if err != sql.ErrNoRows {
+		return dbError(err)
+	}
+	return checkAffected(tx.Exec("DELETE FROM investment WHERE inv_id = ?", id))
 }
@@ -0,0 +1,516 @@
+package backend
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+const testSeed = 42
+
+/* Returns an empty in-memory store, closed when the test finishes */
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := SetupDb(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.FetchPrice = func(code string) (float32, error) {
+		t.Fatalf("unexpected price request for %s", code)
+		return 0, nil
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+/* Returns an in-memory store filled with dummy data generated from testSeed */
+func newDummyStore(t *testing.T) *Store {
+	t.Helper()
+	s := newTestStore(t)
+	if err := s.CreateDummyData(testSeed); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+/* Returns an in-memory store with a small set of hand-written rows */
+func newFixtureStore(t *testing.T) *Store {
+	t.Helper()
+	s := newTestStore(t)
+	for _, cat := range []Category{
+		{Name: "Work", IsIncome: true, Desc: "salary"},       // 1
+		{Name: "Groceries", IsIncome: false, Desc: "food"},   // 2
+		{Name: "Rent", IsIncome: false, Desc: "housing"},     // 3
+		{Name: "Other income", IsIncome: true, Desc: "misc"}, // 4
+	} {
+		mustNil(t, s.InsertCategory(cat))
+	}
+	for _, rec := range []Record{
+		{Date: date(t, "2024-01-01"), Desc: "pay", Amt: 300000, CatId: 1},
+		{Date: date(t, "2024-01-15"), Desc: "coles", Amt: -4250, CatId: 2},
+		{Date: date(t, "2024-01-31"), Desc: "rent", Amt: -150000, CatId: 3},
+		{Date: date(t, "2024-02-01"), Desc: "pay", Amt: 300000, CatId: 1},
+		{Date: date(t, "2024-02-10"), Desc: "woolies", Amt: -6000, CatId: 2},
+		{Date: date(t, "2024-02-29"), Desc: "prize", Amt: 5000, CatId: 4},
+		{Date: date(t, "2023-12-31"), Desc: "last year", Amt: -1000, CatId: 2},
+	} {
+		mustNil(t, s.InsertRecord(rec))
+	}
+	return s
+}
+
+func date(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func mustNil(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func recordIds(rows []DataRow) []int {
+	ids := make([]int, len(rows))
+	for i, r := range rows {
+		ids[i] = r.(Record).Id
+	}
+	return ids
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestInsertAndReadRecord(t *testing.T) {
+	s := newFixtureStore(t)
+
+	recs, err := s.GetRecordsRecent(0)
+	mustNil(t, err)
+	if len(recs) != 7 {
+		t.Fatalf("got %d records, want 7", len(recs))
+	}
+	// most recent first, category name joined in
+	first := recs[0].(Record)
+	if first.Desc != "prize" || first.CatName != "Other income" || first.Amt != 5000 {
+		t.Errorf("unexpected first record %+v", first)
+	}
+}
+
+func TestInsertRecordUnknownCategory(t *testing.T) {
+	s := newFixtureStore(t)
+	err := s.InsertRecord(Record{Date: date(t, "2024-01-01"), Desc: "x", Amt: 1, CatId: 99})
+	if !errors.Is(err, ErrConstraint) {
+		t.Errorf("got %v, want ErrConstraint", err)
+	}
+}
+
+func TestUpdateRecord(t *testing.T) {
+	s := newFixtureStore(t)
+
+	mustNil(t, s.UpdateRecord(2, Record{Date: date(t, "2024-01-16"), Desc: "aldi", Amt: -1234, CatId: 3}))
+	recs, err := s.GetRecordsFilter(NewFilterOpts().WithStartDate(date(t, "2024-01-16")).WithEndDate(date(t, "2024-01-17")))
+	mustNil(t, err)
+	if len(recs) != 1 {
+		t.Fatalf("got %d records, want 1", len(recs))
+	}
+	if rec := recs[0].(Record); rec.Id != 2 || rec.Desc != "aldi" || rec.Amt != -1234 || rec.CatName != "Rent" {
+		t.Errorf("record not updated: %+v", rec)
+	}
+
+	if err := s.UpdateRecord(99, Record{Date: date(t, "2024-01-16"), Desc: "x", Amt: 1, CatId: 1}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("updating missing record: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestUpdateCategory(t *testing.T) {
+	s := newFixtureStore(t)
+	mustNil(t, s.UpdateCategory(2, Category{Name: "Food", IsIncome: false, Desc: "all food"}))
+
+	name, err := s.GetCategoryNameFromId(2)
+	mustNil(t, err)
+	if name != "Food" {
+		t.Errorf("got name %q, want Food", name)
+	}
+	id, err := s.GetCategoryIdFromName("Food")
+	mustNil(t, err)
+	if id != 2 {
+		t.Errorf("got id %d, want 2", id)
+	}
+	if _, err := s.GetCategoryIdFromName("Groceries"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	s := newFixtureStore(t)
+	mustNil(t, s.InsertInvestment(Investment{Date: date(t, "2024-01-01"), Code: "IVV", Unitprice: 10000, Qty: 2}))
+
+	mustNil(t, s.DeleteRecord(1))
+	if err := s.DeleteRecord(1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleting record twice: got %v, want ErrNotFound", err)
+	}
+
+	mustNil(t, s.DeleteInvestment(1))
+	invs, err := s.GetInvestmentsRecent(0)
+	mustNil(t, err)
+	if len(invs) != 0 {
+		t.Errorf("got %d investments after delete, want 0", len(invs))
+	}
+
+	// records keep existing when their category is deleted
+	mustNil(t, s.DeleteCategory(2))
+	recs, err := s.GetRecordsFilter(NewFilterOpts().WithStartDate(date(t, "2024-01-15")).WithEndDate(date(t, "2024-01-16")))
+	mustNil(t, err)
+	if len(recs) != 1 || recs[0].(Record).CatId != -1 {
+		t.Errorf("expected one record with deleted category, got %+v", recs)
+	}
+}
+
+func TestGetRecordsFilter(t *testing.T) {
+	s := newFixtureStore(t)
+
+	tests := []struct {
+		name string
+		opts FilterOpts
+		want []int
+	}{
+		{"defaults", NewFilterOpts(), []int{7, 1, 2, 3, 4, 5, 6}},
+		{"start date", NewFilterOpts().WithStartDate(date(t, "2024-02-01")), []int{4, 5, 6}},
+		{"end date exclusive", NewFilterOpts().WithEndDate(date(t, "2024-01-31")), []int{7, 1, 2}},
+		{"date range", NewFilterOpts().WithStartDate(date(t, "2024-01-02")).WithEndDate(date(t, "2024-02-02")), []int{2, 3, 4}},
+		{"min cost", NewFilterOpts().WithMinCost(0), []int{1, 4, 6}},
+		{"max cost", NewFilterOpts().WithMaxCost(-5000), []int{3, 5}},
+		{"cost range", NewFilterOpts().WithMinCost(-5000).WithMaxCost(5000), []int{7, 2, 6}},
+		{"one category", NewFilterOpts().WithCatId([]int{2}), []int{7, 2, 5}},
+		{"many categories", NewFilterOpts().WithCatId([]int{1, 4}), []int{1, 4, 6}},
+		{"category and date", NewFilterOpts().WithCatId([]int{2}).WithStartDate(date(t, "2024-01-01")), []int{2, 5}},
+		{"everything", NewFilterOpts().WithCatId([]int{2, 3}).WithMaxCost(-5000).WithStartDate(date(t, "2024-01-01")).WithEndDate(date(t, "2024-02-01")), []int{3}},
+		{"no matches", NewFilterOpts().WithCatId([]int{99}), []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recs, err := s.GetRecordsFilter(tt.opts)
+			mustNil(t, err)
+			if got := recordIds(recs); !equalInts(got, tt.want) {
+				t.Errorf("got ids %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetInvestmentsFilter(t *testing.T) {
+	s := newTestStore(t)
+	for _, inv := range []Investment{
+		{Date: date(t, "2024-01-01"), Code: "IVV", Unitprice: 10000, Qty: 2},
+		{Date: date(t, "2024-02-01"), Code: "VGS.AX", Unitprice: 5000, Qty: 10},
+		{Date: date(t, "2024-03-01"), Code: "IVV", Unitprice: 12000, Qty: 1},
+	} {
+		mustNil(t, s.InsertInvestment(inv))
+	}
+
+	tests := []struct {
+		name string
+		opts FilterOpts
+		want int
+	}{
+		{"defaults", NewFilterOpts(), 3},
+		{"code", NewFilterOpts().WithCode("IVV"), 2},
+		{"partial code", NewFilterOpts().WithCode(".AX"), 1},
+		{"date", NewFilterOpts().WithStartDate(date(t, "2024-01-15")), 2},
+		{"value", NewFilterOpts().WithMinCost(15000), 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invs, err := s.GetInvestmentsFilter(tt.opts)
+			mustNil(t, err)
+			if len(invs) != tt.want {
+				t.Errorf("got %d investments, want %d", len(invs), tt.want)
+			}
+		})
+	}
+}
+
+func TestGetMonthInfo(t *testing.T) {
+	s := newFixtureStore(t)
+
+	tests := []struct {
+		month       string
+		records     int
+		income      float32
+		expenditure float32
+	}{
+		{"2024-01-10", 3, 300000, 154250},
+		{"2024-02-10", 3, 305000, 6000},
+		{"2023-12-10", 1, 0, 1000},
+		{"2024-03-10", 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.month, func(t *testing.T) {
+			recs, income, expenditure, err := s.GetMonthInfo(date(t, tt.month))
+			mustNil(t, err)
+			if len(recs) != tt.records || income != tt.income || expenditure != tt.expenditure {
+				t.Errorf("got (%d, %.0f, %.0f), want (%d, %.0f, %.0f)",
+					len(recs), income, expenditure, tt.records, tt.income, tt.expenditure)
+			}
+		})
+	}
+}
+
+func TestGetCategorySum(t *testing.T) {
+	s := newFixtureStore(t)
+	sum, err := s.GetCategorySum(2, date(t, "2024-01-01"), date(t, "2024-12-31"))
+	mustNil(t, err)
+	if sum != -10250 {
+		t.Errorf("got %.0f, want -10250", sum)
+	}
+}
+
+func TestGetYearSummary(t *testing.T) {
+	s := newFixtureStore(t)
+
+	rows, err := s.GetYearSummary(2024)
+	mustNil(t, err)
+
+	type want struct {
+		catId int
+		jan   int
+		feb   int
+	}
+	wants := []want{
+		{1, 300000, 300000}, // Work
+		{4, 0, 5000},        // Other income
+		{-2, 0, 0},
+		{-3, 300000, 305000}, // total income
+		{-2, 0, 0},
+		{2, -4250, -6000}, // Groceries
+		{3, -150000, 0},   // Rent
+		{-2, 0, 0},
+		{-4, -154250, -6000}, // total expenditure
+		{-2, 0, 0},
+		{0, 145750, 299000}, // net change
+	}
+	if len(rows) != len(wants) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(wants))
+	}
+	for i, w := range wants {
+		cy := rows[i].(*CategoryYear)
+		if cy.CatId != w.catId || cy.MonthSums[0] != w.jan || cy.MonthSums[1] != w.feb {
+			t.Errorf("row %d: got %d %v, want %+v", i, cy.CatId, cy.MonthSums[:2], w)
+		}
+		for _, m := range cy.MonthSums[2:] {
+			if m != 0 {
+				t.Errorf("row %d: unexpected sum after February %v", i, cy.MonthSums)
+			}
+		}
+	}
+	if name := rows[0].SpreadToStrings()[0]; name != "Work" {
+		t.Errorf("got category name %q, want Work", name)
+	}
+
+	// year without any records
+	rows, err = s.GetYearSummary(2000)
+	mustNil(t, err)
+	if len(rows) != 4 {
+		t.Errorf("got %d rows for an empty year, want only the 4 dividers", len(rows))
+	}
+}
+
+func TestMaxPage(t *testing.T) {
+	tests := []struct {
+		rows, pageRows, want int
+	}{
+		{0, 15, 0},
+		{1, 15, 0},
+		{15, 15, 0},
+		{16, 15, 1},
+		{30, 15, 1},
+		{31, 15, 2},
+		{10, 0, 0},
+	}
+	for _, tt := range tests {
+		s := &Store{PageRows: tt.pageRows}
+		if got := s.maxPage(tt.rows); got != tt.want {
+			t.Errorf("maxPage(%d) with %d rows per page = %d, want %d", tt.rows, tt.pageRows, got, tt.want)
+		}
+	}
+}
+
+func TestPagination(t *testing.T) {
+	s := newDummyStore(t) // 620 records and 24 transfers (48 legs), 15 investments
+	s.PageRows = 100
+
+	maxPage, err := s.GetRecordsMaxPage()
+	mustNil(t, err)
+	if maxPage != 6 {
+		t.Errorf("got records max page %d, want 6", maxPage)
+	}
+	maxPage, err = s.GetInvestmentsMaxPage()
+	mustNil(t, err)
+	if maxPage != 0 {
+		t.Errorf("got investments max page %d, want 0", maxPage)
+	}
+
+	seen := map[int]bool{}
+	for page := range 7 {
+		recs, err := s.GetRecordsRecent(page)
+		mustNil(t, err)
+		want := 100
+		if page == 6 {
+			want = 68
+		}
+		if len(recs) != want {
+			t.Errorf("page %d: got %d records, want %d", page, len(recs), want)
+		}
+		for _, id := range recordIds(recs) {
+			if seen[id] {
+				t.Errorf("record %d shown on multiple pages", id)
+			}
+			seen[id] = true
+		}
+	}
+}
+
+func TestFilterPagination(t *testing.T) {
+	s := newFixtureStore(t)
+	s.PageRows = 2
+	opts := NewFilterOpts().WithStartDate(date(t, "2024-01-01"))
+
+	maxPage, err := s.GetRecordsFilterMaxPage(opts)
+	mustNil(t, err)
+	if maxPage != 2 {
+		t.Errorf("got max page %d, want 2", maxPage)
+	}
+	var ids []int
+	for page := range 3 {
+		recs, err := s.GetRecordsFilterPage(opts, SortOpts{}, page)
+		mustNil(t, err)
+		ids = append(ids, recordIds(recs)...)
+	}
+	if !equalInts(ids, []int{6, 5, 4, 3, 2, 1}) {
+		t.Errorf("got records %v, want newest first [6 5 4 3 2 1]", ids)
+	}
+
+	// search positions are among the filtered records
+	positions, err := s.SearchRecords(opts.WithCatId([]int{1}), SortOpts{}, "pay")
+	mustNil(t, err)
+	if !equalInts(positions, []int{0, 1}) {
+		t.Errorf("got positions %v, want [0 1]", positions)
+	}
+}
+
+func TestSortedPages(t *testing.T) {
+	s := newFixtureStore(t)
+	s.PageRows = 3
+	mustNil(t, s.InsertInvestment(Investment{Date: date(t, "2024-01-01"), Code: "VGS.AX", Unitprice: 5000, Qty: 10}))
+	mustNil(t, s.InsertInvestment(Investment{Date: date(t, "2024-02-01"), Code: "IVV", Unitprice: 10000, Qty: 2}))
+
+	tests := []struct {
+		name string
+		sort SortOpts
+		want []int
+	}{
+		{"amount", SortBy(5, false), []int{3, 5, 2, 7, 6, 4, 1}},
+		{"amount descending", SortBy(5, true), []int{4, 1, 6, 7, 2, 5, 3}},
+		{"category then newest", SortBy(2, false), []int{5, 2, 7, 6, 3, 4, 1}},
+		{"description", SortBy(4, false), []int{2, 7, 4, 1, 6, 3, 5}},
+		{"unknown column", SortBy(20, false), []int{6, 5, 4, 3, 2, 1, 7}},
+	}
+	for _, tt := range tests {
+		var ids []int
+		for page := range 3 {
+			recs, err := s.GetRecordsFilterPage(NewFilterOpts(), tt.sort, page)
+			mustNil(t, err)
+			ids = append(ids, recordIds(recs)...)
+		}
+		if !equalInts(ids, tt.want) {
+			t.Errorf("%s: got records %v, want %v", tt.name, ids, tt.want)
+		}
+	}
+
+	// search positions follow the sort
+	positions, err := s.SearchRecords(NewFilterOpts(), SortBy(5, false), "pay")
+	mustNil(t, err)
+	if !equalInts(positions, []int{5, 6}) {
+		t.Errorf("got positions %v, want [5 6]", positions)
+	}
+
+	invs, err := s.GetInvestmentsFilterPage(NewFilterOpts(), SortBy(5, true), 0)
+	mustNil(t, err)
+	if len(invs) != 2 || invs[0].(Investment).Code != "VGS.AX" {
+		t.Errorf("got investments %+v, want the biggest first", invs)
+	}
+}
+
+func TestCreateDummyDataDeterministic(t *testing.T) {
+	sum := func(s *Store) float32 {
+		income, err := s.GetIncomeSum(date(t, "2000-01-01"), date(t, "3000-01-01"))
+		mustNil(t, err)
+		return income
+	}
+	a, b := newDummyStore(t), newDummyStore(t)
+	if sum(a) != sum(b) {
+		t.Errorf("same seed generated different data: %.0f vs %.0f", sum(a), sum(b))
+	}
+}
+
+func TestGetInvestmentSummary(t *testing.T) {
+	s := newTestStore(t)
+	for _, inv := range []Investment{
+		{Date: date(t, "2024-01-01"), Code: "IVV", Unitprice: 10000, Qty: 2},
+		{Date: date(t, "2024-02-01"), Code: "IVV", Unitprice: 13000, Qty: 1},
+		{Date: date(t, "2024-02-01"), Code: "VGS.AX", Unitprice: 5000, Qty: 10},
+	} {
+		mustNil(t, s.InsertInvestment(inv))
+	}
+
+	fetches := 0
+	s.FetchPrice = func(code string) (float32, error) {
+		fetches++
+		return map[string]float32{"IVV": 150, "VGS.AX": 40}[code], nil
+	}
+
+	rows, err := s.GetInvestmentSummary(SortOpts{})
+	mustNil(t, err)
+	if len(rows) != 4 { // 2 codes, separator, total
+		t.Fatalf("got %d rows, want 4", len(rows))
+	}
+	ivv := rows[0].(InvSummaryRow)
+	if ivv.code != "IVV" || ivv.qty != 3 || ivv.avgBuy != 11000 || ivv.curPrice != 150 {
+		t.Errorf("unexpected IVV row %+v", ivv)
+	}
+
+	// prices are cached for a day
+	rows, err = s.GetInvestmentSummary(SortBy(6, false))
+	mustNil(t, err)
+	if fetches != 2 {
+		t.Errorf("fetched prices %d times, want 2", fetches)
+	}
+	// sorted by P/L, the totals staying last
+	if code := rows[0].(InvSummaryRow).code; code != "VGS.AX" || rows[3].(InvSummaryRow).code != "total" {
+		t.Errorf("got %s first by P/L, want VGS.AX", code)
+	}
+}
+
+func TestGetInvestmentSummaryNetworkError(t *testing.T) {
+	s := newTestStore(t)
+	mustNil(t, s.InsertInvestment(Investment{Date: date(t, "2024-01-01"), Code: "IVV", Unitprice: 10000, Qty: 2}))
+	s.FetchPrice = func(code string) (float32, error) { return 0, ErrNetwork }
+
+	if _, err := s.GetInvestmentSummary(SortOpts{}); !errors.Is(err, ErrNetwork) {
+		t.Errorf("got %v, want ErrNetwork", err)
+	}
+}
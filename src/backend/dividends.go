@@ -0,0 +1,197 @@
+package backend
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+func checkDividend(d Dividend) error {
+	switch {
+	case strings.TrimSpace(d.Code) == "":
+		return fmt.Errorf("%w: a dividend needs a stock code", ErrConstraint)
+	case d.Amt < 0 || d.Franking < 0 || d.Withholding < 0:
+		return fmt.Errorf("%w: dividend amounts can't be negative", ErrConstraint)
+	case d.Gross() == 0:
+		return fmt.Errorf("%w: a dividend needs a cash amount or tax withheld", ErrConstraint)
+	case d.Reinvested && (d.Amt == 0 || d.DrpPrice <= 0):
+		return fmt.Errorf("%w: a reinvested dividend needs a cash amount and a positive DRP price", ErrConstraint)
+	}
+	return nil
+}
+
+/* The buy of the units a reinvested dividend was paid as */
+func (d Dividend) drpLot() Investment {
+	return Investment{Date: d.Date, Code: d.Code, Unitprice: d.DrpPrice, Qty: float32(d.Amt) / float32(d.DrpPrice), Method: FIFO}
+}
+
+/* Returns every dividend, newest first */
+func (s *Store) GetDividends() ([]DataRow, error) {
+	divs, err := s.getDividends("")
+	if err != nil {
+		return nil, err
+	}
+	res := make([]DataRow, len(divs))
+	for i, d := range divs {
+		res[i] = d
+	}
+	return res, nil
+}
+
+func (s *Store) GetDividend(id int) (Dividend, error) {
+	divs, err := s.getDividends("WHERE div_id = ?", id)
+	if err != nil {
+		return Dividend{}, err
+	}
+	if len(divs) == 0 {
+		return Dividend{}, fmt.Errorf("dividend %d: %w", id, ErrNotFound)
+	}
+	return divs[0], nil
+}
+
+func (s *Store) getDividends(where string, args ...any) ([]Dividend, error) {
+	rows, err := s.db.Query(`SELECT div_id, div_date, div_code, div_amt, div_franking, div_withholding,
+                                  inv_id IS NOT NULL, IFNULL(inv_unitprice, 0), IFNULL(inv_qty, 0), IFNULL(inv_id, 0)
+                           FROM dividend LEFT JOIN investment USING (inv_id)
+                           `+where+`
+                           ORDER BY div_date DESC, div_id DESC`, args...)
+	if err != nil {
+		return nil, dbError(err)
+	}
+	defer rows.Close()
+
+	var divs []Dividend
+	for rows.Next() {
+		var d Dividend
+		err := rows.Scan(&d.Id, &d.Date, &d.Code, &d.Amt, &d.Franking, &d.Withholding, &d.Reinvested, &d.DrpPrice, &d.DrpQty, &d.InvId)
+		if err != nil {
+			return nil, dbError(err)
+		}
+		divs = append(divs, d)
+	}
+	return divs, dbError(rows.Err())
+}
+
+/* Inserts the lot of a reinvested dividend in tx, returning its id */
+func insertDrpLot(tx *sql.Tx, d Dividend) (int64, error) {
+	lot := d.drpLot()
+	res, err := tx.Exec("INSERT INTO investment (inv_date, inv_code, inv_unitprice, inv_qty, inv_method) VALUES (?,?,?,?,?)",
+		lot.Date, lot.Code, lot.Unitprice, lot.Qty, lot.Method)
+	if err != nil {
+		return 0, dbError(err)
+	}
+	return res.LastInsertId()
+}
+
+/* Inserts a dividend, buying its units as a new lot if it was reinvested */
+func (s *Store) InsertDividend(d Dividend) error {
+	if err := checkDividend(d); err != nil {
+		return fmt.Errorf("failed to insert dividend: %w", err)
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return dbError(err)
+	}
+	var invId sql.NullInt64
+	if d.Reinvested {
+		invId.Int64, err = insertDrpLot(tx, d)
+		invId.Valid = true
+	}
+	if err == nil {
+		_, err = tx.Exec("INSERT INTO dividend (div_date, div_code, div_amt, div_franking, div_withholding, inv_id) VALUES (?,?,?,?,?,?)",
+			d.Date, d.Code, d.Amt, d.Franking, d.Withholding, invId)
+	}
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to insert dividend: %w", dbError(err))
+	}
+	return dbError(tx.Commit())
+}
+
+/*
+Updates a dividend and its lot: reinvesting it buys a lot, and no longer
+reinvesting it deletes the lot. Fails with ErrConstraint if the lot's units
+have been sold and the change leaves a sell of units which aren't held.
+*/
+func (s *Store) UpdateDividend(id int, d Dividend) error {
+	if err := checkDividend(d); err != nil {
+		return fmt.Errorf("failed to update dividend %d: %w", id, err)
+	}
+	old, err := s.GetDividend(id)
+	if err != nil {
+		return fmt.Errorf("failed to update dividend %d: %w", id, err)
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return dbError(err)
+	}
+
+	invId := sql.NullInt64{Int64: int64(old.InvId), Valid: old.Reinvested}
+	switch {
+	case d.Reinvested && old.Reinvested:
+		lot := d.drpLot()
+		err = checkAffected(tx.Exec("UPDATE investment SET inv_date = ?, inv_code = ?, inv_unitprice = ?, inv_qty = ? WHERE inv_id = ?",
+			lot.Date, lot.Code, lot.Unitprice, lot.Qty, old.InvId))
+	case d.Reinvested:
+		invId.Int64, err = insertDrpLot(tx, d)
+		invId.Valid = true
+	case old.Reinvested:
+		invId.Valid = false
+	}
+	if err == nil {
+		err = checkAffected(tx.Exec("UPDATE dividend SET div_date = ?, div_code = ?, div_amt = ?, div_franking = ?, div_withholding = ?, inv_id = ? WHERE div_id = ?",
+			d.Date, d.Code, d.Amt, d.Franking, d.Withholding, invId, id))
+	}
+	if err == nil && old.Reinvested && !d.Reinvested {
+		err = deleteInvestment(tx, old.InvId)
+	}
+	if err == nil {
+		err = checkLots(tx)
+	}
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to update dividend %d: %w", id, dbError(err))
+	}
+	return dbError(tx.Commit())
+}
+
+/* Deletes a dividend and the lot it was reinvested in, failing with ErrConstraint if the lot's units have been sold */
+func (s *Store) DeleteDividend(id int) error {
+	d, err := s.GetDividend(id)
+	if err != nil {
+		return fmt.Errorf("failed to delete dividend %d: %w", id, err)
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return dbError(err)
+	}
+	err = checkAffected(tx.Exec("DELETE FROM dividend WHERE div_id = ?", id))
+	if err == nil && d.Reinvested {
+		err = deleteInvestment(tx, d.InvId)
+	}
+	if err == nil {
+		err = checkLots(tx)
+	}
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete dividend %d: %w", id, dbError(err))
+	}
+	return dbError(tx.Commit())
+}
+
+/* Returns an error wrapping ErrConstraint if an investment is the lot of a reinvested dividend, which is changed through the dividend */
+func checkNotDrpLot(q querier, id int) error {
+	rows, err := q.Query("SELECT div_id FROM dividend WHERE inv_id = ?", id)
+	if err != nil {
+		return dbError(err)
+	}
+	defer rows.Close()
+	if rows.Next() {
+		var divId int
+		if err := rows.Scan(&divId); err != nil {
+			return dbError(err)
+		}
+		return fmt.Errorf("%w: it was bought by reinvesting dividend %d, change the dividend instead", ErrConstraint, divId)
+	}
+	return dbError(rows.Err())
+}
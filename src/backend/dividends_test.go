@@ -0,0 +1,100 @@
+package backend
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReinvestedDividend(t *testing.T) {
+	s := newLotStore(t)
+	// $120 reinvested at $150 a unit, with $30 franking credits
+	d := Dividend{Date: date(t, "2024-04-01"), Code: "IVV", Amt: 12000, Franking: 3000, Reinvested: true, DrpPrice: 15000}
+	mustNil(t, s.InsertDividend(d))
+
+	got, err := s.GetDividend(1)
+	mustNil(t, err)
+	if !got.Reinvested || got.InvId != 4 || got.DrpPrice != 15000 || got.DrpQty != 0.8 {
+		t.Errorf("unexpected dividend %+v", got)
+	}
+	lot, err := s.GetInvestment(4)
+	mustNil(t, err)
+	if lot.Code != "IVV" || lot.Qty != 0.8 || lot.Unitprice != 15000 {
+		t.Errorf("unexpected DRP lot %+v", lot)
+	}
+
+	// the lot is changed through the dividend
+	if err := s.DeleteInvestment(4); !errors.Is(err, ErrConstraint) {
+		t.Errorf("deleting the DRP lot: got %v, want ErrConstraint", err)
+	}
+	if err := s.UpdateInvestment(4, lot); !errors.Is(err, ErrConstraint) {
+		t.Errorf("editing the DRP lot: got %v, want ErrConstraint", err)
+	}
+	d.Amt = 30000
+	mustNil(t, s.UpdateDividend(1, d))
+	if lot, err = s.GetInvestment(4); err != nil || lot.Qty != 2 {
+		t.Errorf("got lot %+v (%v) after updating the dividend, want 2 units", lot, err)
+	}
+
+	// once its units are sold, the lot can't be removed
+	mustNil(t, s.InsertInvestment(Investment{Date: date(t, "2024-05-01"), Code: "IVV", Unitprice: 20000, Qty: -10}))
+	d.Reinvested = false
+	if err := s.UpdateDividend(1, d); !errors.Is(err, ErrConstraint) {
+		t.Errorf("no longer reinvesting a sold lot: got %v, want ErrConstraint", err)
+	}
+	if err := s.DeleteDividend(1); !errors.Is(err, ErrConstraint) {
+		t.Errorf("deleting a dividend with a sold lot: got %v, want ErrConstraint", err)
+	}
+
+	mustNil(t, s.DeleteInvestment(5))
+	mustNil(t, s.UpdateDividend(1, d))
+	if _, err := s.GetInvestment(4); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v for the lot of a dividend no longer reinvested, want ErrNotFound", err)
+	}
+	mustNil(t, s.DeleteDividend(1))
+	if divs, err := s.GetDividends(); err != nil || len(divs) != 0 {
+		t.Errorf("got dividends %v (%v), want none", divs, err)
+	}
+}
+
+func TestInsertDividendInvalid(t *testing.T) {
+	s := newTestStore(t)
+	tests := []struct {
+		name string
+		d    Dividend
+	}{
+		{"no code", Dividend{Date: date(t, "2024-04-01"), Amt: 100}},
+		{"nothing paid", Dividend{Date: date(t, "2024-04-01"), Code: "IVV", Franking: 100}},
+		{"negative", Dividend{Date: date(t, "2024-04-01"), Code: "IVV", Amt: 100, Withholding: -10}},
+		{"no DRP price", Dividend{Date: date(t, "2024-04-01"), Code: "IVV", Amt: 100, Reinvested: true}},
+	}
+	for _, tt := range tests {
+		if err := s.InsertDividend(tt.d); !errors.Is(err, ErrConstraint) {
+			t.Errorf("%s: got %v, want ErrConstraint", tt.name, err)
+		}
+	}
+}
+
+func TestSummaryDividends(t *testing.T) {
+	s := newTestStore(t)
+	mustNil(t, s.InsertInvestment(Investment{Date: date(t, "2020-01-01"), Code: "IVV", Unitprice: 10000, Qty: 10}))
+	for _, d := range []Dividend{
+		{Date: time.Now().AddDate(0, -3, 0), Code: "IVV", Amt: 4000, Withholding: 1000, Franking: 500},
+		{Date: time.Now().AddDate(0, -9, 0), Code: "IVV", Amt: 5000},
+		{Date: time.Now().AddDate(-2, 0, 0), Code: "IVV", Amt: 10000}, // not in the yield
+	} {
+		mustNil(t, s.InsertDividend(d))
+	}
+	s.FetchPrice = func(code string) (float32, error) { return 100, nil }
+
+	rows, err := s.GetInvestmentSummary(SortOpts{})
+	mustNil(t, err)
+	ivv := rows[0].(InvSummaryRow)
+	// $100 paid over the last year on $1000 held
+	if ivv.dividends != 20000 || ivv.yield() != 10 || ivv.totalReturn() != 200 {
+		t.Errorf("got dividends %d, yield %g, total return %g, want 20000, 10, 200", ivv.dividends, ivv.yield(), ivv.totalReturn())
+	}
+	if strs := rows[2].SpreadToStrings(); len(strs) != 13 || strs[10] != "10.00%" {
+		t.Errorf("unexpected total row %q", strs)
+	}
+}
@@ -0,0 +1,49 @@
+package backend
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// sentinel errors, check for these using errors.Is
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrConstraint = errors.New("constraint violation")
+	ErrNetwork    = errors.New("network error")
+)
+
+/*
+Wraps an error returned by the database driver with the matching sentinel
+error (if any), so callers don't need to know about sqlite error codes.
+*/
+func dbError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrConstraint {
+		return fmt.Errorf("%w: %w", ErrConstraint, err)
+	}
+	return err
+}
+
+/* Returns ErrNotFound if an UPDATE/DELETE didn't touch any rows */
+func checkAffected(res sql.Result, err error) error {
+	if err != nil {
+		return dbError(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
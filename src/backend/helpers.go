@@ -24,9 +24,45 @@ func getMonthStartAndEnd(t time.Time) (time.Time, time.Time) {
 	return mStart, mEnd
 }
 
+/*
+Returns the start of the Australian financial year ending 30 June in endYear,
+and the start of the next one, e.g. FinancialYear(2026) covers 1 Jul 2025 - 30 Jun 2026.
+*/
+func FinancialYear(endYear int) (time.Time, time.Time) {
+	return time.Date(endYear-1, time.July, 1, 0, 0, 0, 0, time.UTC), time.Date(endYear, time.July, 1, 0, 0, 0, 0, time.UTC)
+}
+
+/* Returns the year in which the financial year containing t ends */
+func FinancialYearOf(t time.Time) int {
+	if t.Month() >= time.July {
+		return t.Year() + 1
+	}
+	return t.Year()
+}
+
 // returns a string right-aligned, with '  $amt.xx' format
 func rightAlign(amt float32, decimals, width int, prefix string) string {
 	fmtStr1 := fmt.Sprintf("%%%ds", width)
 	fmtStr2 := fmt.Sprintf("%s%%.%df", prefix, decimals)
 	return fmt.Sprintf(fmtStr1, fmt.Sprintf(fmtStr2, amt))
 }
+
+/* Returns the display name of a category, or a label for the special (id <= 0) categories */
+func categoryLabel(catId int, name string) string {
+	switch catId {
+	case 0:
+		return "[orange::b:]Net Change"
+	case -1:
+		return "(deleted)"
+	case -2:
+		return ""
+	case -3:
+		return "[orange]Total Income"
+	case -4:
+		return "[orange]Total Expenditure"
+	case -5:
+		return "Transfer"
+	default:
+		return name
+	}
+}
@@ -0,0 +1,135 @@
+package backend
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// description columns are stored as a comma separated list, e.g. "3,4"
+func joinCols(cols []int) string {
+	strs := make([]string, len(cols))
+	for i, c := range cols {
+		strs[i] = strconv.Itoa(c)
+	}
+	return strings.Join(strs, ",")
+}
+
+func splitCols(s string) ([]int, error) {
+	var cols []int
+	for _, c := range strings.Split(s, ",") {
+		if strings.TrimSpace(c) == "" {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(c))
+		if err != nil {
+			return nil, fmt.Errorf("invalid column number %q", c)
+		}
+		cols = append(cols, n)
+	}
+	return cols, nil
+}
+
+func dbRowsToImportProfiles(rows *sql.Rows) ([]DataRow, error) {
+	var profiles []DataRow
+
+	for rows.Next() {
+		var p ImportProfile
+		var descCols string
+		if err := rows.Scan(&p.Id, &p.Name, &p.Delimiter, &p.SkipRows, &p.DateCol, &p.DateFormat,
+			&descCols, &p.AmtCol, &p.DebitCol, &p.Negate); err != nil {
+			return nil, dbError(err)
+		}
+		cols, err := splitCols(descCols)
+		if err != nil {
+			return nil, err
+		}
+		p.DescCols = cols
+		profiles = append(profiles, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err)
+	}
+	return profiles, nil
+}
+
+const importProfileCols = `ip_id, ip_name, ip_delimiter, ip_skip_rows, ip_date_col, ip_date_fmt,
+                           ip_desc_cols, ip_amt_col, ip_debit_col, ip_negate`
+
+/* Returns all saved CSV import profiles, sorted by name */
+func (s *Store) GetImportProfiles() ([]DataRow, error) {
+	rows, err := s.db.Query("SELECT " + importProfileCols + " FROM import_profile ORDER BY ip_name")
+	if err != nil {
+		return nil, dbError(err)
+	}
+	defer rows.Close()
+
+	return dbRowsToImportProfiles(rows)
+}
+
+func (s *Store) GetImportProfile(name string) (ImportProfile, error) {
+	rows, err := s.db.Query("SELECT "+importProfileCols+" FROM import_profile WHERE ip_name = ?", name)
+	if err != nil {
+		return ImportProfile{}, dbError(err)
+	}
+	defer rows.Close()
+
+	profiles, err := dbRowsToImportProfiles(rows)
+	if err != nil {
+		return ImportProfile{}, err
+	}
+	if len(profiles) == 0 {
+		return ImportProfile{}, fmt.Errorf("import profile %q: %w", name, ErrNotFound)
+	}
+	return profiles[0].(ImportProfile), nil
+}
+
+func (s *Store) InsertImportProfile(p ImportProfile) error {
+	_, err := s.db.Exec(`INSERT INTO import_profile (ip_name, ip_delimiter, ip_skip_rows, ip_date_col, ip_date_fmt,
+                                                   ip_desc_cols, ip_amt_col, ip_debit_col, ip_negate)
+                       VALUES (?,?,?,?,?,?,?,?,?)`,
+		p.Name, p.Delimiter, p.SkipRows, p.DateCol, p.DateFormat, joinCols(p.DescCols), p.AmtCol, p.DebitCol, p.Negate)
+	if err != nil {
+		return fmt.Errorf("failed to insert import profile: %w", dbError(err))
+	}
+	return nil
+}
+
+func (s *Store) UpdateImportProfile(id int, p ImportProfile) error {
+	err := checkAffected(s.db.Exec(`UPDATE import_profile
+                                  SET ip_name = ?, ip_delimiter = ?, ip_skip_rows = ?, ip_date_col = ?, ip_date_fmt = ?,
+                                      ip_desc_cols = ?, ip_amt_col = ?, ip_debit_col = ?, ip_negate = ?
+                                  WHERE ip_id = ?`,
+		p.Name, p.Delimiter, p.SkipRows, p.DateCol, p.DateFormat, joinCols(p.DescCols), p.AmtCol, p.DebitCol, p.Negate, id))
+	if err != nil {
+		return fmt.Errorf("failed to update import profile %d: %w", id, err)
+	}
+	return nil
+}
+
+func (s *Store) DeleteImportProfile(id int) error {
+	if err := checkAffected(s.db.Exec("DELETE FROM import_profile WHERE ip_id = ?", id)); err != nil {
+		return fmt.Errorf("failed to delete import profile %d: %w", id, err)
+	}
+	return nil
+}
+
+/*
+Suggests a category for an imported transaction, using the category most often
+given to existing records with the same description. Returns 0 if there are none.
+*/
+func (s *Store) GuessCategory(desc string) (int, error) {
+	var catId int
+	err := s.db.QueryRow(`SELECT cat_id
+                        FROM record
+                        WHERE rec_desc = ? AND cat_id IS NOT NULL
+                        GROUP BY cat_id
+                        ORDER BY COUNT(*) DESC, MAX(rec_date) DESC
+                        LIMIT 1`, desc).Scan(&catId)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return catId, dbError(err)
+}
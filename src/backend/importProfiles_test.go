@@ -0,0 +1,120 @@
+package backend
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestImportProfiles(t *testing.T) {
+	s := newTestStore(t)
+	p := ImportProfile{Name: "CommBank", Delimiter: ",", DateCol: 1, DateFormat: "DD/MM/YYYY", DescCols: []int{3, 4}, AmtCol: 2}
+	mustNil(t, s.InsertImportProfile(p))
+
+	if err := s.InsertImportProfile(p); !errors.Is(err, ErrConstraint) {
+		t.Errorf("duplicate name: got %v, want ErrConstraint", err)
+	}
+
+	got, err := s.GetImportProfile("CommBank")
+	mustNil(t, err)
+	if got.Id != 1 || len(got.DescCols) != 2 || got.DescCols[1] != 4 || got.DateFormat != "DD/MM/YYYY" {
+		t.Errorf("unexpected profile %+v", got)
+	}
+
+	p.Negate = true
+	p.DebitCol = 5
+	mustNil(t, s.UpdateImportProfile(1, p))
+	got, err = s.GetImportProfile("CommBank")
+	mustNil(t, err)
+	if !got.Negate || got.DebitCol != 5 {
+		t.Errorf("profile not updated %+v", got)
+	}
+
+	mustNil(t, s.DeleteImportProfile(1))
+	if _, err := s.GetImportProfile("CommBank"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+}
+
+func TestInsertRecordsIsAtomic(t *testing.T) {
+	s := newFixtureStore(t)
+
+	_, err := s.InsertRecords([]Record{
+		{Date: date(t, "2024-03-01"), Desc: "ok", Amt: -100, CatId: 2},
+		{Date: date(t, "2024-03-02"), Desc: "bad category", Amt: -100, CatId: 99},
+	})
+	if !errors.Is(err, ErrConstraint) {
+		t.Fatalf("got %v, want ErrConstraint", err)
+	}
+	recs, err := s.GetRecordsFilter(NewFilterOpts().WithStartDate(date(t, "2024-03-01")))
+	mustNil(t, err)
+	if len(recs) != 0 {
+		t.Errorf("records inserted by failed import: %+v", recs)
+	}
+
+	n, err := s.InsertRecords([]Record{
+		{Date: date(t, "2024-03-01"), Desc: "a", Amt: -100, CatId: 2},
+		{Date: date(t, "2024-03-02"), Desc: "b", Amt: -100, CatId: 3},
+	})
+	mustNil(t, err)
+	recs, err = s.GetRecordsFilter(NewFilterOpts().WithStartDate(date(t, "2024-03-01")))
+	mustNil(t, err)
+	if n != 2 || len(recs) != 2 {
+		t.Errorf("got %d records (%d reported), want 2", len(recs), n)
+	}
+}
+
+func TestInsertSkipsDuplicateExtIds(t *testing.T) {
+	s := newFixtureStore(t)
+	statement := []Record{
+		{Date: date(t, "2024-03-01"), Desc: "a", Amt: -100, CatId: 2, ExtId: "1001"},
+		{Date: date(t, "2024-03-02"), Desc: "b", Amt: -100, CatId: 3, ExtId: "1002"},
+		{Date: date(t, "2024-03-02"), Desc: "no id", Amt: -100, CatId: 3},
+	}
+	n, err := s.InsertRecords(statement)
+	mustNil(t, err)
+	if n != 3 {
+		t.Errorf("first import: inserted %d, want 3", n)
+	}
+
+	// overlapping statement, only the new transaction and the one without an id are inserted
+	statement = append(statement, Record{Date: date(t, "2024-03-03"), Desc: "c", Amt: -100, CatId: 3, ExtId: "1003"})
+	n, err = s.InsertRecords(statement[1:])
+	mustNil(t, err)
+	if n != 2 {
+		t.Errorf("second import: inserted %d, want 2", n)
+	}
+	exists, err := s.RecordExtIdExists("1003")
+	mustNil(t, err)
+	if !exists {
+		t.Error("RecordExtIdExists(1003) = false")
+	}
+
+	inv := Investment{Date: date(t, "2024-03-01"), Code: "IVV", Unitprice: 50000, Qty: 2, ExtId: "T1"}
+	for i, want := range []int{1, 0} {
+		n, err := s.InsertInvestments([]Investment{inv})
+		mustNil(t, err)
+		if n != want {
+			t.Errorf("investment import %d: inserted %d, want %d", i+1, n, want)
+		}
+	}
+}
+
+func TestGuessCategory(t *testing.T) {
+	s := newFixtureStore(t)
+
+	tests := []struct {
+		desc string
+		want int
+	}{
+		{"pay", 1},
+		{"coles", 2},
+		{"never seen before", 0},
+	}
+	for _, tt := range tests {
+		got, err := s.GuessCategory(tt.desc)
+		mustNil(t, err)
+		if got != tt.want {
+			t.Errorf("GuessCategory(%q) = %d, want %d", tt.desc, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package backend
+
+import (
+	"database/sql"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+/* How a sell is matched to the lots (earlier buys of the same code) it sells units from */
+type LotMethod string
+
+const (
+	FIFO        LotMethod = "fifo"    // oldest lots first
+	LIFO        LotMethod = "lifo"    // newest lots first
+	SpecificLot LotMethod = "lot"     // the lot chosen by the sell's LotId
+	AverageCost LotMethod = "average" // every lot held, in proportion to its units, so each unit sold costs the average
+)
+
+var LotMethods = []LotMethod{FIFO, LIFO, SpecificLot, AverageCost}
+
+func (m LotMethod) String() string {
+	switch m {
+	case FIFO, LIFO:
+		return strings.ToUpper(string(m))
+	case SpecificLot:
+		return "specific lot"
+	case AverageCost:
+		return "average cost"
+	}
+	return string(m)
+}
+
+/* Returns the method named by s, either as stored (e.g. "fifo") or as shown (e.g. "FIFO", "average cost") */
+func ParseLotMethod(s string) (LotMethod, error) {
+	for _, m := range LotMethods {
+		if strings.EqualFold(s, string(m)) || strings.EqualFold(s, m.String()) {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("%w: unknown lot method %q, want fifo, lifo, lot or average", ErrConstraint, s)
+}
+
+/*
+Units of a code bought together, and how many of them are still held after the
+sells matched to them. The code, units and cost are after any corporate actions
+since the buy.
+*/
+type Lot struct {
+	BuyId    int
+	Code     string
+	Date     time.Time
+	Qty      float64 // units bought
+	Held     float64 // units not sold yet
+	UnitCost float64 // cents, including the brokerage paid on the buy
+}
+
+/* Units of a lot sold by a sell, giving a realised gain (or loss) */
+type Disposal struct {
+	SellId   int
+	BuyId    int
+	Code     string
+	Acquired time.Time
+	Sold     time.Time
+	Qty      float64
+	Cost     int // cents, what the units cost when bought, including their share of the buy's brokerage
+	Proceeds int // cents, less their share of the sell's brokerage
+}
+
+func (d Disposal) Gain() int {
+	return d.Proceeds - d.Cost
+}
+
+// quantities are float32 in the database, smaller differences are rounding
+const qtyEpsilon = 1e-4
+
+/*
+Matches each sell to the lots it sells from, using the sell's method. invs must
+be in date order, with investments on the same day in the order they were
+added, and actions in date order. Each action is applied to the lots bought
+before its effective date. Returns the lots with the units still held, and a
+disposal for each lot each sell sold from, or an error wrapping ErrConstraint
+if a sell is of units which aren't held.
+*/
+func matchLots(invs []Investment, actions []CorporateAction) ([]Lot, []Disposal, error) {
+	var lots []*Lot
+	byId := map[int]*Lot{}
+	var disposals []Disposal
+
+	// applies the actions effective on or before a day to the lots bought before them
+	next := 0
+	applyActions := func(day string) {
+		for ; next < len(actions) && dayOf(actions[next].Date) <= day; next++ {
+			a := actions[next]
+			for _, l := range lots {
+				if l.Code != a.Code {
+					continue
+				}
+				if a.Type == ActionRename {
+					l.Code = a.NewCode
+				} else {
+					l.Qty, l.Held, l.UnitCost = l.Qty*a.Ratio(), l.Held*a.Ratio(), l.UnitCost/a.Ratio()
+				}
+			}
+		}
+	}
+
+	for _, inv := range invs {
+		applyActions(dayOf(inv.Date))
+		if !inv.IsSell() {
+			unitCost := float64(inv.Unitprice)
+			if inv.Qty > 0 {
+				unitCost += float64(inv.Fee) / float64(inv.Qty)
+			}
+			lot := &Lot{BuyId: inv.Id, Code: inv.Code, Date: inv.Date, Qty: float64(inv.Qty), Held: float64(inv.Qty), UnitCost: unitCost}
+			lots = append(lots, lot)
+			byId[inv.Id] = lot
+			continue
+		}
+
+		sold := -float64(inv.Qty)
+		fail := func(format string, args ...any) ([]Lot, []Disposal, error) {
+			prefix := fmt.Sprintf("selling %g %s on %s", sold, inv.Code, inv.Date.Format("2006-01-02"))
+			return nil, nil, fmt.Errorf("%w: %s%s", ErrConstraint, prefix, fmt.Sprintf(format, args...))
+		}
+
+		// the lots of the code still held, oldest first
+		var open []*Lot
+		held := 0.0
+		for _, l := range lots {
+			if l.Code == inv.Code && l.Held > qtyEpsilon {
+				open = append(open, l)
+				held += l.Held
+			}
+		}
+		if sold > held+qtyEpsilon {
+			return fail(", but only %g are held", held)
+		}
+
+		// units sold from each lot
+		taken := make([]float64, len(open))
+		switch inv.Method {
+		case FIFO, LIFO, "":
+			if inv.Method == LIFO {
+				slices.Reverse(open)
+			}
+			left := sold
+			for i, l := range open {
+				taken[i] = min(l.Held, left)
+				left -= taken[i]
+			}
+		case SpecificLot:
+			lot := byId[inv.LotId]
+			if lot == nil || lot.Code != inv.Code {
+				return fail(" from lot %d, which isn't an earlier buy of %s", inv.LotId, inv.Code)
+			} else if sold > lot.Held+qtyEpsilon {
+				return fail(" from lot %d, but only %g of it are held", inv.LotId, lot.Held)
+			}
+			open, taken = []*Lot{lot}, []float64{sold}
+		case AverageCost:
+			for i, l := range open {
+				taken[i] = l.Held * sold / held
+			}
+		default:
+			return fail(" with an unknown lot method %q", inv.Method)
+		}
+
+		// the proceeds after brokerage are shared between the lots, with any rounding in the last one
+		unitProceeds := float64(inv.Unitprice) - float64(inv.Fee)/sold
+		proceeds := int(math.Round(sold*float64(inv.Unitprice))) - inv.Fee
+		var ds []Disposal
+		for i, l := range open {
+			if taken[i] <= 0 {
+				continue
+			}
+			l.Held = max(0, l.Held-taken[i])
+			ds = append(ds, Disposal{
+				SellId: inv.Id, BuyId: l.BuyId, Code: inv.Code, Acquired: l.Date, Sold: inv.Date, Qty: taken[i],
+				Cost:     int(math.Round(taken[i] * l.UnitCost)),
+				Proceeds: int(math.Round(taken[i] * unitProceeds)),
+			})
+		}
+		if len(ds) > 0 {
+			for _, d := range ds[:len(ds)-1] {
+				proceeds -= d.Proceeds
+			}
+			ds[len(ds)-1].Proceeds = proceeds
+		}
+		disposals = append(disposals, ds...)
+	}
+	applyActions(dayOf(time.Now()))
+
+	res := make([]Lot, len(lots))
+	for i, l := range lots {
+		res[i] = *l
+	}
+	return res, disposals, nil
+}
+
+// a connection or transaction to read investments from
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+/*
+Reads every investment in the order matchLots needs: by day, as dates entered
+have no time, then in the order they were added
+*/
+func readInvestments(q querier) ([]Investment, error) {
+	rows, err := q.Query("SELECT " + investmentCols + " FROM investment ORDER BY inv_id")
+	if err != nil {
+		return nil, dbError(err)
+	}
+	defer rows.Close()
+
+	dRows, err := dbRowsToInvestments(rows)
+	if err != nil {
+		return nil, err
+	}
+	invs := make([]Investment, len(dRows))
+	for i, r := range dRows {
+		invs[i] = r.(Investment)
+	}
+	slices.SortStableFunc(invs, func(a, b Investment) int { return strings.Compare(dayOf(a.Date), dayOf(b.Date)) })
+	return invs, nil
+}
+
+func dayOf(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+/* Returns an error wrapping ErrConstraint if a change made in tx leaves a sell of units which aren't held */
+func checkLots(tx *sql.Tx) error {
+	invs, err := readInvestments(tx)
+	if err != nil {
+		return err
+	}
+	actions, err := readActions(tx, "")
+	if err != nil {
+		return err
+	}
+	_, _, err = matchLots(invs, actions)
+	return err
+}
+
+/* Returns an error wrapping ErrConstraint if an investment can't be saved */
+func checkInvestment(inv Investment) error {
+	if inv.Fee < 0 {
+		return fmt.Errorf("%w: brokerage fees can't be negative", ErrConstraint)
+	}
+	return nil
+}
+
+/* Returns the method and lot columns of an investment, sells are FIFO unless they say otherwise */
+func lotColumns(inv Investment) (LotMethod, int) {
+	if !inv.IsSell() || inv.Method == "" {
+		return FIFO, 0
+	} else if inv.Method != SpecificLot {
+		return inv.Method, 0
+	}
+	return inv.Method, inv.LotId
+}
+
+/* Returns the lot of every buy, with the units still held, and the disposals of every sell, oldest first */
+func (s *Store) GetLots() ([]Lot, []Disposal, error) {
+	invs, err := readInvestments(s.db)
+	if err != nil {
+		return nil, nil, err
+	}
+	actions, err := readActions(s.db, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	return matchLots(invs, actions)
+}
+
+/* The brokerage paid on a code's buys and sells over a financial year, in cents */
+type FeeTotal struct {
+	Code   string
+	Trades int // buys and sells with a fee
+	Buys   int
+	Sells  int
+}
+
+func (f FeeTotal) Total() int {
+	return f.Buys + f.Sells
+}
+
+/*
+Returns the brokerage paid on each code in the financial year ending 30 June
+year, by the code the holding has now
+*/
+func (s *Store) GetFees(year int) ([]FeeTotal, error) {
+	start, end := FinancialYear(year)
+	actions, err := readActions(s.db, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fees: %w", err)
+	}
+	rows, err := s.db.Query(`SELECT inv_date, inv_code, inv_qty, inv_fee
+                           FROM investment
+                           WHERE inv_fee > 0 AND inv_date >= ? AND inv_date < ?`, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fees: %w", dbError(err))
+	}
+	defer rows.Close()
+
+	byCode := map[string]*FeeTotal{}
+	for rows.Next() {
+		var inv Investment
+		if err := rows.Scan(&inv.Date, &inv.Code, &inv.Qty, &inv.Fee); err != nil {
+			return nil, fmt.Errorf("failed to get fees: %w", dbError(err))
+		}
+		code := currentCode(actions, inv.Code, dayOf(inv.Date))
+		if byCode[code] == nil {
+			byCode[code] = &FeeTotal{Code: code}
+		}
+		f := byCode[code]
+		f.Trades++
+		if inv.IsSell() {
+			f.Sells += inv.Fee
+		} else {
+			f.Buys += inv.Fee
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get fees: %w", dbError(err))
+	}
+
+	var fees []FeeTotal
+	for _, code := range slices.Sorted(maps.Keys(byCode)) {
+		fees = append(fees, *byCode[code])
+	}
+	return fees, nil
+}
+
+func (s *Store) GetInvestment(id int) (Investment, error) {
+	rows, err := s.db.Query("SELECT "+investmentCols+" FROM investment WHERE inv_id = ?", id)
+	if err != nil {
+		return Investment{}, dbError(err)
+	}
+	defer rows.Close()
+
+	invs, err := dbRowsToInvestments(rows)
+	if err != nil {
+		return Investment{}, err
+	} else if len(invs) == 0 {
+		return Investment{}, fmt.Errorf("investment %d: %w", id, ErrNotFound)
+	}
+	return invs[0].(Investment), nil
+}
@@ -0,0 +1,183 @@
+package backend
+
+import (
+	"errors"
+	"testing"
+)
+
+/* A store with IVV bought as lots 1 (2 at $100), 2 (2 at $130) and 3 (4 at $160) */
+func newLotStore(t *testing.T) *Store {
+	t.Helper()
+	s := newTestStore(t)
+	for _, inv := range []Investment{
+		{Date: date(t, "2024-01-01"), Code: "IVV", Unitprice: 10000, Qty: 2},
+		{Date: date(t, "2024-02-01"), Code: "IVV", Unitprice: 13000, Qty: 2},
+		{Date: date(t, "2024-03-01"), Code: "IVV", Unitprice: 16000, Qty: 4},
+	} {
+		mustNil(t, s.InsertInvestment(inv))
+	}
+	return s
+}
+
+func TestSellMethods(t *testing.T) {
+	tests := []struct {
+		name  string
+		sell  Investment
+		held  []float64 // by lot
+		costs []int     // by disposal
+		gain  int
+	}{
+		{"fifo", Investment{Qty: -3}, []float64{0, 1, 4}, []int{20000, 13000}, 60000 - 33000},
+		{"lifo", Investment{Qty: -3, Method: LIFO}, []float64{2, 2, 1}, []int{48000}, 60000 - 48000},
+		{"specific lot", Investment{Qty: -1, Method: SpecificLot, LotId: 2}, []float64{2, 1, 4}, []int{13000}, 20000 - 13000},
+		// average cost is $140 a unit
+		{"average cost", Investment{Qty: -4, Method: AverageCost}, []float64{1, 1, 2}, []int{10000, 13000, 32000}, 80000 - 55000},
+	}
+	for _, tt := range tests {
+		s := newLotStore(t)
+		sell := tt.sell
+		sell.Date, sell.Code, sell.Unitprice = date(t, "2024-04-01"), "IVV", 20000
+		mustNil(t, s.InsertInvestment(sell))
+
+		lots, disposals, err := s.GetLots()
+		mustNil(t, err)
+		for i, l := range lots {
+			if d := l.Held - tt.held[i]; d > qtyEpsilon || d < -qtyEpsilon {
+				t.Errorf("%s: lot %d has %g held, want %g", tt.name, l.BuyId, l.Held, tt.held[i])
+			}
+		}
+		gain := 0
+		costs := make([]int, len(disposals))
+		for i, d := range disposals {
+			costs[i] = d.Cost
+			gain += d.Gain()
+		}
+		if !equalInts(costs, tt.costs) || gain != tt.gain {
+			t.Errorf("%s: got costs %v and gain %d, want %v and %d", tt.name, costs, gain, tt.costs, tt.gain)
+		}
+	}
+}
+
+func TestOversell(t *testing.T) {
+	s := newLotStore(t)
+	sell := Investment{Date: date(t, "2024-04-01"), Code: "IVV", Unitprice: 20000, Qty: -3, Method: SpecificLot, LotId: 1}
+
+	tests := []struct {
+		name string
+		inv  Investment
+	}{
+		{"more than held", Investment{Date: sell.Date, Code: "IVV", Unitprice: 20000, Qty: -9}},
+		{"before buying", Investment{Date: date(t, "2023-12-01"), Code: "IVV", Unitprice: 20000, Qty: -1}},
+		{"other code", Investment{Date: sell.Date, Code: "VGS.AX", Unitprice: 20000, Qty: -1}},
+		{"more than the lot", sell},
+		{"missing lot", Investment{Date: sell.Date, Code: "IVV", Unitprice: 20000, Qty: -1, Method: SpecificLot, LotId: 9}},
+	}
+	for _, tt := range tests {
+		if err := s.InsertInvestment(tt.inv); !errors.Is(err, ErrConstraint) {
+			t.Errorf("%s: got %v, want ErrConstraint", tt.name, err)
+		}
+	}
+	if _, err := s.InsertInvestments([]Investment{tests[0].inv}); !errors.Is(err, ErrConstraint) {
+		t.Errorf("importing: got %v, want ErrConstraint", err)
+	}
+
+	// changes to the buys can't leave a sell of units which aren't held
+	sell.Qty = -2
+	mustNil(t, s.InsertInvestment(sell))
+	err := s.UpdateInvestment(1, Investment{Date: date(t, "2024-01-01"), Code: "IVV", Unitprice: 10000, Qty: 1})
+	if !errors.Is(err, ErrConstraint) {
+		t.Errorf("shrinking a sold lot: got %v, want ErrConstraint", err)
+	}
+	if err := s.DeleteInvestment(1); !errors.Is(err, ErrConstraint) {
+		t.Errorf("deleting a sold lot: got %v, want ErrConstraint", err)
+	}
+	inv, err := s.GetInvestment(1)
+	mustNil(t, err)
+	if inv.Qty != 2 {
+		t.Errorf("failed update changed the lot to %+v", inv)
+	}
+
+	// the sell can be deleted, then the lot
+	mustNil(t, s.DeleteInvestment(4))
+	mustNil(t, s.DeleteInvestment(1))
+	if _, err := s.GetInvestment(1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+}
+
+func TestSummaryRealised(t *testing.T) {
+	s := newLotStore(t)
+	mustNil(t, s.InsertInvestment(Investment{Date: date(t, "2024-01-01"), Code: "VGS.AX", Unitprice: 5000, Qty: 10}))
+	mustNil(t, s.InsertInvestment(Investment{Date: date(t, "2024-04-01"), Code: "VGS.AX", Unitprice: 4000, Qty: -10}))
+	mustNil(t, s.InsertInvestment(Investment{Date: date(t, "2024-04-01"), Code: "IVV", Unitprice: 20000, Qty: -3}))
+
+	fetched := map[string]bool{}
+	s.FetchPrice = func(code string) (float32, error) {
+		fetched[code] = true
+		return 150, nil
+	}
+	rows, err := s.GetInvestmentSummary(SortOpts{})
+	mustNil(t, err)
+	if len(rows) != 4 {
+		t.Fatalf("got %d rows, want 4", len(rows))
+	}
+
+	ivv, vgs, total := rows[0].(InvSummaryRow), rows[1].(InvSummaryRow), rows[3].(InvSummaryRow)
+	// 1 unit of lot 2 and all of lot 3 are left
+	if ivv.qty != 5 || ivv.avgBuy != 15400 || ivv.realised != 27000 {
+		t.Errorf("unexpected IVV row %+v", ivv)
+	}
+	if vgs.code != "VGS.AX" || vgs.qty != 0 || vgs.realised != -10000 {
+		t.Errorf("unexpected VGS.AX row %+v", vgs)
+	}
+	if total.realised != 17000 {
+		t.Errorf("got total realised %d, want 17000", total.realised)
+	}
+	// nothing is held to price
+	if fetched["VGS.AX"] {
+		t.Error("fetched the price of a code that's been sold")
+	}
+}
+
+func TestBrokerageFees(t *testing.T) {
+	s := newTestStore(t)
+	for _, inv := range []Investment{
+		{Date: date(t, "2024-01-01"), Code: "IVV", Unitprice: 10000, Qty: 2, Fee: 1000}, // $105 a unit
+		{Date: date(t, "2024-08-01"), Code: "IVV", Unitprice: 13000, Qty: 2, Fee: 500},  // $132.50 a unit
+		{Date: date(t, "2024-09-01"), Code: "IVV", Unitprice: 20000, Qty: -3, Fee: 900}, // $591 after brokerage
+	} {
+		mustNil(t, s.InsertInvestment(inv))
+	}
+
+	// the sell's brokerage is shared between the lots by units sold
+	_, disposals, err := s.GetLots()
+	mustNil(t, err)
+	if len(disposals) != 2 || disposals[0].Cost != 21000 || disposals[0].Proceeds != 39400 ||
+		disposals[1].Cost != 13250 || disposals[1].Proceeds != 19700 {
+		t.Errorf("unexpected disposals %+v", disposals)
+	}
+
+	s.FetchPrice = func(code string) (float32, error) { return 100, nil }
+	rows, err := s.GetInvestmentSummary(SortOpts{})
+	mustNil(t, err)
+	if ivv := rows[0].(InvSummaryRow); ivv.avgBuy != 13250 || ivv.realised != 24850 || ivv.fees != 2400 {
+		t.Errorf("got avg buy %d, realised %d, fees %d, want 13250, 24850, 2400", ivv.avgBuy, ivv.realised, ivv.fees)
+	}
+
+	fees, err := s.GetFees(2025)
+	mustNil(t, err)
+	if len(fees) != 1 || fees[0] != (FeeTotal{Code: "IVV", Trades: 2, Buys: 500, Sells: 900}) {
+		t.Errorf("unexpected 2024-25 fees %+v", fees)
+	}
+	if fees, err = s.GetFees(2024); err != nil || len(fees) != 1 || fees[0].Total() != 1000 {
+		t.Errorf("got 2023-24 fees %+v (%v), want $10", fees, err)
+	}
+
+	bad := Investment{Date: date(t, "2024-10-01"), Code: "IVV", Unitprice: 10000, Qty: 1, Fee: -100}
+	if err := s.InsertInvestment(bad); !errors.Is(err, ErrConstraint) {
+		t.Errorf("negative fee: got %v, want ErrConstraint", err)
+	}
+	if err := s.UpdateInvestment(1, bad); !errors.Is(err, ErrConstraint) {
+		t.Errorf("updating to a negative fee: got %v, want ErrConstraint", err)
+	}
+}
@@ -0,0 +1,243 @@
+package backend
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+/*
+A single upgrade step for the database schema.
+Migrations are applied in order, each inside its own transaction, and the
+schema version (PRAGMA user_version) is bumped in the same transaction so a
+failed step leaves the database untouched.
+*/
+type migration struct {
+	desc string
+	up   func(tx *sql.Tx) error
+}
+
+// returns a migration step which executes a fixed block of SQL
+func execMigration(desc, stmts string) migration {
+	return migration{
+		desc: desc,
+		up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(stmts)
+			return err
+		},
+	}
+}
+
+/*
+Ordered list of schema upgrades. migrations[i] upgrades the database from
+version i to version i+1. Never edit or reorder an existing entry once it has
+been released, only append new ones.
+*/
+var migrations = []migration{
+	// version 0 is either a brand new file or a database created before
+	// versioning was introduced, so the tables may or may not exist yet
+	execMigration("initial schema", `
+    CREATE TABLE IF NOT EXISTS category (
+      cat_id       INTEGER     NOT NULL PRIMARY KEY,
+      cat_name     VARCHAR(20) NOT NULL,
+      cat_isincome BOOL        NOT NULL,
+      cat_desc     VARCHAR(40)
+    );
+
+    CREATE TABLE IF NOT EXISTS record (
+      rec_id   INTEGER     NOT NULL  PRIMARY KEY,
+      rec_date DATE        NOT NULL,
+      rec_desc VARCHAR(50) NOT NULL,
+      rec_amt  NUMBER(9)   NOT NULL,
+      cat_id   INTEGER,
+      CONSTRAINT category_record_fk FOREIGN KEY (cat_id) REFERENCES category (cat_id) ON UPDATE CASCADE ON DELETE SET NULL
+    );
+
+    CREATE TABLE IF NOT EXISTS investment (
+      inv_id        INTEGER     NOT NULL  PRIMARY KEY,
+      inv_date      DATE        NOT NULL,
+      inv_code      VARCHAR(10) NOT NULL,
+      inv_qty       NUMBER(7,2) NOT NULL,
+      inv_unitprice NUMBER(8)   NOT NULL
+    );
+
+    CREATE TABLE IF NOT EXISTS stock (
+      st_code         VARCHAR(10) NOT NULL PRIMARY KEY,
+      st_unitprice    NUMBER(8,2) NOT NULL,
+      st_last_updated DATE        NOT NULL
+    );`),
+
+	execMigration("csv import profiles", `
+    CREATE TABLE import_profile (
+      ip_id         INTEGER     NOT NULL PRIMARY KEY,
+      ip_name       VARCHAR(30) NOT NULL UNIQUE,
+      ip_delimiter  CHAR(1)     NOT NULL DEFAULT ',',
+      ip_skip_rows  INTEGER     NOT NULL DEFAULT 0,
+      ip_date_col   INTEGER     NOT NULL,
+      ip_date_fmt   VARCHAR(20) NOT NULL,
+      ip_desc_cols  VARCHAR(20) NOT NULL,
+      ip_amt_col    INTEGER     NOT NULL,
+      ip_debit_col  INTEGER     NOT NULL DEFAULT 0,
+      ip_negate     BOOL        NOT NULL DEFAULT false
+    );`),
+
+	execMigration("statement transaction ids", `
+    ALTER TABLE record ADD COLUMN rec_ext_id VARCHAR(40);
+    ALTER TABLE investment ADD COLUMN inv_ext_id VARCHAR(40);
+    CREATE UNIQUE INDEX record_ext_id ON record (rec_ext_id) WHERE rec_ext_id IS NOT NULL;
+    CREATE UNIQUE INDEX investment_ext_id ON investment (inv_ext_id) WHERE inv_ext_id IS NOT NULL;`),
+
+	// existing records are moved to a default account, accounts with records can't be deleted
+	execMigration("accounts", `
+    CREATE TABLE account (
+      acc_id      INTEGER     NOT NULL PRIMARY KEY,
+      acc_name    VARCHAR(30) NOT NULL UNIQUE,
+      acc_type    VARCHAR(10) NOT NULL,
+      acc_opening NUMBER(9)   NOT NULL DEFAULT 0,
+      acc_desc    VARCHAR(40)
+    );
+    INSERT INTO account (acc_id, acc_name, acc_type, acc_desc) VALUES (1, 'Everyday', 'Bank', 'default account');
+    ALTER TABLE record ADD COLUMN acc_id INTEGER REFERENCES account (acc_id) ON UPDATE CASCADE;
+    UPDATE record SET acc_id = 1;`),
+
+	// each leg of a transfer points to the other, legs have no category
+	execMigration("transfers", `
+    ALTER TABLE record ADD COLUMN rec_xfer_id INTEGER REFERENCES record (rec_id);`),
+
+	// at most one budget per category, deleted with its category
+	execMigration("budgets", `
+    CREATE TABLE budget (
+      bud_id       INTEGER   NOT NULL PRIMARY KEY,
+      cat_id       INTEGER   NOT NULL UNIQUE REFERENCES category (cat_id) ON UPDATE CASCADE ON DELETE CASCADE,
+      bud_amt      NUMBER(9) NOT NULL,
+      bud_yearly   BOOL      NOT NULL DEFAULT false,
+      bud_rollover BOOL      NOT NULL DEFAULT false,
+      bud_start    DATE      NOT NULL
+    );`),
+
+	// rules are deleted with their category, rules without an account use the first account
+	execMigration("recurring rules", `
+    CREATE TABLE recurring (
+      rr_id     INTEGER     NOT NULL PRIMARY KEY,
+      rr_desc   VARCHAR(50) NOT NULL,
+      rr_amt    NUMBER(9)   NOT NULL,
+      cat_id    INTEGER     NOT NULL REFERENCES category (cat_id) ON UPDATE CASCADE ON DELETE CASCADE,
+      acc_id    INTEGER     REFERENCES account (acc_id) ON UPDATE CASCADE ON DELETE SET NULL,
+      rr_freq   VARCHAR(12) NOT NULL,
+      rr_day    INTEGER     NOT NULL DEFAULT 0,
+      rr_start  DATE        NOT NULL,
+      rr_end    DATE,
+      rr_next   DATE        NOT NULL,
+      rr_paused BOOL        NOT NULL DEFAULT false
+    );`),
+
+	// subcategories become top level categories when their parent is deleted
+	execMigration("category parents", `
+    ALTER TABLE category ADD COLUMN cat_parent_id INTEGER REFERENCES category (cat_id) ON UPDATE CASCADE ON DELETE SET NULL;`),
+
+	// a record has any number of tags, links are deleted with the record or tag
+	execMigration("tags", `
+    CREATE TABLE tag (
+      tag_id   INTEGER     NOT NULL PRIMARY KEY,
+      tag_name VARCHAR(30) NOT NULL UNIQUE
+    );
+    CREATE TABLE record_tag (
+      rec_id INTEGER NOT NULL REFERENCES record (rec_id) ON UPDATE CASCADE ON DELETE CASCADE,
+      tag_id INTEGER NOT NULL REFERENCES tag (tag_id) ON UPDATE CASCADE ON DELETE CASCADE,
+      PRIMARY KEY (rec_id, tag_id)
+    );
+    CREATE INDEX record_tag_tag_id ON record_tag (tag_id);`),
+
+	// a split record's lines replace it in category sums, so they're summed from record_line
+	execMigration("split records", `
+    CREATE TABLE record_split (
+      spl_id   INTEGER     NOT NULL PRIMARY KEY,
+      rec_id   INTEGER     NOT NULL REFERENCES record (rec_id) ON UPDATE CASCADE ON DELETE CASCADE,
+      cat_id   INTEGER     REFERENCES category (cat_id) ON UPDATE CASCADE ON DELETE SET NULL,
+      spl_amt  NUMBER(9)   NOT NULL,
+      spl_note VARCHAR(50) NOT NULL DEFAULT ''
+    );
+    CREATE INDEX record_split_rec_id ON record_split (rec_id);
+    CREATE VIEW record_line AS
+      SELECT rec_id, rec_date, cat_id, rec_amt, acc_id
+      FROM record
+      WHERE rec_id NOT IN (SELECT rec_id FROM record_split)
+      UNION ALL
+      SELECT rec_id, rec_date, record_split.cat_id, spl_amt, acc_id
+      FROM record_split JOIN record USING (rec_id);`),
+
+	// sells (negative quantities) are matched to earlier buys of the code, see lots.go
+	execMigration("sell lot matching", `
+    ALTER TABLE investment ADD COLUMN inv_method VARCHAR(7) NOT NULL DEFAULT 'fifo';
+    ALTER TABLE investment ADD COLUMN inv_lot_id INTEGER REFERENCES investment (inv_id) ON UPDATE CASCADE ON DELETE SET NULL;`),
+	execMigration("dividends", `
+    CREATE TABLE dividend (
+      div_id          INTEGER     NOT NULL PRIMARY KEY,
+      div_date        DATE        NOT NULL,
+      div_code        VARCHAR(10) NOT NULL,
+      div_amt         NUMBER(8)   NOT NULL,
+      div_franking    NUMBER(8)   NOT NULL DEFAULT 0,
+      div_withholding NUMBER(8)   NOT NULL DEFAULT 0,
+      inv_id          INTEGER     REFERENCES investment (inv_id) ON UPDATE CASCADE ON DELETE SET NULL
+    );
+    CREATE UNIQUE INDEX dividend_inv_id ON dividend (inv_id) WHERE inv_id IS NOT NULL;`),
+	execMigration("brokerage fees", `
+    ALTER TABLE investment ADD COLUMN inv_fee NUMBER(8) NOT NULL DEFAULT 0;`),
+	// applied to the lots when they're matched, the investments keep the units and codes they were traded in
+	execMigration("corporate actions", `
+    CREATE TABLE corporate_action (
+      ca_id       INTEGER     NOT NULL PRIMARY KEY,
+      ca_date     DATE        NOT NULL,
+      ca_type     VARCHAR(13) NOT NULL,
+      ca_code     VARCHAR(10) NOT NULL,
+      ca_new      INTEGER     NOT NULL DEFAULT 1,
+      ca_old      INTEGER     NOT NULL DEFAULT 1,
+      ca_new_code VARCHAR(10)
+    );`),
+}
+
+/* The schema version this binary expects, i.e. the version after all migrations have run */
+func SchemaVersion() int {
+	return len(migrations)
+}
+
+func getSchemaVersion(db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRow("PRAGMA user_version").Scan(&version)
+	return version, err
+}
+
+/*
+Brings the database up to the latest schema version.
+Returns an error without modifying the file if it was written by a newer
+version of the app, as the schema may contain changes this binary doesn't
+understand.
+*/
+func migrate(db *sql.DB) error {
+	version, err := getSchemaVersion(db)
+	if err != nil {
+		return fmt.Errorf("reading schema version: %w", err)
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("database schema version %d is newer than this app supports (%d), please update finance-tracker", version, len(migrations))
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if err := migrations[i].up(tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migrating to version %d (%s): %w", i+1, migrations[i].desc, err)
+		}
+		// PRAGMA doesn't accept bound parameters
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("setting schema version %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("committing schema version %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package backend
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateNewDatabase(t *testing.T) {
+	s := newTestStore(t)
+	version, err := getSchemaVersion(s.db)
+	mustNil(t, err)
+	if version != SchemaVersion() {
+		t.Errorf("got schema version %d, want %d", version, SchemaVersion())
+	}
+}
+
+func TestMigrateKeepsData(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	s, err := SetupDb(path)
+	mustNil(t, err)
+	mustNil(t, s.CreateDummyData(testSeed))
+	before, err := s.GetRecordsMaxPage()
+	mustNil(t, err)
+	s.Close()
+
+	// reopening an up to date database is a no-op
+	s, err = SetupDb(path)
+	mustNil(t, err)
+	defer s.Close()
+	after, err := s.GetRecordsMaxPage()
+	mustNil(t, err)
+	if before != after {
+		t.Errorf("records changed after reopening: max page %d -> %d", before, after)
+	}
+}
+
+func TestMigrateRefusesNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	db, err := sql.Open("sqlite3", path)
+	mustNil(t, err)
+	_, err = db.Exec("PRAGMA user_version = 9999")
+	mustNil(t, err)
+	db.Close()
+
+	if s, err := SetupDb(path); err == nil {
+		s.Close()
+		t.Error("opened a database written by a newer version")
+	}
+}
+
+func TestMigrateRollsBackFailedStep(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	mustNil(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	saved := migrations
+	defer func() { migrations = saved }()
+	migrations = []migration{
+		execMigration("create", "CREATE TABLE a (x INTEGER)"),
+		execMigration("broken", "CREATE TABLE b (y INTEGER); INSERT INTO missing VALUES (1);"),
+	}
+
+	if err := migrate(db); err == nil {
+		t.Fatal("expected the broken migration to fail")
+	}
+	version, err := getSchemaVersion(db)
+	mustNil(t, err)
+	if version != 1 {
+		t.Errorf("got schema version %d, want 1", version)
+	}
+	if err := db.QueryRow("SELECT name FROM sqlite_master WHERE name = 'b'").Scan(new(string)); err != sql.ErrNoRows {
+		t.Errorf("table from failed migration exists: %v", err)
+	}
+}
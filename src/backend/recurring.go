@@ -0,0 +1,258 @@
+package backend
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+/* Returns the date of a day in a month, or the month's last day if it's shorter */
+func dayOfMonth(year int, month time.Month, day int) time.Time {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	return time.Date(year, month, min(day, last), 0, 0, 0, 0, time.UTC)
+}
+
+/* Returns the last weekday of a month */
+func lastBusinessDay(year int, month time.Month) time.Time {
+	d := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+	for d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+		d = d.AddDate(0, 0, -1)
+	}
+	return d
+}
+
+/* Returns the date of the first record on or after the rule's start */
+func (rr RecurringRule) first() time.Time {
+	var d time.Time
+	switch rr.Freq {
+	case Monthly:
+		d = dayOfMonth(rr.Start.Year(), rr.Start.Month(), rr.Day)
+	case LastBusinessDay:
+		d = lastBusinessDay(rr.Start.Year(), rr.Start.Month())
+	default:
+		return rr.Start
+	}
+	if d.Before(rr.Start) {
+		return rr.after(rr.Start)
+	}
+	return d
+}
+
+/* Returns the date of the record after the one on d */
+func (rr RecurringRule) after(d time.Time) time.Time {
+	switch rr.Freq {
+	case Weekly:
+		return d.AddDate(0, 0, 7)
+	case Fortnightly:
+		return d.AddDate(0, 0, 14)
+	case Monthly:
+		return dayOfMonth(d.Year(), d.Month()+1, rr.Day)
+	case LastBusinessDay:
+		return lastBusinessDay(d.Year(), d.Month()+1)
+	default: // yearly
+		return dayOfMonth(d.Year()+1, rr.Start.Month(), rr.Start.Day())
+	}
+}
+
+/* Returns the date of the first record on or after date */
+func (rr RecurringRule) nextFrom(next, date time.Time) time.Time {
+	for next.Before(date) {
+		next = rr.after(next)
+	}
+	return next
+}
+
+/* Returns the start of the day t falls on, in UTC as dates are stored */
+func toDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func checkRecurringRule(rr RecurringRule) error {
+	valid := false
+	for _, f := range Frequencies {
+		valid = valid || rr.Freq == f
+	}
+	if !valid {
+		return fmt.Errorf("%w: unknown frequency %q", ErrConstraint, rr.Freq)
+	}
+	if rr.Freq == Monthly && (rr.Day < 1 || rr.Day > 31) {
+		return fmt.Errorf("%w: day of the month must be from 1 to 31", ErrConstraint)
+	}
+	if !rr.End.IsZero() && rr.End.Before(rr.Start) {
+		return fmt.Errorf("%w: a rule can't end before it starts", ErrConstraint)
+	}
+	return nil
+}
+
+/* Returns all recurring rules, in the order they were added */
+func (s *Store) GetRecurringRules() ([]DataRow, error) {
+	rules, err := s.getRecurringRules("")
+	if err != nil {
+		return nil, err
+	}
+	res := make([]DataRow, len(rules))
+	for i, rr := range rules {
+		res[i] = rr
+	}
+	return res, nil
+}
+
+func (s *Store) GetRecurringRule(id int) (RecurringRule, error) {
+	rules, err := s.getRecurringRules("WHERE rr_id = ?", id)
+	if err != nil {
+		return RecurringRule{}, err
+	}
+	if len(rules) == 0 {
+		return RecurringRule{}, fmt.Errorf("recurring rule %d: %w", id, ErrNotFound)
+	}
+	return rules[0], nil
+}
+
+func (s *Store) getRecurringRules(where string, args ...any) ([]RecurringRule, error) {
+	rows, err := s.db.Query(`SELECT rr_id, rr_desc, rr_amt, cat_id, cat_name, acc_id,
+                                  COALESCE(acc_name, (SELECT acc_name FROM account ORDER BY acc_id LIMIT 1)),
+                                  rr_freq, rr_day, rr_start, rr_end, rr_next, rr_paused
+                           FROM recurring JOIN category USING (cat_id) LEFT JOIN account USING (acc_id)
+                           `+where+`
+                           ORDER BY rr_id`, args...)
+	if err != nil {
+		return nil, dbError(err)
+	}
+	defer rows.Close()
+
+	var rules []RecurringRule
+	for rows.Next() {
+		var rr RecurringRule
+		var accId sql.NullInt64
+		var accName sql.NullString
+		var end sql.NullTime
+		err := rows.Scan(&rr.Id, &rr.Desc, &rr.Amt, &rr.CatId, &rr.CatName, &accId, &accName,
+			&rr.Freq, &rr.Day, &rr.Start, &end, &rr.Next, &rr.Paused)
+		if err != nil {
+			return nil, dbError(err)
+		}
+		rr.AccId, rr.AccName, rr.End = int(accId.Int64), accName.String, end.Time
+		rules = append(rules, rr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err)
+	}
+	return rules, nil
+}
+
+/* Adds a rule, its first record is created by the next call to MaterialiseRecurring */
+func (s *Store) InsertRecurringRule(rr RecurringRule) error {
+	if err := checkRecurringRule(rr); err != nil {
+		return fmt.Errorf("failed to insert recurring rule: %w", err)
+	}
+	_, err := s.db.Exec(`INSERT INTO recurring (rr_desc, rr_amt, cat_id, acc_id, rr_freq, rr_day, rr_start, rr_end, rr_next, rr_paused)
+                       VALUES (?,?,?,NULLIF(?, 0),?,?,?,?,?,?)`,
+		rr.Desc, rr.Amt, rr.CatId, rr.AccId, rr.Freq, rr.Day, rr.Start, nullTime(rr.End), rr.first(), rr.Paused)
+	if err != nil {
+		return fmt.Errorf("failed to insert recurring rule: %w", dbError(err))
+	}
+	return nil
+}
+
+/*
+Updates a rule. Records it has already created are kept, and aren't created
+again if the rule's schedule changes.
+*/
+func (s *Store) UpdateRecurringRule(id int, rr RecurringRule) error {
+	if err := checkRecurringRule(rr); err != nil {
+		return fmt.Errorf("failed to update recurring rule %d: %w", id, err)
+	}
+	old, err := s.GetRecurringRule(id)
+	if err != nil {
+		return fmt.Errorf("failed to update recurring rule %d: %w", id, err)
+	}
+	// the first date on the new schedule after the records already created
+	next := rr.nextFrom(rr.first(), old.Next)
+	err = checkAffected(s.db.Exec(`UPDATE recurring SET rr_desc = ?, rr_amt = ?, cat_id = ?, acc_id = NULLIF(?, 0), rr_freq = ?,
+                                                     rr_day = ?, rr_start = ?, rr_end = ?, rr_next = ?
+                                 WHERE rr_id = ?`,
+		rr.Desc, rr.Amt, rr.CatId, rr.AccId, rr.Freq, rr.Day, rr.Start, nullTime(rr.End), next, id))
+	if err != nil {
+		return fmt.Errorf("failed to update recurring rule %d: %w", id, err)
+	}
+	return nil
+}
+
+/*
+Pauses or resumes a rule. Records which fell due while a rule was paused are
+skipped when it's resumed on today.
+*/
+func (s *Store) SetRecurringRulePaused(id int, paused bool, today time.Time) error {
+	rr, err := s.GetRecurringRule(id)
+	if err != nil {
+		return fmt.Errorf("failed to pause recurring rule %d: %w", id, err)
+	}
+	next := rr.Next
+	if !paused {
+		next = rr.nextFrom(next, toDate(today))
+	}
+	err = checkAffected(s.db.Exec("UPDATE recurring SET rr_paused = ?, rr_next = ? WHERE rr_id = ?", paused, next, id))
+	if err != nil {
+		return fmt.Errorf("failed to pause recurring rule %d: %w", id, err)
+	}
+	return nil
+}
+
+/* Deletes a rule, keeping the records it created */
+func (s *Store) DeleteRecurringRule(id int) error {
+	if err := checkAffected(s.db.Exec("DELETE FROM recurring WHERE rr_id = ?", id)); err != nil {
+		return fmt.Errorf("failed to delete recurring rule %d: %w", id, err)
+	}
+	return nil
+}
+
+/*
+Creates a record for every date up to and including today on which an active
+rule was due, in a single transaction. Returns the records created, with
+their category and account names set.
+*/
+func (s *Store) MaterialiseRecurring(today time.Time) ([]Record, error) {
+	rules, err := s.getRecurringRules("WHERE NOT rr_paused")
+	if err != nil {
+		return nil, err
+	}
+	today = toDate(today)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, dbError(err)
+	}
+	fail := func(rr RecurringRule, err error) ([]Record, error) {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to create records for recurring rule %d (%s): %w", rr.Id, rr.Desc, dbError(err))
+	}
+
+	var created []Record
+	for _, rr := range rules {
+		next := rr.Next
+		for !next.After(today) && (rr.End.IsZero() || !next.After(rr.End)) {
+			rec := Record{Date: next, Desc: rr.Desc, Amt: rr.Amt, CatId: rr.CatId, CatName: rr.CatName, AccId: rr.AccId, AccName: rr.AccName}
+			_, err := tx.Exec("INSERT INTO record (rec_date, rec_desc, rec_amt, cat_id, acc_id) VALUES (?,?,?,?,"+defaultAccount+")",
+				rec.Date, rec.Desc, rec.Amt, rec.CatId, rec.AccId)
+			if err != nil {
+				return fail(rr, err)
+			}
+			created = append(created, rec)
+			next = rr.after(next)
+		}
+		if !next.Equal(rr.Next) {
+			if _, err := tx.Exec("UPDATE recurring SET rr_next = ? WHERE rr_id = ?", next, rr.Id); err != nil {
+				return fail(rr, err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, dbError(err)
+	}
+	return created, nil
+}
+
+/* Returns NULL for a zero time, so optional dates are stored as NULL */
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
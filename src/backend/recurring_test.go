@@ -0,0 +1,113 @@
+package backend
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRecurringSchedules(t *testing.T) {
+	tests := []struct {
+		name string
+		rule RecurringRule
+		want []string
+	}{
+		{"weekly", RecurringRule{Freq: Weekly, Start: date(t, "2024-01-01")}, []string{"2024-01-01", "2024-01-08", "2024-01-15"}},
+		{"fortnightly", RecurringRule{Freq: Fortnightly, Start: date(t, "2024-01-01")}, []string{"2024-01-01", "2024-01-15", "2024-01-29"}},
+		{"monthly on the 31st", RecurringRule{Freq: Monthly, Day: 31, Start: date(t, "2024-01-15")}, []string{"2024-01-31", "2024-02-29", "2024-03-31"}},
+		{"monthly starting after the day", RecurringRule{Freq: Monthly, Day: 10, Start: date(t, "2024-01-15")}, []string{"2024-02-10", "2024-03-10"}},
+		{"last business day", RecurringRule{Freq: LastBusinessDay, Start: date(t, "2024-03-01")}, []string{"2024-03-29", "2024-04-30", "2024-05-31"}},
+		{"yearly on a leap day", RecurringRule{Freq: Yearly, Start: date(t, "2024-02-29")}, []string{"2024-02-29", "2025-02-28", "2026-02-28"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for d := tt.rule.first(); len(got) < len(tt.want); d = tt.rule.after(d) {
+				got = append(got, d.Format("2006-01-02"))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestMaterialiseRecurring(t *testing.T) {
+	s := newFixtureStore(t)
+	rule := RecurringRule{Desc: "rent", Amt: -150000, CatId: 3, Freq: Monthly, Day: 1, Start: date(t, "2024-03-01"), End: date(t, "2024-05-31")}
+	mustNil(t, s.InsertRecurringRule(rule))
+
+	created, err := s.MaterialiseRecurring(date(t, "2024-04-15"))
+	mustNil(t, err)
+	if len(created) != 2 || created[1].Date.Format("2006-01-02") != "2024-04-01" || created[0].CatName != "Rent" || created[0].AccName != "Everyday" {
+		t.Errorf("unexpected records created %+v", created)
+	}
+
+	// nothing is created twice
+	created, err = s.MaterialiseRecurring(date(t, "2024-04-15"))
+	mustNil(t, err)
+	if len(created) != 0 {
+		t.Errorf("records created twice %+v", created)
+	}
+
+	// nothing after the end date
+	created, err = s.MaterialiseRecurring(date(t, "2024-08-15"))
+	mustNil(t, err)
+	if len(created) != 1 {
+		t.Errorf("got %d records up to the end date, want 1", len(created))
+	}
+	rr, err := s.GetRecurringRule(1)
+	mustNil(t, err)
+	if !rr.Ended() || rr.SpreadToStrings()[8] != "Ended" {
+		t.Errorf("rule hasn't ended %+v", rr)
+	}
+
+	recs, err := s.GetRecordsFilter(NewFilterOpts().WithCatId([]int{3}))
+	mustNil(t, err)
+	if len(recs) != 4 { // and the rent in the fixture
+		t.Errorf("got %d rent records, want 4", len(recs))
+	}
+}
+
+func TestPauseAndEditRecurringRule(t *testing.T) {
+	s := newFixtureStore(t)
+	rule := RecurringRule{Desc: "coffee", Amt: -500, CatId: 2, Freq: Weekly, Start: date(t, "2024-03-04")}
+	mustNil(t, s.InsertRecurringRule(rule))
+	_, err := s.MaterialiseRecurring(date(t, "2024-03-04"))
+	mustNil(t, err)
+
+	mustNil(t, s.SetRecurringRulePaused(1, true, date(t, "2024-03-05")))
+	created, err := s.MaterialiseRecurring(date(t, "2024-04-01"))
+	mustNil(t, err)
+	if len(created) != 0 {
+		t.Errorf("paused rule created records %+v", created)
+	}
+
+	// weeks missed while paused are skipped
+	mustNil(t, s.SetRecurringRulePaused(1, false, date(t, "2024-04-02")))
+	rr, err := s.GetRecurringRule(1)
+	mustNil(t, err)
+	if rr.Paused || rr.Next.Format("2006-01-02") != "2024-04-08" {
+		t.Errorf("unexpected rule after resuming %+v", rr)
+	}
+
+	// changing the schedule doesn't repeat records already created
+	rule.Freq, rule.Day, rule.Amt = Monthly, 1, -2000
+	mustNil(t, s.UpdateRecurringRule(1, rule))
+	rr, err = s.GetRecurringRule(1)
+	mustNil(t, err)
+	if rr.Next.Format("2006-01-02") != "2024-05-01" || rr.Amt != -2000 || rr.Schedule() != "Monthly on day 1" {
+		t.Errorf("unexpected rule after updating %+v", rr)
+	}
+
+	rule.Day = 0
+	if err := s.UpdateRecurringRule(1, rule); !errors.Is(err, ErrConstraint) {
+		t.Errorf("monthly rule without a day: got %v, want ErrConstraint", err)
+	}
+	mustNil(t, s.DeleteRecurringRule(1))
+	if _, err := s.GetRecurringRule(1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,127 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+/*
+The full text index of record descriptions. It's kept outside the migrations as
+sqlite only has FTS5 when built with -tags sqlite_fts5: without it the index's
+triggers are dropped so records can still be changed, and searches fall back to
+LIKE. The index is rebuilt the next time a build with FTS5 opens the database.
+More columns (e.g. payee) can be added by recreating the table.
+*/
+const searchIndex = `
+  CREATE VIRTUAL TABLE IF NOT EXISTS record_fts USING fts5 (rec_desc, content = 'record', content_rowid = 'rec_id');
+  CREATE TRIGGER record_fts_insert AFTER INSERT ON record BEGIN
+    INSERT INTO record_fts (rowid, rec_desc) VALUES (new.rec_id, new.rec_desc);
+  END;
+  CREATE TRIGGER record_fts_delete AFTER DELETE ON record BEGIN
+    INSERT INTO record_fts (record_fts, rowid, rec_desc) VALUES ('delete', old.rec_id, old.rec_desc);
+  END;
+  CREATE TRIGGER record_fts_update AFTER UPDATE OF rec_desc ON record BEGIN
+    INSERT INTO record_fts (record_fts, rowid, rec_desc) VALUES ('delete', old.rec_id, old.rec_desc);
+    INSERT INTO record_fts (rowid, rec_desc) VALUES (new.rec_id, new.rec_desc);
+  END;
+  INSERT INTO record_fts (record_fts) VALUES ('rebuild');`
+
+/* Creates the search index if sqlite has FTS5, or removes its triggers if it doesn't */
+func (s *Store) setupSearch() error {
+	if err := s.db.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&s.fts); err != nil {
+		return dbError(err)
+	}
+	var indexed bool
+	err := s.db.QueryRow("SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'trigger' AND name = 'record_fts_insert')").Scan(&indexed)
+	if err != nil {
+		return dbError(err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return dbError(err)
+	}
+	if s.fts && !indexed {
+		_, err = tx.Exec(searchIndex)
+	} else if !s.fts && indexed {
+		_, err = tx.Exec("DROP TRIGGER record_fts_insert; DROP TRIGGER record_fts_delete; DROP TRIGGER record_fts_update;")
+	}
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to set up the search index: %w", dbError(err))
+	}
+	return dbError(tx.Commit())
+}
+
+/* Returns whether searches use the full text index, false if sqlite was built without FTS5 */
+func (s *Store) FullTextSearch() bool {
+	return s.fts
+}
+
+/*
+Splits a search into lowercase words, ignoring punctuation. A record matches if
+its description has a word starting with each of them.
+*/
+func SearchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+/* Returns a condition on rec_id and rec_desc matching records with every search term, or "" for none */
+func (s *Store) searchCondition(query string) (string, []any) {
+	terms := SearchTerms(query)
+	if len(terms) == 0 {
+		return "", nil
+	}
+	if s.fts {
+		// prefix queries of quoted terms, which can't be mistaken for FTS5 operators
+		match := make([]string, len(terms))
+		for i, t := range terms {
+			match[i] = `"` + t + `"*`
+		}
+		return "rec_id IN (SELECT rowid FROM record_fts WHERE record_fts MATCH ?)", []any{strings.Join(match, " ")}
+	}
+	conds := make([]string, len(terms))
+	var args []any
+	for i, t := range terms {
+		// terms are only letters and digits, so don't need escaping
+		conds[i] = "(rec_desc LIKE ? OR rec_desc LIKE ?)"
+		args = append(args, t+"%", "% "+t+"%")
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+/*
+Returns the positions of the records matching a search among those matching
+opts, in the order of GetRecordsFilterPage (page = position / PageRows), so
+they can be found without paging through every record.
+*/
+func (s *Store) SearchRecords(opts FilterOpts, sort SortOpts, query string) ([]int, error) {
+	cond, args := s.searchCondition(query)
+	if cond == "" {
+		return nil, nil
+	}
+	filter, filterArgs := s.recordFilter(opts)
+	rows, err := s.db.Query(`SELECT pos
+                           FROM (SELECT rec_id, rec_desc, ROW_NUMBER() OVER (ORDER BY `+sort.orderBy(recordSortCols, recentOrder)+`) - 1 AS pos
+                                 FROM record LEFT JOIN category USING (cat_id) LEFT JOIN account USING (acc_id)
+                                 WHERE `+filter+`)
+                           WHERE `+cond+`
+                           ORDER BY pos`, append(filterArgs, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search records: %w", dbError(err))
+	}
+	defer rows.Close()
+
+	var res []int
+	for rows.Next() {
+		var pos int
+		if err := rows.Scan(&pos); err != nil {
+			return nil, dbError(err)
+		}
+		res = append(res, pos)
+	}
+	return res, dbError(rows.Err())
+}
@@ -0,0 +1,61 @@
+package backend
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSearchRecords(t *testing.T) {
+	t.Run("index", func(t *testing.T) {
+		s := newFixtureStore(t)
+		if !s.FullTextSearch() {
+			t.Skip("sqlite was built without FTS5, run with -tags sqlite_fts5")
+		}
+		testSearchRecords(t, s)
+	})
+	// what builds without the sqlite_fts5 tag use
+	t.Run("fallback", func(t *testing.T) {
+		s := newFixtureStore(t)
+		s.fts = false
+		testSearchRecords(t, s)
+	})
+}
+
+func testSearchRecords(t *testing.T, s *Store) {
+
+	// newest first: prize, woolies, pay (4), rent, coles, pay (1), last year
+	positions, err := s.SearchRecords(NewFilterOpts(), SortOpts{}, "PAY")
+	mustNil(t, err)
+	if !slices.Equal(positions, []int{2, 5}) {
+		t.Errorf("got positions %v for pay, want [2 5]", positions)
+	}
+
+	// the index follows inserts, updates and deletes
+	mustNil(t, s.UpdateRecord(2, Record{Date: date(t, "2024-01-15"), Desc: "Coles Sydney", Amt: -4250, CatId: 2}))
+	mustNil(t, s.DeleteRecord(1))
+	mustNil(t, s.InsertRecord(Record{Date: date(t, "2024-03-01"), Desc: "payroll bonus", Amt: 1000, CatId: 1}))
+
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"pay", []int{4, 8}},     // prefixes of words
+		{"syd, coles", []int{2}}, // every word, in any order
+		{"ydney", nil},
+		{"year last", []int{7}},
+		{"!!", []int{7, 2, 3, 4, 5, 6, 8}}, // no words, not filtered
+	}
+	for _, tt := range tests {
+		rows, err := s.GetRecordsFilter(NewFilterOpts().WithText(tt.query))
+		mustNil(t, err)
+		if ids := recordIds(rows); !equalInts(ids, tt.want) {
+			t.Errorf("%q: got records %v, want %v", tt.query, ids, tt.want)
+		}
+	}
+
+	positions, err = s.SearchRecords(NewFilterOpts(), SortOpts{}, "pay")
+	mustNil(t, err)
+	if !slices.Equal(positions, []int{0, 3}) {
+		t.Errorf("got positions %v for pay after changes, want [0 3]", positions)
+	}
+}
@@ -0,0 +1,133 @@
+package backend
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/* Returns an error wrapping ErrConstraint if a record's split lines can't be saved */
+func checkSplits(rec Record) error {
+	if len(rec.Splits) == 0 {
+		return nil
+	}
+	if len(rec.Splits) == 1 {
+		return fmt.Errorf("%w: a split record needs at least two lines", ErrConstraint)
+	}
+	sum := 0
+	for i, spl := range rec.Splits {
+		if spl.CatId <= 0 {
+			return fmt.Errorf("%w: split line %d has no category", ErrConstraint, i+1)
+		}
+		if spl.Amt == 0 {
+			return fmt.Errorf("%w: split line %d has no amount", ErrConstraint, i+1)
+		}
+		sum += spl.Amt
+	}
+	if sum != rec.Amt {
+		return fmt.Errorf("%w: split lines add up to %.2f, not the record's %.2f", ErrConstraint, float64(sum)/100, float64(rec.Amt)/100)
+	}
+	return nil
+}
+
+/* Replaces a record's split lines, splits is empty for a record which isn't split */
+func setRecordSplits(tx *sql.Tx, recId int64, splits []Split) error {
+	if len(splits) > 0 {
+		var isTransfer bool
+		if err := tx.QueryRow("SELECT rec_xfer_id IS NOT NULL FROM record WHERE rec_id = ?", recId).Scan(&isTransfer); err != nil {
+			return dbError(err)
+		}
+		if isTransfer {
+			return fmt.Errorf("%w: transfers can't be split", ErrConstraint)
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM record_split WHERE rec_id = ?", recId); err != nil {
+		return dbError(err)
+	}
+	for _, spl := range splits {
+		_, err := tx.Exec("INSERT INTO record_split (rec_id, cat_id, spl_amt, spl_note) VALUES (?,?,?,?)",
+			recId, spl.CatId, spl.Amt, strings.TrimSpace(spl.Note))
+		if err != nil {
+			return dbError(err)
+		}
+	}
+	return nil
+}
+
+/* Returns the split lines of each of the records which have them, keyed by record id */
+func (s *Store) getSplits(recIds []int) (map[int][]Split, error) {
+	res := map[int][]Split{}
+	if len(recIds) == 0 {
+		return res, nil
+	}
+	// ids are written into the query, there may be more than sqlite allows as parameters
+	ids := make([]string, len(recIds))
+	for i, id := range recIds {
+		ids[i] = strconv.Itoa(id)
+	}
+	rows, err := s.db.Query(`SELECT rec_id, cat_id, cat_name, spl_amt, spl_note
+                           FROM record_split LEFT JOIN category USING (cat_id)
+                           WHERE rec_id IN (` + strings.Join(ids, ",") + `)
+                           ORDER BY rec_id, spl_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get split lines: %w", dbError(err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var recId int
+		var catId sql.NullInt64
+		var catName sql.NullString
+		var spl Split
+		if err := rows.Scan(&recId, &catId, &catName, &spl.Amt, &spl.Note); err != nil {
+			return nil, dbError(err)
+		}
+		spl.CatId, spl.CatName = -1, ""
+		if catId.Valid {
+			spl.CatId, spl.CatName = int(catId.Int64), catName.String
+		}
+		res[recId] = append(res[recId], spl)
+	}
+	return res, dbError(rows.Err())
+}
+
+/*
+Adds the split lines to any split records in rows (Records or LedgerRows). The
+rows must have been read before calling, as the store has a single connection.
+*/
+func (s *Store) attachSplits(rows []DataRow) ([]DataRow, error) {
+	ids := make([]int, 0, len(rows))
+	for _, r := range rows {
+		switch rec := r.(type) {
+		case Record:
+			ids = append(ids, rec.Id)
+		case LedgerRow:
+			ids = append(ids, rec.Id)
+		}
+	}
+	splits, err := s.getSplits(ids)
+	if err != nil || len(splits) == 0 {
+		return rows, err
+	}
+	for i, r := range rows {
+		switch rec := r.(type) {
+		case Record:
+			rec.Splits = splits[rec.Id]
+			rows[i] = rec
+		case LedgerRow:
+			rec.Splits = splits[rec.Id]
+			rows[i] = rec
+		}
+	}
+	return rows, nil
+}
+
+/* Returns a record's split lines, or none if it isn't split */
+func (s *Store) GetRecordSplits(recId int) ([]Split, error) {
+	splits, err := s.getSplits([]int{recId})
+	if err != nil {
+		return nil, err
+	}
+	return splits[recId], nil
+}
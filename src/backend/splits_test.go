@@ -0,0 +1,116 @@
+package backend
+
+import (
+	"errors"
+	"testing"
+)
+
+/* The fixture store with a $100 receipt (id 8) on 20 February split between Groceries and Rent */
+func newSplitStore(t *testing.T) *Store {
+	t.Helper()
+	s := newFixtureStore(t)
+	mustNil(t, s.InsertRecord(Record{Date: date(t, "2024-02-20"), Desc: "receipt", Amt: -10000, Splits: []Split{
+		{CatId: 2, Amt: -6000, Note: "food"},
+		{CatId: 3, Amt: -4000, Note: "bin bags"},
+	}}))
+	return s
+}
+
+func TestSplitRecordSums(t *testing.T) {
+	s := newSplitStore(t)
+	feb, end := date(t, "2024-02-01"), date(t, "2024-02-29")
+
+	if sum, err := s.GetCategorySum(3, feb, end); err != nil || sum != -4000 {
+		t.Errorf("got Rent sum (%.0f, %v), want -4000", sum, err)
+	}
+	_, income, expenditure, err := s.GetMonthInfo(feb)
+	mustNil(t, err)
+	if income != 305000 || expenditure != 16000 {
+		t.Errorf("got income %.0f, expenditure %.0f, want 305000, 16000", income, expenditure)
+	}
+
+	rows, err := s.GetYearSummary(2024)
+	mustNil(t, err)
+	feb2024 := map[int]int{}
+	for _, r := range rows {
+		feb2024[r.(*CategoryYear).CatId] = r.(*CategoryYear).MonthSums[1]
+	}
+	if feb2024[2] != -12000 || feb2024[3] != -4000 || feb2024[-4] != -16000 {
+		t.Errorf("unexpected February sums %v", feb2024)
+	}
+
+	// a split record matches a filter on any of its lines' categories
+	rows, err = s.GetRecordsFilter(NewFilterOpts().WithCatId([]int{3}))
+	mustNil(t, err)
+	if ids := recordIds(rows); !equalInts(ids, []int{3, 8}) {
+		t.Errorf("got records %v in Rent, want [3 8]", ids)
+	}
+	rec := rows[1].(Record)
+	if len(rec.Splits) != 2 || rec.Splits[1].CatName != "Rent" || rec.Splits[1].Note != "bin bags" {
+		t.Errorf("unexpected split lines %+v", rec.Splits)
+	}
+	if label := rec.SpreadToStrings()[2]; label != "Split: Groceries, Rent" {
+		t.Errorf("got category %q", label)
+	}
+}
+
+func TestUpdateSplitRecord(t *testing.T) {
+	s := newSplitStore(t)
+
+	tests := []struct {
+		name   string
+		splits []Split
+	}{
+		{"wrong total", []Split{{CatId: 2, Amt: -6000}, {CatId: 3, Amt: -3000}}},
+		{"single line", []Split{{CatId: 2, Amt: -10000}}},
+		{"no category", []Split{{CatId: 2, Amt: -6000}, {Amt: -4000}}},
+		{"missing category", []Split{{CatId: 2, Amt: -6000}, {CatId: 99, Amt: -4000}}},
+	}
+	for _, tt := range tests {
+		err := s.UpdateRecord(8, Record{Date: date(t, "2024-02-20"), Desc: "receipt", Amt: -10000, Splits: tt.splits})
+		if !errors.Is(err, ErrConstraint) {
+			t.Errorf("%s: got %v, want ErrConstraint", tt.name, err)
+		}
+	}
+	splits, err := s.GetRecordSplits(8)
+	mustNil(t, err)
+	if len(splits) != 2 || splits[0].Amt != -6000 {
+		t.Errorf("failed update changed the split lines %+v", splits)
+	}
+
+	// no lines makes it an ordinary record
+	mustNil(t, s.UpdateRecord(8, Record{Date: date(t, "2024-02-20"), Desc: "receipt", Amt: -10000, CatId: 3}))
+	splits, err = s.GetRecordSplits(8)
+	mustNil(t, err)
+	if sum, _ := s.GetCategorySum(3, date(t, "2024-02-01"), date(t, "2024-02-29")); len(splits) != 0 || sum != -10000 {
+		t.Errorf("got lines %+v and Rent sum %.0f, want none and -10000", splits, sum)
+	}
+
+	// deleting a split line's category leaves the line uncategorised
+	mustNil(t, s.UpdateRecord(8, Record{Date: date(t, "2024-02-20"), Desc: "receipt", Amt: -10000, Splits: []Split{
+		{CatId: 2, Amt: -6000}, {CatId: 3, Amt: -4000},
+	}}))
+	mustNil(t, s.DeleteCategory(3))
+	splits, err = s.GetRecordSplits(8)
+	mustNil(t, err)
+	if len(splits) != 2 || splits[1].CatId != -1 {
+		t.Errorf("unexpected lines after deleting a category %+v", splits)
+	}
+
+	mustNil(t, s.DeleteRecord(8))
+	var n int
+	mustNil(t, s.db.QueryRow("SELECT COUNT(*) FROM record_split").Scan(&n))
+	if n != 0 {
+		t.Errorf("%d split lines left after deleting the record", n)
+	}
+}
+
+func TestTransferCantBeSplit(t *testing.T) {
+	s, outId, _ := newTransferStore(t)
+	err := s.UpdateRecord(outId, Record{Date: date(t, "2024-01-20"), Desc: "pay card", Amt: -20000, Splits: []Split{
+		{CatId: 2, Amt: -10000}, {CatId: 3, Amt: -10000},
+	}})
+	if !errors.Is(err, ErrConstraint) {
+		t.Errorf("got %v, want ErrConstraint", err)
+	}
+}
@@ -0,0 +1,136 @@
+package backend
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// subquery selecting a record's tags separated by spaces, NULL if it has none
+const recordTags = `(SELECT group_concat(tag_name, ' ') FROM record_tag JOIN tag USING (tag_id) WHERE record_tag.rec_id = record.rec_id)`
+
+var tagRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,29}$`)
+
+/* Returns a tag as it is stored, lowercase without a leading '#' */
+func normaliseTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+/* Normalises, sorts and removes duplicates from tags, returning ErrConstraint if any are invalid */
+func normaliseTags(tags []string) ([]string, error) {
+	res := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = normaliseTag(tag)
+		if !tagRegex.MatchString(tag) {
+			return nil, fmt.Errorf("%w: invalid tag %q, tags are up to 30 letters, digits, '-' or '_'", ErrConstraint, tag)
+		}
+		res = append(res, tag)
+	}
+	slices.Sort(res)
+	return slices.Compact(res), nil
+}
+
+/* Returns tags as space separated '#tag's */
+func tagsString(tags []string) string {
+	res := make([]string, len(tags))
+	for i, tag := range tags {
+		res[i] = "#" + tag
+	}
+	return strings.Join(res, " ")
+}
+
+/* Replaces a record's tags, creating any tags which don't exist yet */
+func setRecordTags(tx *sql.Tx, recId int64, tags []string) error {
+	tags, err := normaliseTags(tags)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM record_tag WHERE rec_id = ?", recId); err != nil {
+		return dbError(err)
+	}
+	for _, tag := range tags {
+		if _, err := tx.Exec("INSERT INTO tag (tag_name) VALUES (?) ON CONFLICT (tag_name) DO NOTHING", tag); err != nil {
+			return dbError(err)
+		}
+		if _, err := tx.Exec("INSERT INTO record_tag (rec_id, tag_id) SELECT ?, tag_id FROM tag WHERE tag_name = ?", recId, tag); err != nil {
+			return dbError(err)
+		}
+	}
+	return nil
+}
+
+/* Returns the names of all tags in alphabetical order */
+func (s *Store) GetTagNames() ([]string, error) {
+	rows, err := s.db.Query("SELECT tag_name FROM tag ORDER BY tag_name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags: %w", dbError(err))
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, dbError(err)
+		}
+		names = append(names, name)
+	}
+	return names, dbError(rows.Err())
+}
+
+/*
+Returns the number of records, income and expenditure for each tag from start
+up to (not including) end, in alphabetical order. Transfers are left out, and
+tags with no records in the range are included with zero totals.
+*/
+func (s *Store) GetTagTotals(start, end time.Time) ([]DataRow, error) {
+	rows, err := s.db.Query(`SELECT tag_name,
+                                  COUNT(DISTINCT rec_id),
+                                  IFNULL(SUM(CASE WHEN cat_isincome THEN rec_amt END), 0),
+                                  IFNULL(SUM(CASE WHEN NOT cat_isincome THEN rec_amt END), 0)
+                           FROM tag
+                             LEFT JOIN (SELECT rec_id, tag_id, rec_amt, cat_isincome
+                                        FROM record_tag JOIN record_line USING (rec_id) JOIN category USING (cat_id)
+                                        WHERE rec_date >= ? AND rec_date < ?) USING (tag_id)
+                           GROUP BY tag_id
+                           ORDER BY tag_name`, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tag totals: %w", dbError(err))
+	}
+	defer rows.Close()
+
+	var res []DataRow
+	for rows.Next() {
+		var tt TagTotal
+		if err := rows.Scan(&tt.Name, &tt.Records, &tt.Income, &tt.Expenditure); err != nil {
+			return nil, dbError(err)
+		}
+		res = append(res, tt)
+	}
+	return res, dbError(rows.Err())
+}
+
+/* Renames a tag on every record, returning ErrConstraint if the new name is invalid or taken */
+func (s *Store) RenameTag(oldName, newName string) error {
+	newName = normaliseTag(newName)
+	if !tagRegex.MatchString(newName) {
+		return fmt.Errorf("failed to rename tag: %w: invalid tag %q", ErrConstraint, newName)
+	}
+	err := checkAffected(s.db.Exec("UPDATE tag SET tag_name = ? WHERE tag_name = ?", newName, normaliseTag(oldName)))
+	if err != nil {
+		return fmt.Errorf("failed to rename tag #%s: %w", normaliseTag(oldName), err)
+	}
+	return nil
+}
+
+/* Deletes a tag, removing it from every record */
+func (s *Store) DeleteTag(name string) error {
+	err := checkAffected(s.db.Exec("DELETE FROM tag WHERE tag_name = ?", normaliseTag(name)))
+	if err != nil {
+		return fmt.Errorf("failed to delete tag #%s: %w", normaliseTag(name), err)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package backend
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+/* Tags coles (2) and woolies (5) #food, and woolies and the prize (6) #tax-deductible */
+func newTagStore(t *testing.T) *Store {
+	t.Helper()
+	s := newFixtureStore(t)
+	mustNil(t, s.UpdateRecord(2, Record{Date: date(t, "2024-01-15"), Desc: "coles", Amt: -4250, CatId: 2, Tags: []string{"Food"}}))
+	mustNil(t, s.UpdateRecord(5, Record{Date: date(t, "2024-02-10"), Desc: "woolies", Amt: -6000, CatId: 2, Tags: []string{"#tax-deductible", "food", "FOOD"}}))
+	mustNil(t, s.InsertRecord(Record{Date: date(t, "2024-02-29"), Desc: "prize", Amt: 5000, CatId: 4, Tags: []string{"tax-deductible"}}))
+	return s
+}
+
+func TestRecordTags(t *testing.T) {
+	s := newTagStore(t)
+
+	rows, err := s.GetRecordsFilter(NewFilterOpts())
+	mustNil(t, err)
+	tags := map[string][]string{}
+	for _, r := range rows {
+		rec := r.(Record)
+		tags[rec.Desc] = rec.Tags
+	}
+	if !slices.Equal(tags["woolies"], []string{"food", "tax-deductible"}) || !slices.Equal(tags["coles"], []string{"food"}) || tags["rent"] != nil {
+		t.Errorf("unexpected tags %v", tags)
+	}
+
+	names, err := s.GetTagNames()
+	mustNil(t, err)
+	if !slices.Equal(names, []string{"food", "tax-deductible"}) {
+		t.Errorf("got tag names %v", names)
+	}
+
+	rows, err = s.GetRecordsFilter(NewFilterOpts().WithTags([]string{"#Tax-Deductible"}))
+	mustNil(t, err)
+	if ids := recordIds(rows); !equalInts(ids, []int{5, 8}) {
+		t.Errorf("got records %v tagged #tax-deductible, want [5 8]", ids)
+	}
+
+	// updating replaces the tags
+	mustNil(t, s.UpdateRecord(5, Record{Date: date(t, "2024-02-10"), Desc: "woolies", Amt: -6000, CatId: 2}))
+	rows, err = s.GetRecordsFilter(NewFilterOpts().WithTags([]string{"food", "tax-deductible"}))
+	mustNil(t, err)
+	if ids := recordIds(rows); !equalInts(ids, []int{2, 8}) {
+		t.Errorf("got records %v after removing tags, want [2 8]", ids)
+	}
+
+	err = s.InsertRecord(Record{Date: date(t, "2024-03-01"), Desc: "bad", Amt: -100, CatId: 2, Tags: []string{"two words"}})
+	if !errors.Is(err, ErrConstraint) {
+		t.Errorf("got %v for an invalid tag, want ErrConstraint", err)
+	}
+	rows, err = s.GetRecordsFilter(NewFilterOpts())
+	mustNil(t, err)
+	if len(rows) != 8 {
+		t.Errorf("record with an invalid tag was inserted")
+	}
+}
+
+func TestTagTotals(t *testing.T) {
+	s := newTagStore(t)
+
+	start, end := date(t, "2024-01-01"), date(t, "2024-03-01")
+	rows, err := s.GetTagTotals(start, end)
+	mustNil(t, err)
+	want := []TagTotal{
+		{Name: "food", Records: 2, Expenditure: -10250},
+		{Name: "tax-deductible", Records: 2, Income: 5000, Expenditure: -6000},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d tag totals, want %d", len(rows), len(want))
+	}
+	for i, r := range rows {
+		if r.(TagTotal) != want[i] {
+			t.Errorf("got %+v, want %+v", r, want[i])
+		}
+	}
+
+	// tags without records in the range have zero totals
+	rows, err = s.GetTagTotals(start, date(t, "2024-02-01"))
+	mustNil(t, err)
+	if tt := rows[1].(TagTotal); tt.Records != 0 || tt.Net() != 0 {
+		t.Errorf("got %+v, want no records", tt)
+	}
+
+	mustNil(t, s.RenameTag("#food", "groceries"))
+	if err := s.RenameTag("groceries", "tax-deductible"); !errors.Is(err, ErrConstraint) {
+		t.Errorf("got %v renaming to an existing tag, want ErrConstraint", err)
+	}
+	mustNil(t, s.DeleteTag("tax-deductible"))
+	if err := s.DeleteTag("tax-deductible"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v deleting a missing tag, want ErrNotFound", err)
+	}
+	rows, err = s.GetTagTotals(start, end)
+	mustNil(t, err)
+	if len(rows) != 1 || rows[0].(TagTotal).Name != "groceries" || rows[0].(TagTotal).Records != 2 {
+		t.Errorf("unexpected totals after rename and delete %v", rows)
+	}
+}
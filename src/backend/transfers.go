@@ -0,0 +1,103 @@
+package backend
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+/* Returns an error wrapping ErrConstraint if a transfer can't be saved */
+func checkTransfer(tr Transfer) error {
+	if tr.FromAccId == tr.ToAccId {
+		return fmt.Errorf("%w: a transfer must be between two different accounts", ErrConstraint)
+	}
+	if tr.Amt <= 0 {
+		return fmt.Errorf("%w: a transfer amount must be positive", ErrConstraint)
+	}
+	return nil
+}
+
+/* Inserts both legs of a transfer in a single transaction */
+func (s *Store) InsertTransfer(tr Transfer) error {
+	if err := checkTransfer(tr); err != nil {
+		return fmt.Errorf("failed to insert transfer: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return dbError(err)
+	}
+	fail := func(err error) error {
+		tx.Rollback()
+		return fmt.Errorf("failed to insert transfer: %w", dbError(err))
+	}
+
+	insertLeg := func(accId, amt int, xferId any) (int64, error) {
+		res, err := tx.Exec("INSERT INTO record (rec_date, rec_desc, rec_amt, acc_id, rec_xfer_id) VALUES (?,?,?,?,?)",
+			tr.Date, tr.Desc, amt, accId, xferId)
+		if err != nil {
+			return 0, err
+		}
+		return res.LastInsertId()
+	}
+
+	// the legs can only point to each other once both exist
+	outId, err := insertLeg(tr.FromAccId, -tr.Amt, nil)
+	if err != nil {
+		return fail(err)
+	}
+	inId, err := insertLeg(tr.ToAccId, tr.Amt, outId)
+	if err != nil {
+		return fail(err)
+	}
+	if _, err := tx.Exec("UPDATE record SET rec_xfer_id = ? WHERE rec_id = ?", inId, outId); err != nil {
+		return fail(err)
+	}
+	return dbError(tx.Commit())
+}
+
+/* Returns the transfer which the record is a leg of, or ErrNotFound if it isn't part of a transfer */
+func (s *Store) GetTransfer(recId int) (Transfer, error) {
+	var tr Transfer
+	err := s.db.QueryRow(`SELECT o.rec_id, o.rec_date, o.rec_desc, -o.rec_amt, o.acc_id, i.acc_id
+                        FROM record o JOIN record i ON i.rec_id = o.rec_xfer_id
+                        WHERE o.rec_amt < 0 AND ? IN (o.rec_id, i.rec_id)`, recId).
+		Scan(&tr.Id, &tr.Date, &tr.Desc, &tr.Amt, &tr.FromAccId, &tr.ToAccId)
+	if err == sql.ErrNoRows {
+		return tr, fmt.Errorf("record %d is not a transfer: %w", recId, ErrNotFound)
+	} else if err != nil {
+		return tr, dbError(err)
+	}
+	return tr, nil
+}
+
+/* Updates both legs of the transfer which the record is a leg of */
+func (s *Store) UpdateTransfer(recId int, tr Transfer) error {
+	if err := checkTransfer(tr); err != nil {
+		return fmt.Errorf("failed to update transfer: %w", err)
+	}
+	old, err := s.GetTransfer(recId)
+	if err != nil {
+		return fmt.Errorf("failed to update transfer: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return dbError(err)
+	}
+	for _, leg := range []struct {
+		where string
+		accId int
+		amt   int
+	}{
+		{"rec_id = ?", tr.FromAccId, -tr.Amt},
+		{"rec_xfer_id = ?", tr.ToAccId, tr.Amt},
+	} {
+		_, err := tx.Exec("UPDATE record SET rec_date = ?, rec_desc = ?, rec_amt = ?, acc_id = ? WHERE "+leg.where,
+			tr.Date, tr.Desc, leg.amt, leg.accId, old.Id)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to update transfer %d: %w", old.Id, dbError(err))
+		}
+	}
+	return dbError(tx.Commit())
+}
@@ -0,0 +1,112 @@
+package backend
+
+import (
+	"errors"
+	"testing"
+)
+
+/* The accounts store with $200 moved from Everyday to Visa on 20 January, returns the legs' ids */
+func newTransferStore(t *testing.T) (*Store, int, int) {
+	t.Helper()
+	s := newAccountsStore(t)
+	mustNil(t, s.InsertTransfer(Transfer{Date: date(t, "2024-01-20"), FromAccId: 1, ToAccId: 2, Desc: "pay card", Amt: 20000}))
+	return s, 10, 11
+}
+
+/* Returns the legs of transfers in the month, keyed by account name */
+func transferLegs(t *testing.T, s *Store, month string) map[string]Record {
+	t.Helper()
+	recs, _, _, err := s.GetMonthInfo(date(t, month))
+	mustNil(t, err)
+	legs := map[string]Record{}
+	for _, r := range recs {
+		if rec := r.(Record); rec.XferId != 0 {
+			legs[rec.AccName] = rec
+		}
+	}
+	return legs
+}
+
+func TestTransferNotIncomeOrExpenditure(t *testing.T) {
+	s, outId, inId := newTransferStore(t)
+
+	recs, income, expenditure, err := s.GetMonthInfo(date(t, "2024-01-10"))
+	mustNil(t, err)
+	if len(recs) != 6 || income != 300000 || expenditure != 154250+5000 {
+		t.Errorf("got (%d, %.0f, %.0f), want (6, 300000, 159250)", len(recs), income, expenditure)
+	}
+
+	legs := transferLegs(t, s, "2024-01-10")
+	out, in := legs["Everyday"], legs["Visa"]
+	if out.Id != outId || out.XferId != inId || out.Amt != -20000 || in.XferId != outId || in.Amt != 20000 {
+		t.Errorf("unexpected legs %+v", legs)
+	}
+	if label := in.SpreadToStrings()[2]; label != "Transfer" {
+		t.Errorf("got category %q, want Transfer", label)
+	}
+
+	rows, err := s.GetYearSummary(2024)
+	mustNil(t, err)
+	if net := rows[len(rows)-1].(*CategoryYear); net.MonthSums[0] != 145750-5000 {
+		t.Errorf("got January net change %d, want %d", net.MonthSums[0], 145750-5000)
+	}
+
+	rows, err = s.GetAccounts()
+	mustNil(t, err)
+	if got := accountBalances(t, rows); got["Everyday"] != 443750-20000 || got["Visa"] != -17000+20000 {
+		t.Errorf("unexpected balances %v", got)
+	}
+}
+
+func TestUpdateTransfer(t *testing.T) {
+	s, outId, inId := newTransferStore(t)
+
+	// either leg can be used to find the transfer
+	tr, err := s.GetTransfer(inId)
+	mustNil(t, err)
+	if tr.Id != outId || tr.FromAccId != 1 || tr.ToAccId != 2 || tr.Amt != 20000 || tr.Desc != "pay card" {
+		t.Errorf("unexpected transfer %+v", tr)
+	}
+
+	tr = Transfer{Date: date(t, "2024-02-03"), FromAccId: 2, ToAccId: 1, Desc: "refund", Amt: 500}
+	mustNil(t, s.UpdateTransfer(inId, tr))
+	legs := transferLegs(t, s, "2024-02-10")
+	if legs["Visa"].Amt != -500 || legs["Everyday"].Amt != 500 || legs["Everyday"].Desc != "refund" {
+		t.Errorf("unexpected legs after update %+v", legs)
+	}
+
+	// editing one leg as a record edits the other
+	mustNil(t, s.UpdateRecord(outId, Record{Date: date(t, "2024-02-04"), Desc: "refunded", Amt: 700, CatId: 2}))
+	legs = transferLegs(t, s, "2024-02-10")
+	if legs["Visa"].Amt != 700 || legs["Everyday"].Amt != -700 || legs["Everyday"].Desc != "refunded" ||
+		legs["Visa"].CatId != -5 || !legs["Everyday"].Date.Equal(date(t, "2024-02-04")) {
+		t.Errorf("unexpected legs after updating a record %+v", legs)
+	}
+
+	// moving a leg to the other leg's account
+	if err := s.UpdateRecord(outId, Record{Date: date(t, "2024-02-04"), Desc: "refunded", Amt: 700, AccId: 1}); !errors.Is(err, ErrConstraint) {
+		t.Errorf("leg moved to the other leg's account: got %v, want ErrConstraint", err)
+	}
+	if legs := transferLegs(t, s, "2024-02-10"); legs["Visa"].Amt != 700 || legs["Everyday"].Amt != -700 {
+		t.Errorf("unexpected legs after a refused update %+v", legs)
+	}
+
+	if err := s.UpdateTransfer(outId, Transfer{FromAccId: 1, ToAccId: 1, Amt: 100}); !errors.Is(err, ErrConstraint) {
+		t.Errorf("transfer to the same account: got %v, want ErrConstraint", err)
+	}
+	if _, err := s.GetTransfer(1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("record which isn't a transfer: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestDeleteTransfer(t *testing.T) {
+	s, _, inId := newTransferStore(t)
+
+	mustNil(t, s.DeleteRecord(inId))
+	if legs := transferLegs(t, s, "2024-01-10"); len(legs) != 0 {
+		t.Errorf("legs left after deleting %+v", legs)
+	}
+	if err := s.InsertTransfer(Transfer{FromAccId: 1, ToAccId: 2, Amt: -100}); !errors.Is(err, ErrConstraint) {
+		t.Errorf("negative amount: got %v, want ErrConstraint", err)
+	}
+}
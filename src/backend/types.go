@@ -3,6 +3,9 @@ package backend
 import (
 	"database/sql"
 	"fmt"
+	"math"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -12,32 +15,241 @@ type DataRow interface {
 }
 
 type Record struct {
-	Id    int
-	Date  time.Time
-	CatId int
-	Desc  string
-	Amt   int
+	Id      int
+	Date    time.Time
+	CatId   int
+	CatName string // set when read from the database, or the category named in an imported statement
+	AccId   int    // 0 when inserting puts the record in the first account
+	AccName string // set when read from the database
+	Desc    string
+	Amt     int
+	ExtId   string // id of the transaction in an imported statement (e.g. OFX FITID), used to skip duplicates
+	XferId  int    // id of the other leg if the record is part of a transfer, set when read from the database
+	Tags    []string
+	Splits  []Split // lines dividing the amount between categories, none if it's all in CatId
 }
 
+/* A split record is stored with the category of its first line */
 func (rec Record) Spread() (int, time.Time, string, int, int) {
+	if len(rec.Splits) > 0 {
+		return rec.Id, rec.Date, rec.Desc, rec.Amt, rec.Splits[0].CatId
+	}
 	return rec.Id, rec.Date, rec.Desc, rec.Amt, rec.CatId
 }
 
+/* Returns the display name of the record's category, or of each category it's split between */
+func (rec Record) CategoryLabel() string {
+	if len(rec.Splits) == 0 {
+		return categoryLabel(rec.CatId, rec.CatName)
+	}
+	names := make([]string, len(rec.Splits))
+	for i, spl := range rec.Splits {
+		names[i] = categoryLabel(spl.CatId, spl.CatName)
+	}
+	return "Split: " + strings.Join(names, ", ")
+}
+
 func (rec Record) SpreadToStrings() []string {
 	return []string{
 		fmt.Sprint(rec.Id),
 		rec.Date.Format("2006-01-02"),
-		GetCategoryNameFromId(rec.CatId),
+		rec.CategoryLabel(),
+		rec.AccName,
 		rec.Desc,
 		rightAlign(float32(rec.Amt)/100, 2, 8, "$"),
+		"#" + tagsString(rec.Tags),
+	}
+}
+
+/* A line of a split record, giving part of its amount to a category */
+type Split struct {
+	CatId   int    // -1 if the category was deleted, when read from the database
+	CatName string // set when read from the database
+	Amt     int
+	Note    string
+}
+
+/*
+Money moved between two accounts. Stored as two linked records (legs), which
+have no category so aren't counted as income or expenditure.
+*/
+type Transfer struct {
+	Id        int // id of the leg leaving FromAccId
+	Date      time.Time
+	FromAccId int
+	ToAccId   int
+	Desc      string
+	Amt       int // amount moved, always positive
+}
+
+/* A bank account, card or cash wallet which records are paid from/into */
+type Account struct {
+	Id      int
+	Name    string
+	Type    string // e.g. Bank, Card, Cash
+	Opening int    // balance before the first record
+	Desc    string
+	Balance int // opening balance plus the account's records, set when read from the database
+}
+
+func (acc Account) Spread() (int, string, string, int, string) {
+	return acc.Id, acc.Name, acc.Type, acc.Opening, acc.Desc
+}
+
+func (acc Account) SpreadToStrings() []string {
+	return []string{
+		fmt.Sprint(acc.Id),
+		acc.Name,
+		acc.Type,
+		"#" + rightAlign(float32(acc.Opening)/100, 2, 10, "$"),
+		rightAlign(float32(acc.Balance)/100, 2, 10, "$"),
+		acc.Desc,
+	}
+}
+
+/* A record in an account's ledger, with the account's balance after it */
+type LedgerRow struct {
+	Record
+	Balance int
+}
+
+func (lr LedgerRow) SpreadToStrings() []string {
+	return []string{
+		fmt.Sprint(lr.Id),
+		lr.Date.Format("2006-01-02"),
+		lr.CategoryLabel(),
+		lr.Desc,
+		rightAlign(float32(lr.Amt)/100, 2, 8, "$"),
+		"#" + rightAlign(float32(lr.Balance)/100, 2, 10, "$"),
+	}
+}
+
+/* A spending limit for an expense category, per month or per calendar year */
+type Budget struct {
+	Id       int
+	CatId    int
+	CatName  string // set when read from the database
+	Amt      int    // limit for each period, always positive
+	Yearly   bool
+	Rollover bool      // unspent funds (or overspending) carry into the next period
+	Start    time.Time // the first period the budget applies to, set to the current period when inserting if zero
+}
+
+/* A budget's progress through one period */
+type BudgetRow struct {
+	Budget
+	Available int // the budget plus anything rolled over from previous periods
+	Spent     int // net spending in the category, positive when money was spent
+}
+
+func (br BudgetRow) Remaining() int {
+	return br.Available - br.Spent
+}
+
+func (br BudgetRow) SpreadToStrings() []string {
+	period, rollover := "Monthly", ""
+	if br.Yearly {
+		period = "Yearly"
+	}
+	if br.Rollover {
+		rollover = "Yes"
+	}
+	return []string{
+		fmt.Sprint(br.Id),
+		br.CatName,
+		period,
+		rollover,
+		"#" + rightAlign(float32(br.Amt)/100, 2, 9, "$"),
+		"#" + rightAlign(float32(br.Available)/100, 2, 9, "$"),
+		"#" + rightAlign(float32(br.Spent)/100, 2, 9, "$"),
+		rightAlign(float32(br.Remaining())/100, 2, 9, "$"),
+	}
+}
+
+/* How often a recurring rule creates a record */
+type Frequency string
+
+const (
+	Weekly          Frequency = "weekly"
+	Fortnightly     Frequency = "fortnightly"
+	Monthly         Frequency = "monthly" // on RecurringRule.Day
+	LastBusinessDay Frequency = "lastbusday"
+	Yearly          Frequency = "yearly" // on the date of RecurringRule.Start
+)
+
+var Frequencies = []Frequency{Weekly, Fortnightly, Monthly, LastBusinessDay, Yearly}
+
+/* A record which is created automatically every period, e.g. rent or salary */
+type RecurringRule struct {
+	Id      int
+	Desc    string
+	Amt     int
+	CatId   int
+	CatName string // set when read from the database
+	AccId   int    // 0 for the first account
+	AccName string // set when read from the database
+	Freq    Frequency
+	Day     int       // day of the month for Monthly rules, the last day is used in shorter months
+	Start   time.Time // date of the first record, or the earliest it can be for Monthly/LastBusinessDay rules
+	End     time.Time // no records are created after the end, zero if the rule never ends
+	Next    time.Time // date of the next record to create, set when read from the database
+	Paused  bool
+}
+
+/* Returns a description of when the rule creates records, e.g. "Monthly on day 15" */
+func (rr RecurringRule) Schedule() string {
+	switch rr.Freq {
+	case Weekly:
+		return "Weekly on " + rr.Start.Weekday().String()
+	case Fortnightly:
+		return "Fortnightly on " + rr.Start.Weekday().String()
+	case Monthly:
+		return fmt.Sprintf("Monthly on day %d", rr.Day)
+	case LastBusinessDay:
+		return "Last business day"
+	case Yearly:
+		return "Yearly on " + rr.Start.Format("2 Jan")
+	default:
+		return string(rr.Freq)
+	}
+}
+
+/* Returns whether the rule won't create any more records */
+func (rr RecurringRule) Ended() bool {
+	return !rr.End.IsZero() && rr.Next.After(rr.End)
+}
+
+func (rr RecurringRule) SpreadToStrings() []string {
+	end, next := "", rr.Next.Format("2006-01-02")
+	if !rr.End.IsZero() {
+		end = rr.End.Format("2006-01-02")
+	}
+	if rr.Ended() {
+		next = "Ended"
+	} else if rr.Paused {
+		next = "Paused"
+	}
+	return []string{
+		fmt.Sprint(rr.Id),
+		rr.Desc,
+		rr.CatName,
+		rr.AccName,
+		rightAlign(float32(rr.Amt)/100, 2, 8, "$"),
+		rr.Schedule(),
+		rr.Start.Format("2006-01-02"),
+		end,
+		next,
 	}
 }
 
 type Category struct {
-	Id       int // special: 0 = "Net Change" | -1 = "Deleted" | -2 = blank | -3 = "Total Income" | -4 = "Total Expenditure"
-	Name     string
-	IsIncome bool
-	Desc     string
+	Id         int // special: 0 = "Net Change" | -1 = "Deleted" | -2 = blank | -3 = "Total Income" | -4 = "Total Expenditure" | -5 = "Transfer"
+	Name       string
+	IsIncome   bool
+	Desc       string
+	ParentId   int // 0 for a top level category
+	ParentName string
+	Depth      int // number of ancestors, set by GetCategories
 }
 
 func (cat Category) Spread() (int, string, bool, string) {
@@ -45,10 +257,14 @@ func (cat Category) Spread() (int, string, bool, string) {
 }
 
 func (c Category) SpreadToStrings() []string {
+	name := c.Name
+	if c.Depth > 0 { // draw the tree
+		name = strings.Repeat("  ", c.Depth-1) + "└ " + name
+	}
 	if c.IsIncome {
-		return []string{fmt.Sprint(c.Id), c.Name, "Income", c.Desc}
+		return []string{fmt.Sprint(c.Id), name, "Income", c.Desc}
 	} else {
-		return []string{fmt.Sprint(c.Id), c.Name, "Expenditure", c.Desc}
+		return []string{fmt.Sprint(c.Id), name, "Expenditure", c.Desc}
 	}
 }
 
@@ -57,85 +273,248 @@ type Investment struct {
 	Date      time.Time
 	Code      string
 	Unitprice int
-	Qty       float32
+	Qty       float32   // negative for a sell
+	Fee       int       // cents, brokerage paid on the trade
+	Method    LotMethod // how a sell is matched to the lots it sells from, ignored for buys
+	LotId     int       // the buy a sell with the SpecificLot method sells from
+	ExtId     string    // id of the transaction in an imported statement, used to skip duplicates
 }
 
 func (inv Investment) Spread() (id int, date time.Time, code string, unitprice int, qty float32) {
 	return inv.Id, inv.Date, inv.Code, inv.Unitprice, inv.Qty
 }
 
-// returns in order: ID, date, code, unitprice, qty, total value
+func (inv Investment) IsSell() bool {
+	return inv.Qty < 0
+}
+
+/* Returns "Buy", or "Sell" with how the sell is matched to lots, e.g. "Sell FIFO" or "Sell lot 12" */
+func (inv Investment) TypeString() string {
+	if !inv.IsSell() {
+		return "Buy"
+	}
+	if inv.Method == SpecificLot {
+		return fmt.Sprintf("Sell lot %d", inv.LotId)
+	}
+	return "Sell " + inv.Method.String()
+}
+
+// returns in order: ID, date, type, code, unitprice, qty, total value, brokerage fee (quantities and totals of sells are positive)
 func (inv Investment) SpreadToStrings() []string {
+	qty := float32(math.Abs(float64(inv.Qty)))
 	return []string{
 		fmt.Sprint(inv.Id),            // id
 		inv.Date.Format("2006-01-02"), // date
+		inv.TypeString(),              // type
 		inv.Code,                      // code
-		"#" + rightAlign(float32(inv.Unitprice)/100, 2, 8, "$"),   // unitprice
-		rightAlign(inv.Qty, 1, 6, ""),                             // qty
-		rightAlign(float32(inv.Unitprice)*inv.Qty/100, 2, 9, "$"), // value
+		"#" + rightAlign(float32(inv.Unitprice)/100, 2, 8, "$"), // unitprice
+		rightAlign(qty, 1, 6, ""),                               // qty
+		rightAlign(float32(inv.Unitprice)*qty/100, 2, 9, "$"),   // value
+		"#" + rightAlign(float32(inv.Fee)/100, 2, 7, "$"),       // fee
+	}
+}
+
+/*
+A dividend or distribution paid on a stock code. A reinvested one was paid as
+units through a dividend reinvestment plan (DRP), bought as a new lot.
+*/
+type Dividend struct {
+	Id          int
+	Date        time.Time
+	Code        string
+	Amt         int     // cents, the cash paid after any tax withheld
+	Franking    int     // cents, franking credits attached
+	Withholding int     // cents, tax withheld
+	Reinvested  bool    // paid as units through a DRP
+	DrpPrice    int     // cents per unit issued by the DRP, if reinvested
+	DrpQty      float32 // units issued, set when read from the database
+	InvId       int     // the lot bought by reinvesting, set when read from the database
+}
+
+/* The dividend before tax was withheld, not counting franking credits */
+func (d Dividend) Gross() int {
+	return d.Amt + d.Withholding
+}
+
+func (d Dividend) SpreadToStrings() []string {
+	drp := ""
+	if d.Reinvested {
+		drp = fmt.Sprintf("%g @ $%.2f (lot %d)", d.DrpQty, float32(d.DrpPrice)/100, d.InvId)
+	}
+	return []string{
+		fmt.Sprint(d.Id),
+		d.Date.Format("2006-01-02"),
+		d.Code,
+		rightAlign(float32(d.Amt)/100, 2, 9, "$"),
+		"#" + rightAlign(float32(d.Franking)/100, 2, 9, "$"),
+		"#" + rightAlign(float32(d.Withholding)/100, 2, 9, "$"),
+		drp,
 	}
 }
 
-func dbRowsToInvestments(rows *sql.Rows) []DataRow {
+/* The kind of a corporate action */
+type ActionType string
+
+const (
+	ActionSplit         ActionType = "split"         // more units, each worth less
+	ActionConsolidation ActionType = "consolidation" // fewer units, each worth more
+	ActionRename        ActionType = "rename"        // a new stock code for the same units
+)
+
+var ActionTypes = []ActionType{ActionSplit, ActionConsolidation, ActionRename}
+
+func (a ActionType) String() string {
+	if a == ActionRename {
+		return "Code Change"
+	}
+	return strings.ToUpper(string(a[:1])) + string(a[1:])
+}
+
+/*
+A change to a stock code's units from its effective date: every Old units held
+becoming New units (a split or consolidation), or the code changing to NewCode.
+Trades before the date are in the old units and code, trades from it in the new.
+*/
+type CorporateAction struct {
+	Id      int
+	Date    time.Time
+	Type    ActionType
+	Code    string
+	New     int // units after, for each Old units before, 1 for a rename
+	Old     int
+	NewCode string // the code after a rename
+}
+
+/* New units for each unit held before the action */
+func (a CorporateAction) Ratio() float64 {
+	return float64(a.New) / float64(a.Old)
+}
+
+// returns in order: ID, date, type, code, change (e.g. "2 for 1" or "to XYZ")
+func (a CorporateAction) SpreadToStrings() []string {
+	change := fmt.Sprintf("%d for %d", a.New, a.Old)
+	if a.Type == ActionRename {
+		change = "to " + a.NewCode
+	}
+	return []string{
+		fmt.Sprint(a.Id),
+		a.Date.Format("2006-01-02"),
+		a.Type.String(),
+		a.Code,
+		change,
+	}
+}
+
+func dbRowsToInvestments(rows *sql.Rows) ([]DataRow, error) {
 	var investments []DataRow
 
 	// for each row, assign column data to struct fields and append struct to slice
 	for rows.Next() {
 		var inv Investment
-		if err := rows.Scan(&inv.Id, &inv.Date, &inv.Code, &inv.Unitprice, &inv.Qty); err != nil {
-			panic(err)
+		if err := rows.Scan(&inv.Id, &inv.Date, &inv.Code, &inv.Unitprice, &inv.Qty, &inv.Method, &inv.LotId, &inv.Fee); err != nil {
+			return nil, dbError(err)
 		}
 		investments = append(investments, inv)
 	}
 
 	// check for errors then return
 	if err := rows.Err(); err != nil {
-		panic(err)
+		return nil, dbError(err)
 	}
-	return investments
+	return investments, nil
 }
 
-func dbRowsToRecords(rows *sql.Rows) []DataRow {
+/*
+Scans a row of rec_id, rec_date, rec_desc, rec_amt, cat_id, cat_name, acc_id, acc_name,
+rec_xfer_id, tags (see recordTags), followed by any extra columns
+*/
+func scanRecord(rows *sql.Rows, extra ...any) (Record, error) {
+	var rec Record
+	var catId, accId, xferId sql.NullInt64
+	var catName, accName, tags sql.NullString
+	dest := append([]any{&rec.Id, &rec.Date, &rec.Desc, &rec.Amt, &catId, &catName, &accId, &accName, &xferId, &tags}, extra...)
+	if err := rows.Scan(dest...); err != nil {
+		return rec, dbError(err)
+	}
+	if tags.Valid {
+		rec.Tags = strings.Fields(tags.String)
+		slices.Sort(rec.Tags)
+	}
+	rec.XferId = int(xferId.Int64)
+	// category is set to NULL when deleted, transfers never have one
+	rec.CatId = -1
+	if xferId.Valid {
+		rec.CatId = -5
+	} else if catId.Valid {
+		rec.CatId = int(catId.Int64)
+		rec.CatName = catName.String
+	}
+	rec.AccId, rec.AccName = int(accId.Int64), accName.String
+	return rec, nil
+}
+
+func dbRowsToRecords(rows *sql.Rows) ([]DataRow, error) {
 	var records []DataRow
 
 	// for each row, assign column data to struct fields and append struct to slice
 	for rows.Next() {
-		var rec Record
-		if err := rows.Scan(&rec.Id, &rec.Date, &rec.Desc, &rec.Amt, &rec.CatId); err != nil {
-			rec.CatId = -1
+		rec, err := scanRecord(rows)
+		if err != nil {
+			return nil, err
 		}
 		records = append(records, rec)
 	}
 
 	// check for errors then return
 	if err := rows.Err(); err != nil {
-		panic(err)
+		return nil, dbError(err)
 	}
-	return records
+	return records, nil
 }
 
-func dbRowsToCategories(rows *sql.Rows) []DataRow {
+func dbRowsToCategories(rows *sql.Rows) ([]DataRow, error) {
 	var categories []DataRow
 
 	// for each row, assign column data to struct fields and append struct to slice
 	for rows.Next() {
 		var cat Category
-		if err := rows.Scan(&cat.Id, &cat.Name, &cat.Desc, &cat.IsIncome); err != nil {
-			panic(err)
+		if err := rows.Scan(&cat.Id, &cat.Name, &cat.Desc, &cat.IsIncome, &cat.ParentId, &cat.ParentName, &cat.Depth); err != nil {
+			return nil, dbError(err)
 		}
 		categories = append(categories, cat)
 	}
 
 	// check for errors then return
 	if err := rows.Err(); err != nil {
-		panic(err)
+		return nil, dbError(err)
 	}
-	return categories
+	return categories, nil
+}
+
+/* A category's place in the category tree, for rows of totals which roll up into their parents */
+type CategoryNode struct {
+	CatId       int
+	Name        string
+	ParentId    int
+	Depth       int
+	HasChildren bool // any subcategories are shown after it
+	Collapsed   bool // its subcategories are hidden, set by the frontend
+}
+
+/* Returns the category's name, indented by its depth and marked if it can be expanded or collapsed */
+func (n CategoryNode) Label() string {
+	label := strings.Repeat("  ", n.Depth) + categoryLabel(n.CatId, n.Name)
+	if n.Collapsed {
+		return label + " ▸"
+	} else if n.HasChildren {
+		return label + " ▾"
+	}
+	return label
 }
 
 type CategoryYear struct {
-	CatId     int
-	MonthSums [12]int // sum of records for this category for each month
+	CategoryNode
+	MonthSums [12]int // sum of records for this category and its subcategories for each month
 }
 
 func (cy CategoryYear) SpreadToStrings() []string {
@@ -143,26 +522,92 @@ func (cy CategoryYear) SpreadToStrings() []string {
 		return []string{"-----------------", "------", "------", "------", "------", "------", "------", "------", "------", "------", "------", "------", "------"}
 	}
 	var res = make([]string, 13, 13)
-	res[0] = GetCategoryNameFromId(cy.CatId)
+	res[0] = cy.Label()
 	for i, val := range cy.MonthSums {
 		res[i+1] = rightAlign(float32(val)/100, 0, 6, "$")
 	}
 	return res
 }
 
+/* Sum of a category's records and its subcategories' over a period */
+type CategoryTotal struct {
+	CategoryNode
+	Sum int
+}
+
+func (ct CategoryTotal) SpreadToStrings() []string {
+	if ct.CatId == -2 { // blank line
+		return []string{"-----------------", "--------"}
+	}
+	return []string{ct.Label(), rightAlign(float32(ct.Sum)/100, 0, 8, "$")}
+}
+
+/* Totals of the records with a tag over a period, transfers aren't included */
+type TagTotal struct {
+	Name        string
+	Records     int
+	Income      int
+	Expenditure int // negative when money was spent
+}
+
+func (tt TagTotal) Net() int {
+	return tt.Income + tt.Expenditure
+}
+
+func (tt TagTotal) SpreadToStrings() []string {
+	return []string{
+		"#" + tagsString([]string{tt.Name}),
+		fmt.Sprintf("%7d", tt.Records),
+		rightAlign(float32(tt.Income)/100, 2, 10, "$"),
+		rightAlign(float32(tt.Expenditure)/100, 2, 10, "$"),
+		rightAlign(float32(tt.Net())/100, 2, 10, "$"),
+	}
+}
+
 type InvSummaryRow struct {
-	code     string
-	qty      float32
-	avgBuy   int
-	curPrice float32 // float32 as retrieved from yahoo finance
+	code          string
+	qty           float32 // units held
+	avgBuy        int     // cost of the units held, per unit
+	curPrice      float32 // float32 as retrieved from yahoo finance
+	realised      int     // gains less losses on the units sold
+	dividends     int     // paid before tax withheld, ever
+	yearDividends int     // paid over the last 12 months
+	fees          int     // brokerage paid on every buy and sell, ever
+}
+
+/* The last 12 months' dividends as a percentage of the current value (cents per dollar), 0 if nothing is held */
+func (isr InvSummaryRow) yield() float32 {
+	curVal := isr.curPrice * isr.qty
+	if isr.code == "total" {
+		curVal = isr.curPrice
+	}
+	if curVal <= 0 {
+		return 0
+	}
+	return float32(isr.yearDividends) / curVal
+}
+
+/* Unrealised and realised P/L plus dividends, in dollars */
+func (isr InvSummaryRow) totalReturn() float32 {
+	unrealised := (isr.curPrice - float32(isr.avgBuy)/100) * isr.qty
+	if isr.code == "total" {
+		unrealised = isr.curPrice - float32(isr.avgBuy)/100
+	}
+	return unrealised + float32(isr.realised+isr.dividends)/100
 }
 
 func (isr InvSummaryRow) SpreadToStrings() []string {
 	if isr.code == "separator" {
-		return []string{"------", "------", "-------------", "-------------", "----------", "-------------", "---------", "-------"}
+		return []string{"------", "------", "-------------", "-------------", "----------", "-------------", "---------", "-------", "-------------",
+			"----------", "-------", "-------------", "---------"}
 	}
 
 	avgBuyF := float32(isr.avgBuy) / 100
+	realised := rightAlign(float32(isr.realised)/100, 2, 12, "$")        // realised P/L
+	dividends := "#" + rightAlign(float32(isr.dividends)/100, 2, 9, "$") // dividends
+	yield := rightAlign(isr.yield(), 2, 5, "") + "%"                     // yield
+	totalReturn := rightAlign(isr.totalReturn(), 2, 12, "$")             // total return
+	fees := "#" + rightAlign(float32(isr.fees)/100, 2, 8, "$")           // brokerage fees
 
 	if isr.code == "total" {
 		return []string{
@@ -172,9 +617,15 @@ func (isr InvSummaryRow) SpreadToStrings() []string {
 			"#" + rightAlign(isr.curPrice, 2, 12, "$"),                     // current value
 			rightAlign(isr.curPrice-avgBuyF, 2, 9, "$"),                    // P/L
 			rightAlign(100*(isr.curPrice-avgBuyF)/avgBuyF, 2, 6, "") + "%", // %P/L
+			realised, dividends, yield, totalReturn, fees,
 		}
 	}
 
+	// every unit has been sold
+	if isr.qty == 0 {
+		return []string{isr.code, rightAlign(0, 2, 6, ""), "", "", "", "", "", "", realised, dividends, "", totalReturn, fees}
+	}
+
 	totalIn := avgBuyF * isr.qty
 	curVal := isr.curPrice * isr.qty
 	return []string{
@@ -186,6 +637,7 @@ func (isr InvSummaryRow) SpreadToStrings() []string {
 		"#" + rightAlign(curVal, 2, 12, "$"),                     // current val
 		rightAlign(curVal-totalIn, 2, 9, "$"),                    // P/L
 		rightAlign(100*(curVal-totalIn)/totalIn, 2, 6, "") + "%", // %P/L
+		realised, dividends, yield, totalReturn, fees,
 	}
 }
 
@@ -195,6 +647,9 @@ type FilterOpts struct {
 	startDate time.Time
 	endDate   time.Time
 	catIds    []int
+	accIds    []int
+	tags      []string
+	text      string // search of record descriptions, see SearchTerms
 	code      string
 }
 
@@ -202,15 +657,16 @@ func NewFilterOpts() FilterOpts {
 	/*
 	  Set default options for filters, allow functions to be passed to modify these
 	*/
-	startDate, _ := makeDate(2000, 1, 1)
+	startDate, _ := makeDate(1, 1, 1)
 	endDate, _ := makeDate(3000, 1, 1)
 
 	opts := &FilterOpts{
-		minCost:   -10000000,
-		maxCost:   10000000,
+		minCost:   -math.MaxFloat32,
+		maxCost:   math.MaxFloat32,
 		startDate: startDate,
 		endDate:   endDate,
 		catIds:    []int{},
+		accIds:    []int{},
 		code:      "",
 	}
 
@@ -242,7 +698,97 @@ func (opts FilterOpts) WithCatId(val []int) FilterOpts {
 	return opts
 }
 
+func (opts FilterOpts) WithAccId(val []int) FilterOpts {
+	opts.accIds = val
+	return opts
+}
+
+/* Only records with any of the tags */
+func (opts FilterOpts) WithTags(val []string) FilterOpts {
+	opts.tags = val
+	return opts
+}
+
+func (opts FilterOpts) WithText(val string) FilterOpts {
+	opts.text = val
+	return opts
+}
+
 func (opts FilterOpts) WithCode(val string) FilterOpts {
 	opts.code = val
 	return opts
 }
+
+/* The order of a table's rows, by one of its columns. The zero value is the table's default order */
+type SortOpts struct {
+	col  int // index of the column in SpreadToStrings + 1, 0 for the default order
+	desc bool
+}
+
+/* Sorts by the column at index col of the rows' SpreadToStrings, descending if desc */
+func SortBy(col int, desc bool) SortOpts {
+	return SortOpts{col: col + 1, desc: desc}
+}
+
+/* Returns the index of the column sorted by, or -1 for the default order */
+func (opts SortOpts) Col() int { return opts.col - 1 }
+
+func (opts SortOpts) Desc() bool { return opts.desc }
+
+/*
+Returns an ORDER BY clause sorting by opts, given the expression for each
+column ("" if it can't be sorted by), with the default order breaking ties
+*/
+func (opts SortOpts) orderBy(cols []string, defaultOrder string) string {
+	col := opts.Col()
+	if col < 0 || col >= len(cols) || cols[col] == "" {
+		return defaultOrder
+	}
+	if opts.desc {
+		return cols[col] + " DESC, " + defaultOrder
+	}
+	return cols[col] + " ASC, " + defaultOrder
+}
+
+/*
+Column mapping for importing a bank's CSV statements.
+Column numbers start from 1, as shown in a spreadsheet.
+*/
+type ImportProfile struct {
+	Id         int
+	Name       string
+	Delimiter  string // single character
+	SkipRows   int    // header rows before the first transaction
+	DateCol    int
+	DateFormat string // e.g. DD/MM/YYYY, see importer.DateLayout
+	DescCols   []int  // joined with a space to form the description
+	AmtCol     int    // amount, or credits if DebitCol is set
+	DebitCol   int    // 0 if debits and credits share AmtCol
+	Negate     bool   // true if money spent is shown as a positive amount
+}
+
+func (p ImportProfile) SpreadToStrings() []string {
+	descCols := make([]string, len(p.DescCols))
+	for i, c := range p.DescCols {
+		descCols[i] = fmt.Sprint(c)
+	}
+	debitCol, negate := "", ""
+	if p.DebitCol > 0 {
+		debitCol = fmt.Sprint(p.DebitCol)
+	}
+	if p.Negate {
+		negate = "Yes"
+	}
+	return []string{
+		fmt.Sprint(p.Id),
+		p.Name,
+		p.Delimiter,
+		fmt.Sprint(p.SkipRows),
+		fmt.Sprint(p.DateCol),
+		p.DateFormat,
+		strings.Join(descCols, ","),
+		fmt.Sprint(p.AmtCol),
+		debitCol,
+		negate,
+	}
+}
@@ -43,10 +43,16 @@ func getJson(symbol string, target interface{}) error {
 
 	r, err := myClient.Do(req)
 	if err != nil {
-		return err
+		return fmt.Errorf("%w: %w", ErrNetwork, err)
 	}
 	defer r.Body.Close()
-	return json.NewDecoder(r.Body).Decode(target)
+	if r.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s returned %s", ErrNetwork, symbol, r.Status)
+	}
+	if err := json.NewDecoder(r.Body).Decode(target); err != nil {
+		return fmt.Errorf("%w: decoding response for %s: %w", ErrNetwork, symbol, err)
+	}
+	return nil
 }
 
 func GetCurrentStockPrice(symbol string) (float32, error) {
@@ -57,7 +63,9 @@ func GetCurrentStockPrice(symbol string) (float32, error) {
 	}
 
 	// no price returned from backend
-	if len(res.Chart.Result) == 0 || len(res.Chart.Result[0].Indicators.AdjClose[0].AdjClose) == 0 {
+	if len(res.Chart.Result) == 0 ||
+		len(res.Chart.Result[0].Indicators.AdjClose) == 0 ||
+		len(res.Chart.Result[0].Indicators.AdjClose[0].AdjClose) == 0 {
 		return 0, nil
 	}
 	return float32(res.Chart.Result[0].Indicators.AdjClose[0].AdjClose[0]), nil
@@ -0,0 +1,1399 @@
+/*
+Non-interactive subcommands, for scripting the finance tracker from the shell:
+
+	finance-tracker <path_to_db> add record --date 2026-10-01 --cat Groceries --amt -42.50 --desc "Coles"
+	finance-tracker <path_to_db> list records --from 2026-01-01 --json
+	finance-tracker <path_to_db> summary month 2026-09
+	finance-tracker <path_to_db> export records --fy 2026 --out records-2025-26.csv
+	finance-tracker <path_to_db> import journal main.ledger
+*/
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/shen-kit/finance-tracker/backend"
+	"github.com/shen-kit/finance-tracker/exporter"
+	"github.com/shen-kit/finance-tracker/importer"
+)
+
+const Usage = `Usage:
+  finance-tracker <path_to_db>                     open the TUI
+  finance-tracker <path_to_db> <command> [flags]   run a single command
+
+Commands:
+  add record --date YYYY-MM-DD --cat NAME --amt AMOUNT --desc TEXT [--acct NAME] [--tags TAG,...]
+             [--split CAT:AMOUNT[:NOTE] ...]
+  add category --name NAME [--desc TEXT] [--income] [--parent NAME]
+  add account --name NAME --type TYPE [--opening AMOUNT] [--desc TEXT]
+  add transfer --date YYYY-MM-DD --from NAME --to NAME --amt AMOUNT --desc TEXT
+  add budget --cat NAME --amt AMOUNT [--yearly] [--rollover]
+  add rule --desc TEXT --cat NAME --amt AMOUNT --freq FREQ [--day N] [--start YYYY-MM-DD] [--end YYYY-MM-DD] [--acct NAME]
+  add recurring
+  add investment --date YYYY-MM-DD --code CODE --price UNITPRICE --qty QTY [--fee AMOUNT] [--sell [--method METHOD] [--lot ID]]
+  add dividend --date YYYY-MM-DD --code CODE --amt AMOUNT [--franking AMOUNT] [--withholding AMOUNT] [--drp UNITPRICE]
+  add action --date YYYY-MM-DD --code CODE (--split NEW:OLD | --consolidate NEW:OLD | --rename NEWCODE)
+  list records [--from YYYY-MM-DD] [--to YYYY-MM-DD] [--cat NAME,...] [--acct NAME,...] [--tag TAG,...] [--search TEXT] [--min AMOUNT] [--max AMOUNT]
+  list categories
+  list accounts
+  list budgets [--month YYYY-MM]
+  list rules
+  list investments [--from YYYY-MM-DD] [--to YYYY-MM-DD] [--code CODE]
+  list dividends [--code CODE]
+  list actions [--code CODE]
+  summary month YYYY-MM
+  summary year YYYY
+  summary tags [--from YYYY-MM-DD] [--to YYYY-MM-DD]
+  summary cgt [--fy YEAR]
+  summary fees [--fy YEAR]
+  export records [--format csv|json] [--out FILE] [list records flags]
+  export categories [--format csv|json] [--out FILE]
+  export investments [--format csv|json] [--out FILE] [list investments flags]
+  export journal [--format ledger|hledger|beancount] [--out FILE] [--from YYYY-MM-DD] [--to YYYY-MM-DD]
+  export cgt [--format csv|json] [--out FILE] [--fy YEAR]
+  import journal FILE
+
+list and summary commands accept --json to print JSON instead of a table.
+Records are added to the first account unless --acct is given. Transfers move a
+positive amount between two accounts and aren't counted as income or expenditure.
+Repeating --split divides a record between categories instead of --cat, the split
+amounts must add up to --amt.
+A category's --parent must have the same type, and its sums in year summaries include
+its subcategories'.
+Tags are letters, digits, '-' or '_', with or without a leading '#'. list records --tag
+shows records with any of the tags. summary tags totals each tag's records over the
+current financial year unless a range is given.
+list records --search shows records with a word in the description starting with each
+word searched for, using the full text index if built with -tags sqlite_fts5.
+Recurring rules repeat weekly, fortnightly, monthly (on --day), lastbusday or yearly,
+and create their records when the TUI is opened, or an add or import command is run,
+on or after they fall due. add recurring only creates them, e.g. from a cron job.
+Commands filtering by date accept --fy YEAR for the financial year ending 30 June YEAR.
+Exports are written to stdout unless --out is given, in CSV unless --out ends in .json.
+Journals are written in ledger format unless --out ends in .beancount, .bean or .hledger.
+Importing a journal creates any categories it uses which don't exist yet, and skips
+transactions imported from it before.
+Investments with --sell (or a negative --qty) are sells, matched to the units bought
+earlier by --method fifo (the default), lifo, average (average cost) or lot, which
+sells from the buy with ID --lot. Their brokerage --fee is included in the cost of
+the units bought, and taken from the proceeds of the units sold.
+Dividends are the cash paid after --withholding tax, a dividend reinvested with --drp
+buys the units it paid for at that price as a new investment.
+Corporate actions split or consolidate every OLD units of a code into NEW units, e.g.
+--split 2:1, or rename it, from --date. Trades before the date are in the old units
+and code, and are converted when working out holdings, lots and capital gains.
+summary cgt and export cgt report the capital gains of the current financial year's
+sells unless --fy is given, with the 50% discount on lots held over 12 months and
+capital losses carried forward from earlier years. summary fees totals the
+brokerage paid on each code's buys and sells over the financial year.
+`
+
+// returned for malformed commands, the caller should print Usage
+var ErrUsage = errors.New("invalid command")
+
+/*
+Returns whether a command can change the database, so recurring records which
+have fallen due should be created before it runs. add recurring isn't one, as
+it creates them itself to list them on stdout.
+*/
+func Mutates(args []string) bool {
+	if len(args) > 1 && args[0] == "add" && args[1] == "recurring" {
+		return false
+	}
+	return len(args) > 0 && (args[0] == "add" || args[0] == "import")
+}
+
+/* Runs a single command against the store, writing any output to out */
+func Run(store *backend.Store, args []string, out io.Writer) error {
+	if len(args) < 2 {
+		return ErrUsage
+	}
+	cmd, target, rest := args[0], args[1], args[2:]
+
+	var run func(*backend.Store, []string, io.Writer) error
+	switch cmd + " " + target {
+	case "add record":
+		run = addRecord
+	case "add category":
+		run = addCategory
+	case "add account":
+		run = addAccount
+	case "add transfer":
+		run = addTransfer
+	case "add budget":
+		run = addBudget
+	case "add rule":
+		run = addRule
+	case "add recurring":
+		run = addRecurring
+	case "add investment":
+		run = addInvestment
+	case "add dividend":
+		run = addDividend
+	case "add action":
+		run = addAction
+	case "list records":
+		run = listRecords
+	case "list categories":
+		run = listCategories
+	case "list accounts":
+		run = listAccounts
+	case "list budgets":
+		run = listBudgets
+	case "list rules":
+		run = listRules
+	case "list investments":
+		run = listInvestments
+	case "list dividends":
+		run = listDividends
+	case "list actions":
+		run = listActions
+	case "export records":
+		run = exportRecords
+	case "export categories":
+		run = exportCategories
+	case "export investments":
+		run = exportInvestments
+	case "export journal":
+		run = exportJournal
+	case "export cgt":
+		run = exportCGT
+	case "import journal":
+		run = importJournal
+	case "summary month":
+		run = summaryMonth
+	case "summary year":
+		run = summaryYear
+	case "summary tags":
+		run = summaryTags
+	case "summary cgt":
+		run = summaryCGT
+	case "summary fees":
+		run = summaryFees
+	default:
+		return fmt.Errorf("%w: %s %s", ErrUsage, cmd, target)
+	}
+	return run(store, rest, out)
+}
+
+/*
+Parses flags which may be mixed in with positional arguments
+(the flag package stops at the first positional argument), returning the
+positional arguments.
+*/
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrUsage, err)
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard) // errors are returned and reported by the caller
+	return fs
+}
+
+func parseDate(name, s string) (time.Time, error) {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: --%s must be in YYYY-MM-DD format", ErrUsage, name)
+	}
+	return d, nil
+}
+
+/* Converts a dollar amount to cents, rounding to the nearest cent */
+func toCents(amt float64) int {
+	return int(math.Round(amt * 100))
+}
+
+/* Splits a comma separated flag value, dropping empty items */
+func splitList(s string) []string {
+	var res []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			res = append(res, item)
+		}
+	}
+	return res
+}
+
+func requireFlags(fs *flag.FlagSet, names ...string) error {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for _, n := range names {
+		if !set[n] {
+			return fmt.Errorf("%w: --%s is required", ErrUsage, n)
+		}
+	}
+	return nil
+}
+
+// Adding
+
+func addRecord(store *backend.Store, args []string, out io.Writer) error {
+	fs := newFlagSet("add record")
+	date := fs.String("date", time.Now().Format("2006-01-02"), "")
+	cat := fs.String("cat", "", "")
+	amt := fs.Float64("amt", 0, "")
+	desc := fs.String("desc", "", "")
+	acct := fs.String("acct", "", "")
+	tags := fs.String("tags", "", "")
+	var splits []backend.Split
+	var splitCats []string // looked up once the flags are parsed
+	fs.Func("split", "", func(v string) error {
+		parts := strings.SplitN(v, ":", 3)
+		if len(parts) < 2 {
+			return errors.New("expected CAT:AMOUNT[:NOTE]")
+		}
+		amt, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil || amt == 0 {
+			return errors.New("split amounts must be non-zero numbers")
+		}
+		spl := backend.Split{Amt: toCents(amt)}
+		if len(parts) == 3 {
+			spl.Note = parts[2]
+		}
+		splits = append(splits, spl)
+		splitCats = append(splitCats, parts[0])
+		return nil
+	})
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := requireFlags(fs, "amt", "desc"); err != nil {
+		return err
+	}
+	if (*cat == "") == (len(splits) == 0) {
+		return fmt.Errorf("%w: expected one of --cat or --split", ErrUsage)
+	}
+
+	d, err := parseDate("date", *date)
+	if err != nil {
+		return err
+	}
+	catId := 0
+	if *cat != "" {
+		if catId, err = store.GetCategoryIdFromName(*cat); err != nil {
+			return err
+		}
+	}
+	for i, name := range splitCats {
+		if splits[i].CatId, err = store.GetCategoryIdFromName(name); err != nil {
+			return err
+		}
+	}
+	accId := 0 // first account
+	if *acct != "" {
+		if accId, err = store.GetAccountIdFromName(*acct); err != nil {
+			return err
+		}
+	}
+	if *amt == 0 {
+		return fmt.Errorf("%w: --amt can't be 0", ErrUsage)
+	}
+	return store.InsertRecord(backend.Record{Date: d, CatId: catId, AccId: accId, Desc: *desc, Amt: toCents(*amt), Tags: splitList(*tags), Splits: splits})
+}
+
+func addCategory(store *backend.Store, args []string, out io.Writer) error {
+	fs := newFlagSet("add category")
+	name := fs.String("name", "", "")
+	desc := fs.String("desc", "", "")
+	isIncome := fs.Bool("income", false, "")
+	parent := fs.String("parent", "", "")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := requireFlags(fs, "name"); err != nil {
+		return err
+	}
+
+	parentId := 0 // top level
+	if *parent != "" {
+		var err error
+		if parentId, err = store.GetCategoryIdFromName(*parent); err != nil {
+			return err
+		}
+	}
+	return store.InsertCategory(backend.Category{Name: *name, Desc: *desc, IsIncome: *isIncome, ParentId: parentId})
+}
+
+func addAccount(store *backend.Store, args []string, out io.Writer) error {
+	fs := newFlagSet("add account")
+	name := fs.String("name", "", "")
+	accType := fs.String("type", "", "")
+	opening := fs.Float64("opening", 0, "")
+	desc := fs.String("desc", "", "")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := requireFlags(fs, "name", "type"); err != nil {
+		return err
+	}
+	return store.InsertAccount(backend.Account{Name: *name, Type: *accType, Opening: toCents(*opening), Desc: *desc})
+}
+
+func addTransfer(store *backend.Store, args []string, out io.Writer) error {
+	fs := newFlagSet("add transfer")
+	date := fs.String("date", time.Now().Format("2006-01-02"), "")
+	from := fs.String("from", "", "")
+	to := fs.String("to", "", "")
+	amt := fs.Float64("amt", 0, "")
+	desc := fs.String("desc", "", "")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := requireFlags(fs, "from", "to", "amt", "desc"); err != nil {
+		return err
+	}
+
+	d, err := parseDate("date", *date)
+	if err != nil {
+		return err
+	}
+	fromId, err := store.GetAccountIdFromName(*from)
+	if err != nil {
+		return err
+	}
+	toId, err := store.GetAccountIdFromName(*to)
+	if err != nil {
+		return err
+	}
+	if *amt <= 0 {
+		return fmt.Errorf("%w: --amt must be positive", ErrUsage)
+	}
+	return store.InsertTransfer(backend.Transfer{Date: d, FromAccId: fromId, ToAccId: toId, Desc: *desc, Amt: toCents(*amt)})
+}
+
+func addBudget(store *backend.Store, args []string, out io.Writer) error {
+	fs := newFlagSet("add budget")
+	cat := fs.String("cat", "", "")
+	amt := fs.Float64("amt", 0, "")
+	yearly := fs.Bool("yearly", false, "")
+	rollover := fs.Bool("rollover", false, "")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := requireFlags(fs, "cat", "amt"); err != nil {
+		return err
+	}
+
+	catId, err := store.GetCategoryIdFromName(*cat)
+	if err != nil {
+		return err
+	}
+	if *amt <= 0 {
+		return fmt.Errorf("%w: --amt must be positive", ErrUsage)
+	}
+	return store.InsertBudget(backend.Budget{CatId: catId, Amt: toCents(*amt), Yearly: *yearly, Rollover: *rollover})
+}
+
+func addRule(store *backend.Store, args []string, out io.Writer) error {
+	fs := newFlagSet("add rule")
+	desc := fs.String("desc", "", "")
+	cat := fs.String("cat", "", "")
+	amt := fs.Float64("amt", 0, "")
+	freq := fs.String("freq", "", "")
+	day := fs.Int("day", 0, "")
+	start := fs.String("start", time.Now().Format("2006-01-02"), "")
+	end := fs.String("end", "", "")
+	acct := fs.String("acct", "", "")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := requireFlags(fs, "desc", "cat", "amt", "freq"); err != nil {
+		return err
+	}
+
+	rr := backend.RecurringRule{Desc: *desc, Amt: toCents(*amt), Freq: backend.Frequency(*freq), Day: *day}
+	var err error
+	if rr.Start, err = parseDate("start", *start); err != nil {
+		return err
+	}
+	if *end != "" {
+		if rr.End, err = parseDate("end", *end); err != nil {
+			return err
+		}
+	}
+	if rr.CatId, err = store.GetCategoryIdFromName(*cat); err != nil {
+		return err
+	}
+	if *acct != "" {
+		if rr.AccId, err = store.GetAccountIdFromName(*acct); err != nil {
+			return err
+		}
+	}
+	if rr.Amt == 0 {
+		return fmt.Errorf("%w: --amt can't be 0", ErrUsage)
+	}
+	if err := store.InsertRecurringRule(rr); err != nil {
+		return err
+	}
+
+	// create any records already due, rather than waiting for the next run
+	created, err := store.MaterialiseRecurring(time.Now())
+	for _, rec := range created {
+		fmt.Fprintf(out, "created recurring record: %s %s %.2f\n", rec.Date.Format("2006-01-02"), rec.Desc, float64(rec.Amt)/100)
+	}
+	return err
+}
+
+/* Creates the records of recurring rules which have fallen due, nothing else */
+func addRecurring(store *backend.Store, args []string, out io.Writer) error {
+	fs := newFlagSet("add recurring")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	created, err := store.MaterialiseRecurring(time.Now())
+	for _, rec := range created {
+		fmt.Fprintf(out, "created recurring record: %s %s %.2f\n", rec.Date.Format("2006-01-02"), rec.Desc, float64(rec.Amt)/100)
+	}
+	return err
+}
+
+func addInvestment(store *backend.Store, args []string, out io.Writer) error {
+	fs := newFlagSet("add investment")
+	date := fs.String("date", time.Now().Format("2006-01-02"), "")
+	code := fs.String("code", "", "")
+	price := fs.Float64("price", 0, "")
+	qty := fs.Float64("qty", 0, "")
+	fee := fs.Float64("fee", 0, "")
+	sell := fs.Bool("sell", false, "")
+	method := fs.String("method", "", "")
+	lot := fs.Int("lot", 0, "")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := requireFlags(fs, "code", "price", "qty"); err != nil {
+		return err
+	}
+
+	d, err := parseDate("date", *date)
+	if err != nil {
+		return err
+	}
+	if *price <= 0 || *qty == 0 || *fee < 0 {
+		return fmt.Errorf("%w: --price must be positive, --qty can't be 0 and --fee can't be negative", ErrUsage)
+	}
+
+	// a negative quantity is a sell, as in exports
+	inv := backend.Investment{Date: d, Code: *code, Unitprice: toCents(*price), Qty: float32(*qty), Fee: toCents(*fee)}
+	if *sell {
+		inv.Qty = -float32(math.Abs(*qty))
+	}
+	if *lot != 0 && *method == "" {
+		*method = string(backend.SpecificLot)
+	}
+	if *method != "" {
+		if !inv.IsSell() {
+			return fmt.Errorf("%w: --method and --lot are only for sells", ErrUsage)
+		}
+		if inv.Method, err = backend.ParseLotMethod(*method); err != nil {
+			return fmt.Errorf("%w: --method must be fifo, lifo, lot or average", ErrUsage)
+		}
+		if (inv.Method == backend.SpecificLot) != (*lot != 0) {
+			return fmt.Errorf("%w: --lot is needed for --method lot, and only for it", ErrUsage)
+		}
+		inv.LotId = *lot
+	}
+	return store.InsertInvestment(inv)
+}
+
+func addDividend(store *backend.Store, args []string, out io.Writer) error {
+	fs := newFlagSet("add dividend")
+	date := fs.String("date", time.Now().Format("2006-01-02"), "")
+	code := fs.String("code", "", "")
+	amt := fs.Float64("amt", 0, "")
+	franking := fs.Float64("franking", 0, "")
+	withholding := fs.Float64("withholding", 0, "")
+	drp := fs.Float64("drp", 0, "")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := requireFlags(fs, "code", "amt"); err != nil {
+		return err
+	}
+
+	d, err := parseDate("date", *date)
+	if err != nil {
+		return err
+	}
+	if *drp < 0 {
+		return fmt.Errorf("%w: --drp must be positive", ErrUsage)
+	}
+	return store.InsertDividend(backend.Dividend{
+		Date: d, Code: *code, Amt: toCents(*amt), Franking: toCents(*franking), Withholding: toCents(*withholding),
+		Reinvested: *drp > 0, DrpPrice: toCents(*drp),
+	})
+}
+
+func addAction(store *backend.Store, args []string, out io.Writer) error {
+	fs := newFlagSet("add action")
+	date := fs.String("date", time.Now().Format("2006-01-02"), "")
+	code := fs.String("code", "", "")
+	split := fs.String("split", "", "")
+	consolidate := fs.String("consolidate", "", "")
+	rename := fs.String("rename", "", "")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := requireFlags(fs, "code"); err != nil {
+		return err
+	}
+
+	d, err := parseDate("date", *date)
+	if err != nil {
+		return err
+	}
+	a := backend.CorporateAction{Date: d, Code: *code}
+	ratio := ""
+	switch {
+	case *split != "" && *consolidate == "" && *rename == "":
+		a.Type, ratio = backend.ActionSplit, *split
+	case *consolidate != "" && *split == "" && *rename == "":
+		a.Type, ratio = backend.ActionConsolidation, *consolidate
+	case *rename != "" && *split == "" && *consolidate == "":
+		a.Type, a.NewCode = backend.ActionRename, *rename
+	default:
+		return fmt.Errorf("%w: give one of --split, --consolidate or --rename", ErrUsage)
+	}
+	if ratio != "" {
+		newUnits, oldUnits, ok := strings.Cut(ratio, ":")
+		a.New, err = strconv.Atoi(newUnits)
+		if err == nil {
+			a.Old, err = strconv.Atoi(oldUnits)
+		}
+		if !ok || err != nil {
+			return fmt.Errorf("%w: the ratio must be NEW:OLD units, e.g. 2:1, got %q", ErrUsage, ratio)
+		}
+	}
+	return store.InsertCorporateAction(a)
+}
+
+// Listing
+
+func listActions(store *backend.Store, args []string, out io.Writer) error {
+	fs := newFlagSet("list actions")
+	code := fs.String("code", "", "")
+	asJson := fs.Bool("json", false, "")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	rows, err := store.GetCorporateActions()
+	if err != nil {
+		return err
+	}
+	var shown []backend.CorporateAction
+	actions := []actionJson{}
+	for _, r := range rows {
+		a := r.(backend.CorporateAction)
+		if *code != "" && !strings.EqualFold(a.Code, *code) && !strings.EqualFold(a.NewCode, *code) {
+			continue
+		}
+		shown = append(shown, a)
+		actions = append(actions, actionJson{Id: a.Id, Date: a.Date.Format("2006-01-02"), Type: string(a.Type), Code: a.Code, New: a.New, Old: a.Old, NewCode: a.NewCode})
+	}
+	if *asJson {
+		return exporter.WriteJson(out, actions)
+	}
+
+	tw := newTable(out, "ID", "Date", "Type", "Code", "Change")
+	for _, a := range shown {
+		fmt.Fprintln(tw, strings.Join(a.SpreadToStrings(), "\t"))
+	}
+	return tw.Flush()
+}
+
+func listDividends(store *backend.Store, args []string, out io.Writer) error {
+	fs := newFlagSet("list dividends")
+	code := fs.String("code", "", "")
+	asJson := fs.Bool("json", false, "")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	rows, err := store.GetDividends()
+	if err != nil {
+		return err
+	}
+	divs := []dividendJson{}
+	for _, r := range rows {
+		d := r.(backend.Dividend)
+		if *code != "" && !strings.EqualFold(d.Code, *code) {
+			continue
+		}
+		divs = append(divs, dividendJson{
+			Id: d.Id, Date: d.Date.Format("2006-01-02"), Code: d.Code, Amount: float64(d.Amt) / 100,
+			Franking: float64(d.Franking) / 100, Withholding: float64(d.Withholding) / 100,
+			DrpPrice: float64(d.DrpPrice) / 100, DrpQty: float64(d.DrpQty), Lot: d.InvId,
+		})
+	}
+	if *asJson {
+		return exporter.WriteJson(out, divs)
+	}
+
+	tw := newTable(out, "ID", "Date", "Code", "Amount", "Franking", "Withholding", "Reinvested")
+	for _, d := range divs {
+		drp := ""
+		if d.Lot != 0 {
+			drp = fmt.Sprintf("%g @ %.2f (lot %d)", d.DrpQty, d.DrpPrice, d.Lot)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%.2f\t%.2f\t%.2f\t%s\n", d.Id, d.Date, d.Code, d.Amount, d.Franking, d.Withholding, drp)
+	}
+	return tw.Flush()
+}
+
+/* Adds the flags shared by list commands for filtering by date, returns a function to build the filter */
+func dateFilterFlags(fs *flag.FlagSet) func(backend.FilterOpts) (backend.FilterOpts, error) {
+	from := fs.String("from", "", "")
+	to := fs.String("to", "", "")
+	fy := fs.Int("fy", 0, "")
+	return func(opts backend.FilterOpts) (backend.FilterOpts, error) {
+		if *fy != 0 {
+			if *from != "" || *to != "" {
+				return opts, fmt.Errorf("%w: --fy can't be used with --from or --to", ErrUsage)
+			}
+			start, end := backend.FinancialYear(*fy)
+			return opts.WithStartDate(start).WithEndDate(end), nil
+		}
+		if *from != "" {
+			d, err := parseDate("from", *from)
+			if err != nil {
+				return opts, err
+			}
+			opts = opts.WithStartDate(d)
+		}
+		if *to != "" {
+			d, err := parseDate("to", *to)
+			if err != nil {
+				return opts, err
+			}
+			opts = opts.WithEndDate(d.AddDate(0, 0, 1)) // inclusive
+		}
+		return opts, nil
+	}
+}
+
+/* Adds the flags for filtering records, returns a function to build the filter once the flags are parsed */
+func recordFilterFlags(fs *flag.FlagSet, store *backend.Store) func() (backend.FilterOpts, error) {
+	withDates := dateFilterFlags(fs)
+	cats := fs.String("cat", "", "")
+	accts := fs.String("acct", "", "")
+	tags := fs.String("tag", "", "")
+	search := fs.String("search", "", "")
+	minAmt := fs.Float64("min", math.NaN(), "")
+	maxAmt := fs.Float64("max", math.NaN(), "")
+	return func() (backend.FilterOpts, error) {
+		opts, err := withDates(backend.NewFilterOpts())
+		if err != nil {
+			return opts, err
+		}
+		if *cats != "" {
+			var ids []int
+			for _, name := range strings.Split(*cats, ",") {
+				id, err := store.GetCategoryIdFromName(strings.TrimSpace(name))
+				if err != nil {
+					return opts, err
+				}
+				ids = append(ids, id)
+			}
+			opts = opts.WithCatId(ids)
+		}
+		if *accts != "" {
+			var ids []int
+			for _, name := range strings.Split(*accts, ",") {
+				id, err := store.GetAccountIdFromName(strings.TrimSpace(name))
+				if err != nil {
+					return opts, err
+				}
+				ids = append(ids, id)
+			}
+			opts = opts.WithAccId(ids)
+		}
+		if *tags != "" {
+			opts = opts.WithTags(splitList(*tags))
+		}
+		opts = opts.WithText(*search)
+		if !math.IsNaN(*minAmt) {
+			opts = opts.WithMinCost(float32(toCents(*minAmt)))
+		}
+		if !math.IsNaN(*maxAmt) {
+			opts = opts.WithMaxCost(float32(toCents(*maxAmt)))
+		}
+		return opts, nil
+	}
+}
+
+func listRecords(store *backend.Store, args []string, out io.Writer) error {
+	fs := newFlagSet("list records")
+	filter := recordFilterFlags(fs, store)
+	asJson := fs.Bool("json", false, "")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	opts, err := filter()
+	if err != nil {
+		return err
+	}
+	rows, err := store.GetRecordsFilter(opts)
+	if err != nil {
+		return err
+	}
+	return writeRecords(out, rows, *asJson)
+}
+
+func listCategories(store *backend.Store, args []string, out io.Writer) error {
+	fs := newFlagSet("list categories")
+	asJson := fs.Bool("json", false, "")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	rows, err := store.GetCategories(0)
+	if err != nil {
+		return err
+	}
+
+	cats := make([]exporter.Category, len(rows))
+	for i, r := range rows {
+		cats[i] = exporter.FromCategory(r.(backend.Category))
+	}
+	if *asJson {
+		return exporter.WriteJson(out, cats)
+	}
+
+	tw := newTable(out, "ID", "Name", "Type", "Parent", "Description")
+	for _, c := range cats {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", c.Id, c.Name, c.Type, c.Parent, c.Desc)
+	}
+	return tw.Flush()
+}
+
+func listAccounts(store *backend.Store, args []string, out io.Writer) error {
+	fs := newFlagSet("list accounts")
+	asJson := fs.Bool("json", false, "")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	rows, err := store.GetAccounts()
+	if err != nil {
+		return err
+	}
+
+	accs := make([]accountJson, len(rows))
+	for i, r := range rows {
+		acc := r.(backend.Account)
+		accs[i] = accountJson{
+			Id: acc.Id, Name: acc.Name, Type: acc.Type, Desc: acc.Desc,
+			Opening: float64(acc.Opening) / 100, Balance: float64(acc.Balance) / 100,
+		}
+	}
+	if *asJson {
+		return exporter.WriteJson(out, accs)
+	}
+
+	tw := newTable(out, "ID", "Name", "Type", "Opening", "Balance", "Description")
+	for _, a := range accs {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%.2f\t%.2f\t%s\n", a.Id, a.Name, a.Type, a.Opening, a.Balance, a.Desc)
+	}
+	return tw.Flush()
+}
+
+func listBudgets(store *backend.Store, args []string, out io.Writer) error {
+	fs := newFlagSet("list budgets")
+	monthStr := fs.String("month", time.Now().Format("2006-01"), "")
+	asJson := fs.Bool("json", false, "")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	month, err := time.Parse("2006-01", *monthStr)
+	if err != nil {
+		return fmt.Errorf("%w: --month must be in YYYY-MM format", ErrUsage)
+	}
+
+	rows, err := store.GetBudgets(month)
+	if err != nil {
+		return err
+	}
+
+	budgets := make([]budgetJson, len(rows))
+	for i, r := range rows {
+		br := r.(backend.BudgetRow)
+		period := "monthly"
+		if br.Yearly {
+			period = "yearly"
+		}
+		budgets[i] = budgetJson{
+			Category: br.CatName, Period: period, Rollover: br.Rollover,
+			Budget: float64(br.Amt) / 100, Available: float64(br.Available) / 100,
+			Spent: float64(br.Spent) / 100, Remaining: float64(br.Remaining()) / 100,
+		}
+	}
+	if *asJson {
+		return exporter.WriteJson(out, budgets)
+	}
+
+	tw := newTable(out, "Category", "Period", "Budget", "Available", "Spent", "Remaining")
+	for _, b := range budgets {
+		fmt.Fprintf(tw, "%s\t%s\t%.2f\t%.2f\t%.2f\t%.2f\n", b.Category, b.Period, b.Budget, b.Available, b.Spent, b.Remaining)
+	}
+	return tw.Flush()
+}
+
+func listRules(store *backend.Store, args []string, out io.Writer) error {
+	fs := newFlagSet("list rules")
+	asJson := fs.Bool("json", false, "")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	rows, err := store.GetRecurringRules()
+	if err != nil {
+		return err
+	}
+
+	rules := make([]ruleJson, len(rows))
+	for i, r := range rows {
+		rr := r.(backend.RecurringRule)
+		rules[i] = ruleJson{
+			Id: rr.Id, Desc: rr.Desc, Category: rr.CatName, Account: rr.AccName, Amount: float64(rr.Amt) / 100,
+			Freq: string(rr.Freq), Day: rr.Day, Start: rr.Start.Format("2006-01-02"), Paused: rr.Paused,
+		}
+		if !rr.End.IsZero() {
+			rules[i].End = rr.End.Format("2006-01-02")
+		}
+		if !rr.Ended() {
+			rules[i].Next = rr.Next.Format("2006-01-02")
+		}
+	}
+	if *asJson {
+		return exporter.WriteJson(out, rules)
+	}
+
+	tw := newTable(out, "ID", "Description", "Category", "Account", "Amount", "Schedule", "Start", "End", "Next")
+	for i, r := range rows {
+		s := r.(backend.RecurringRule).SpreadToStrings()
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%.2f\t%s\t%s\t%s\t%s\n", rules[i].Id, rules[i].Desc, rules[i].Category, rules[i].Account,
+			rules[i].Amount, s[5], s[6], s[7], strings.TrimSpace(s[8]))
+	}
+	return tw.Flush()
+}
+
+func listInvestments(store *backend.Store, args []string, out io.Writer) error {
+	fs := newFlagSet("list investments")
+	withDates := dateFilterFlags(fs)
+	code := fs.String("code", "", "")
+	asJson := fs.Bool("json", false, "")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	opts, err := withDates(backend.NewFilterOpts().WithCode(*code))
+	if err != nil {
+		return err
+	}
+	rows, err := store.GetInvestmentsFilter(opts)
+	if err != nil {
+		return err
+	}
+
+	invs := make([]exporter.Investment, len(rows))
+	for i, r := range rows {
+		invs[i] = exporter.FromInvestment(r.(backend.Investment))
+	}
+	if *asJson {
+		return exporter.WriteJson(out, invs)
+	}
+
+	tw := newTable(out, "ID", "Date", "Type", "Code", "Unitprice", "Qty", "Total", "Fee")
+	for i, inv := range invs {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%.2f\t%g\t%.2f\t%.2f\n", inv.Id, inv.Date, rows[i].(backend.Investment).TypeString(), inv.Code,
+			inv.Unitprice, math.Abs(inv.Qty), math.Abs(inv.Unitprice*inv.Qty), inv.Fee)
+	}
+	return tw.Flush()
+}
+
+// Exporting
+
+/*
+Adds the flags shared by export commands, returns a function which writes the
+export once the flags are parsed. journal chooses between the journal formats
+and csv/json.
+*/
+func exportFlags(fs *flag.FlagSet, out io.Writer, journal bool) func(write func(io.Writer, exporter.Format) error) error {
+	format := fs.String("format", "", "")
+	outPath := fs.String("out", "", "")
+	return func(write func(io.Writer, exporter.Format) error) error {
+		if *format == "" {
+			*format = string(defaultFormat(*outPath, journal))
+		}
+		f, err := exporter.ParseFormat(*format)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrUsage, err)
+		}
+		if exporter.IsJournalFormat(f) != journal {
+			return fmt.Errorf("%w: can't export %s with this command", ErrUsage, f)
+		}
+		if *outPath == "" {
+			return write(out, f)
+		}
+
+		file, err := os.Create(*outPath)
+		if err != nil {
+			return err
+		}
+		if err := write(file, f); err != nil {
+			file.Close()
+			return err
+		}
+		return file.Close()
+	}
+}
+
+/* Picks the export format from the output file's extension */
+func defaultFormat(path string, journal bool) exporter.Format {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch {
+	case journal && (ext == ".beancount" || ext == ".bean"):
+		return exporter.Beancount
+	case journal && ext == ".hledger":
+		return exporter.Hledger
+	case journal:
+		return exporter.Ledger
+	case ext == ".json":
+		return exporter.JSON
+	}
+	return exporter.CSV
+}
+
+func exportRecords(store *backend.Store, args []string, out io.Writer) error {
+	fs := newFlagSet("export records")
+	filter := recordFilterFlags(fs, store)
+	export := exportFlags(fs, out, false)
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	opts, err := filter()
+	if err != nil {
+		return err
+	}
+	rows, err := store.GetRecordsFilter(opts)
+	if err != nil {
+		return err
+	}
+	return export(func(w io.Writer, f exporter.Format) error { return exporter.WriteRecords(w, f, rows) })
+}
+
+func exportCategories(store *backend.Store, args []string, out io.Writer) error {
+	fs := newFlagSet("export categories")
+	export := exportFlags(fs, out, false)
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	rows, err := store.GetCategories(0)
+	if err != nil {
+		return err
+	}
+	return export(func(w io.Writer, f exporter.Format) error { return exporter.WriteCategories(w, f, rows) })
+}
+
+func exportInvestments(store *backend.Store, args []string, out io.Writer) error {
+	fs := newFlagSet("export investments")
+	withDates := dateFilterFlags(fs)
+	code := fs.String("code", "", "")
+	export := exportFlags(fs, out, false)
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	opts, err := withDates(backend.NewFilterOpts().WithCode(*code))
+	if err != nil {
+		return err
+	}
+	rows, err := store.GetInvestmentsFilter(opts)
+	if err != nil {
+		return err
+	}
+	return export(func(w io.Writer, f exporter.Format) error { return exporter.WriteInvestments(w, f, rows) })
+}
+
+/* Exports the capital gains report of a financial year */
+func exportCGT(store *backend.Store, args []string, out io.Writer) error {
+	fs := newFlagSet("export cgt")
+	fy := fs.Int("fy", backend.FinancialYearOf(time.Now()), "")
+	export := exportFlags(fs, out, false)
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	report, err := store.GetCapitalGains(*fy)
+	if err != nil {
+		return err
+	}
+	return export(func(w io.Writer, f exporter.Format) error { return exporter.WriteCapitalGains(w, f, report) })
+}
+
+/* Exports records and investments in a date range, as a ledger, hledger or beancount journal */
+func exportJournal(store *backend.Store, args []string, out io.Writer) error {
+	fs := newFlagSet("export journal")
+	withDates := dateFilterFlags(fs)
+	export := exportFlags(fs, out, true)
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	opts, err := withDates(backend.NewFilterOpts())
+	if err != nil {
+		return err
+	}
+	var j exporter.Journal
+	recs, err := store.GetRecordsFilter(opts)
+	if err != nil {
+		return err
+	}
+	for _, r := range recs {
+		j.Records = append(j.Records, r.(backend.Record))
+	}
+	invs, err := store.GetInvestmentsFilter(opts)
+	if err != nil {
+		return err
+	}
+	for _, r := range invs {
+		j.Investments = append(j.Investments, r.(backend.Investment))
+	}
+	cats, err := store.GetCategories(0)
+	if err != nil {
+		return err
+	}
+	for _, r := range cats {
+		j.Categories = append(j.Categories, r.(backend.Category))
+	}
+	return export(func(w io.Writer, f exporter.Format) error { return exporter.WriteJournal(w, f, j) })
+}
+
+// Importing
+
+/* Imports the records and investments in a journal, creating the categories it needs */
+func importJournal(store *backend.Store, args []string, out io.Writer) error {
+	fs := newFlagSet("import journal")
+	paths, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(paths) != 1 {
+		return fmt.Errorf("%w: expected a journal file to import", ErrUsage)
+	}
+
+	var existing []backend.Category
+	rows, err := store.GetCategories(0)
+	if err != nil {
+		return err
+	}
+	for _, r := range rows {
+		existing = append(existing, r.(backend.Category))
+	}
+
+	f, err := os.Open(paths[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	st, err := importer.ParseJournal(f, existing)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range st.Categories {
+		if err := store.InsertCategory(c); err != nil {
+			return err
+		}
+	}
+	for i := range st.Records {
+		if st.Records[i].CatId, err = store.GetCategoryIdFromName(st.Records[i].CatName); err != nil {
+			return err
+		}
+	}
+	nRecs, err := store.InsertRecords(st.Records)
+	if err != nil {
+		return err
+	}
+	nInvs, err := store.InsertInvestments(st.Investments)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "imported %d records and %d investments, skipped %d already imported, created %d categories\n",
+		nRecs, nInvs, len(st.Records)+len(st.Investments)-nRecs-nInvs, len(st.Categories))
+	return nil
+}
+
+// Summaries
+
+func summaryMonth(store *backend.Store, args []string, out io.Writer) error {
+	fs := newFlagSet("summary month")
+	asJson := fs.Bool("json", false, "")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("%w: expected a month in YYYY-MM format", ErrUsage)
+	}
+	month, err := time.Parse("2006-01", positional[0])
+	if err != nil {
+		return fmt.Errorf("%w: expected a month in YYYY-MM format", ErrUsage)
+	}
+
+	rows, income, expenditure, err := store.GetMonthInfo(month)
+	if err != nil {
+		return err
+	}
+
+	summary := monthSummaryJson{
+		Month:       month.Format("2006-01"),
+		Income:      float64(income) / 100,
+		Expenditure: float64(expenditure) / 100,
+		NetChange:   float64(income-expenditure) / 100,
+		Records:     make([]exporter.Record, len(rows)),
+	}
+	for i, r := range rows {
+		summary.Records[i] = exporter.FromRecord(r.(backend.Record))
+	}
+	if *asJson {
+		return exporter.WriteJson(out, summary)
+	}
+
+	fmt.Fprintf(out, "%s\n\nIncome:      %10.2f\nExpenditure: %10.2f\nNet Change:  %10.2f\n\n",
+		month.Format("January 2006"), summary.Income, summary.Expenditure, summary.NetChange)
+	return writeRecords(out, rows, false)
+}
+
+func summaryYear(store *backend.Store, args []string, out io.Writer) error {
+	fs := newFlagSet("summary year")
+	asJson := fs.Bool("json", false, "")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("%w: expected a year in YYYY format", ErrUsage)
+	}
+	year, err := time.Parse("2006", positional[0])
+	if err != nil {
+		return fmt.Errorf("%w: expected a year in YYYY format", ErrUsage)
+	}
+
+	rows, err := store.GetYearSummary(year.Year())
+	if err != nil {
+		return err
+	}
+
+	var summary []yearRowJson
+	for _, r := range rows {
+		cy := r.(*backend.CategoryYear)
+		if cy.CatId == -2 { // divider
+			continue
+		}
+		row := yearRowJson{Category: yearRowLabel(cy), Depth: cy.Depth}
+		for i, sum := range cy.MonthSums {
+			row.Months[i] = float64(sum) / 100
+			row.Total += float64(sum) / 100
+		}
+		summary = append(summary, row)
+	}
+	if *asJson {
+		return exporter.WriteJson(out, summary)
+	}
+
+	tw := newTable(out, "Category", "Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec", "Total")
+	for _, row := range summary {
+		fmt.Fprint(tw, strings.Repeat("  ", row.Depth)+row.Category)
+		for _, m := range row.Months {
+			fmt.Fprintf(tw, "\t%.2f", m)
+		}
+		fmt.Fprintf(tw, "\t%.2f\n", row.Total)
+	}
+	return tw.Flush()
+}
+
+func summaryTags(store *backend.Store, args []string, out io.Writer) error {
+	fs := newFlagSet("summary tags")
+	from := fs.String("from", "", "")
+	to := fs.String("to", "", "")
+	fy := fs.Int("fy", backend.FinancialYearOf(time.Now()), "")
+	asJson := fs.Bool("json", false, "")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	start, end := backend.FinancialYear(*fy)
+	var err error
+	if *from != "" {
+		if start, err = parseDate("from", *from); err != nil {
+			return err
+		}
+	}
+	if *to != "" {
+		if end, err = parseDate("to", *to); err != nil {
+			return err
+		}
+		end = end.AddDate(0, 0, 1) // inclusive
+	}
+
+	rows, err := store.GetTagTotals(start, end)
+	if err != nil {
+		return err
+	}
+
+	totals := make([]tagTotalJson, len(rows))
+	for i, r := range rows {
+		tt := r.(backend.TagTotal)
+		totals[i] = tagTotalJson{
+			Tag: tt.Name, Records: tt.Records, Income: float64(tt.Income) / 100,
+			Expenditure: float64(tt.Expenditure) / 100, Net: float64(tt.Net()) / 100,
+		}
+	}
+	if *asJson {
+		return exporter.WriteJson(out, totals)
+	}
+
+	fmt.Fprintf(out, "%s to %s\n\n", start.Format("2006-01-02"), end.AddDate(0, 0, -1).Format("2006-01-02"))
+	tw := newTable(out, "Tag", "Records", "Income", "Expenditure", "Net")
+	for _, tt := range totals {
+		fmt.Fprintf(tw, "#%s\t%d\t%.2f\t%.2f\t%.2f\n", tt.Tag, tt.Records, tt.Income, tt.Expenditure, tt.Net)
+	}
+	return tw.Flush()
+}
+
+/* Lists a financial year's disposals of investments, with the net capital gain after losses and the discount */
+func summaryCGT(store *backend.Store, args []string, out io.Writer) error {
+	fs := newFlagSet("summary cgt")
+	fy := fs.Int("fy", backend.FinancialYearOf(time.Now()), "")
+	asJson := fs.Bool("json", false, "")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	report, err := store.GetCapitalGains(*fy)
+	if err != nil {
+		return err
+	}
+	cg := exporter.FromCGTReport(report)
+	if *asJson {
+		return exporter.WriteJson(out, cg)
+	}
+
+	start, end := backend.FinancialYear(*fy)
+	fmt.Fprintf(out, "%s to %s\n\n", start.Format("2006-01-02"), end.AddDate(0, 0, -1).Format("2006-01-02"))
+	tw := newTable(out, "Sell", "Sold", "Code", "Qty", "Acquired", "Cost Base", "Proceeds", "Gain", "Discount")
+	for _, e := range cg.Disposals {
+		discount := ""
+		if e.Discounted {
+			discount = "Yes"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%g\t%s\t%.2f\t%.2f\t%.2f\t%s\n", e.SellId, e.Sold, e.Code, e.Qty, e.Acquired,
+			e.CostBase, e.Proceeds, e.Gain, discount)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "\nDiscountable gains:  %10.2f\nOther gains:         %10.2f\nLosses:              %10.2f\n", cg.DiscountGains, cg.OtherGains, cg.Losses)
+	fmt.Fprintf(out, "Losses carried in:   %10.2f\nDiscount:            %10.2f\nNet capital gain:    %10.2f\n", cg.CarriedIn, cg.Discount, cg.NetGain)
+	_, err = fmt.Fprintf(out, "Losses carried out:  %10.2f\n", cg.CarriedOut)
+	return err
+}
+
+func summaryFees(store *backend.Store, args []string, out io.Writer) error {
+	fs := newFlagSet("summary fees")
+	fy := fs.Int("fy", backend.FinancialYearOf(time.Now()), "")
+	asJson := fs.Bool("json", false, "")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	fees, err := store.GetFees(*fy)
+	if err != nil {
+		return err
+	}
+	totals := make([]feeTotalJson, len(fees))
+	total := feeTotalJson{Code: "Total"}
+	for i, f := range fees {
+		totals[i] = feeTotalJson{Code: f.Code, Trades: f.Trades, Buys: float64(f.Buys) / 100, Sells: float64(f.Sells) / 100, Total: float64(f.Total()) / 100}
+		total.Trades += f.Trades
+		total.Buys += totals[i].Buys
+		total.Sells += totals[i].Sells
+		total.Total += totals[i].Total
+	}
+	if *asJson {
+		return exporter.WriteJson(out, totals)
+	}
+
+	start, end := backend.FinancialYear(*fy)
+	fmt.Fprintf(out, "%s to %s\n\n", start.Format("2006-01-02"), end.AddDate(0, 0, -1).Format("2006-01-02"))
+	tw := newTable(out, "Code", "Trades", "On Buys", "On Sells", "Total")
+	for _, f := range append(totals, total) {
+		fmt.Fprintf(tw, "%s\t%d\t%.2f\t%.2f\t%.2f\n", f.Code, f.Trades, f.Buys, f.Sells, f.Total)
+	}
+	return tw.Flush()
+}
+
+/* Plain text name for a row of the year summary, without the TUI's colour tags */
+func yearRowLabel(cy *backend.CategoryYear) string {
+	switch cy.CatId {
+	case 0:
+		return "Net Change"
+	case -3:
+		return "Total Income"
+	case -4:
+		return "Total Expenditure"
+	default:
+		return cy.Name
+	}
+}
+
+// Output
+
+/* Returns a tabwriter with the header row already written, call Flush once all rows are written */
+func newTable(out io.Writer, headers ...string) *tabwriter.Writer {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	return tw
+}
+
+func writeRecords(out io.Writer, rows []backend.DataRow, asJson bool) error {
+	recs := make([]exporter.Record, len(rows))
+	for i, r := range rows {
+		recs[i] = exporter.FromRecord(r.(backend.Record))
+	}
+	if asJson {
+		return exporter.WriteJson(out, recs)
+	}
+
+	tw := newTable(out, "ID", "Date", "Category", "Account", "Description", "Amount", "Tags")
+	for _, r := range recs {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%.2f\t%s\n", r.Id, r.Date, r.Category, r.Account, r.Desc, r.Amount, strings.Join(r.Tags, " "))
+	}
+	return tw.Flush()
+}
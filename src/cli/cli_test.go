@@ -0,0 +1,600 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shen-kit/finance-tracker/backend"
+	"github.com/shen-kit/finance-tracker/exporter"
+)
+
+func newTestStore(t *testing.T) *backend.Store {
+	t.Helper()
+	s, err := backend.SetupDb(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+/* Runs a command, failing the test on error, returns the output */
+func run(t *testing.T, s *backend.Store, args ...string) string {
+	t.Helper()
+	var out bytes.Buffer
+	if err := Run(s, args, &out); err != nil {
+		t.Fatalf("%v: %v", args, err)
+	}
+	return out.String()
+}
+
+func seed(t *testing.T, s *backend.Store) {
+	run(t, s, "add", "category", "--name", "Groceries")
+	run(t, s, "add", "category", "--name", "Work", "--income")
+	run(t, s, "add", "record", "--date", "2026-10-01", "--cat", "Groceries", "--amt", "-42.50", "--desc", "Coles")
+	run(t, s, "add", "record", "--date", "2026-09-15", "--cat", "Work", "--amt", "3000", "--desc", "pay")
+	run(t, s, "add", "record", "--date", "2026-09-20", "--cat", "Groceries", "--amt", "-10.01", "--desc", "Aldi")
+}
+
+func TestAddAndListRecords(t *testing.T) {
+	s := newTestStore(t)
+	seed(t, s)
+
+	out := run(t, s, "list", "records")
+	for _, want := range []string{"Coles", "-42.50", "pay", "3000.00", "Groceries"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestListRecordsFilters(t *testing.T) {
+	s := newTestStore(t)
+	seed(t, s)
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"--cat", "Groceries"}, []string{"Aldi", "Coles"}},
+		{[]string{"--cat", "Groceries,Work"}, []string{"pay", "Aldi", "Coles"}},
+		{[]string{"--from", "2026-09-16"}, []string{"Aldi", "Coles"}},
+		{[]string{"--to", "2026-09-20"}, []string{"pay", "Aldi"}},
+		{[]string{"--max", "-20"}, []string{"Coles"}},
+		{[]string{"--min", "-20", "--max", "0"}, []string{"Aldi"}},
+		{[]string{"--search", "col", "--cat", "Groceries"}, []string{"Coles"}},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			var recs []exporter.Record
+			out := run(t, s, append([]string{"list", "records", "--json"}, tt.args...)...)
+			if err := json.Unmarshal([]byte(out), &recs); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range recs {
+				got = append(got, r.Desc)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummaryMonthJson(t *testing.T) {
+	s := newTestStore(t)
+	seed(t, s)
+
+	var summary monthSummaryJson
+	if err := json.Unmarshal([]byte(run(t, s, "summary", "month", "2026-09", "--json")), &summary); err != nil {
+		t.Fatal(err)
+	}
+	if summary.Income != 3000 || summary.Expenditure != 10.01 || summary.NetChange != 2989.99 || len(summary.Records) != 2 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+}
+
+func TestSummaryYear(t *testing.T) {
+	s := newTestStore(t)
+	seed(t, s)
+
+	var rows []yearRowJson
+	if err := json.Unmarshal([]byte(run(t, s, "summary", "year", "2026", "--json")), &rows); err != nil {
+		t.Fatal(err)
+	}
+	last := rows[len(rows)-1]
+	if last.Category != "Net Change" || last.Months[8] != 2989.99 || last.Months[9] != -42.5 {
+		t.Errorf("unexpected net change row %+v", last)
+	}
+
+	out := run(t, s, "summary", "year", "2026")
+	if strings.Contains(out, "[orange") {
+		t.Errorf("colour tags in plain text output:\n%s", out)
+	}
+}
+
+func TestSubcategories(t *testing.T) {
+	s := newTestStore(t)
+	seed(t, s)
+	run(t, s, "add", "category", "--name", "Takeaway", "--parent", "Groceries")
+	run(t, s, "add", "record", "--date", "2026-10-03", "--cat", "Takeaway", "--amt", "-20", "--desc", "pizza")
+
+	var rows []yearRowJson
+	if err := json.Unmarshal([]byte(run(t, s, "summary", "year", "2026", "--json")), &rows); err != nil {
+		t.Fatal(err)
+	}
+	// Work, total income, then expenditure (dividers are skipped)
+	if len(rows) < 4 || rows[2].Category != "Groceries" || rows[2].Months[9] != -62.5 || rows[3].Category != "Takeaway" || rows[3].Depth != 1 {
+		t.Errorf("unexpected year summary %+v", rows)
+	}
+	if out := run(t, s, "list", "categories"); !strings.Contains(out, "Groceries") || !strings.Contains(out, "Takeaway") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+
+	err := Run(s, []string{"add", "category", "--name", "Bonus", "--parent", "Groceries", "--income"}, &bytes.Buffer{})
+	if !errors.Is(err, backend.ErrConstraint) {
+		t.Errorf("income subcategory of an expenditure category: got %v, want ErrConstraint", err)
+	}
+}
+
+func TestTags(t *testing.T) {
+	s := newTestStore(t)
+	seed(t, s)
+	run(t, s, "add", "record", "--date", "2026-10-05", "--cat", "Groceries", "--amt", "-20", "--desc", "airport", "--tags", "#holiday-japan-2026, tax-deductible")
+	run(t, s, "add", "record", "--date", "2026-10-06", "--cat", "Work", "--amt", "50", "--desc", "refund", "--tags", "tax-deductible")
+
+	var recs []exporter.Record
+	if err := json.Unmarshal([]byte(run(t, s, "list", "records", "--tag", "holiday-japan-2026", "--json")), &recs); err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 1 || recs[0].Desc != "airport" || len(recs[0].Tags) != 2 {
+		t.Errorf("unexpected records %+v", recs)
+	}
+
+	var totals []tagTotalJson
+	if err := json.Unmarshal([]byte(run(t, s, "summary", "tags", "--fy", "2027", "--json")), &totals); err != nil {
+		t.Fatal(err)
+	}
+	want := []tagTotalJson{
+		{Tag: "holiday-japan-2026", Records: 1, Expenditure: -20, Net: -20},
+		{Tag: "tax-deductible", Records: 2, Income: 50, Expenditure: -20, Net: 30},
+	}
+	if !slices.Equal(totals, want) {
+		t.Errorf("got tag totals %+v, want %+v", totals, want)
+	}
+	if out := run(t, s, "summary", "tags", "--from", "2026-10-06", "--to", "2026-10-06"); !strings.Contains(out, "#tax-deductible") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+
+	err := Run(s, []string{"add", "record", "--cat", "Work", "--amt", "1", "--desc", "bad", "--tags", "not valid"}, &bytes.Buffer{})
+	if !errors.Is(err, backend.ErrConstraint) {
+		t.Errorf("invalid tag: got %v, want ErrConstraint", err)
+	}
+}
+
+func TestSplitRecord(t *testing.T) {
+	s := newTestStore(t)
+	seed(t, s)
+	run(t, s, "add", "record", "--date", "2026-10-03", "--amt", "-100", "--desc", "receipt",
+		"--split", "Groceries:-60", "--split", "Work:-40:refund owed")
+
+	var recs []exporter.Record
+	if err := json.Unmarshal([]byte(run(t, s, "list", "records", "--cat", "Work", "--from", "2026-10-01", "--json")), &recs); err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 1 || recs[0].Category != "Groceries (-60.00); Work (-40.00)" || recs[0].Splits[1].Note != "refund owed" {
+		t.Errorf("unexpected records %+v", recs)
+	}
+
+	for _, args := range [][]string{
+		{"--split", "Groceries:-60", "--split", "Work:-30"}, // doesn't add up
+		{"--split", "Groceries:-100"},                       // single line
+	} {
+		err := Run(s, append([]string{"add", "record", "--amt", "-100", "--desc", "bad"}, args...), &bytes.Buffer{})
+		if !errors.Is(err, backend.ErrConstraint) {
+			t.Errorf("%v: got %v, want ErrConstraint", args, err)
+		}
+	}
+	err := Run(s, []string{"add", "record", "--cat", "Work", "--amt", "-100", "--desc", "bad", "--split", "Groceries:-100"}, &bytes.Buffer{})
+	if !errors.Is(err, ErrUsage) {
+		t.Errorf("--cat and --split: got %v, want ErrUsage", err)
+	}
+}
+
+func TestInvestments(t *testing.T) {
+	s := newTestStore(t)
+	run(t, s, "add", "investment", "--date", "2026-01-05", "--code", "IVV", "--price", "550.10", "--qty", "3")
+	run(t, s, "add", "investment", "--date", "2026-02-05", "--code", "VGS.AX", "--price", "120", "--qty", "10")
+
+	var invs []exporter.Investment
+	if err := json.Unmarshal([]byte(run(t, s, "list", "investments", "--code", "IVV", "--json")), &invs); err != nil {
+		t.Fatal(err)
+	}
+	if len(invs) != 1 || invs[0].Unitprice != 550.1 || invs[0].Qty != 3 {
+		t.Errorf("unexpected investments %+v", invs)
+	}
+
+	// sells
+	run(t, s, "add", "investment", "--date", "2026-03-05", "--code", "IVV", "--price", "600", "--qty", "1", "--sell", "--lot", "1")
+	if err := json.Unmarshal([]byte(run(t, s, "list", "investments", "--code", "IVV", "--json")), &invs); err != nil {
+		t.Fatal(err)
+	}
+	if len(invs) != 2 || invs[0].Qty != -1 || invs[0].Method != "lot" || invs[0].Lot != 1 {
+		t.Errorf("unexpected sell %+v", invs)
+	}
+	for _, args := range [][]string{
+		{"--qty", "1", "--method", "lifo"},                         // a buy
+		{"--qty", "1", "--sell", "--method", "newest"},             // unknown method
+		{"--qty", "1", "--sell", "--method", "lot"},                // no lot
+		{"--qty", "1", "--sell", "--method", "fifo", "--lot", "1"}, // lot without the method
+	} {
+		err := Run(s, append([]string{"add", "investment", "--code", "IVV", "--price", "600"}, args...), &bytes.Buffer{})
+		if !errors.Is(err, ErrUsage) {
+			t.Errorf("%v: got %v, want ErrUsage", args, err)
+		}
+	}
+	err := Run(s, []string{"add", "investment", "--code", "IVV", "--price", "600", "--qty", "-5"}, &bytes.Buffer{})
+	if !errors.Is(err, backend.ErrConstraint) {
+		t.Errorf("overselling: got %v, want ErrConstraint", err)
+	}
+
+	// capital gains of the sell, held for under 12 months
+	var cg exporter.CGTReport
+	if err := json.Unmarshal([]byte(run(t, s, "summary", "cgt", "--fy", "2026", "--json")), &cg); err != nil {
+		t.Fatal(err)
+	}
+	if len(cg.Disposals) != 1 || cg.Disposals[0].Gain != 49.9 || cg.Disposals[0].Discounted || cg.NetGain != 49.9 {
+		t.Errorf("unexpected capital gains %+v", cg)
+	}
+	if out := run(t, s, "export", "cgt", "--fy", "2026"); !strings.Contains(out, "3,2026-03-05,IVV,1,1,2026-01-05,550.10,600.00,49.90,no\n") ||
+		!strings.Contains(out, "net capital gain,49.90\n") {
+		t.Errorf("unexpected export:\n%s", out)
+	}
+}
+
+func TestFees(t *testing.T) {
+	s := newTestStore(t)
+	run(t, s, "add", "investment", "--date", "2026-01-05", "--code", "IVV", "--price", "100", "--qty", "10", "--fee", "10")
+	run(t, s, "add", "investment", "--date", "2026-03-05", "--code", "IVV", "--price", "120", "--qty", "10", "--sell", "--fee", "5")
+
+	// $1010 cost base, $1195 proceeds
+	var cg exporter.CGTReport
+	if err := json.Unmarshal([]byte(run(t, s, "summary", "cgt", "--fy", "2026", "--json")), &cg); err != nil {
+		t.Fatal(err)
+	}
+	if len(cg.Disposals) != 1 || cg.Disposals[0].CostBase != 1010 || cg.Disposals[0].Proceeds != 1195 || cg.NetGain != 185 {
+		t.Errorf("unexpected capital gains %+v", cg)
+	}
+
+	var fees []feeTotalJson
+	if err := json.Unmarshal([]byte(run(t, s, "summary", "fees", "--fy", "2026", "--json")), &fees); err != nil {
+		t.Fatal(err)
+	}
+	if len(fees) != 1 || fees[0] != (feeTotalJson{Code: "IVV", Trades: 2, Buys: 10, Sells: 5, Total: 15}) {
+		t.Errorf("unexpected fees %+v", fees)
+	}
+	if out := run(t, s, "summary", "fees", "--fy", "2025"); !strings.Contains(out, "Total  0       0.00     0.00      0.00") {
+		t.Errorf("unexpected fees for a year without any:\n%s", out)
+	}
+
+	err := Run(s, []string{"add", "investment", "--code", "IVV", "--price", "100", "--qty", "1", "--fee", "-1"}, &bytes.Buffer{})
+	if !errors.Is(err, ErrUsage) {
+		t.Errorf("negative fee: got %v, want ErrUsage", err)
+	}
+}
+
+func TestCorporateActions(t *testing.T) {
+	s := newTestStore(t)
+	run(t, s, "add", "investment", "--date", "2026-01-05", "--code", "IVV", "--price", "100", "--qty", "10")
+	run(t, s, "add", "action", "--date", "2026-03-01", "--code", "IVV", "--split", "2:1")
+	run(t, s, "add", "action", "--date", "2026-04-01", "--code", "IVV", "--rename", "IVVN")
+	run(t, s, "add", "investment", "--date", "2026-05-01", "--code", "IVVN", "--price", "60", "--qty", "20", "--sell")
+
+	var actions []actionJson
+	if err := json.Unmarshal([]byte(run(t, s, "list", "actions", "--code", "ivvn", "--json")), &actions); err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 1 || actions[0] != (actionJson{Id: 2, Date: "2026-04-01", Type: "rename", Code: "IVV", New: 1, Old: 1, NewCode: "IVVN"}) {
+		t.Errorf("unexpected actions %+v", actions)
+	}
+	if out := run(t, s, "list", "actions"); !strings.Contains(out, "2 for 1") || !strings.Contains(out, "to IVVN") {
+		t.Errorf("unexpected actions table:\n%s", out)
+	}
+
+	// the 20 units sold are the 10 bought, split
+	var cg exporter.CGTReport
+	if err := json.Unmarshal([]byte(run(t, s, "summary", "cgt", "--fy", "2026", "--json")), &cg); err != nil {
+		t.Fatal(err)
+	}
+	if len(cg.Disposals) != 1 || cg.Disposals[0].CostBase != 1000 || cg.Disposals[0].Proceeds != 1200 {
+		t.Errorf("unexpected capital gains %+v", cg)
+	}
+
+	for _, args := range [][]string{
+		{"--split", "2"},                       // no old units
+		{"--split", "2:1", "--rename", "IVVX"}, // two actions
+		{},                                     // no action
+	} {
+		err := Run(s, append([]string{"add", "action", "--code", "IVVN"}, args...), &bytes.Buffer{})
+		if !errors.Is(err, ErrUsage) {
+			t.Errorf("%v: got %v, want ErrUsage", args, err)
+		}
+	}
+	err := Run(s, []string{"add", "action", "--code", "IVVN", "--date", "2026-04-15", "--consolidate", "1:2"}, &bytes.Buffer{})
+	if !errors.Is(err, backend.ErrConstraint) {
+		t.Errorf("consolidating units sold later: got %v, want ErrConstraint", err)
+	}
+}
+
+func TestDividends(t *testing.T) {
+	s := newTestStore(t)
+	run(t, s, "add", "investment", "--date", "2026-01-05", "--code", "VAS.AX", "--price", "100", "--qty", "10")
+	run(t, s, "add", "dividend", "--date", "2026-04-01", "--code", "VAS.AX", "--amt", "25", "--franking", "8.50")
+	run(t, s, "add", "dividend", "--date", "2026-07-01", "--code", "VAS.AX", "--amt", "30", "--drp", "120")
+
+	var divs []dividendJson
+	if err := json.Unmarshal([]byte(run(t, s, "list", "dividends", "--code", "vas.ax", "--json")), &divs); err != nil {
+		t.Fatal(err)
+	}
+	if len(divs) != 2 || divs[1].Franking != 8.5 || divs[0].Lot != 2 || divs[0].DrpQty != 0.25 {
+		t.Errorf("unexpected dividends %+v", divs)
+	}
+	var invs []exporter.Investment
+	if err := json.Unmarshal([]byte(run(t, s, "list", "investments", "--json")), &invs); err != nil {
+		t.Fatal(err)
+	}
+	if len(invs) != 2 || invs[0].Qty != 0.25 || invs[0].Unitprice != 120 {
+		t.Errorf("got investments %+v, want the reinvested units", invs)
+	}
+
+	err := Run(s, []string{"add", "dividend", "--code", "VAS.AX", "--amt", "-1"}, &bytes.Buffer{})
+	if !errors.Is(err, backend.ErrConstraint) {
+		t.Errorf("negative amount: got %v, want ErrConstraint", err)
+	}
+}
+
+func TestAccounts(t *testing.T) {
+	s := newTestStore(t)
+	seed(t, s)
+	run(t, s, "add", "account", "--name", "Visa", "--type", "Card", "--opening", "-100")
+	run(t, s, "add", "record", "--date", "2026-10-02", "--cat", "Groceries", "--amt", "-20", "--desc", "IGA", "--acct", "Visa")
+
+	var accs []accountJson
+	if err := json.Unmarshal([]byte(run(t, s, "list", "accounts", "--json")), &accs); err != nil {
+		t.Fatal(err)
+	}
+	if len(accs) != 2 || accs[0].Balance != 2947.49 || accs[1].Name != "Visa" || accs[1].Balance != -120 {
+		t.Errorf("unexpected accounts %+v", accs)
+	}
+
+	var recs []exporter.Record
+	if err := json.Unmarshal([]byte(run(t, s, "list", "records", "--acct", "Visa", "--json")), &recs); err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 1 || recs[0].Desc != "IGA" || recs[0].Account != "Visa" {
+		t.Errorf("unexpected records %+v", recs)
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	s := newTestStore(t)
+	seed(t, s)
+	run(t, s, "add", "account", "--name", "Savings", "--type", "Bank")
+	run(t, s, "add", "transfer", "--date", "2026-09-16", "--from", "Everyday", "--to", "Savings", "--amt", "1000", "--desc", "saving")
+
+	var summary monthSummaryJson
+	if err := json.Unmarshal([]byte(run(t, s, "summary", "month", "2026-09", "--json")), &summary); err != nil {
+		t.Fatal(err)
+	}
+	if summary.Income != 3000 || summary.Expenditure != 10.01 || len(summary.Records) != 4 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+
+	var accs []accountJson
+	if err := json.Unmarshal([]byte(run(t, s, "list", "accounts", "--json")), &accs); err != nil {
+		t.Fatal(err)
+	}
+	if len(accs) != 2 || accs[0].Balance != 1947.49 || accs[1].Balance != 1000 {
+		t.Errorf("unexpected accounts %+v", accs)
+	}
+}
+
+func TestBudgets(t *testing.T) {
+	s := newTestStore(t)
+	seed(t, s)
+	run(t, s, "add", "budget", "--cat", "Groceries", "--amt", "50")
+
+	var budgets []budgetJson
+	if err := json.Unmarshal([]byte(run(t, s, "list", "budgets", "--month", "2026-10", "--json")), &budgets); err != nil {
+		t.Fatal(err)
+	}
+	if len(budgets) != 1 || budgets[0].Spent != 42.5 || budgets[0].Remaining != 7.5 {
+		t.Errorf("unexpected budgets %+v", budgets)
+	}
+
+	out := run(t, s, "list", "budgets", "--month", "2026-09")
+	if !strings.Contains(out, "Groceries") || !strings.Contains(out, "39.99") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+	if err := Run(s, []string{"add", "budget", "--cat", "Work", "--amt", "50"}, &bytes.Buffer{}); !errors.Is(err, backend.ErrConstraint) {
+		t.Errorf("budget for an income category: got %v, want ErrConstraint", err)
+	}
+}
+
+func TestRecurringRules(t *testing.T) {
+	s := newTestStore(t)
+	seed(t, s)
+	start := time.Now().AddDate(0, -2, 0).Format("2006-01-02")
+	out := run(t, s, "add", "rule", "--desc", "rent", "--cat", "Groceries", "--amt", "-100", "--freq", "monthly", "--day", "1", "--start", start)
+	if n := strings.Count(out, "created recurring record"); n < 2 || n > 3 {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+
+	var rules []ruleJson
+	if err := json.Unmarshal([]byte(run(t, s, "list", "rules", "--json")), &rules); err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 1 || rules[0].Freq != "monthly" || rules[0].Account != "Everyday" || rules[0].Next <= time.Now().Format("2006-01-02") {
+		t.Errorf("unexpected rules %+v", rules)
+	}
+	if out := run(t, s, "list", "rules"); !strings.Contains(out, "Monthly on day 1") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+
+	err := Run(s, []string{"add", "rule", "--desc", "x", "--cat", "Groceries", "--amt", "-1", "--freq", "daily"}, &bytes.Buffer{})
+	if !errors.Is(err, backend.ErrConstraint) {
+		t.Errorf("unknown frequency: got %v, want ErrConstraint", err)
+	}
+}
+
+func TestAddRecurring(t *testing.T) {
+	s := newTestStore(t)
+	seed(t, s)
+	catId, err := s.GetCategoryIdFromName("Groceries")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule := backend.RecurringRule{Desc: "shop", Amt: -100, CatId: catId, Freq: backend.Weekly, Start: time.Now().AddDate(0, 0, -15)}
+	if err := s.InsertRecurringRule(rule); err != nil {
+		t.Fatal(err)
+	}
+
+	// records which have fallen due are only created before commands which can change the database
+	for _, args := range [][]string{{"list", "records"}, {"summary", "month", "2026-09"}, {"export", "journal"}, {"add", "recurring"}} {
+		if Mutates(args) {
+			t.Errorf("%v shouldn't create recurring records first", args)
+		}
+	}
+	for _, args := range [][]string{{"add", "record"}, {"import", "journal", "main.ledger"}} {
+		if !Mutates(args) {
+			t.Errorf("%v should create recurring records first", args)
+		}
+	}
+
+	if out := run(t, s, "add", "recurring"); strings.Count(out, "created recurring record: ") != 3 {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+	if out := run(t, s, "add", "recurring"); out != "" {
+		t.Errorf("created records twice:\n%s", out)
+	}
+}
+
+func TestExportRecords(t *testing.T) {
+	s := newTestStore(t)
+	seed(t, s)
+	run(t, s, "add", "record", "--date", "2026-06-30", "--cat", "Groceries", "--amt", "-5", "--desc", "last day of FY")
+	run(t, s, "add", "record", "--date", "2026-07-01", "--cat", "Groceries", "--amt", "-5", "--desc", "next FY")
+
+	// financial year ending 30 June 2026
+	want := "id,date,category,account,description,amount,tags\n4,2026-06-30,Groceries,Everyday,last day of FY,-5.00,\n"
+	if out := run(t, s, "export", "records", "--fy", "2026"); out != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out, want)
+	}
+
+	path := filepath.Join(t.TempDir(), "records.json")
+	if out := run(t, s, "export", "records", "--cat", "Work", "--out", path); out != "" {
+		t.Errorf("unexpected output %q", out)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var recs []exporter.Record
+	if err := json.Unmarshal(data, &recs); err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 1 || recs[0].Category != "Work" || recs[0].Amount != 3000 {
+		t.Errorf("unexpected records %+v", recs)
+	}
+
+	if out := run(t, s, "export", "categories"); !strings.Contains(out, "2,Work,Income,") {
+		t.Errorf("unexpected categories export:\n%s", out)
+	}
+}
+
+func TestJournalRoundTrip(t *testing.T) {
+	s := newTestStore(t)
+	seed(t, s)
+	run(t, s, "add", "investment", "--date", "2026-01-05", "--code", "IVV", "--price", "550.10", "--qty", "3")
+
+	path := filepath.Join(t.TempDir(), "main.beancount")
+	run(t, s, "export", "journal", "--out", path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `open Expenses:Groceries`) {
+		t.Errorf("expected a beancount journal, got:\n%s", data)
+	}
+
+	// into an empty database, creating the categories
+	s2 := newTestStore(t)
+	want := "imported 3 records and 1 investments, skipped 0 already imported, created 2 categories\n"
+	if out := run(t, s2, "import", "journal", path); out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+	// ids differ, as the journal is sorted by date
+	withoutIds := func(s *backend.Store, what string) string {
+		var rows []map[string]any
+		if err := json.Unmarshal([]byte(run(t, s, "list", what, "--json")), &rows); err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range rows {
+			delete(r, "id")
+		}
+		data, _ := json.Marshal(rows)
+		return string(data)
+	}
+	for _, what := range []string{"records", "investments"} {
+		if got, want := withoutIds(s2, what), withoutIds(s, what); got != want {
+			t.Errorf("%s differ after round trip, got:\n%s\nwant:\n%s", what, got, want)
+		}
+	}
+
+	want = "imported 0 records and 0 investments, skipped 4 already imported, created 0 categories\n"
+	if out := run(t, s2, "import", "journal", path); out != want {
+		t.Errorf("reimporting: got %q, want %q", out, want)
+	}
+}
+
+func TestInvalidCommands(t *testing.T) {
+	s := newTestStore(t)
+	seed(t, s)
+
+	for _, args := range [][]string{
+		{},
+		{"list"},
+		{"remove", "record"},
+		{"add", "record", "--cat", "Groceries", "--amt", "1"},
+		{"add", "record", "--date", "01/10/2026", "--cat", "Groceries", "--amt", "1", "--desc", "x"},
+		{"summary", "month"},
+		{"summary", "month", "September"},
+		{"list", "records", "--bogus"},
+		{"list", "records", "--fy", "2026", "--from", "2025-01-01"},
+		{"export", "records", "--format", "xlsx"},
+		{"export", "records", "--format", "ledger"},
+		{"export", "journal", "--format", "csv"},
+		{"import", "journal"},
+		{"add", "account", "--name", "Visa"},
+		{"list", "budgets", "--month", "October"},
+		{"add", "rule", "--desc", "x", "--cat", "Groceries", "--amt", "-1"},
+		{"add", "transfer", "--from", "Everyday", "--to", "Everyday", "--amt", "-5", "--desc", "x"},
+	} {
+		if err := Run(s, args, &bytes.Buffer{}); !errors.Is(err, ErrUsage) {
+			t.Errorf("%v: got %v, want ErrUsage", args, err)
+		}
+	}
+
+	err := Run(s, []string{"add", "record", "--cat", "Missing", "--amt", "1", "--desc", "x"}, &bytes.Buffer{})
+	if !errors.Is(err, backend.ErrNotFound) {
+		t.Errorf("unknown category: got %v, want ErrNotFound", err)
+	}
+}
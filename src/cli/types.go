@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"github.com/shen-kit/finance-tracker/exporter"
+)
+
+type monthSummaryJson struct {
+	Month       string            `json:"month"`
+	Income      float64           `json:"income"`
+	Expenditure float64           `json:"expenditure"`
+	NetChange   float64           `json:"net_change"`
+	Records     []exporter.Record `json:"records"`
+}
+
+type accountJson struct {
+	Id      int     `json:"id"`
+	Name    string  `json:"name"`
+	Type    string  `json:"type"`
+	Opening float64 `json:"opening"`
+	Balance float64 `json:"balance"`
+	Desc    string  `json:"description"`
+}
+
+type budgetJson struct {
+	Category  string  `json:"category"`
+	Period    string  `json:"period"`
+	Rollover  bool    `json:"rollover"`
+	Budget    float64 `json:"budget"`
+	Available float64 `json:"available"`
+	Spent     float64 `json:"spent"`
+	Remaining float64 `json:"remaining"`
+}
+
+type ruleJson struct {
+	Id       int     `json:"id"`
+	Desc     string  `json:"description"`
+	Category string  `json:"category"`
+	Account  string  `json:"account"`
+	Amount   float64 `json:"amount"`
+	Freq     string  `json:"frequency"`
+	Day      int     `json:"day,omitempty"`
+	Start    string  `json:"start"`
+	End      string  `json:"end,omitempty"`
+	Next     string  `json:"next,omitempty"`
+	Paused   bool    `json:"paused"`
+}
+
+type tagTotalJson struct {
+	Tag         string  `json:"tag"`
+	Records     int     `json:"records"`
+	Income      float64 `json:"income"`
+	Expenditure float64 `json:"expenditure"`
+	Net         float64 `json:"net"`
+}
+
+type yearRowJson struct {
+	Category string      `json:"category"`
+	Depth    int         `json:"depth,omitempty"` // subcategories are included in their parent's sums
+	Months   [12]float64 `json:"months"`
+	Total    float64     `json:"total"`
+}
+
+type feeTotalJson struct {
+	Code   string  `json:"code"`
+	Trades int     `json:"trades"`
+	Buys   float64 `json:"on_buys"`
+	Sells  float64 `json:"on_sells"`
+	Total  float64 `json:"total"`
+}
+
+type actionJson struct {
+	Id      int    `json:"id"`
+	Date    string `json:"date"` // effective date
+	Type    string `json:"type"`
+	Code    string `json:"code"`
+	New     int    `json:"new_units"` // for each old_units, 1 for a rename
+	Old     int    `json:"old_units"`
+	NewCode string `json:"new_code,omitempty"`
+}
+
+type dividendJson struct {
+	Id          int     `json:"id"`
+	Date        string  `json:"date"`
+	Code        string  `json:"code"`
+	Amount      float64 `json:"amount"`
+	Franking    float64 `json:"franking_credits"`
+	Withholding float64 `json:"withholding_tax"`
+	DrpPrice    float64 `json:"drp_price,omitempty"` // the unit price a reinvested dividend bought units at
+	DrpQty      float64 `json:"drp_qty,omitempty"`
+	Lot         int     `json:"lot,omitempty"` // the investment the units were bought as
+}
@@ -0,0 +1,282 @@
+/*
+Writers for getting data out of the database as CSV or JSON, for spreadsheets,
+accountants and other programs.
+*/
+package exporter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+type Format string
+
+const (
+	CSV  Format = "csv"
+	JSON Format = "json"
+)
+
+var Formats = []Format{CSV, JSON}
+
+func ParseFormat(s string) (Format, error) {
+	for _, f := range append(Formats, JournalFormats...) {
+		if strings.EqualFold(s, string(f)) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown export format %q, expected csv, json, ledger, hledger or beancount", s)
+}
+
+// plain versions of the backend types, with category names resolved and amounts in dollars rather than cents
+
+type Record struct {
+	Id       int      `json:"id"`
+	Date     string   `json:"date"`
+	Category string   `json:"category"`
+	Account  string   `json:"account"`
+	Desc     string   `json:"description"`
+	Amount   float64  `json:"amount"`
+	Tags     []string `json:"tags,omitempty"`
+	Splits   []Split  `json:"splits,omitempty"`
+}
+
+type Split struct {
+	Category string  `json:"category"`
+	Amount   float64 `json:"amount"`
+	Note     string  `json:"note,omitempty"`
+}
+
+func categoryName(catId int, catName string) string {
+	if catId == -1 {
+		return "(deleted)"
+	}
+	return catName
+}
+
+/* A split record's category lists each line's category and amount, e.g. "Groceries (-60.00); Rent (-40.00)" */
+func FromRecord(rec backend.Record) Record {
+	category := categoryName(rec.CatId, rec.CatName)
+	if rec.XferId != 0 {
+		category = "Transfer"
+	}
+	var splits []Split
+	var lines []string
+	for _, spl := range rec.Splits {
+		s := Split{Category: categoryName(spl.CatId, spl.CatName), Amount: float64(spl.Amt) / 100, Note: spl.Note}
+		splits = append(splits, s)
+		lines = append(lines, fmt.Sprintf("%s (%s)", s.Category, money(s.Amount)))
+	}
+	if len(lines) > 0 {
+		category = strings.Join(lines, "; ")
+	}
+	return Record{
+		Id:       rec.Id,
+		Date:     rec.Date.Format("2006-01-02"),
+		Category: category,
+		Account:  rec.AccName,
+		Desc:     rec.Desc,
+		Amount:   float64(rec.Amt) / 100,
+		Tags:     rec.Tags,
+		Splits:   splits,
+	}
+}
+
+type Category struct {
+	Id     int    `json:"id"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Desc   string `json:"description"`
+	Parent string `json:"parent,omitempty"`
+}
+
+func FromCategory(cat backend.Category) Category {
+	catType := "Expenditure"
+	if cat.IsIncome {
+		catType = "Income"
+	}
+	return Category{Id: cat.Id, Name: cat.Name, Type: catType, Desc: cat.Desc, Parent: cat.ParentName}
+}
+
+type Investment struct {
+	Id        int     `json:"id"`
+	Date      string  `json:"date"`
+	Code      string  `json:"code"`
+	Unitprice float64 `json:"unitprice"`
+	Qty       float64 `json:"qty"`              // negative for a sell
+	Fee       float64 `json:"fee"`              // brokerage
+	Method    string  `json:"method,omitempty"` // how a sell is matched to lots
+	Lot       int     `json:"lot,omitempty"`    // the buy a specific lot sell is from
+}
+
+func FromInvestment(inv backend.Investment) Investment {
+	res := Investment{
+		Id:        inv.Id,
+		Date:      inv.Date.Format("2006-01-02"),
+		Code:      inv.Code,
+		Unitprice: float64(inv.Unitprice) / 100,
+		Qty:       float64(inv.Qty),
+		Fee:       float64(inv.Fee) / 100,
+		Method:    string(inv.Method),
+		Lot:       inv.LotId,
+	}
+	if !inv.IsSell() {
+		res.Method, res.Lot = "", 0
+	}
+	return res
+}
+
+func WriteJson(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func writeCsv(w io.Writer, header []string, rows [][]string) error {
+	cw := csv.NewWriter(w)
+	cw.Write(header)
+	cw.WriteAll(rows) // flushes
+	return cw.Error()
+}
+
+func money(amt float64) string {
+	return strconv.FormatFloat(amt, 'f', 2, 64)
+}
+
+/* Writes records (as returned by the backend) in format f */
+func WriteRecords(w io.Writer, f Format, rows []backend.DataRow) error {
+	recs := make([]Record, len(rows))
+	for i, r := range rows {
+		recs[i] = FromRecord(r.(backend.Record))
+	}
+	if f == JSON {
+		return WriteJson(w, recs)
+	}
+
+	lines := make([][]string, len(recs))
+	for i, r := range recs {
+		lines[i] = []string{strconv.Itoa(r.Id), r.Date, r.Category, r.Account, r.Desc, money(r.Amount), strings.Join(r.Tags, " ")}
+	}
+	return writeCsv(w, []string{"id", "date", "category", "account", "description", "amount", "tags"}, lines)
+}
+
+/* Writes categories (as returned by the backend) in format f */
+func WriteCategories(w io.Writer, f Format, rows []backend.DataRow) error {
+	cats := make([]Category, len(rows))
+	for i, r := range rows {
+		cats[i] = FromCategory(r.(backend.Category))
+	}
+	if f == JSON {
+		return WriteJson(w, cats)
+	}
+
+	lines := make([][]string, len(cats))
+	for i, c := range cats {
+		lines[i] = []string{strconv.Itoa(c.Id), c.Name, c.Type, c.Desc, c.Parent}
+	}
+	return writeCsv(w, []string{"id", "name", "type", "description", "parent"}, lines)
+}
+
+/* Writes investments (as returned by the backend) in format f */
+func WriteInvestments(w io.Writer, f Format, rows []backend.DataRow) error {
+	invs := make([]Investment, len(rows))
+	for i, r := range rows {
+		invs[i] = FromInvestment(r.(backend.Investment))
+	}
+	if f == JSON {
+		return WriteJson(w, invs)
+	}
+
+	lines := make([][]string, len(invs))
+	for i, inv := range invs {
+		lines[i] = []string{
+			strconv.Itoa(inv.Id), inv.Date, inv.Code, money(inv.Unitprice),
+			strconv.FormatFloat(inv.Qty, 'f', -1, 32), money(inv.Unitprice * inv.Qty), inv.Method, "", money(inv.Fee),
+		}
+		if inv.Lot != 0 {
+			lines[i][7] = strconv.Itoa(inv.Lot)
+		}
+	}
+	return writeCsv(w, []string{"id", "date", "code", "unitprice", "qty", "total", "method", "lot", "fee"}, lines)
+}
+
+type CGTReport struct {
+	Year          int        `json:"financial_year"` // ending 30 June
+	Disposals     []CGTEvent `json:"disposals"`
+	DiscountGains float64    `json:"discountable_gains"`
+	OtherGains    float64    `json:"other_gains"`
+	Losses        float64    `json:"losses"`
+	CarriedIn     float64    `json:"losses_carried_in"`
+	Discount      float64    `json:"discount"`
+	NetGain       float64    `json:"net_capital_gain"`
+	CarriedOut    float64    `json:"losses_carried_forward"`
+}
+
+type CGTEvent struct {
+	SellId     int     `json:"sell_id"`
+	BuyId      int     `json:"buy_id"`
+	Code       string  `json:"code"`
+	Qty        float64 `json:"qty"`
+	Acquired   string  `json:"acquired"`
+	Sold       string  `json:"sold"`
+	CostBase   float64 `json:"cost_base"`
+	Proceeds   float64 `json:"proceeds"`
+	Gain       float64 `json:"gain"`
+	Discounted bool    `json:"discounted"`
+}
+
+func FromCGTReport(r backend.CGTReport) CGTReport {
+	dollars := func(cents int) float64 { return float64(cents) / 100 }
+	res := CGTReport{
+		Year: r.Year, Disposals: make([]CGTEvent, len(r.Events)),
+		DiscountGains: dollars(r.DiscountGains), OtherGains: dollars(r.OtherGains), Losses: dollars(r.Losses),
+		CarriedIn: dollars(r.CarriedIn), Discount: dollars(r.Discount), NetGain: dollars(r.NetGain), CarriedOut: dollars(r.CarriedOut),
+	}
+	for i, e := range r.Events {
+		res.Disposals[i] = CGTEvent{
+			SellId: e.SellId, BuyId: e.BuyId, Code: e.Code, Qty: e.Qty,
+			Acquired: e.Acquired.Format("2006-01-02"), Sold: e.Sold.Format("2006-01-02"),
+			CostBase: dollars(e.Cost), Proceeds: dollars(e.Proceeds), Gain: dollars(e.Gain()), Discounted: e.Discounted,
+		}
+	}
+	return res
+}
+
+/*
+Writes a capital gains report in format f. The CSV has a row for each
+disposal, then a blank line and a row for each of the report's totals.
+*/
+func WriteCapitalGains(w io.Writer, f Format, r backend.CGTReport) error {
+	cg := FromCGTReport(r)
+	if f == JSON {
+		return WriteJson(w, cg)
+	}
+
+	var lines [][]string
+	for _, e := range cg.Disposals {
+		discounted := "no"
+		if e.Discounted {
+			discounted = "yes"
+		}
+		lines = append(lines, []string{
+			strconv.Itoa(e.SellId), e.Sold, e.Code, strconv.FormatFloat(e.Qty, 'f', -1, 32), strconv.Itoa(e.BuyId), e.Acquired,
+			money(e.CostBase), money(e.Proceeds), money(e.Gain), discounted,
+		})
+	}
+	lines = append(lines, nil,
+		[]string{"financial year", fmt.Sprintf("%d-%02d", cg.Year-1, cg.Year%100)},
+		[]string{"discountable gains", money(cg.DiscountGains)},
+		[]string{"other gains", money(cg.OtherGains)},
+		[]string{"losses", money(cg.Losses)},
+		[]string{"losses carried in", money(cg.CarriedIn)},
+		[]string{"discount", money(cg.Discount)},
+		[]string{"net capital gain", money(cg.NetGain)},
+		[]string{"losses carried forward", money(cg.CarriedOut)},
+	)
+	return writeCsv(w, []string{"sell_id", "sold", "code", "qty", "buy_id", "acquired", "cost_base", "proceeds", "gain", "discounted"}, lines)
+}
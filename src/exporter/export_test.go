@@ -0,0 +1,137 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+func day(s string) time.Time {
+	d, _ := time.Parse("2006-01-02", s)
+	return d
+}
+
+var testRecords = []backend.DataRow{
+	backend.Record{Id: 1, Date: day("2025-07-01"), CatId: 2, CatName: "Groceries", AccName: "Everyday", Desc: `Coles, "Sydney"`, Amt: -4250, Tags: []string{"food", "holiday"}},
+	backend.Record{Id: 2, Date: day("2025-07-15"), CatId: -1, Desc: "old category", Amt: 300000},
+}
+
+func TestWriteRecordsCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteRecords(&buf, CSV, testRecords); err != nil {
+		t.Fatal(err)
+	}
+	want := `id,date,category,account,description,amount,tags
+1,2025-07-01,Groceries,Everyday,"Coles, ""Sydney""",-42.50,food holiday
+2,2025-07-15,(deleted),,old category,3000.00,
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteRecordsJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteRecords(&buf, JSON, testRecords); err != nil {
+		t.Fatal(err)
+	}
+	var recs []Record
+	if err := json.Unmarshal(buf.Bytes(), &recs); err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 2 || recs[0].Category != "Groceries" || recs[0].Amount != -42.5 || recs[1].Date != "2025-07-15" || len(recs[0].Tags) != 2 || recs[1].Tags != nil {
+		t.Errorf("unexpected records %+v", recs)
+	}
+}
+
+func TestWriteCategoriesAndInvestmentsCSV(t *testing.T) {
+	var buf bytes.Buffer
+	cats := []backend.DataRow{
+		backend.Category{Id: 1, Name: "Work", IsIncome: true, Desc: "salary"},
+		backend.Category{Id: 2, Name: "Bonus", IsIncome: true, Desc: "yearly", ParentId: 1, ParentName: "Work"},
+	}
+	if err := WriteCategories(&buf, CSV, cats); err != nil {
+		t.Fatal(err)
+	}
+	if want := "id,name,type,description,parent\n1,Work,Income,salary,\n2,Bonus,Income,yearly,Work\n"; buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	invs := []backend.DataRow{
+		backend.Investment{Id: 3, Date: day("2026-01-05"), Code: "IVV", Unitprice: 55010, Qty: 2.5, Fee: 995, Method: backend.FIFO},
+		backend.Investment{Id: 4, Date: day("2026-03-05"), Code: "IVV", Unitprice: 60000, Qty: -1, Method: backend.SpecificLot, LotId: 3},
+	}
+	if err := WriteInvestments(&buf, CSV, invs); err != nil {
+		t.Fatal(err)
+	}
+	if want := "id,date,code,unitprice,qty,total,method,lot,fee\n3,2026-01-05,IVV,550.10,2.5,1375.25,,,9.95\n4,2026-03-05,IVV,600.00,-1,-600.00,lot,3,0.00\n"; buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteCapitalGainsCSV(t *testing.T) {
+	var buf bytes.Buffer
+	r := backend.CGTReport{Year: 2026, DiscountGains: 10000, Losses: 2500, Discount: 3750, NetGain: 3750, Events: []backend.CGTEvent{
+		{Disposal: backend.Disposal{SellId: 4, BuyId: 1, Code: "IVV", Acquired: day("2024-07-01"), Sold: day("2025-08-01"), Qty: 2, Cost: 20000, Proceeds: 30000}, Discounted: true},
+		{Disposal: backend.Disposal{SellId: 5, BuyId: 2, Code: "VGS.AX", Acquired: day("2025-07-01"), Sold: day("2025-09-01"), Qty: 0.5, Cost: 5000, Proceeds: 2500}},
+	}}
+	if err := WriteCapitalGains(&buf, CSV, r); err != nil {
+		t.Fatal(err)
+	}
+	want := `sell_id,sold,code,qty,buy_id,acquired,cost_base,proceeds,gain,discounted
+4,2025-08-01,IVV,2,1,2024-07-01,200.00,300.00,100.00,yes
+5,2025-09-01,VGS.AX,0.5,2,2025-07-01,50.00,25.00,-25.00,no
+
+financial year,2025-26
+discountable gains,100.00
+other gains,0.00
+losses,25.00
+losses carried in,0.00
+discount,37.50
+net capital gain,37.50
+losses carried forward,0.00
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat("JSON"); err != nil || f != JSON {
+		t.Errorf("ParseFormat(JSON) = %v, %v", f, err)
+	}
+	if _, err := ParseFormat("xlsx"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestWriteSplitRecord(t *testing.T) {
+	rows := []backend.DataRow{backend.Record{Id: 3, Date: day("2025-07-20"), CatId: 2, CatName: "Groceries", Desc: "receipt", Amt: -10000, Splits: []backend.Split{
+		{CatId: 2, CatName: "Groceries", Amt: -6000},
+		{CatId: -1, Amt: -4000, Note: "bin bags"},
+	}}}
+
+	var buf bytes.Buffer
+	if err := WriteRecords(&buf, CSV, rows); err != nil {
+		t.Fatal(err)
+	}
+	if want := "id,date,category,account,description,amount,tags\n3,2025-07-20,Groceries (-60.00); (deleted) (-40.00),,receipt,-100.00,\n"; buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := WriteRecords(&buf, JSON, rows); err != nil {
+		t.Fatal(err)
+	}
+	var recs []Record
+	if err := json.Unmarshal(buf.Bytes(), &recs); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Split{Category: "(deleted)", Amount: -40, Note: "bin bags"}); len(recs[0].Splits) != 2 || recs[0].Splits[1] != want {
+		t.Errorf("unexpected split lines %+v", recs[0].Splits)
+	}
+}
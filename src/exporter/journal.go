@@ -0,0 +1,226 @@
+package exporter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+// plain text accounting formats, see WriteJournal
+const (
+	Ledger    Format = "ledger"
+	Hledger   Format = "hledger"
+	Beancount Format = "beancount"
+)
+
+var JournalFormats = []Format{Ledger, Hledger, Beancount}
+
+func IsJournalFormat(f Format) bool {
+	return f == Ledger || f == Hledger || f == Beancount
+}
+
+// accounts used for the other side of each transaction
+const (
+	Currency           = "AUD"
+	BankAccount        = "Assets:Bank"
+	InvestmentsAccount = "Assets:Investments"
+	CapitalGains       = "Income:Capital-Gains"
+	BrokerageAccount   = "Expenses:Brokerage"
+)
+
+/* Data written to a journal, categories are needed to tell income from expenditure */
+type Journal struct {
+	Records     []backend.Record
+	Categories  []backend.Category
+	Investments []backend.Investment
+}
+
+var accountChars = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+/*
+Returns the account for a category, e.g. "Expenses:Eating-Out". Beancount only
+allows letters, numbers and dashes in account names (starting with a capital),
+the other formats are written the same way so they can be parsed the same way.
+*/
+func CategoryAccount(name string, isIncome bool) string {
+	prefix := "Expenses:"
+	if isIncome {
+		prefix = "Income:"
+	}
+	leaf := strings.Trim(accountChars.ReplaceAllString(name, "-"), "-")
+	if leaf == "" {
+		leaf = "Uncategorised"
+	}
+	r := []rune(leaf)
+	if r[0] >= 'a' && r[0] <= 'z' {
+		r[0] -= 'a' - 'A'
+	}
+	return prefix + string(r)
+}
+
+/* Formats an amount of cents (or units of a commodity) in the given commodity */
+func journalAmount(amt float64, decimals int, commodity string, f Format) string {
+	num := strconv.FormatFloat(amt, 'f', decimals, 64)
+	// ledger and hledger need commodities with anything but letters quoted, e.g. "IVV.AX"
+	if f != Beancount && strings.ContainsFunc(commodity, func(r rune) bool { return !('A' <= r && r <= 'Z' || 'a' <= r && r <= 'z') }) {
+		commodity = strconv.Quote(commodity)
+	}
+	return num + " " + commodity
+}
+
+func journalMoney(cents int, f Format) string {
+	return journalAmount(float64(cents)/100, 2, Currency, f)
+}
+
+/* Beancount commodities must be upper case, starting with a letter */
+func commodity(code string, f Format) string {
+	if f == Beancount {
+		return strings.ToUpper(code)
+	}
+	return code
+}
+
+type journalEntry struct {
+	date     time.Time
+	header   string
+	postings [][2]string // account, amount
+}
+
+/*
+Writes records and investments as a ledger, hledger or beancount journal,
+sorted by date. Records are posted between the category's Income: or
+Expenses: account and BankAccount, with a posting to each line's category for
+split records. Investments are bought into InvestmentsAccount at cost, with
+any brokerage posted to BrokerageAccount. Record tags are written in each
+format's tag syntax. Transfers between accounts move money within
+BankAccount, so aren't written.
+*/
+func WriteJournal(w io.Writer, f Format, j Journal) error {
+	if !IsJournalFormat(f) {
+		return fmt.Errorf("%s is not a journal format", f)
+	}
+
+	header := func(date time.Time, desc string) string {
+		if f == Beancount {
+			return date.Format("2006-01-02") + " * " + strconv.Quote(desc)
+		}
+		return date.Format("2006-01-02") + " * " + strings.ReplaceAll(desc, "\n", " ")
+	}
+
+	tags := func(tags []string) string {
+		if len(tags) == 0 {
+			return ""
+		}
+		switch f {
+		case Beancount:
+			return " #" + strings.Join(tags, " #")
+		case Hledger:
+			return "  ; " + strings.Join(tags, ":, ") + ":"
+		default:
+			return "  ; :" + strings.Join(tags, ":") + ":"
+		}
+	}
+
+	cats := map[int]backend.Category{}
+	for _, c := range j.Categories {
+		cats[c.Id] = c
+	}
+	accounts := map[string]bool{BankAccount: true}
+
+	var entries []journalEntry
+	for _, rec := range j.Records {
+		if rec.XferId != 0 {
+			continue
+		}
+		lines := rec.Splits
+		if len(lines) == 0 {
+			lines = []backend.Split{{CatId: rec.CatId, Amt: rec.Amt}}
+		}
+		entry := journalEntry{date: rec.Date, header: header(rec.Date, rec.Desc) + tags(rec.Tags)}
+		for _, line := range lines {
+			cat, ok := cats[line.CatId]
+			if !ok { // deleted category
+				cat = backend.Category{IsIncome: line.Amt > 0}
+			}
+			account := CategoryAccount(cat.Name, cat.IsIncome)
+			accounts[account] = true
+			entry.postings = append(entry.postings, [2]string{account, journalMoney(-line.Amt, f)})
+		}
+		entry.postings = append(entry.postings, [2]string{BankAccount, journalMoney(rec.Amt, f)})
+		entries = append(entries, entry)
+	}
+
+	for _, inv := range j.Investments {
+		code := commodity(inv.Code, f)
+		qty := float64(inv.Qty)
+		units := journalAmount(qty, -1, code, f)
+		cost := journalAmount(float64(inv.Unitprice)/100, 2, Currency, f)
+		total := -int(math.Round(qty*float64(inv.Unitprice))) - inv.Fee
+		accounts[InvestmentsAccount] = true
+
+		entry := journalEntry{date: inv.Date}
+		switch {
+		case qty > 0 && f == Beancount:
+			entry.header = header(inv.Date, "Buy "+inv.Code)
+			entry.postings = [][2]string{{InvestmentsAccount, units + " {" + cost + "}"}, {BankAccount, journalMoney(total, f)}}
+		case qty > 0:
+			entry.header = header(inv.Date, "Buy "+inv.Code)
+			entry.postings = [][2]string{{InvestmentsAccount, units + " @ " + cost}, {BankAccount, journalMoney(total, f)}}
+		case f == Beancount:
+			// lots are matched by beancount's booking method, the gain balances the transaction
+			accounts[CapitalGains] = true
+			entry.header = header(inv.Date, "Sell "+inv.Code)
+			entry.postings = [][2]string{{InvestmentsAccount, units + " {} @ " + cost}, {BankAccount, journalMoney(total, f)}, {CapitalGains, ""}}
+		default:
+			entry.header = header(inv.Date, "Sell "+inv.Code)
+			entry.postings = [][2]string{{InvestmentsAccount, units + " @ " + cost}, {BankAccount, journalMoney(total, f)}}
+		}
+		if inv.Fee != 0 {
+			accounts[BrokerageAccount] = true
+			entry.postings = slices.Insert(entry.postings, 1, [2]string{BrokerageAccount, journalMoney(inv.Fee, f)})
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(a, b int) bool { return entries[a].date.Before(entries[b].date) })
+
+	bw := bufio.NewWriter(w)
+	if f == Beancount {
+		// beancount needs every account opened before it's used
+		fmt.Fprintf(bw, "option \"operating_currency\" \"%s\"\n\n", Currency)
+		names := make([]string, 0, len(accounts))
+		for a := range accounts {
+			names = append(names, a)
+		}
+		sort.Strings(names)
+		opened := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+		if len(entries) > 0 && entries[0].date.Before(opened) {
+			opened = entries[0].date
+		}
+		for _, a := range names {
+			fmt.Fprintf(bw, "%s open %s\n", opened.Format("2006-01-02"), a)
+		}
+		fmt.Fprintln(bw)
+	}
+	for _, e := range entries {
+		fmt.Fprintln(bw, e.header)
+		for _, p := range e.postings {
+			if p[1] == "" {
+				fmt.Fprintf(bw, "    %s\n", p[0])
+			} else {
+				fmt.Fprintf(bw, "    %-40s  %s\n", p[0], p[1])
+			}
+		}
+		fmt.Fprintln(bw)
+	}
+	return bw.Flush()
+}
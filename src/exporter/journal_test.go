@@ -0,0 +1,95 @@
+package exporter
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+var testJournal = Journal{
+	Records: []backend.Record{
+		{Date: day("2025-07-15"), CatId: -1, Desc: "old category", Amt: 300000},
+		{Date: day("2025-07-01"), CatId: 2, Desc: "Coles", Amt: -4250, Tags: []string{"food", "holiday"}},
+		{Date: day("2025-07-02"), CatId: -5, Desc: "to savings", Amt: -10000, XferId: 4}, // not written
+	},
+	Categories: []backend.Category{{Id: 2, Name: "eating out"}},
+	Investments: []backend.Investment{
+		{Date: day("2025-07-10"), Code: "IVV.AX", Unitprice: 5510, Qty: 10},
+		{Date: day("2025-08-01"), Code: "IVV.AX", Unitprice: 6000, Qty: -4, Fee: 1000},
+	},
+}
+
+func TestWriteLedger(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJournal(&buf, Ledger, testJournal); err != nil {
+		t.Fatal(err)
+	}
+	want := `2025-07-01 * Coles  ; :food:holiday:
+    Expenses:Eating-out                       42.50 AUD
+    Assets:Bank                               -42.50 AUD
+
+2025-07-10 * Buy IVV.AX
+    Assets:Investments                        10 "IVV.AX" @ 55.10 AUD
+    Assets:Bank                               -551.00 AUD
+
+2025-07-15 * old category
+    Income:Uncategorised                      -3000.00 AUD
+    Assets:Bank                               3000.00 AUD
+
+2025-08-01 * Sell IVV.AX
+    Assets:Investments                        -4 "IVV.AX" @ 60.00 AUD
+    Expenses:Brokerage                        10.00 AUD
+    Assets:Bank                               230.00 AUD
+
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteBeancount(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJournal(&buf, Beancount, testJournal); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"2000-01-01 open Expenses:Eating-out\n",
+		"2000-01-01 open Income:Capital-Gains\n",
+		"2025-07-01 * \"Coles\" #food #holiday\n",
+		"Assets:Investments                        10 IVV.AX {55.10 AUD}\n",
+		"Assets:Investments                        -4 IVV.AX {} @ 60.00 AUD\n",
+		"    Expenses:Brokerage                        10.00 AUD\n",
+		"    Income:Capital-Gains\n",
+	} {
+		if !bytes.Contains(buf.Bytes(), []byte(want)) {
+			t.Errorf("missing %q in:\n%s", want, buf.String())
+		}
+	}
+
+	if err := WriteJournal(&buf, CSV, testJournal); err == nil {
+		t.Error("expected an error writing a journal as CSV")
+	}
+}
+
+func TestWriteSplitRecordJournal(t *testing.T) {
+	var buf bytes.Buffer
+	j := Journal{
+		Records: []backend.Record{{Date: day("2025-07-20"), CatId: 2, Desc: "receipt", Amt: -10000, Splits: []backend.Split{
+			{CatId: 2, Amt: -6000}, {CatId: 3, Amt: -4000},
+		}}},
+		Categories: []backend.Category{{Id: 2, Name: "Groceries"}, {Id: 3, Name: "Household"}},
+	}
+	if err := WriteJournal(&buf, Hledger, j); err != nil {
+		t.Fatal(err)
+	}
+	want := `2025-07-20 * receipt
+    Expenses:Groceries                        60.00 AUD
+    Expenses:Household                        40.00 AUD
+    Assets:Bank                               -100.00 AUD
+
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
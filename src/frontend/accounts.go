@@ -0,0 +1,222 @@
+package frontend
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+type accountForm struct {
+	store    *backend.Store
+	form     *tview.Form
+	iName    *tview.InputField
+	iType    *tview.InputField
+	iOpening *tview.InputField
+	iDesc    *tview.InputField
+	tvMsg    *tview.TextView
+}
+
+func createAccountsTable(store *backend.Store) *updatableTable {
+	table := newUpdatableTable(store, strings.Split("ID:Name:Type:Opening Balance:Balance:Description", ":"), nil)
+	table.title = "Accounts"
+	table.fGetMaxPage = func() (int, error) { return 0, nil }
+	return &table
+}
+
+/* The records of a single account (set by accId), with the balance after each */
+func createAccountLedgerTable(store *backend.Store) *updatableTable {
+	table := newUpdatableTable(store, strings.Split("ID:Date:Category:Description:Amount:Balance", ":"), nil)
+	table.title = "Account Ledger"
+	table.fGetMaxPage = func() (int, error) { return store.GetAccountLedgerMaxPage(table.accId) }
+	return &table
+}
+
+func setAccTableKeybinds(t *updatableTable, ledger *updatableTable, af accountForm) {
+	t.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if res := t.defaultInputCapture(event); res == nil {
+			return nil
+		}
+
+		if event.Rune() == 'a' {
+			showAccountForm(t, af, -1, backend.Account{})
+		} else if event.Rune() == 'd' { // delete account
+			row, _ := t.GetSelection()
+			id := t.getCellInt(row, 0)
+			showModal("Delete this account? (y/n)", func() {
+				if err := t.store.DeleteAccount(id); errors.Is(err, backend.ErrConstraint) {
+					showMessage("Move or delete this account's records before deleting it")
+					return
+				} else if err != nil {
+					showError(err)
+					return
+				}
+				refresh(t)
+				// set focus if deleted last row
+				if row > t.GetRowCount()-1 {
+					t.Select(max(0, row-1), 0)
+				}
+			}, t)
+		} else if event.Rune() == 'e' { // edit account
+			row, _ := t.GetSelection()
+			opening, _ := strconv.ParseFloat(t.getCellString(row, 3), 64)
+			showAccountForm(t, af, t.getCellInt(row, 0), backend.Account{
+				Name:    t.getCellString(row, 1),
+				Type:    t.getCellString(row, 2),
+				Opening: int(math.Round(opening * 100)),
+				Desc:    t.getCellString(row, 5),
+			})
+		} else if event.Key() == tcell.KeyEnter || event.Rune() == 'l' { // show the account's records
+			row, _ := t.GetSelection()
+			showAccountLedger(t, ledger, t.getCellInt(row, 0))
+		} else {
+			return event
+		}
+		return nil
+	})
+}
+
+/* Replaces the accounts table with the ledger of one account, back keys return to the accounts */
+func showAccountLedger(accounts, ledger *updatableTable, accId int) {
+	ledger.accId = accId
+	flex.RemoveItem(accounts)
+	showUpdatablePrim(ledger)
+	app.SetFocus(ledger)
+
+	ledger.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if isBackKey(event) {
+			flex.RemoveItem(ledger)
+			showUpdatablePrim(accounts)
+			app.SetFocus(accounts)
+			return nil
+		}
+		return ledger.defaultInputCapture(event)
+	})
+}
+
+func createAccountForm(store *backend.Store) accountForm {
+	af := accountForm{
+		store: store,
+		iName: tview.NewInputField().
+			SetLabel("Name").
+			SetFieldWidth(30),
+		iType: tview.NewInputField().
+			SetLabel("Type").
+			SetFieldWidth(10).
+			SetPlaceholder("e.g. Bank"),
+		iOpening: tview.NewInputField().
+			SetLabel("Opening Balance").
+			SetFieldWidth(10).
+			SetAcceptanceFunc(tview.InputFieldFloat),
+		iDesc: tview.NewInputField().
+			SetLabel("Description").
+			SetFieldWidth(40),
+		tvMsg: tview.NewTextView().
+			SetSize(1, 35).
+			SetDynamicColors(true).
+			SetScrollable(false),
+	}
+
+	af.form = tview.NewForm().
+		AddFormItem(af.iName).
+		AddFormItem(af.iType).
+		AddFormItem(af.iOpening).
+		AddFormItem(af.iDesc).
+		AddFormItem(af.tvMsg).
+		AddButton("Save", nil).
+		AddButton("Cancel", nil).
+		SetFieldBackgroundColor(tview.Styles.MoreContrastBackgroundColor).
+		SetButtonBackgroundColor(tview.Styles.MoreContrastBackgroundColor)
+
+	af.form.SetBorder(true).
+		SetBorderColor(tview.Styles.TertiaryTextColor)
+
+	return af
+}
+
+func showAccountForm(t *updatableTable, af accountForm, id int, acc backend.Account) {
+
+	/* ===== Helper Functions ===== */
+
+	closeForm := func() {
+		flex.RemoveItem(af.form)
+		app.SetFocus(t)
+	}
+
+	onSubmit := func() {
+		acc, err := parseAccForm(af)
+		if err != nil {
+			af.tvMsg.SetText("[red]" + err.Error())
+			return
+		}
+
+		if id == -1 {
+			err = af.store.InsertAccount(acc)
+		} else {
+			err = af.store.UpdateAccount(id, acc)
+		}
+		if errors.Is(err, backend.ErrConstraint) {
+			af.tvMsg.SetText("[red]An account with this name already exists")
+			return
+		} else if err != nil {
+			af.tvMsg.SetText("[red]" + err.Error())
+			return
+		}
+		refresh(t)
+		closeForm()
+	}
+
+	/* ===== Function Body ===== */
+
+	if id == -1 {
+		af.form.SetTitle("Add Account")
+	} else {
+		af.form.SetTitle("Edit Account Details")
+	}
+
+	af.iName.SetText(acc.Name)
+	af.iType.SetText(acc.Type)
+	af.iOpening.SetText("")
+	if acc.Opening != 0 {
+		af.iOpening.SetText(strconv.FormatFloat(float64(acc.Opening)/100, 'f', 2, 64))
+	}
+	af.iDesc.SetText(acc.Desc)
+	af.tvMsg.SetText("")
+
+	af.form.SetInputCapture(formInputCapture(closeForm, onSubmit))
+	af.form.GetButton(af.form.GetButtonIndex("Cancel")).SetSelectedFunc(closeForm)
+	af.form.GetButton(af.form.GetButtonIndex("Save")).SetSelectedFunc(onSubmit)
+
+	// display + focus form
+	flex.AddItem(af.form, 55, 0, true)
+	af.form.SetFocus(0)
+	app.SetFocus(af.form)
+}
+
+/* Takes input from the form and returns an Account object */
+func parseAccForm(af accountForm) (backend.Account, error) {
+	name := strings.TrimSpace(af.iName.GetText())
+	accType := strings.TrimSpace(af.iType.GetText())
+	if name == "" || accType == "" {
+		return backend.Account{}, errors.New("Name and type are required")
+	}
+
+	var opening float64
+	if af.iOpening.GetText() != "" {
+		var err error
+		if opening, err = strconv.ParseFloat(af.iOpening.GetText(), 64); err != nil {
+			return backend.Account{}, errors.New("Invalid opening balance entered")
+		}
+	}
+
+	return backend.Account{
+		Name:    name,
+		Type:    accType,
+		Opening: int(math.Round(opening * 100)),
+		Desc:    af.iDesc.GetText(),
+	}, nil
+}
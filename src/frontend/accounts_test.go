@@ -0,0 +1,74 @@
+package frontend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+func TestAddAccountAndRecord(t *testing.T) {
+	h := startTUI(t, seedRecord(t))
+
+	h.openOption("accounts")
+	h.waitFor("Everyday")
+	h.typeText("a")
+	h.waitFor("Add Account")
+	h.typeText("Visa")
+	h.press(tcell.KeyTab)
+	h.typeText("Card")
+	h.press(tcell.KeyTab)
+	h.typeText("-100")
+	h.submit()
+	h.waitForGone("Add Account")
+	h.waitFor("-$100.00")
+
+	// add a record to the card from the month view
+	h.typeText("ma")
+	h.waitFor("Add Record")
+	h.press(tcell.KeyTab, tcell.KeyTab)
+	h.press(tcell.KeyEnter)
+	h.typeText("j")
+	h.press(tcell.KeyEnter, tcell.KeyTab)
+	h.typeText("-20")
+	h.press(tcell.KeyTab)
+	h.typeText("lunch")
+	h.submit()
+	h.waitForGone("Add Record")
+	h.waitFor("Visa:")
+	h.waitFor("(-20)") // the card's change this month
+
+	for _, rec := range getRecords(t, h.store) {
+		if want := map[string]string{"lunch": "Visa", "weekly shop": "Everyday"}[rec.Desc]; rec.AccName != want {
+			t.Errorf("%s: got account %q, want %q", rec.Desc, rec.AccName, want)
+		}
+	}
+}
+
+func TestAccountLedger(t *testing.T) {
+	h := startTUI(t, func(s *backend.Store) {
+		seedCategories(t)(s)
+		for i, amt := range []int{10000, -2500, -1000} {
+			err := s.InsertRecord(backend.Record{Date: time.Now().AddDate(0, 0, i-3), Desc: "ledger test", Amt: amt, CatId: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+
+	h.openOption("accounts")
+	h.waitFor("$65.00") // balance
+	h.press(tcell.KeyEnter)
+	h.waitFor("Account Ledger")
+	h.waitFor("$100.00") // balance after each record
+	h.waitFor("$75.00")
+
+	h.typeText("q")
+	h.waitForGone("Account Ledger")
+	h.waitFor("Opening Balance")
+
+	// accounts with records can't be deleted
+	h.typeText("dy")
+	h.waitFor("Move or delete this account's records")
+}
@@ -12,41 +12,93 @@ var (
 	flex        *tview.Flex
 	optionsList *tview.List
 	modalText   *tview.TextView
+	pickerList  *tview.List
+	reportText  *tview.TextView
 	screenWidth int
 )
 
-func CreateTUI() {
+/* Runs the TUI, first showing any records created by recurring rules on startup */
+func CreateTUI(store *backend.Store, created []backend.Record) error {
+	buildTUI(store)
+	if len(created) > 0 {
+		showCreatedRecords(created)
+	}
+	return app.SetRoot(pages, true).SetFocus(pages).Run()
+}
+
+/* Creates the application and all of its views, without starting it */
+func buildTUI(store *backend.Store) {
 	setTheme()
 	app = tview.NewApplication()
 	pages = tview.NewPages()
 
-	rf := createRecordForm()
-	cf := createCategoryForm()
-	invForm := createInvestmentForm()
+	rf := createRecordForm(store)
+	cf := createCategoryForm(store)
+	invForm := createInvestmentForm(store)
+	divForm := createDividendForm(store)
+	actForm := createActionForm(store)
+	imf := createImportForm(store)
+	ef := createExportForm(store)
+	pf := createImportProfileForm(store)
+	af := createAccountForm(store)
+	tf := createTransferForm(store)
+	bf := createBudgetForm(store)
+	rrf := createRecurringForm(store)
+	tagf := createTagForm(store)
+	drf := createDateRangeForm()
+	sf := createSearchForm(store)
+	recFilter := createFilterForm(store, false)
+	invFilter := createFilterForm(store, true)
 
-	monthView := createMonthSummary()
-	setMonthGridKeybinds(monthView, rf)
+	monthView := createMonthSummary(store)
+	setMonthGridKeybinds(monthView, rf, tf, imf, ef, sf)
 
-	recTable := createRecordsTable(monthView)
-	setRecTableKeybinds(recTable, rf)
+	recTable := createRecordsTable(store, monthView)
+	setRecTableKeybinds(recTable, rf, tf, imf, ef, sf, recFilter)
 
 	monthView.table = recTable
-	monthView.AddItem(recTable, 2, 0, 1, 1, 0, 0, true)
+	monthView.AddItem(recTable, 2, 0, 1, 2, 0, 0, true)
 
-	yearView := createYearView()
+	yearView := createYearView(store)
 	setYearViewKeybinds(yearView)
 
-	catTable := createCategoriesView()
-	setCatTableKeybinds(catTable, cf)
+	catTable := createCategoriesView(store)
+	setCatTableKeybinds(catTable, cf, ef)
+
+	accTable := createAccountsTable(store)
+	ledgerTable := createAccountLedgerTable(store)
+	setAccTableKeybinds(accTable, ledgerTable, af)
+
+	budgetTable := createBudgetsTable(store)
+	setBudgetTableKeybinds(budgetTable, bf)
+
+	rulesTable := createRecurringTable(store)
+	setRecurringTableKeybinds(rulesTable, rrf)
+
+	tagsTable := createTagsTable(store)
+	taggedTable := createTaggedRecordsTable(store)
+	setTagsTableKeybinds(tagsTable, taggedTable, tagf, drf)
 
-	invTable := createInvestmentsTable()
-	setInvTableKeybinds(invTable, invForm)
+	invTable := createInvestmentsTable(store)
+	setInvTableKeybinds(invTable, invForm, ef, invFilter)
 
-	invSummary := createInvSummaryTable()
+	invSummary := createInvSummaryTable(store)
 	setInvSummaryTableKeybinds(invSummary)
 
-	createHomepage(recTable, catTable, invTable, invSummary, monthView, yearView)
+	cgtView := createCGTView(store)
+	setCGTViewKeybinds(cgtView, ef)
+
+	divTable := createDividendsTable(store)
+	setDividendsTableKeybinds(divTable, divForm)
+
+	actTable := createActionsTable(store)
+	setActionsTableKeybinds(actTable, actForm)
+
+	profilesTable := createImportProfilesTable(store)
+	setImportProfilesTableKeybinds(profilesTable, pf)
+
 	createModal()
+	createHomepage(recTable, catTable, accTable, budgetTable, rulesTable, tagsTable, invTable, invSummary, divTable, actTable, profilesTable, monthView, yearView, cgtView)
 
 	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		// ctrl+D to exit, or any typical 'back' key when on option select page
@@ -56,22 +108,22 @@ func CreateTUI() {
 			return nil
 		} else if event.Key() == tcell.KeyCtrlC { // disable default behaviour (exit app)
 			return tcell.NewEventKey(tcell.KeyCtrlC, 0, tcell.ModNone)
-		} else if !modalText.HasFocus() && flex.GetItemCount() < 3 {
+		} else if !modalText.HasFocus() && !pickerList.HasFocus() && !reportText.HasFocus() && flex.GetItemCount() < 3 {
 			switch event.Rune() {
 			case 'y':
-				optionsList.SetCurrentItem(0)
+				optionsList.SetCurrentItem(optionIndex("year"))
 				focusUpdatablePrim(yearView)
 			case 'm':
-				optionsList.SetCurrentItem(1)
+				optionsList.SetCurrentItem(optionIndex("month"))
 				focusUpdatablePrim(monthView)
 			case 'r':
-				optionsList.SetCurrentItem(2)
+				optionsList.SetCurrentItem(optionIndex("records"))
 				focusUpdatablePrim(recTable)
 			case 'c':
-				optionsList.SetCurrentItem(3)
+				optionsList.SetCurrentItem(optionIndex("categories"))
 				focusUpdatablePrim(catTable)
 			case 'i':
-				optionsList.SetCurrentItem(4)
+				optionsList.SetCurrentItem(optionIndex("investments"))
 				focusUpdatablePrim(invTable)
 			}
 		}
@@ -80,17 +132,13 @@ func CreateTUI() {
 
 	app.SetBeforeDrawFunc(func(screen tcell.Screen) bool {
 		w, h := screen.Size()
-		backend.PAGE_ROWS = h - 5
+		store.PageRows = h - 5
 		screenWidth = w
 		return false
 	})
-
-	if err := app.SetRoot(pages, true).SetFocus(pages).Run(); err != nil {
-		panic(err)
-	}
 }
 
-func createHomepage(recTable, catTable, invTable, invSummary *updatableTable, monthView *monthGridView, yearView *yearView) {
+func createHomepage(recTable, catTable, accTable, budgetTable, rulesTable, tagsTable, invTable, invSummary, divTable, actTable, profilesTable *updatableTable, monthView *monthGridView, yearView *yearView, cgtView *cgtView) {
 	flex = tview.NewFlex()
 
 	optionsList = tview.NewList().
@@ -103,6 +151,14 @@ func createHomepage(recTable, catTable, invTable, invSummary *updatableTable, mo
 		AddItem("  Categories", "categories", 0, func() { focusUpdatablePrim(catTable) }).
 		AddItem("  Investments", "investments", 0, func() { focusUpdatablePrim(invTable) }).
 		AddItem("  Investment Summary ", "invSummary", 0, func() { focusUpdatablePrim(invSummary) }).
+		AddItem("  Import Profiles", "profiles", 0, func() { focusUpdatablePrim(profilesTable) }).
+		AddItem("  Accounts", "accounts", 0, func() { focusUpdatablePrim(accTable) }).
+		AddItem("  Budgets", "budgets", 0, func() { focusUpdatablePrim(budgetTable) }).
+		AddItem("  Recurring", "recurring", 0, func() { focusUpdatablePrim(rulesTable) }).
+		AddItem("  Tags", "tags", 0, func() { focusUpdatablePrim(tagsTable) }).
+		AddItem("  Capital Gains", "cgt", 0, func() { focusUpdatablePrim(cgtView) }).
+		AddItem("  Dividends", "dividends", 0, func() { focusUpdatablePrim(divTable) }).
+		AddItem("  Corporate Actions", "actions", 0, func() { focusUpdatablePrim(actTable) }).
 		AddItem("  Quit", "quit", 0, func() { app.Stop() })
 
 	optionsList.SetChangedFunc(func(index int, mainText string, secondaryText string, shortcut rune) {
@@ -116,10 +172,26 @@ func createHomepage(recTable, catTable, invTable, invSummary *updatableTable, mo
 			showUpdatablePrim(recTable)
 		case "categories":
 			showUpdatablePrim(catTable)
+		case "accounts":
+			showUpdatablePrim(accTable)
+		case "budgets":
+			showUpdatablePrim(budgetTable)
+		case "recurring":
+			showUpdatablePrim(rulesTable)
+		case "tags":
+			showUpdatablePrim(tagsTable)
 		case "investments":
 			showUpdatablePrim(invTable)
 		case "invSummary":
 			showUpdatablePrim(invSummary)
+		case "cgt":
+			showUpdatablePrim(cgtView)
+		case "dividends":
+			showUpdatablePrim(divTable)
+		case "actions":
+			showUpdatablePrim(actTable)
+		case "profiles":
+			showUpdatablePrim(profilesTable)
 		}
 	})
 
@@ -145,11 +217,21 @@ func createHomepage(recTable, catTable, invTable, invSummary *updatableTable, mo
 		SetBlurFunc(func() { optionsList.SetBorderColor(tview.Styles.BorderColor) })
 
 	flex.AddItem(optionsList, 30, 0, true)
-	optionsList.SetCurrentItem(1) // default to month view
+	optionsList.SetCurrentItem(optionIndex("month")) // default to month view
 
 	pages.AddPage("main", flex, true, true)
 }
 
+/* Returns the index in the options list of the view with the given secondary text, e.g. "records" */
+func optionIndex(name string) int {
+	for i := range optionsList.GetItemCount() {
+		if _, secondary := optionsList.GetItemText(i); secondary == name {
+			return i
+		}
+	}
+	return -1
+}
+
 func createModal() {
 	modal := func(p tview.Primitive, width, height int) *tview.Flex {
 		return tview.NewFlex().
@@ -167,6 +249,25 @@ func createModal() {
 		SetBorderColor(tview.Styles.TertiaryTextColor)
 
 	pages.AddPage("modal", modal(modalText, 80, 3), true, false)
+
+	pickerList = tview.NewList().
+		ShowSecondaryText(false).
+		SetHighlightFullLine(true).
+		SetSelectedBackgroundColor(tview.Styles.ContrastBackgroundColor)
+	pickerList.
+		SetBorder(true).
+		SetBorderPadding(0, 0, 1, 1).
+		SetBorderColor(tview.Styles.TertiaryTextColor)
+
+	pages.AddPage("picker", modal(pickerList, 40, 20), true, false)
+
+	reportText = tview.NewTextView().SetDynamicColors(true)
+	reportText.
+		SetBorder(true).
+		SetBorderPadding(0, 0, 1, 1).
+		SetBorderColor(tview.Styles.TertiaryTextColor)
+
+	pages.AddPage("report", modal(reportText, 80, 20), true, false)
 }
 
 func setTheme() {
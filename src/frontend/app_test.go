@@ -0,0 +1,198 @@
+package frontend
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+/* Drives the TUI on a simulated screen, backed by a temporary database */
+type tuiHarness struct {
+	t      *testing.T
+	screen tcell.SimulationScreen
+	store  *backend.Store
+}
+
+/*
+Starts the TUI on a simulated screen. seed (optional) is called to fill the
+database before the first view is drawn.
+*/
+func startTUI(t *testing.T, seed func(*backend.Store)) *tuiHarness {
+	t.Helper()
+
+	store, err := backend.SetupDb(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.FetchPrice = func(code string) (float32, error) { return 100, nil }
+	if seed != nil {
+		seed(store)
+	}
+
+	screen := tcell.NewSimulationScreen("UTF-8")
+	buildTUI(store)
+	app.SetScreen(screen)
+	screen.SetSize(200, 50)
+
+	done := make(chan error)
+	go func() { done <- app.SetRoot(pages, true).SetFocus(pages).Run() }()
+	t.Cleanup(func() {
+		app.Stop()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+		store.Close()
+	})
+
+	h := &tuiHarness{t: t, screen: screen, store: store}
+	h.waitFor("Options")
+	return h
+}
+
+/* Returns everything currently drawn on the screen, one line per row */
+func (h *tuiHarness) screenText() string {
+	// read from the app's event loop, the simulation screen isn't safe to read while drawing.
+	// QueueUpdate returns once the function has run
+	var res string
+	app.QueueUpdate(func() {
+		cells, width, _ := h.screen.GetContents()
+		var sb strings.Builder
+		for i, c := range cells {
+			if len(c.Runes) > 0 {
+				sb.WriteRune(c.Runes[0])
+			} else {
+				sb.WriteRune(' ')
+			}
+			if (i+1)%width == 0 {
+				sb.WriteRune('\n')
+			}
+		}
+		res = sb.String()
+	})
+	return res
+}
+
+/* Waits until cond returns true for the text on screen, failing the test after a timeout */
+func (h *tuiHarness) waitUntil(desc string, cond func(screen string) bool) {
+	h.t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		screen := h.screenText()
+		if cond(screen) {
+			return
+		}
+		if time.Now().After(deadline) {
+			h.t.Fatalf("timed out waiting for %s, screen:\n%s", desc, screen)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (h *tuiHarness) waitFor(text string) {
+	h.t.Helper()
+	h.waitUntil(text, func(screen string) bool { return strings.Contains(screen, text) })
+}
+
+func (h *tuiHarness) waitForGone(text string) {
+	h.t.Helper()
+	h.waitUntil(text+" to disappear", func(screen string) bool { return !strings.Contains(screen, text) })
+}
+
+/* Types each rune as a separate key press */
+func (h *tuiHarness) typeText(s string) {
+	for _, r := range s {
+		h.screen.InjectKey(tcell.KeyRune, r, tcell.ModNone)
+	}
+}
+
+func (h *tuiHarness) press(keys ...tcell.Key) {
+	for _, k := range keys {
+		h.screen.InjectKey(k, 0, tcell.ModNone)
+	}
+}
+
+/* Opens a view from the options list by its secondary text, e.g. "budgets", moving down from the top */
+func (h *tuiHarness) openOption(name string) {
+	h.t.Helper()
+	var i int
+	app.QueueUpdate(func() { i = optionIndex(name) })
+	if i == -1 {
+		h.t.Fatalf("no option %q", name)
+	}
+	h.typeText("g" + strings.Repeat("j", i))
+	h.press(tcell.KeyEnter)
+}
+
+/* Submits the focused form */
+func (h *tuiHarness) submit() {
+	h.screen.InjectKey(tcell.KeyEnter, 0, tcell.ModCtrl)
+}
+
+/* Clears the focused input field and types s */
+func (h *tuiHarness) replaceText(s string) {
+	h.screen.InjectKey(tcell.KeyCtrlU, 0, tcell.ModCtrl) // delete to start of line
+	h.screen.InjectKey(tcell.KeyCtrlK, 0, tcell.ModCtrl) // delete to end of line
+	h.typeText(s)
+}
+
+func seedCategories(t *testing.T) func(*backend.Store) {
+	return func(s *backend.Store) {
+		for _, cat := range []backend.Category{
+			{Name: "Groceries", IsIncome: false, Desc: "food"},
+			{Name: "Work", IsIncome: true, Desc: "salary"},
+		} {
+			if err := s.InsertCategory(cat); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestNavigationShortcuts(t *testing.T) {
+	h := startTUI(t, nil)
+	thisYear := time.Now().Format("2006")
+	lastYear := time.Now().AddDate(-1, 0, 0).Format("2006")
+
+	h.typeText("y")
+	h.waitFor("Year Overview")
+	h.waitFor(thisYear)
+
+	// previous/next year
+	h.typeText("H")
+	h.waitFor(lastYear)
+	h.typeText("L")
+	h.waitFor(thisYear)
+
+	h.typeText("m")
+	h.waitFor("Month Summary")
+	h.waitFor(time.Now().Month().String())
+
+	h.typeText("r")
+	h.waitFor("ID │ Date │ Category")
+	h.typeText("c")
+	h.waitFor("ID │ Name │ Type")
+	h.typeText("i")
+	h.waitFor("ID │ Date │ Type │ Code")
+
+	// back to the options list, then down to the investment summary
+	h.typeText("qj")
+	h.press(tcell.KeyEnter)
+	h.waitFor("Avg Buy Price")
+}
+
+func TestBackKeyLeavesForm(t *testing.T) {
+	h := startTUI(t, seedCategories(t))
+
+	h.typeText("ma")
+	h.waitFor("Add Record")
+	h.press(tcell.KeyEscape)
+	h.waitForGone("Add Record")
+
+	// shortcuts work again once the form is closed
+	h.typeText("c")
+	h.waitFor("Groceries")
+}
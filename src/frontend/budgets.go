@@ -0,0 +1,224 @@
+package frontend
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+type budgetForm struct {
+	store     *backend.Store
+	form      *tview.Form
+	iCat      *tview.DropDown
+	iAmt      *tview.InputField
+	iPeriod   *tview.DropDown
+	iRollover *tview.Checkbox
+	tvMsg     *tview.TextView
+}
+
+var budgetPeriods = []string{"Monthly", "Yearly"}
+
+/* Progress of each budget through the current month (or year for yearly budgets) */
+func createBudgetsTable(store *backend.Store) *updatableTable {
+	table := newUpdatableTable(store, strings.Split("ID:Category:Period:Rollover:Budget:Available:Spent:Remaining", ":"), nil)
+	table.title = "Budgets"
+	table.fGetMaxPage = func() (int, error) { return 0, nil }
+	return &table
+}
+
+func setBudgetTableKeybinds(t *updatableTable, bf budgetForm) {
+	t.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if res := t.defaultInputCapture(event); res == nil {
+			return nil
+		}
+
+		if event.Rune() == 'a' {
+			showBudgetForm(t, bf, -1, backend.Budget{})
+		} else if event.Rune() == 'd' { // delete budget
+			row, _ := t.GetSelection()
+			id := t.getCellInt(row, 0)
+			showModal("Delete this budget? (y/n)", func() {
+				if err := t.store.DeleteBudget(id); err != nil {
+					showError(err)
+					return
+				}
+				refresh(t)
+				// set focus if deleted last row
+				if row > t.GetRowCount()-1 {
+					t.Select(max(0, row-1), 0)
+				}
+			}, t)
+		} else if event.Rune() == 'e' { // edit budget
+			row, _ := t.GetSelection()
+			amt, _ := strconv.ParseFloat(t.getCellString(row, 4), 64)
+			showBudgetForm(t, bf, t.getCellInt(row, 0), backend.Budget{
+				CatName:  t.getCellString(row, 1),
+				Amt:      int(math.Round(amt * 100)),
+				Yearly:   t.getCellString(row, 2) == "Yearly",
+				Rollover: t.getCellString(row, 3) == "Yes",
+			})
+		} else {
+			return event
+		}
+		return nil
+	})
+}
+
+func createBudgetForm(store *backend.Store) budgetForm {
+	bf := budgetForm{
+		store: store,
+		iCat: tview.NewDropDown().
+			SetLabel("Category"),
+		iAmt: tview.NewInputField().
+			SetLabel("Amount").
+			SetFieldWidth(10).
+			SetAcceptanceFunc(tview.InputFieldFloat),
+		iPeriod: tview.NewDropDown().
+			SetLabel("Period").
+			SetOptions(budgetPeriods, nil),
+		iRollover: tview.NewCheckbox().
+			SetLabel("Roll Over Unspent?"),
+		tvMsg: tview.NewTextView().
+			SetSize(1, 35).
+			SetDynamicColors(true).
+			SetScrollable(false),
+	}
+
+	dropDownCapture := func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Rune() == 'j' || event.Key() == tcell.KeyCtrlN {
+			return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+		} else if event.Rune() == 'k' || event.Key() == tcell.KeyCtrlP {
+			return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+		}
+		return event
+	}
+	bf.iCat.SetInputCapture(dropDownCapture)
+	bf.iPeriod.SetInputCapture(dropDownCapture)
+
+	bf.form = tview.NewForm().
+		AddFormItem(bf.iCat).
+		AddFormItem(bf.iAmt).
+		AddFormItem(bf.iPeriod).
+		AddFormItem(bf.iRollover).
+		AddFormItem(bf.tvMsg).
+		AddButton("Save", nil).
+		AddButton("Cancel", nil).
+		SetFieldBackgroundColor(tview.Styles.MoreContrastBackgroundColor).
+		SetButtonBackgroundColor(tview.Styles.MoreContrastBackgroundColor)
+
+	bf.form.SetBorder(true).
+		SetBorderColor(tview.Styles.TertiaryTextColor)
+
+	return bf
+}
+
+func showBudgetForm(t *updatableTable, bf budgetForm, id int, b backend.Budget) {
+
+	/* ===== Helper Functions ===== */
+
+	// only expenditure categories can have a budget
+	setCategoryOptions := func() {
+		cats, err := bf.store.GetCategories(0)
+		if err != nil {
+			bf.tvMsg.SetText("[red]" + err.Error())
+		}
+		catOpt := 0
+		var catNames []string
+		for _, row := range cats {
+			if cat := row.(backend.Category); !cat.IsIncome {
+				if cat.Name == b.CatName {
+					catOpt = len(catNames)
+				}
+				catNames = append(catNames, cat.Name)
+			}
+		}
+		bf.iCat.SetOptions(catNames, nil).SetCurrentOption(catOpt)
+	}
+
+	closeForm := func() {
+		flex.RemoveItem(bf.form)
+		app.SetFocus(t)
+	}
+
+	onSubmit := func() {
+		b, err := parseBudgetForm(bf)
+		if err != nil {
+			bf.tvMsg.SetText("[red]" + err.Error())
+			return
+		}
+
+		if id == -1 {
+			err = bf.store.InsertBudget(b)
+		} else {
+			err = bf.store.UpdateBudget(id, b)
+		}
+		if errors.Is(err, backend.ErrConstraint) {
+			bf.tvMsg.SetText("[red]This category already has a budget")
+			return
+		} else if err != nil {
+			bf.tvMsg.SetText("[red]" + err.Error())
+			return
+		}
+		refresh(t)
+		closeForm()
+	}
+
+	/* ===== Function Body ===== */
+
+	if id == -1 {
+		bf.form.SetTitle("Add Budget")
+	} else {
+		bf.form.SetTitle("Edit Budget Details")
+	}
+
+	bf.tvMsg.SetText("")
+	setCategoryOptions()
+	bf.iAmt.SetText("")
+	if b.Amt != 0 {
+		bf.iAmt.SetText(strconv.FormatFloat(float64(b.Amt)/100, 'f', 2, 64))
+	}
+	bf.iPeriod.SetCurrentOption(0)
+	if b.Yearly {
+		bf.iPeriod.SetCurrentOption(1)
+	}
+	bf.iRollover.SetChecked(b.Rollover)
+
+	bf.form.SetInputCapture(formInputCapture(closeForm, onSubmit))
+	bf.form.GetButton(bf.form.GetButtonIndex("Cancel")).SetSelectedFunc(closeForm)
+	bf.form.GetButton(bf.form.GetButtonIndex("Save")).SetSelectedFunc(onSubmit)
+
+	// display + focus form
+	flex.AddItem(bf.form, 55, 0, true)
+	bf.form.SetFocus(0)
+	app.SetFocus(bf.form)
+}
+
+/* Takes input from the form and returns a Budget object */
+func parseBudgetForm(bf budgetForm) (backend.Budget, error) {
+	_, cname := bf.iCat.GetCurrentOption()
+	if cname == "" {
+		return backend.Budget{}, errors.New("Please add an expenditure category first")
+	}
+	catId, err := bf.store.GetCategoryIdFromName(cname)
+	if err != nil {
+		return backend.Budget{}, err
+	}
+
+	amt, err := strconv.ParseFloat(bf.iAmt.GetText(), 64)
+	if err != nil || amt <= 0 {
+		return backend.Budget{}, errors.New("Amount must be a positive number")
+	}
+
+	period, _ := bf.iPeriod.GetCurrentOption()
+	return backend.Budget{
+		CatId:    catId,
+		Amt:      int(math.Round(amt * 100)),
+		Yearly:   period == 1,
+		Rollover: bf.iRollover.IsChecked(),
+	}, nil
+}
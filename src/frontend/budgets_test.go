@@ -0,0 +1,37 @@
+package frontend
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestAddBudget(t *testing.T) {
+	h := startTUI(t, seedRecord(t))
+
+	h.openOption("budgets")
+	h.typeText("a")
+	h.waitFor("Add Budget")
+	h.press(tcell.KeyTab) // Groceries is the only expenditure category
+	h.typeText("40")
+	h.submit()
+	h.waitForGone("Add Budget")
+	h.waitFor("$40.00")
+	h.waitFor("-$2.50") // remaining
+
+	h.typeText("m")
+	h.waitFor("Over budget: Groceries -$2")
+
+	// raising the budget clears the warning
+	h.typeText("q")
+	h.openOption("budgets")
+	h.typeText("e")
+	h.waitFor("Edit Budget Details")
+	h.press(tcell.KeyTab)
+	h.replaceText("100")
+	h.submit()
+	h.waitFor("$57.50")
+	h.typeText("m")
+	h.waitFor("weekly shop")
+	h.waitForGone("Over budget")
+}
@@ -0,0 +1,96 @@
+package frontend
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+/* The capital gains of a financial year's sells, with the net capital gain after losses and the discount */
+func createCGTView(store *backend.Store) *cgtView {
+	tvTitle := tview.NewTextView().SetTextAlign(tview.AlignCenter)
+	tvTitle.SetBorderPadding(1, 1, 3, 3)
+
+	tvSummary := tview.NewTextView()
+	tvSummary.SetBorderPadding(0, 0, 3, 3)
+
+	cgtGrid := tview.NewGrid().
+		SetRows(3, 4, 0).
+		SetColumns(0, 0).
+		SetBorders(true)
+
+	cgtTable := newUpdatableTable(store, strings.Split("Sell ID:Sold:Code:Qty:Acquired:Cost Base:Proceeds:Gain/Loss:Discount", ":"), cgtGrid)
+	cgtTable.SetBorder(false)
+	cgtTable.fGetMaxPage = func() (int, error) { return 0, nil }
+
+	tvNet := tview.NewTextView()
+	tvNet.SetBorderPadding(0, 0, 3, 3)
+
+	cgtGrid.AddItem(tvTitle, 0, 0, 1, 2, 0, 0, false).
+		AddItem(tvSummary, 1, 0, 1, 1, 0, 0, false).
+		AddItem(tvNet, 1, 1, 1, 1, 0, 0, false).
+		AddItem(cgtTable, 2, 0, 1, 2, 0, 0, true).
+		SetBorder(true).
+		SetTitle("Capital Gains")
+
+	return &cgtView{
+		store:     store,
+		Grid:      cgtGrid,
+		table:     &cgtTable,
+		tvTitle:   tvTitle,
+		tvSummary: tvSummary,
+		tvNet:     tvNet,
+	}
+}
+
+func setCGTViewKeybinds(cv *cgtView, ef exportForm) {
+	cv.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if isBackKey(event) {
+			app.SetFocus(flex)
+		} else if event.Rune() == 'H' {
+			cv.changeYear(-1)
+		} else if event.Rune() == 'L' {
+			cv.changeYear(1)
+		} else if event.Rune() == 'X' {
+			showExportForm(cv, ef, "Capital Gains", strconv.Itoa(cv.year()), "")
+		} else {
+			return event
+		}
+		return nil
+	})
+}
+
+func (cv *cgtView) update(rows []backend.DataRow) {
+	year := cv.year()
+	start, end := backend.FinancialYear(year)
+	cv.tvTitle.SetText(fmt.Sprintf("Financial Year %d-%02d (%s to %s)", year-1, year%100,
+		start.Format("2 Jan 2006"), end.AddDate(0, 0, -1).Format("2 Jan 2006")))
+
+	report, err := cv.store.GetCapitalGains(year)
+	if err != nil {
+		showError(err)
+	}
+	fees, err := cv.store.GetFees(year)
+	if err != nil {
+		showError(err)
+	}
+	feesPaid := 0
+	for _, f := range fees {
+		feesPaid += f.Total()
+	}
+	dollars := func(cents int) string { return fmt.Sprintf("$%.2f", float32(cents)/100) }
+	cv.tvSummary.SetText(fmt.Sprintf("Discountable Gains: %12s\nOther Gains:        %12s\nCapital Losses:     %12s\nLosses Carried In:  %12s",
+		dollars(report.DiscountGains), dollars(report.OtherGains), dollars(report.Losses), dollars(report.CarriedIn)))
+	cv.tvNet.SetText(fmt.Sprintf("CGT Discount (50%%):  %12s\nNet Capital Gain:    %12s\nLosses Carried Out:  %12s\nBrokerage Paid:      %12s",
+		dollars(report.Discount), dollars(report.NetGain), dollars(report.CarriedOut), dollars(feesPaid)))
+
+	cv.table.update(rows)
+}
+
+func (cv *cgtView) reset() {
+	cv.changeYear(-cv.yearOffset)
+}
@@ -0,0 +1,50 @@
+package frontend
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+func TestCapitalGains(t *testing.T) {
+	h := startTUI(t, func(s *backend.Store) {
+		for _, inv := range []backend.Investment{
+			{Date: time.Now().AddDate(-2, 0, 0), Code: "IVV", Unitprice: 10000, Qty: 3},
+			{Date: time.Now(), Code: "IVV", Unitprice: 15000, Qty: -2},
+		} {
+			if err := s.InsertInvestment(inv); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+	path := filepath.Join(t.TempDir(), "cgt.csv")
+
+	h.openOption("cgt")
+	h.waitFor("Financial Year")
+	h.waitFor("$100.00") // gain on 2 units, held for 2 years
+	h.waitFor("Yes")
+	h.waitFor("$50.00") // after the discount
+
+	h.typeText("H")
+	h.waitForGone("$100.00")
+	h.typeText("L")
+	h.waitFor("$100.00")
+
+	h.typeText("X")
+	h.waitFor("Export Capital Gains")
+	h.replaceText(path)
+	h.submit()
+	h.waitFor("Exported capital gains to")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), ",IVV,2,1,") || !strings.Contains(string(data), "net capital gain,50.00\n") {
+		t.Errorf("unexpected export:\n%s", data)
+	}
+}
@@ -10,34 +10,43 @@ import (
 )
 
 type categoryForm struct {
+	store     *backend.Store
 	form      *tview.Form
 	iName     *tview.InputField
 	iDesc     *tview.InputField
 	iIsIncome *tview.Checkbox
+	iParent   *tview.DropDown
 	tvMsg     *tview.TextView
 }
 
-func createCategoriesView() *updatableTable {
-	table := newUpdatableTable(strings.Split("ID:Name:Type:Description", ":"), nil)
+// parent option for a top level category
+const noParent = "(none)"
+
+func createCategoriesView(store *backend.Store) *updatableTable {
+	table := newUpdatableTable(store, strings.Split("ID:Name:Type:Description", ":"), nil)
 	table.title = "Categories"
-	table.fGetMaxPage = func() int { return 0 }
+	table.fGetMaxPage = func() (int, error) { return 0, nil }
+	table.sortable = true
 	return &table
 }
 
-func setCatTableKeybinds(t *updatableTable, cf categoryForm) {
+func setCatTableKeybinds(t *updatableTable, cf categoryForm, ef exportForm) {
 	t.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		if res := t.defaultInputCapture(event); res == nil {
 			return nil
 		}
 
 		if event.Rune() == 'a' {
-			showCategoryForm(t, cf, -1, "", "", false)
+			showCategoryForm(t, cf, -1, backend.Category{})
 		} else if event.Rune() == 'd' { // delete category
 			row, _ := t.GetSelection()
 			id := t.getCellInt(row, 0)
-			showModal("Delete this category? (y/n)", func() {
-				backend.DeleteCategory(id)
-				t.update(t.fGetData(t.curPage))
+			showModal("Delete this category? Its subcategories become top level categories (y/n)", func() {
+				if err := t.store.DeleteCategory(id); err != nil {
+					showError(err)
+					return
+				}
+				refresh(t)
 				// set focus if deleted last row
 				if row > t.GetRowCount()-1 {
 					t.Select(max(0, row-1), 0)
@@ -45,11 +54,14 @@ func setCatTableKeybinds(t *updatableTable, cf categoryForm) {
 			}, t)
 		} else if event.Rune() == 'e' { // edit category
 			row, _ := t.GetSelection()
-			id := t.getCellInt(row, 0)
-			name := t.getCellString(row, 1)
-			isIncome := strings.EqualFold("income", t.getCellString(row, 2))
-			desc := t.getCellString(row, 3)
-			showCategoryForm(t, cf, id, name, desc, isIncome)
+			cat, err := t.store.GetCategory(t.getCellInt(row, 0))
+			if err != nil {
+				showError(err)
+				return nil
+			}
+			showCategoryForm(t, cf, cat.Id, cat)
+		} else if event.Rune() == 'X' { // export
+			showExportForm(t, ef, "Categories", "", "")
 		} else {
 			return event
 		}
@@ -57,10 +69,11 @@ func setCatTableKeybinds(t *updatableTable, cf categoryForm) {
 	})
 }
 
-func createCategoryForm() categoryForm {
+func createCategoryForm(store *backend.Store) categoryForm {
 	var form *tview.Form
 	var inName, inDesc *tview.InputField
 	var inIsIncome *tview.Checkbox
+	var inParent *tview.DropDown
 	var formMsg *tview.TextView
 
 	inName = tview.NewInputField().
@@ -74,6 +87,17 @@ func createCategoryForm() categoryForm {
 	inIsIncome = tview.NewCheckbox().
 		SetLabel("Is Income?")
 
+	inParent = tview.NewDropDown().
+		SetLabel("Parent")
+	inParent.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Rune() == 'j' || event.Key() == tcell.KeyCtrlN {
+			return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+		} else if event.Rune() == 'k' || event.Key() == tcell.KeyCtrlP {
+			return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+		}
+		return event
+	})
+
 	formMsg = tview.NewTextView().
 		SetSize(1, 35).
 		SetDynamicColors(true).
@@ -83,6 +107,7 @@ func createCategoryForm() categoryForm {
 		AddFormItem(inName).
 		AddFormItem(inDesc).
 		AddFormItem(inIsIncome).
+		AddFormItem(inParent).
 		AddFormItem(formMsg).
 		AddButton("Save", nil).
 		AddButton("Cancel", nil).
@@ -93,19 +118,35 @@ func createCategoryForm() categoryForm {
 		SetBorderColor(tview.Styles.TertiaryTextColor)
 
 	return categoryForm{
-		form: form, iName: inName, iDesc: inDesc, iIsIncome: inIsIncome, tvMsg: formMsg,
+		store: store, form: form, iName: inName, iDesc: inDesc, iIsIncome: inIsIncome, iParent: inParent, tvMsg: formMsg,
 	}
 }
 
-func showCategoryForm(ct *updatableTable, cf categoryForm, id int, name, desc string, isIncome bool) {
+func showCategoryForm(ct *updatableTable, cf categoryForm, id int, cat backend.Category) {
 
 	/* ===== Helper Functions ===== */
 
 	setInputFieldValues := func() {
-		cf.iName.SetText(name)
-		cf.iDesc.SetText(desc)
-		cf.iIsIncome.SetChecked(isIncome)
+		cf.iName.SetText(cat.Name)
+		cf.iDesc.SetText(cat.Desc)
+		cf.iIsIncome.SetChecked(cat.IsIncome)
 		cf.tvMsg.SetText("")
+
+		// any category except this one can be the parent, the backend rejects cycles
+		cats, err := cf.store.GetCategories(0)
+		if err != nil {
+			cf.tvMsg.SetText("[red]" + err.Error())
+		}
+		parentOpt, parentNames := 0, []string{noParent}
+		for _, row := range cats {
+			if c := row.(backend.Category); c.Id != id {
+				if c.Id == cat.ParentId {
+					parentOpt = len(parentNames)
+				}
+				parentNames = append(parentNames, c.Name)
+			}
+		}
+		cf.iParent.SetOptions(parentNames, nil).SetCurrentOption(parentOpt)
 	}
 
 	closeForm := func() {
@@ -121,11 +162,15 @@ func showCategoryForm(ct *updatableTable, cf categoryForm, id int, name, desc st
 		}
 
 		if id == -1 {
-			backend.InsertCategory(cat)
+			err = cf.store.InsertCategory(cat)
 		} else {
-			backend.UpdateCategory(id, cat)
+			err = cf.store.UpdateCategory(id, cat)
+		}
+		if err != nil {
+			cf.tvMsg.SetText("[red]" + err.Error())
+			return
 		}
-		ct.update(ct.fGetData(ct.curPage))
+		refresh(ct)
 		closeForm()
 	}
 
@@ -154,12 +199,83 @@ func showCategoryForm(ct *updatableTable, cf categoryForm, id int, name, desc st
 func parseCatForm(cf categoryForm) (backend.Category, error) {
 
 	if cf.iName.GetText() == "" || cf.iDesc.GetText() == "" {
-		return backend.Category{}, errors.New("All fields are required")
+		return backend.Category{}, errors.New("Name and description are required")
+	}
+
+	parentId := 0
+	if _, parent := cf.iParent.GetCurrentOption(); parent != noParent && parent != "" {
+		var err error
+		if parentId, err = cf.store.GetCategoryIdFromName(parent); err != nil {
+			return backend.Category{}, err
+		}
 	}
 
 	return backend.Category{
 			Name:     cf.iName.GetText(),
 			Desc:     cf.iDesc.GetText(),
-			IsIncome: cf.iIsIncome.IsChecked()},
+			IsIncome: cf.iIsIncome.IsChecked(),
+			ParentId: parentId},
 		nil
 }
+
+/* Returns the node of a row of category totals, or nil for other rows */
+func categoryNode(row backend.DataRow) *backend.CategoryNode {
+	switch r := row.(type) {
+	case *backend.CategoryYear:
+		return &r.CategoryNode
+	case *backend.CategoryTotal:
+		return &r.CategoryNode
+	}
+	return nil
+}
+
+/*
+Shows rows of category totals in t, hiding the subcategories of collapsed
+categories. Each row's node is kept as the reference of its first cell.
+*/
+func updateCategoryTree(t *updatableTable, rows []backend.DataRow, collapsed map[int]bool) {
+	var visible []backend.DataRow
+	hideBelow := -1 // depth of the collapsed category being skipped
+	for _, row := range rows {
+		node := categoryNode(row)
+		if hideBelow >= 0 && node.Depth > hideBelow {
+			continue
+		}
+		hideBelow = -1
+		node.Collapsed = node.HasChildren && collapsed[node.CatId]
+		if node.Collapsed {
+			hideBelow = node.Depth
+		}
+		visible = append(visible, row)
+	}
+
+	t.update(visible)
+	for i, row := range visible {
+		t.GetCell(i+1, 0).SetReference(categoryNode(row))
+	}
+}
+
+/*
+Handles the keys to expand or collapse categories in a table shown by
+updateCategoryTree, returning whether the key was handled
+*/
+func toggleCategoryTree(t *updatableTable, collapsed map[int]bool, event *tcell.EventKey) bool {
+	switch {
+	case event.Key() == tcell.KeyEnter || event.Rune() == ' ': // selected category
+		row, _ := t.GetSelection()
+		if node, ok := t.GetCell(row, 0).GetReference().(*backend.CategoryNode); ok && node.HasChildren {
+			collapsed[node.CatId] = !node.Collapsed
+		}
+	case event.Rune() == '-': // all categories
+		for row := 1; row < t.GetRowCount(); row++ {
+			if node, ok := t.GetCell(row, 0).GetReference().(*backend.CategoryNode); ok && node.HasChildren {
+				collapsed[node.CatId] = true
+			}
+		}
+	case event.Rune() == '+':
+		clear(collapsed)
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,115 @@
+package frontend
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+func getCategories(t *testing.T, s *backend.Store) []backend.Category {
+	t.Helper()
+	rows, err := s.GetCategories(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cats := make([]backend.Category, len(rows))
+	for i, r := range rows {
+		cats[i] = r.(backend.Category)
+	}
+	return cats
+}
+
+func TestAddCategory(t *testing.T) {
+	h := startTUI(t, nil)
+
+	h.typeText("ca")
+	h.waitFor("Add Category")
+	h.typeText("Salary")
+	h.press(tcell.KeyTab)
+	h.typeText("from work")
+	h.press(tcell.KeyTab)
+	h.typeText(" ") // tick 'Is Income?'
+	h.submit()
+
+	h.waitForGone("Add Category")
+	h.waitFor("Salary")
+	h.waitFor("Income")
+
+	cats := getCategories(t, h.store)
+	if len(cats) != 1 || cats[0].Name != "Salary" || cats[0].Desc != "from work" || !cats[0].IsIncome {
+		t.Errorf("unexpected categories %+v", cats)
+	}
+}
+
+func TestEditCategory(t *testing.T) {
+	h := startTUI(t, seedCategories(t))
+
+	h.typeText("c")
+	h.waitFor("Groceries")
+	h.typeText("e")
+	h.waitFor("Edit Category Details")
+	h.replaceText("Food")
+	h.submit()
+
+	h.waitForGone("Edit Category Details")
+	h.waitFor("Food")
+
+	if cats := getCategories(t, h.store); cats[0].Name != "Food" || cats[0].IsIncome {
+		t.Errorf("unexpected categories %+v", cats)
+	}
+}
+
+func TestDeleteCategory(t *testing.T) {
+	h := startTUI(t, seedCategories(t))
+
+	h.typeText("c")
+	h.waitFor("Groceries")
+	h.typeText("jdy") // second row
+	h.waitForGone("Work")
+
+	if cats := getCategories(t, h.store); len(cats) != 1 || cats[0].Name != "Groceries" {
+		t.Errorf("unexpected categories %+v", cats)
+	}
+}
+
+func TestSubcategoryTree(t *testing.T) {
+	h := startTUI(t, seedRecord(t))
+
+	h.typeText("ca")
+	h.waitFor("Add Category")
+	h.typeText("Takeaway")
+	h.press(tcell.KeyTab)
+	h.typeText("pizza etc")
+	h.press(tcell.KeyTab, tcell.KeyTab, tcell.KeyEnter)
+	h.typeText("j") // Groceries, after (none)
+	h.press(tcell.KeyEnter)
+	h.submit()
+	h.waitForGone("Add Category")
+	h.waitFor("└ Takeaway")
+
+	cats := getCategories(t, h.store)
+	if len(cats) != 3 || cats[1].Name != "Takeaway" || cats[1].ParentName != "Groceries" {
+		t.Fatalf("unexpected categories %+v", cats)
+	}
+
+	// the month's category totals include subcategories
+	h.typeText("ma")
+	h.waitFor("Add Record")
+	h.press(tcell.KeyTab, tcell.KeyEnter)
+	h.typeText("j") // Takeaway, after its parent
+	h.press(tcell.KeyEnter, tcell.KeyTab, tcell.KeyTab)
+	h.typeText("-20")
+	h.press(tcell.KeyTab)
+	h.typeText("pizza")
+	h.submit()
+	h.waitForGone("Add Record")
+	h.waitFor("Groceries ▾")
+	h.waitFor("-$62")
+	h.press(tcell.KeyTab)
+	h.typeText("j") // below the divider between income and expenditure
+	h.press(tcell.KeyEnter)
+	h.waitFor("Groceries ▸")
+	h.typeText("+")
+	h.waitFor("Groceries ▾")
+}
@@ -0,0 +1,250 @@
+package frontend
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+type actionForm struct {
+	store    *backend.Store
+	form     *tview.Form
+	iDate    *tview.InputField
+	iType    *tview.DropDown
+	iCode    *tview.InputField
+	iNew     *tview.InputField // units after, for splits and consolidations
+	iOld     *tview.InputField // units before
+	iNewCode *tview.InputField // only shown for code changes
+	tvMsg    *tview.TextView
+}
+
+func createActionsTable(store *backend.Store) *updatableTable {
+	table := newUpdatableTable(store, strings.Split("ID:Effective Date:Type:Code:Change", ":"), nil)
+	table.title = "Corporate Actions"
+	table.fGetMaxPage = func() (int, error) { return 0, nil }
+	return &table
+}
+
+func setActionsTableKeybinds(t *updatableTable, af actionForm) {
+	t.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if res := t.defaultInputCapture(event); res == nil {
+			return nil
+		}
+
+		if event.Rune() == 'a' {
+			showActionForm(t, af, -1, backend.CorporateAction{})
+		} else if event.Rune() == 'd' { // delete action
+			row, _ := t.GetSelection()
+			id := t.getCellInt(row, 0)
+			showModal("Delete this corporate action? (y/n)", func() {
+				if err := t.store.DeleteCorporateAction(id); err != nil {
+					showError(err)
+					return
+				}
+				refresh(t)
+				// set focus if deleted last row
+				if row > t.GetRowCount()-1 {
+					t.Select(max(0, row-1), 0)
+				}
+			}, t)
+		} else if event.Rune() == 'e' { // edit action
+			row, _ := t.GetSelection()
+			a, err := t.store.GetCorporateAction(t.getCellInt(row, 0))
+			if err != nil {
+				showError(err)
+				return nil
+			}
+			showActionForm(t, af, a.Id, a)
+		} else {
+			return event
+		}
+		return nil
+	})
+}
+
+func createActionForm(store *backend.Store) actionForm {
+	types := make([]string, len(backend.ActionTypes))
+	for i, typ := range backend.ActionTypes {
+		types[i] = typ.String()
+	}
+	unitsField := func(label string) *tview.InputField {
+		return tview.NewInputField().
+			SetLabel(label).
+			SetFieldWidth(7).
+			SetAcceptanceFunc(tview.InputFieldInteger)
+	}
+
+	af := actionForm{
+		store: store,
+		iDate: tview.NewInputField().
+			SetLabel("Effective Date").
+			SetFieldWidth(11).
+			SetPlaceholder("YYYY-MM-DD").
+			SetAcceptanceFunc(isPartialDate),
+		iType: tview.NewDropDown().
+			SetLabel("Type").
+			SetOptions(types, nil),
+		iCode: tview.NewInputField().
+			SetLabel("Stock Code").
+			SetFieldWidth(10),
+		iNew: unitsField("Units After"),
+		iOld: unitsField("For Units Before"),
+		iNewCode: tview.NewInputField().
+			SetLabel("New Stock Code").
+			SetFieldWidth(10),
+		tvMsg: tview.NewTextView().
+			SetSize(3, 45).
+			SetDynamicColors(true).
+			SetScrollable(false),
+	}
+
+	af.form = tview.NewForm().
+		AddButton("Save", nil).
+		AddButton("Cancel", nil).
+		SetFieldBackgroundColor(tview.Styles.MoreContrastBackgroundColor).
+		SetButtonBackgroundColor(tview.Styles.MoreContrastBackgroundColor)
+
+	af.form.SetBorder(true).
+		SetBorderColor(tview.Styles.TertiaryTextColor)
+
+	return af
+}
+
+/* Returns the type chosen in the form */
+func (af actionForm) actionType() backend.ActionType {
+	i, _ := af.iType.GetCurrentOption()
+	return backend.ActionTypes[max(i, 0)]
+}
+
+/* Adds the form's items, with the new code for code changes and the ratio for the others, keeping the focused item */
+func layoutActionForm(af actionForm) {
+	focused := af.form.GetFormItemCount()
+	for i := range af.form.GetFormItemCount() {
+		if af.form.GetFormItem(i).HasFocus() {
+			focused = i
+		}
+	}
+
+	af.form.Clear(false).
+		AddFormItem(af.iDate).
+		AddFormItem(af.iType).
+		AddFormItem(af.iCode)
+	if af.actionType() == backend.ActionRename {
+		af.form.AddFormItem(af.iNewCode)
+	} else {
+		af.form.AddFormItem(af.iNew).
+			AddFormItem(af.iOld)
+	}
+	af.form.AddFormItem(af.tvMsg)
+
+	if focused < af.form.GetFormItemCount() {
+		af.form.SetFocus(focused)
+	}
+}
+
+/* Shows the form to add (id -1) or edit a corporate action */
+func showActionForm(t *updatableTable, af actionForm, id int, a backend.CorporateAction) {
+
+	/* ===== Helper Functions ===== */
+
+	setInputFieldValues := func() {
+		date, newUnits, oldUnits := time.Now().Format("2006-01-02"), "", ""
+		if id != -1 {
+			date = a.Date.Format("2006-01-02")
+			newUnits, oldUnits = strconv.Itoa(a.New), strconv.Itoa(a.Old)
+		}
+		af.iDate.SetText(date)
+		af.iType.SetCurrentOption(max(0, slices.Index(backend.ActionTypes, a.Type)))
+		af.iCode.SetText(a.Code)
+		af.iNew.SetText(newUnits)
+		af.iOld.SetText(oldUnits)
+		af.iNewCode.SetText(a.NewCode)
+		af.tvMsg.SetText("")
+	}
+
+	closeForm := func() {
+		flex.RemoveItem(af.form)
+		app.SetFocus(t)
+	}
+
+	onSubmit := func() {
+		a, err := parseActionForm(af)
+		if err != nil {
+			af.tvMsg.SetText("[red]" + err.Error())
+			return
+		}
+
+		if id == -1 {
+			err = af.store.InsertCorporateAction(a)
+		} else {
+			err = af.store.UpdateCorporateAction(id, a)
+		}
+		if err != nil {
+			af.tvMsg.SetText("[red]" + err.Error())
+			return
+		}
+
+		refresh(t)
+		closeForm()
+	}
+
+	/* ===== Function Body ===== */
+
+	if id == -1 {
+		af.form.SetTitle("Add Corporate Action")
+	} else {
+		af.form.SetTitle("Edit Corporate Action")
+	}
+
+	af.iType.SetSelectedFunc(nil)
+	setInputFieldValues()
+	layoutActionForm(af)
+	af.iType.SetSelectedFunc(func(string, int) { layoutActionForm(af) })
+
+	af.form.SetInputCapture(formInputCapture(closeForm, onSubmit))
+	af.form.GetButton(af.form.GetButtonIndex("Cancel")).SetSelectedFunc(closeForm)
+	af.form.GetButton(af.form.GetButtonIndex("Save")).SetSelectedFunc(onSubmit)
+
+	flex.AddItem(af.form, 55, 0, true)
+	af.form.SetFocus(0)
+	app.SetFocus(af.form)
+}
+
+/* Takes input from the form and returns a CorporateAction */
+func parseActionForm(af actionForm) (backend.CorporateAction, error) {
+
+	fail := func(msg string) (backend.CorporateAction, error) {
+		return backend.CorporateAction{}, errors.New(msg)
+	}
+
+	date, err := time.Parse("2006-01-02", af.iDate.GetText())
+	if err != nil {
+		return fail("Date must be in YYYY-MM-DD format")
+	}
+	code := strings.TrimSpace(af.iCode.GetText())
+	if code == "" {
+		return fail("Please enter the stock code")
+	}
+
+	a := backend.CorporateAction{Date: date, Type: af.actionType(), Code: code}
+	if a.Type == backend.ActionRename {
+		if a.NewCode = strings.TrimSpace(af.iNewCode.GetText()); a.NewCode == "" {
+			return fail("Please enter the new stock code")
+		}
+		return a, nil
+	}
+	a.New, err = strconv.Atoi(af.iNew.GetText())
+	if err == nil {
+		a.Old, err = strconv.Atoi(af.iOld.GetText())
+	}
+	if err != nil || a.New <= 0 || a.Old <= 0 {
+		return fail("Enter the units held after for each number of units before, e.g. 2 for 1")
+	}
+	return a, nil
+}
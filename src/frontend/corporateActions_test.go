@@ -0,0 +1,45 @@
+package frontend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+func TestAddSplit(t *testing.T) {
+	h := startTUI(t, func(s *backend.Store) {
+		err := s.InsertInvestment(backend.Investment{Date: time.Now().AddDate(-1, 0, 0), Code: "IVV", Unitprice: 60000, Qty: 3})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	h.openOption("actions")
+	h.waitFor("Effective Date")
+
+	h.typeText("a")
+	h.waitFor("Add Corporate Action")
+	h.press(tcell.KeyTab, tcell.KeyEnter, tcell.KeyDown, tcell.KeyDown, tcell.KeyEnter) // code change
+	h.waitFor("New Stock Code")
+	h.press(tcell.KeyEnter, tcell.KeyUp, tcell.KeyUp, tcell.KeyEnter) // back to a split
+	h.waitFor("For Units Before")
+	h.press(tcell.KeyTab)
+	h.typeText("IVV")
+	h.press(tcell.KeyTab)
+	h.typeText("3")
+	h.press(tcell.KeyTab)
+	h.typeText("1")
+	h.submit()
+	h.waitForGone("Add Corporate Action")
+	h.waitFor("3 for 1")
+
+	lots, _, err := h.store.GetLots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lots) != 1 || lots[0].Held != 9 || lots[0].UnitCost != 20000 {
+		t.Errorf("got lots %+v, want 9 units at $200 after the split", lots)
+	}
+}
@@ -0,0 +1,257 @@
+package frontend
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+type dividendForm struct {
+	store        *backend.Store
+	form         *tview.Form
+	iDate        *tview.InputField
+	iCode        *tview.InputField
+	iAmt         *tview.InputField
+	iFranking    *tview.InputField
+	iWithholding *tview.InputField
+	iReinvested  *tview.Checkbox
+	iDrpPrice    *tview.InputField // only shown for reinvested dividends
+	tvMsg        *tview.TextView
+}
+
+func createDividendsTable(store *backend.Store) *updatableTable {
+	table := newUpdatableTable(store, strings.Split("ID:Date:Code:Cash:Franking Credits:Withholding Tax:Reinvested", ":"), nil)
+	table.title = "Dividends"
+	table.fGetMaxPage = func() (int, error) { return 0, nil }
+	return &table
+}
+
+func setDividendsTableKeybinds(t *updatableTable, df dividendForm) {
+	t.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if res := t.defaultInputCapture(event); res == nil {
+			return nil
+		}
+
+		if event.Rune() == 'a' {
+			showDividendForm(t, df, -1, backend.Dividend{})
+		} else if event.Rune() == 'd' { // delete dividend
+			row, _ := t.GetSelection()
+			id := t.getCellInt(row, 0)
+			showModal("Delete this dividend, and any lot it was reinvested in? (y/n)", func() {
+				if err := t.store.DeleteDividend(id); err != nil {
+					showError(err)
+					return
+				}
+				refresh(t)
+				// set focus if deleted last row
+				if row > t.GetRowCount()-1 {
+					t.Select(max(0, row-1), 0)
+				}
+			}, t)
+		} else if event.Rune() == 'e' { // edit dividend
+			row, _ := t.GetSelection()
+			d, err := t.store.GetDividend(t.getCellInt(row, 0))
+			if err != nil {
+				showError(err)
+				return nil
+			}
+			showDividendForm(t, df, d.Id, d)
+		} else {
+			return event
+		}
+		return nil
+	})
+}
+
+func createDividendForm(store *backend.Store) dividendForm {
+	amtField := func(label string) *tview.InputField {
+		return tview.NewInputField().
+			SetLabel(label).
+			SetFieldWidth(10).
+			SetAcceptanceFunc(tview.InputFieldFloat)
+	}
+
+	df := dividendForm{
+		store: store,
+		iDate: tview.NewInputField().
+			SetLabel("Date").
+			SetFieldWidth(11).
+			SetPlaceholder("YYYY-MM-DD").
+			SetAcceptanceFunc(isPartialDate),
+		iCode: tview.NewInputField().
+			SetLabel("Stock Code").
+			SetFieldWidth(10),
+		iAmt:         amtField("Cash Amount"),
+		iFranking:    amtField("Franking Credits"),
+		iWithholding: amtField("Withholding Tax"),
+		iReinvested: tview.NewCheckbox().
+			SetLabel("Reinvested (DRP)?"),
+		iDrpPrice: amtField("DRP Unit Price"),
+		tvMsg: tview.NewTextView().
+			SetSize(3, 45).
+			SetDynamicColors(true).
+			SetScrollable(false),
+	}
+
+	df.form = tview.NewForm().
+		AddButton("Save", nil).
+		AddButton("Cancel", nil).
+		SetFieldBackgroundColor(tview.Styles.MoreContrastBackgroundColor).
+		SetButtonBackgroundColor(tview.Styles.MoreContrastBackgroundColor)
+
+	df.form.SetBorder(true).
+		SetBorderColor(tview.Styles.TertiaryTextColor)
+
+	return df
+}
+
+/* Adds the form's items, with the DRP price only shown for reinvested dividends, keeping the focused item */
+func layoutDividendForm(df dividendForm) {
+	focused := df.form.GetFormItemCount()
+	for i := range df.form.GetFormItemCount() {
+		if df.form.GetFormItem(i).HasFocus() {
+			focused = i
+		}
+	}
+
+	df.form.Clear(false).
+		AddFormItem(df.iDate).
+		AddFormItem(df.iCode).
+		AddFormItem(df.iAmt).
+		AddFormItem(df.iFranking).
+		AddFormItem(df.iWithholding).
+		AddFormItem(df.iReinvested)
+	if df.iReinvested.IsChecked() {
+		df.form.AddFormItem(df.iDrpPrice)
+	}
+	df.form.AddFormItem(df.tvMsg)
+
+	if focused < df.form.GetFormItemCount() {
+		df.form.SetFocus(focused)
+	}
+}
+
+/* Shows the form to add (id -1) or edit a dividend */
+func showDividendForm(t *updatableTable, df dividendForm, id int, d backend.Dividend) {
+
+	/* ===== Helper Functions ===== */
+
+	setInputFieldValues := func() {
+		date := time.Now().Format("2006-01-02")
+		if id != -1 {
+			date = d.Date.Format("2006-01-02")
+		}
+		dollars := func(cents int) string {
+			if cents == 0 {
+				return ""
+			}
+			return strconv.FormatFloat(float64(cents)/100, 'f', 2, 64)
+		}
+		df.iDate.SetText(date)
+		df.iCode.SetText(d.Code)
+		df.iAmt.SetText(dollars(d.Amt))
+		df.iFranking.SetText(dollars(d.Franking))
+		df.iWithholding.SetText(dollars(d.Withholding))
+		df.iReinvested.SetChecked(d.Reinvested)
+		df.iDrpPrice.SetText(dollars(d.DrpPrice))
+		df.tvMsg.SetText("")
+	}
+
+	closeForm := func() {
+		flex.RemoveItem(df.form)
+		app.SetFocus(t)
+	}
+
+	onSubmit := func() {
+		d, err := parseDividendForm(df)
+		if err != nil {
+			df.tvMsg.SetText("[red]" + err.Error())
+			return
+		}
+
+		if id == -1 {
+			err = df.store.InsertDividend(d)
+		} else {
+			err = df.store.UpdateDividend(id, d)
+		}
+		if err != nil {
+			df.tvMsg.SetText("[red]" + err.Error())
+			return
+		}
+
+		refresh(t)
+		closeForm()
+	}
+
+	/* ===== Function Body ===== */
+
+	if id == -1 {
+		df.form.SetTitle("Add Dividend")
+	} else {
+		df.form.SetTitle("Edit Dividend Details")
+	}
+
+	df.iReinvested.SetChangedFunc(nil)
+	setInputFieldValues()
+	layoutDividendForm(df)
+	df.iReinvested.SetChangedFunc(func(bool) { layoutDividendForm(df) })
+
+	df.form.SetInputCapture(formInputCapture(closeForm, onSubmit))
+	df.form.GetButton(df.form.GetButtonIndex("Cancel")).SetSelectedFunc(closeForm)
+	df.form.GetButton(df.form.GetButtonIndex("Save")).SetSelectedFunc(onSubmit)
+
+	flex.AddItem(df.form, 55, 0, true)
+	df.form.SetFocus(0)
+	app.SetFocus(df.form)
+}
+
+/* Takes input from the form and returns a Dividend, empty amounts are 0 */
+func parseDividendForm(df dividendForm) (backend.Dividend, error) {
+
+	fail := func(msg string) (backend.Dividend, error) {
+		return backend.Dividend{}, errors.New(msg)
+	}
+
+	date, err := time.Parse("2006-01-02", df.iDate.GetText())
+	if err != nil {
+		return fail("Date must be in YYYY-MM-DD format")
+	}
+	code := strings.TrimSpace(df.iCode.GetText())
+	if code == "" {
+		return fail("Please enter the stock code")
+	}
+
+	// amounts in cents
+	cents := func(field *tview.InputField, name string) (int, error) {
+		if field.GetText() == "" {
+			return 0, nil
+		}
+		amt, err := strconv.ParseFloat(field.GetText(), 64)
+		if err != nil || amt < 0 {
+			return 0, errors.New(name + " must be a positive number")
+		}
+		return int(math.Round(amt * 100)), nil
+	}
+	d := backend.Dividend{Date: date, Code: code, Reinvested: df.iReinvested.IsChecked()}
+	if d.Amt, err = cents(df.iAmt, "Cash amount"); err != nil {
+		return d, err
+	}
+	if d.Franking, err = cents(df.iFranking, "Franking credits"); err != nil {
+		return d, err
+	}
+	if d.Withholding, err = cents(df.iWithholding, "Withholding tax"); err != nil {
+		return d, err
+	}
+	if d.Reinvested {
+		if d.DrpPrice, err = cents(df.iDrpPrice, "DRP unit price"); err != nil || d.DrpPrice == 0 {
+			return fail("Enter the unit price of the units the DRP issued")
+		}
+	}
+	return d, nil
+}
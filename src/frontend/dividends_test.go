@@ -0,0 +1,44 @@
+package frontend
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestAddReinvestedDividend(t *testing.T) {
+	h := startTUI(t, seedInvestment(t))
+
+	h.openOption("dividends")
+	h.waitFor("Withholding Tax")
+
+	h.typeText("a")
+	h.waitFor("Add Dividend")
+	h.press(tcell.KeyTab)
+	h.typeText("IVV")
+	h.press(tcell.KeyTab)
+	h.typeText("110")
+	h.press(tcell.KeyTab)
+	h.typeText("20")
+	h.press(tcell.KeyTab, tcell.KeyTab, tcell.KeyEnter) // reinvested
+	h.waitFor("DRP Unit Price")
+	h.submit()
+	h.waitFor("Enter the unit price")
+
+	h.press(tcell.KeyTab)
+	h.typeText("550")
+	h.submit()
+	h.waitForGone("Add Dividend")
+	h.waitFor("0.2 @ $550.00 (lot 2)")
+
+	d, err := h.store.GetDividend(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Amt != 11000 || d.Franking != 2000 || !d.Reinvested || d.InvId != 2 {
+		t.Errorf("unexpected dividend %+v", d)
+	}
+	if invs := getInvestments(t, h.store); len(invs) != 2 || invs[1].Qty != 0.2 {
+		t.Errorf("unexpected investments %+v", invs)
+	}
+}
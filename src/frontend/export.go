@@ -0,0 +1,337 @@
+package frontend
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/shen-kit/finance-tracker/backend"
+	"github.com/shen-kit/finance-tracker/exporter"
+)
+
+type exportForm struct {
+	store   *backend.Store
+	form    *tview.Form
+	iPath   *tview.InputField
+	iFormat *tview.DropDown
+	iFrom   *tview.InputField
+	iTo     *tview.InputField
+	iCats   *tview.InputField
+	iCode   *tview.InputField
+	iMin    *tview.InputField
+	iMax    *tview.InputField
+	iYear   *tview.InputField
+	tvMsg   *tview.TextView
+}
+
+func createExportForm(store *backend.Store) exportForm {
+	dateField := func(label string) *tview.InputField {
+		return tview.NewInputField().
+			SetLabel(label).
+			SetFieldWidth(11).
+			SetPlaceholder("YYYY-MM-DD").
+			SetAcceptanceFunc(isPartialDate)
+	}
+	amtField := func(label string) *tview.InputField {
+		return tview.NewInputField().
+			SetLabel(label).
+			SetFieldWidth(10).
+			SetAcceptanceFunc(tview.InputFieldFloat)
+	}
+
+	inFormat := tview.NewDropDown().
+		SetLabel("Format")
+
+	inFormat.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Rune() == 'j' || event.Key() == tcell.KeyCtrlN {
+			return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+		} else if event.Rune() == 'k' || event.Key() == tcell.KeyCtrlP {
+			return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+		}
+		return event
+	})
+
+	ef := exportForm{
+		store: store,
+		form:  tview.NewForm(),
+		iPath: tview.NewInputField().
+			SetLabel("File").
+			SetFieldWidth(35),
+		iFormat: inFormat,
+		iFrom:   dateField("From"),
+		iTo:     dateField("To (inclusive)"),
+		iCats: tview.NewInputField().
+			SetLabel("Categories").
+			SetFieldWidth(30).
+			SetPlaceholder("all, or e.g. Groceries,Rent"),
+		iCode: tview.NewInputField().
+			SetLabel("Code").
+			SetFieldWidth(10),
+		iMin: amtField("Min Amount"),
+		iMax: amtField("Max Amount"),
+		iYear: tview.NewInputField().
+			SetLabel("Financial Year Ending").
+			SetFieldWidth(5).
+			SetAcceptanceFunc(tview.InputFieldInteger),
+		tvMsg: tview.NewTextView().
+			SetSize(2, 35).
+			SetDynamicColors(true).
+			SetScrollable(false),
+	}
+
+	ef.form.
+		SetFieldBackgroundColor(tview.Styles.MoreContrastBackgroundColor).
+		SetButtonBackgroundColor(tview.Styles.MoreContrastBackgroundColor).
+		SetBorder(true).
+		SetBorderColor(tview.Styles.TertiaryTextColor)
+
+	return ef
+}
+
+/*
+Shows the form to export the rows of a table, what ("Records", "Categories",
+"Investments" or "Capital Gains"). Records and investments can be filtered,
+from and to prefill the date range. For capital gains, from is the financial
+year instead.
+*/
+func showExportForm(t updatablePrim, ef exportForm, what string, from, to string) {
+
+	/* ===== Helper Functions ===== */
+
+	closeForm := func() {
+		flex.RemoveItem(ef.form)
+		app.SetFocus(t)
+	}
+
+	onSubmit := func() {
+		path, err := exportRows(ef, what)
+		if err != nil {
+			ef.tvMsg.SetText("[red]" + err.Error())
+			return
+		}
+		closeForm()
+		showMessage("Exported " + strings.ToLower(what) + " to " + path)
+	}
+
+	/* ===== Function Body ===== */
+
+	// only show the filters which apply to this table
+	ef.form.Clear(true).
+		AddFormItem(ef.iPath).
+		AddFormItem(ef.iFormat)
+	switch what {
+	case "Records":
+		ef.form.AddFormItem(ef.iFrom).AddFormItem(ef.iTo).AddFormItem(ef.iCats).AddFormItem(ef.iMin).AddFormItem(ef.iMax)
+	case "Investments":
+		ef.form.AddFormItem(ef.iFrom).AddFormItem(ef.iTo).AddFormItem(ef.iCode)
+	case "Capital Gains":
+		ef.form.AddFormItem(ef.iYear)
+		ef.iYear.SetText(from)
+		from = ""
+	}
+	ef.form.AddFormItem(ef.tvMsg).
+		AddButton("Export", onSubmit).
+		AddButton("Cancel", closeForm).
+		SetTitle("Export " + what)
+
+	ef.iPath.SetText(fmt.Sprintf("%s-%s.csv", strings.ReplaceAll(strings.ToLower(what), " ", "-"), time.Now().Format("2006-01-02")))
+	// records can also be exported as a journal, along with investments in the same date range
+	formats := exporter.Formats
+	if what == "Records" {
+		formats = append(formats[:len(formats):len(formats)], exporter.JournalFormats...)
+	}
+	options := make([]string, len(formats))
+	for i, f := range formats {
+		options[i] = strings.ToUpper(string(f))
+	}
+	ef.iFormat.SetSelectedFunc(nil).SetOptions(options, nil).SetCurrentOption(0)
+	// keep the file extension matching the format
+	ef.iFormat.SetSelectedFunc(func(text string, _ int) {
+		path := ef.iPath.GetText()
+		ef.iPath.SetText(strings.TrimSuffix(path, filepath.Ext(path)) + "." + strings.ToLower(text))
+	})
+	ef.iFrom.SetText(from)
+	ef.iTo.SetText(to)
+	for _, field := range []*tview.InputField{ef.iCats, ef.iCode, ef.iMin, ef.iMax} {
+		field.SetText("")
+	}
+	ef.tvMsg.SetText("")
+
+	ef.form.SetInputCapture(formInputCapture(closeForm, onSubmit))
+
+	flex.AddItem(ef.form, 55, 0, true)
+	ef.form.SetFocus(0)
+	app.SetFocus(ef.form)
+}
+
+/* Writes the rows chosen in the export form to a file, returning its path */
+func exportRows(ef exportForm, what string) (string, error) {
+	_, formatName := ef.iFormat.GetCurrentOption()
+	format, err := exporter.ParseFormat(formatName)
+	if err != nil {
+		return "", err
+	}
+	path := strings.TrimSpace(ef.iPath.GetText())
+	if path == "" {
+		return "", errors.New("Please enter a file to export to")
+	}
+	if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(path, "~/") {
+		path = home + path[1:]
+	}
+
+	opts, err := parseExportFilter(ef)
+	if err != nil {
+		return "", err
+	}
+
+	var write func(io.Writer) error
+	switch what {
+	case "Records":
+		rows, err := ef.store.GetRecordsFilter(opts)
+		if err != nil {
+			return "", err
+		}
+		write = func(w io.Writer) error { return exporter.WriteRecords(w, format, rows) }
+		if exporter.IsJournalFormat(format) {
+			j, err := exportJournal(ef, rows)
+			if err != nil {
+				return "", err
+			}
+			write = func(w io.Writer) error { return exporter.WriteJournal(w, format, j) }
+		}
+	case "Categories":
+		rows, err := ef.store.GetCategories(0)
+		if err != nil {
+			return "", err
+		}
+		write = func(w io.Writer) error { return exporter.WriteCategories(w, format, rows) }
+	case "Investments":
+		rows, err := ef.store.GetInvestmentsFilter(opts.WithCode(strings.TrimSpace(ef.iCode.GetText())))
+		if err != nil {
+			return "", err
+		}
+		write = func(w io.Writer) error { return exporter.WriteInvestments(w, format, rows) }
+	case "Capital Gains":
+		year, err := strconv.Atoi(ef.iYear.GetText())
+		if err != nil {
+			return "", errors.New("Please enter the year the financial year ends in")
+		}
+		report, err := ef.store.GetCapitalGains(year)
+		if err != nil {
+			return "", err
+		}
+		write = func(w io.Writer) error { return exporter.WriteCapitalGains(w, format, report) }
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return "", err
+	}
+	return path, f.Close()
+}
+
+/* Collects the records being exported, with the categories and investments (in the same date range) a journal needs */
+func exportJournal(ef exportForm, recs []backend.DataRow) (exporter.Journal, error) {
+	var j exporter.Journal
+	for _, r := range recs {
+		j.Records = append(j.Records, r.(backend.Record))
+	}
+
+	cats, err := ef.store.GetCategories(0)
+	if err != nil {
+		return j, err
+	}
+	for _, r := range cats {
+		j.Categories = append(j.Categories, r.(backend.Category))
+	}
+
+	opts, err := parseExportDates(ef)
+	if err != nil {
+		return j, err
+	}
+	invs, err := ef.store.GetInvestmentsFilter(opts)
+	if err != nil {
+		return j, err
+	}
+	for _, r := range invs {
+		j.Investments = append(j.Investments, r.(backend.Investment))
+	}
+	return j, nil
+}
+
+/* Builds a filter from the export form's date fields */
+func parseExportDates(ef exportForm) (backend.FilterOpts, error) {
+	opts := backend.NewFilterOpts()
+	if from := ef.iFrom.GetText(); from != "" {
+		d, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return opts, errors.New("Dates must be in YYYY-MM-DD format")
+		}
+		opts = opts.WithStartDate(d)
+	}
+	if to := ef.iTo.GetText(); to != "" {
+		d, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return opts, errors.New("Dates must be in YYYY-MM-DD format")
+		}
+		opts = opts.WithEndDate(d.AddDate(0, 0, 1))
+	}
+	return opts, nil
+}
+
+/* Builds a filter from the export form's date, category and amount fields, empty fields aren't filtered on */
+func parseExportFilter(ef exportForm) (backend.FilterOpts, error) {
+	opts, err := parseExportDates(ef)
+	if err != nil {
+		return opts, err
+	}
+	fail := func(msg string) (backend.FilterOpts, error) {
+		return opts, errors.New(msg)
+	}
+
+	if cats := strings.TrimSpace(ef.iCats.GetText()); cats != "" {
+		var ids []int
+		for _, name := range strings.Split(cats, ",") {
+			id, err := ef.store.GetCategoryIdFromName(strings.TrimSpace(name))
+			if err != nil {
+				return fail(err.Error())
+			}
+			ids = append(ids, id)
+		}
+		opts = opts.WithCatId(ids)
+	}
+
+	// amounts are entered in dollars, filtered in cents
+	parseAmt := func(field *tview.InputField) (float32, bool, error) {
+		if field.GetText() == "" {
+			return 0, false, nil
+		}
+		amt, err := strconv.ParseFloat(field.GetText(), 32)
+		if err != nil {
+			return 0, false, errors.New("Invalid amount entered")
+		}
+		return float32(amt * 100), true, nil
+	}
+	if amt, ok, err := parseAmt(ef.iMin); err != nil {
+		return opts, err
+	} else if ok {
+		opts = opts.WithMinCost(amt)
+	}
+	if amt, ok, err := parseAmt(ef.iMax); err != nil {
+		return opts, err
+	} else if ok {
+		opts = opts.WithMaxCost(amt)
+	}
+	return opts, nil
+}
@@ -0,0 +1,38 @@
+package frontend
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+func TestExportMonthRecords(t *testing.T) {
+	h := startTUI(t, func(s *backend.Store) {
+		seedRecord(t)(s)
+		err := s.InsertRecord(backend.Record{Date: time.Now().AddDate(0, -2, 0), Desc: "older shop", Amt: -100, CatId: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	path := filepath.Join(t.TempDir(), "month.csv")
+
+	h.typeText("mX")
+	h.waitFor("Export Records")
+	h.waitFor(time.Now().Format("2006-01") + "-01") // the month's dates are filled in
+	h.replaceText(path)
+	h.submit()
+	h.waitFor("Exported records to")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "1," + time.Now().Format("2006-01-02") + ",Groceries,Everyday,weekly shop,-42.50,"
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 2 || lines[1] != want {
+		t.Errorf("unexpected export:\n%s", data)
+	}
+}
@@ -0,0 +1,258 @@
+package frontend
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+/* The filter of the records (categories) or investments (code) table, which keeps its inputs while the filter is applied */
+type filterForm struct {
+	store       *backend.Store
+	investments bool
+	form        *tview.Form
+	iFrom       *tview.InputField
+	iTo         *tview.InputField
+	iMin        *tview.InputField
+	iMax        *tview.InputField
+	iCode       *tview.InputField
+	catBoxes    *[]categoryCheckbox // rebuilt from the categories each time the form is shown
+	tvMsg       *tview.TextView
+}
+
+type categoryCheckbox struct {
+	id   int
+	name string
+	box  *tview.Checkbox
+}
+
+func createFilterForm(store *backend.Store, investments bool) filterForm {
+	ff := filterForm{
+		store:       store,
+		investments: investments,
+		iFrom: tview.NewInputField().
+			SetLabel("From").
+			SetFieldWidth(11).
+			SetPlaceholder("YYYY-MM-DD").
+			SetAcceptanceFunc(isPartialDate),
+		iTo: tview.NewInputField().
+			SetLabel("To").
+			SetFieldWidth(11).
+			SetPlaceholder("YYYY-MM-DD").
+			SetAcceptanceFunc(isPartialDate),
+		iMin: tview.NewInputField().
+			SetLabel("Min Amount").
+			SetFieldWidth(9).
+			SetAcceptanceFunc(tview.InputFieldFloat),
+		iMax: tview.NewInputField().
+			SetLabel("Max Amount").
+			SetFieldWidth(9).
+			SetAcceptanceFunc(tview.InputFieldFloat),
+		iCode: tview.NewInputField().
+			SetLabel("Code").
+			SetFieldWidth(10),
+		catBoxes: &[]categoryCheckbox{},
+		tvMsg: tview.NewTextView().
+			SetSize(1, 35).
+			SetDynamicColors(true).
+			SetScrollable(false),
+	}
+
+	ff.form = tview.NewForm().
+		AddButton("Apply", nil).
+		AddButton("Cancel", nil).
+		SetFieldBackgroundColor(tview.Styles.MoreContrastBackgroundColor).
+		SetButtonBackgroundColor(tview.Styles.MoreContrastBackgroundColor)
+
+	title := "Filter Records"
+	if investments {
+		title = "Filter Investments"
+	}
+	ff.form.SetBorder(true).
+		SetBorderColor(tview.Styles.TertiaryTextColor).
+		SetTitle(title)
+
+	return ff
+}
+
+/* Shows every row of the table again, emptying the form's inputs */
+func clearFilter(t *updatableTable, ff filterForm) {
+	for _, in := range []*tview.InputField{ff.iFrom, ff.iTo, ff.iMin, ff.iMax, ff.iCode} {
+		in.SetText("")
+	}
+	*ff.catBoxes = nil
+	t.filter, t.filterDesc = nil, ""
+	t.changePage(-t.curPage)
+}
+
+/* Shows the form to filter a table, applying an empty filter clears it */
+func showFilterForm(t *updatableTable, ff filterForm) {
+
+	/* ===== Helper Functions ===== */
+
+	// a checkbox for each category, keeping the categories checked last time
+	setCategoryBoxes := func() error {
+		checked := map[int]bool{}
+		for _, cb := range *ff.catBoxes {
+			checked[cb.id] = cb.box.IsChecked()
+		}
+		cats, err := ff.store.GetCategories(0)
+		if err != nil {
+			return err
+		}
+		boxes := make([]categoryCheckbox, len(cats))
+		for i, row := range cats {
+			cat := row.(backend.Category)
+			boxes[i] = categoryCheckbox{id: cat.Id, name: cat.Name, box: tview.NewCheckbox().
+				SetLabel(strings.Repeat("  ", cat.Depth) + cat.Name).
+				SetChecked(checked[cat.Id])}
+		}
+		*ff.catBoxes = boxes
+		return nil
+	}
+
+	closeForm := func() {
+		flex.RemoveItem(ff.form)
+		app.SetFocus(t)
+	}
+
+	onSubmit := func() {
+		opts, desc, err := parseFilterForm(ff)
+		if err != nil {
+			ff.tvMsg.SetText("[red]" + err.Error())
+			return
+		}
+		if desc == "" {
+			t.filter, t.filterDesc = nil, ""
+		} else {
+			t.filter, t.filterDesc = &opts, desc
+		}
+		closeForm()
+		t.changePage(-t.curPage)
+		t.Select(1, 0)
+	}
+
+	/* ===== Function Body ===== */
+
+	ff.tvMsg.SetText("")
+	ff.form.Clear(false).
+		AddFormItem(ff.iFrom).
+		AddFormItem(ff.iTo).
+		AddFormItem(ff.iMin).
+		AddFormItem(ff.iMax)
+	if ff.investments {
+		ff.form.AddFormItem(ff.iCode)
+	} else {
+		if err := setCategoryBoxes(); err != nil {
+			ff.tvMsg.SetText("[red]" + err.Error())
+		}
+		for _, cb := range *ff.catBoxes {
+			ff.form.AddFormItem(cb.box)
+		}
+	}
+	ff.form.AddFormItem(ff.tvMsg)
+
+	ff.form.SetInputCapture(formInputCapture(closeForm, onSubmit))
+	ff.form.GetButton(ff.form.GetButtonIndex("Cancel")).SetSelectedFunc(closeForm)
+	ff.form.GetButton(ff.form.GetButtonIndex("Apply")).SetSelectedFunc(onSubmit)
+
+	// display + focus form
+	flex.AddItem(ff.form, 55, 0, true)
+	ff.form.SetFocus(0)
+	app.SetFocus(ff.form)
+}
+
+/*
+Takes input from the form and returns the filter, with a description of it for
+the table's title ("" if nothing is filtered). Dates are inclusive.
+*/
+func parseFilterForm(ff filterForm) (backend.FilterOpts, string, error) {
+	opts := backend.NewFilterOpts()
+	var desc []string
+
+	fail := func(msg string) (backend.FilterOpts, string, error) {
+		return opts, "", errors.New(msg)
+	}
+
+	// dates
+	var from, to time.Time
+	var err error
+	if text := ff.iFrom.GetText(); text != "" {
+		if from, err = time.Parse("2006-01-02", text); err != nil {
+			return fail("Dates must be in YYYY-MM-DD format")
+		}
+		opts = opts.WithStartDate(from)
+	}
+	if text := ff.iTo.GetText(); text != "" {
+		if to, err = time.Parse("2006-01-02", text); err != nil {
+			return fail("Dates must be in YYYY-MM-DD format")
+		}
+		opts = opts.WithEndDate(to.AddDate(0, 0, 1))
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		return fail("The range must end after it starts")
+	}
+	switch {
+	case !from.IsZero() && !to.IsZero():
+		desc = append(desc, from.Format("2006-01-02")+" to "+to.Format("2006-01-02"))
+	case !from.IsZero():
+		desc = append(desc, "from "+from.Format("2006-01-02"))
+	case !to.IsZero():
+		desc = append(desc, "to "+to.Format("2006-01-02"))
+	}
+
+	// amounts, in cents
+	minAmt, maxAmt := math.Inf(-1), math.Inf(1)
+	if text := ff.iMin.GetText(); text != "" {
+		if minAmt, err = strconv.ParseFloat(text, 64); err != nil {
+			return fail("Invalid minimum amount")
+		}
+		opts = opts.WithMinCost(float32(math.Round(minAmt * 100)))
+	}
+	if text := ff.iMax.GetText(); text != "" {
+		if maxAmt, err = strconv.ParseFloat(text, 64); err != nil {
+			return fail("Invalid maximum amount")
+		}
+		opts = opts.WithMaxCost(float32(math.Round(maxAmt * 100)))
+	}
+	if minAmt > maxAmt {
+		return fail("The minimum amount is more than the maximum")
+	}
+	switch {
+	case ff.iMin.GetText() != "" && ff.iMax.GetText() != "":
+		desc = append(desc, fmt.Sprintf("$%.2f to $%.2f", minAmt, maxAmt))
+	case ff.iMin.GetText() != "":
+		desc = append(desc, fmt.Sprintf(">= $%.2f", minAmt))
+	case ff.iMax.GetText() != "":
+		desc = append(desc, fmt.Sprintf("<= $%.2f", maxAmt))
+	}
+
+	// categories or code
+	if ff.investments {
+		if code := strings.TrimSpace(ff.iCode.GetText()); code != "" {
+			opts = opts.WithCode(code)
+			desc = append(desc, "code "+strings.ToUpper(code))
+		}
+	} else {
+		var ids []int
+		var names []string
+		for _, cb := range *ff.catBoxes {
+			if cb.box.IsChecked() {
+				ids = append(ids, cb.id)
+				names = append(names, cb.name)
+			}
+		}
+		if len(ids) > 0 {
+			opts = opts.WithCatId(ids)
+			desc = append(desc, strings.Join(names, ", "))
+		}
+	}
+
+	return opts, strings.Join(desc, ", "), nil
+}
@@ -0,0 +1,77 @@
+package frontend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+func TestFilterRecords(t *testing.T) {
+	h := startTUI(t, func(s *backend.Store) {
+		seedCategories(t)(s)
+		recs := []backend.Record{
+			{Date: time.Now(), Desc: "weekly shop", Amt: -4250, CatId: 1},
+			{Date: time.Now().AddDate(0, 0, -1), Desc: "big shop", Amt: -25000, CatId: 1},
+			{Date: time.Now().AddDate(0, 0, -2), Desc: "pay", Amt: 300000, CatId: 2},
+		}
+		if _, err := s.InsertRecords(recs); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	h.typeText("rf")
+	h.waitFor("Filter Records")
+	h.typeText("2024-02-30")
+	h.press(tcell.KeyTab, tcell.KeyTab, tcell.KeyTab)
+	h.typeText("-100")
+	h.submit()
+	h.waitFor("Dates must be in YYYY-MM-DD format")
+
+	// spending over $100 in Groceries
+	h.press(tcell.KeyBacktab, tcell.KeyBacktab, tcell.KeyBacktab)
+	h.replaceText("")
+	h.press(tcell.KeyTab, tcell.KeyTab, tcell.KeyTab, tcell.KeyTab)
+	h.typeText(" ")
+	h.submit()
+	h.waitForGone("Filter Records")
+	h.waitFor("filtered: <= $-100.00, Groceries")
+	h.waitFor("big shop")
+	h.waitForGone("weekly shop")
+	h.waitForGone("pay")
+
+	// the form keeps the filter's inputs
+	h.typeText("f")
+	h.waitFor("Filter Records")
+	h.press(tcell.KeyEscape)
+	h.waitForGone("Filter Records")
+	h.waitFor("filtered:")
+
+	h.typeText("F")
+	h.waitForGone("filtered:")
+	h.waitFor("weekly shop")
+	h.waitFor("pay")
+}
+
+func TestFilterInvestments(t *testing.T) {
+	h := startTUI(t, func(s *backend.Store) {
+		seedInvestment(t)(s)
+		if err := s.InsertInvestment(backend.Investment{Date: time.Now(), Code: "VGS.AX", Unitprice: 12050, Qty: 10}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	h.typeText("if")
+	h.waitFor("Filter Investments")
+	h.press(tcell.KeyTab, tcell.KeyTab, tcell.KeyTab, tcell.KeyTab)
+	h.typeText("ivv")
+	h.submit()
+	h.waitForGone("Filter Investments")
+	h.waitFor("filtered: code IVV")
+	h.waitForGone("VGS.AX")
+
+	h.typeText("F")
+	h.waitForGone("filtered:")
+	h.waitFor("VGS.AX")
+}
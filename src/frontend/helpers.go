@@ -34,11 +34,13 @@ func formInputCapture(onCancel, onSubmit func()) func(*tcell.EventKey) *tcell.Ev
 
 func showModal(s string, actionFunc func(), prev tview.Primitive) {
 	modalText.SetText(s).SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		if event.Rune() == 'n' || event.Rune() == 'N' || isBackKey(event) {
+		if event.Rune() == 'y' || event.Rune() == 'Y' {
+			// close first, so actionFunc can show an error message in the modal
 			pages.HidePage("modal")
-		} else if event.Rune() == 'y' || event.Rune() == 'Y' {
+			app.SetFocus(prev)
 			actionFunc()
-		} else {
+			return nil
+		} else if event.Rune() != 'n' && event.Rune() != 'N' && !isBackKey(event) {
 			return event
 		}
 		pages.HidePage("modal")
@@ -46,10 +48,92 @@ func showModal(s string, actionFunc func(), prev tview.Primitive) {
 		return nil
 	})
 
-	pages.ShowPage("modal")
+	pages.ShowPage("modal").SendToFront("modal")
 	app.SetFocus(modalText)
 }
 
+/* Shows an error message in the modal, any key closes it and returns focus to the previous primitive */
+func showError(err error) {
+	showMessage("Error: " + err.Error())
+}
+
+/* Shows a message in the modal, any key closes it and returns focus to the previous primitive */
+func showMessage(s string) {
+	prev := app.GetFocus()
+	if prev == nil || prev == modalText { // already showing a message, return to the options list
+		prev = flex
+	}
+	modalText.SetText(s).SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		pages.HidePage("modal")
+		app.SetFocus(prev)
+		return nil
+	})
+
+	pages.ShowPage("modal").SendToFront("modal")
+	app.SetFocus(modalText)
+}
+
+/* Shows scrollable text in a popup, back keys close it and return focus to the previous primitive */
+func showReport(title, text string) {
+	prev := app.GetFocus()
+	if prev == nil {
+		prev = flex
+	}
+	reportText.SetText(text).ScrollToBeginning().SetTitle(title)
+	reportText.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if isBackKey(event) || event.Key() == tcell.KeyEnter {
+			pages.HidePage("report")
+			app.SetFocus(prev)
+			return nil
+		} else if event.Rune() == 'j' {
+			return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+		} else if event.Rune() == 'k' {
+			return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+		}
+		return event
+	})
+
+	pages.ShowPage("report").SendToFront("report")
+	app.SetFocus(reportText)
+}
+
+/*
+Shows a list of options in a popup, calling onSelect with the index of the
+chosen option. Back keys close the popup without choosing.
+*/
+func showPicker(title string, options []string, current int, onSelect func(int)) {
+	prev := app.GetFocus()
+	closePicker := func() {
+		pages.HidePage("picker")
+		app.SetFocus(prev)
+	}
+
+	pickerList.Clear().SetTitle(title)
+	for i, opt := range options {
+		pickerList.AddItem(opt, "", 0, func() {
+			closePicker()
+			onSelect(i)
+		})
+	}
+	pickerList.SetCurrentItem(current)
+	pickerList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if isBackKey(event) {
+			closePicker()
+			return nil
+		} else if event.Rune() == 'j' {
+			return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+		} else if event.Rune() == 'k' {
+			return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+		} else if event.Rune() == 'l' {
+			return tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone)
+		}
+		return event
+	})
+
+	pages.ShowPage("picker").SendToFront("picker")
+	app.SetFocus(pickerList)
+}
+
 // Utility
 
 func isPartialDate(s string, _ rune) bool {
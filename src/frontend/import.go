@@ -0,0 +1,392 @@
+package frontend
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/shen-kit/finance-tracker/backend"
+	"github.com/shen-kit/finance-tracker/importer"
+)
+
+type importForm struct {
+	store       *backend.Store
+	form        *tview.Form
+	iPath       *tview.InputField
+	iAccount    *tview.DropDown
+	iProfile    *tview.DropDown
+	iDateFormat *tview.InputField
+	tvMsg       *tview.TextView
+}
+
+/* A transaction read from a statement, waiting to be reviewed before it is inserted */
+type importRow struct {
+	rec       backend.Record
+	line      int
+	skip      bool
+	duplicate bool // already imported from an earlier statement
+}
+
+func (r importRow) SpreadToStrings() []string {
+	strs := r.rec.SpreadToStrings()
+	strs[0] = fmt.Sprint(r.line)
+	if r.duplicate && r.skip {
+		strs[2] = "[gray](duplicate)"
+	} else if r.skip {
+		strs[2] = "[gray](skipped)"
+	} else if r.rec.CatId == 0 {
+		strs[2] = "[red](none)"
+	}
+	return strs
+}
+
+func createImportForm(store *backend.Store) importForm {
+	inPath := tview.NewInputField().
+		SetLabel("File").
+		SetFieldWidth(35).
+		SetPlaceholder("~/Downloads/statement.csv")
+
+	inAccount := tview.NewDropDown().
+		SetLabel("Account")
+
+	inProfile := tview.NewDropDown().
+		SetLabel("Profile (CSV)")
+
+	dropDownCapture := func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Rune() == 'j' || event.Key() == tcell.KeyCtrlN {
+			return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+		} else if event.Rune() == 'k' || event.Key() == tcell.KeyCtrlP {
+			return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+		}
+		return event
+	}
+	inAccount.SetInputCapture(dropDownCapture)
+	inProfile.SetInputCapture(dropDownCapture)
+
+	inDateFormat := tview.NewInputField().
+		SetLabel("Date Format (QIF)").
+		SetFieldWidth(12).
+		SetText("DD/MM/YYYY")
+
+	formMsg := tview.NewTextView().
+		SetSize(2, 35).
+		SetDynamicColors(true).
+		SetScrollable(false)
+
+	form := tview.NewForm().
+		AddFormItem(inPath).
+		AddFormItem(inAccount).
+		AddFormItem(inProfile).
+		AddFormItem(inDateFormat).
+		AddFormItem(formMsg).
+		AddButton("Preview", nil).
+		AddButton("Cancel", nil).
+		SetFieldBackgroundColor(tview.Styles.MoreContrastBackgroundColor).
+		SetButtonBackgroundColor(tview.Styles.MoreContrastBackgroundColor)
+
+	form.SetBorder(true).
+		SetBorderColor(tview.Styles.TertiaryTextColor).
+		SetTitle("Import Statement")
+
+	return importForm{
+		store: store, form: form, iPath: inPath, iAccount: inAccount, iProfile: inProfile, iDateFormat: inDateFormat, tvMsg: formMsg,
+	}
+}
+
+/* Shows the import form, and once a file is parsed, a preview of its transactions */
+func showImportForm(t updatablePrim, imf importForm) {
+
+	/* ===== Helper Functions ===== */
+
+	closeForm := func() {
+		flex.RemoveItem(imf.form)
+		app.SetFocus(t)
+	}
+
+	onSubmit := func() {
+		rows, invs, err := readStatement(imf)
+		if err != nil {
+			imf.tvMsg.SetText("[red]" + err.Error())
+			return
+		}
+		if len(rows) == 0 && len(invs) == 0 {
+			imf.tvMsg.SetText("[red]No transactions found in file")
+			return
+		}
+		flex.RemoveItem(imf.form)
+		showImportPreview(t, imf.store, rows, invs)
+	}
+
+	/* ===== Function Body ===== */
+
+	profiles, err := imf.store.GetImportProfiles()
+	if err != nil {
+		showError(err)
+		return
+	}
+	names := make([]string, len(profiles))
+	for i, p := range profiles {
+		names[i] = p.(backend.ImportProfile).Name
+	}
+	imf.iProfile.SetOptions(names, nil).SetCurrentOption(0)
+
+	accounts, err := imf.store.GetAccounts()
+	if err != nil {
+		showError(err)
+		return
+	}
+	accNames := make([]string, len(accounts))
+	for i, a := range accounts {
+		accNames[i] = a.(backend.Account).Name
+	}
+	imf.iAccount.SetOptions(accNames, nil).SetCurrentOption(0)
+	imf.tvMsg.SetText("")
+
+	imf.form.SetInputCapture(formInputCapture(closeForm, onSubmit))
+	imf.form.GetButton(imf.form.GetButtonIndex("Cancel")).SetSelectedFunc(closeForm)
+	imf.form.GetButton(imf.form.GetButtonIndex("Preview")).SetSelectedFunc(onSubmit)
+
+	flex.AddItem(imf.form, 55, 0, true)
+	imf.form.SetFocus(0)
+	app.SetFocus(imf.form)
+}
+
+/*
+Parses the file chosen in the import form, with the parser chosen by its extension.
+Guesses a category for each transaction, and skips those which were already imported.
+*/
+func readStatement(imf importForm) ([]importRow, []backend.Investment, error) {
+	path := strings.TrimSpace(imf.iPath.GetText())
+	if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(path, "~/") {
+		path = home + path[1:]
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	// every transaction in a statement is from the same account
+	_, accName := imf.iAccount.GetCurrentOption()
+	if accName == "" {
+		return nil, nil, errors.New("Add an account from the Accounts view first")
+	}
+	accId, err := imf.store.GetAccountIdFromName(accName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var recs []backend.Record
+	var invs []backend.Investment
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ofx", ".qfx":
+		var st importer.Statement
+		st, err = importer.ParseOFX(f)
+		recs, invs = st.Records, st.Investments
+	case ".qif":
+		recs, err = importer.ParseQIF(f, strings.TrimSpace(imf.iDateFormat.GetText()), accName)
+	case ".ledger", ".journal", ".hledger", ".beancount", ".bean":
+		recs, invs, err = readJournal(imf.store, f)
+	default:
+		_, profileName := imf.iProfile.GetCurrentOption()
+		if profileName == "" {
+			return nil, nil, errors.New("Add an import profile from the Import Profiles view first")
+		}
+		var profile backend.ImportProfile
+		if profile, err = imf.store.GetImportProfile(profileName); err == nil {
+			recs, err = importer.ParseCSV(f, profile)
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows := make([]importRow, len(recs))
+	for i, rec := range recs {
+		rec.AccId, rec.AccName = accId, accName
+		if rec.CatId, err = statementCategory(imf.store, rec); err != nil {
+			return nil, nil, err
+		}
+		rec.CatName = ""
+		if rec.CatId != 0 {
+			if rec.CatName, err = imf.store.GetCategoryNameFromId(rec.CatId); err != nil {
+				return nil, nil, err
+			}
+		}
+		rows[i] = importRow{rec: rec, line: i + 1}
+		if rec.ExtId != "" {
+			if rows[i].duplicate, err = imf.store.RecordExtIdExists(rec.ExtId); err != nil {
+				return nil, nil, err
+			}
+			rows[i].skip = rows[i].duplicate
+		}
+	}
+	return rows, invs, nil
+}
+
+/* Parses a journal, creating the categories its records use which don't exist yet */
+func readJournal(store *backend.Store, r io.Reader) ([]backend.Record, []backend.Investment, error) {
+	rows, err := store.GetCategories(0)
+	if err != nil {
+		return nil, nil, err
+	}
+	cats := make([]backend.Category, len(rows))
+	for i, r := range rows {
+		cats[i] = r.(backend.Category)
+	}
+
+	st, err := importer.ParseJournal(r, cats)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, c := range st.Categories {
+		if err := store.InsertCategory(c); err != nil {
+			return nil, nil, err
+		}
+	}
+	return st.Records, st.Investments, nil
+}
+
+/* Uses the category named in the statement if there's one with that name, otherwise guesses it */
+func statementCategory(store *backend.Store, rec backend.Record) (int, error) {
+	if rec.CatName != "" {
+		catId, err := store.GetCategoryIdFromName(rec.CatName)
+		if !errors.Is(err, backend.ErrNotFound) {
+			return catId, err
+		}
+	}
+	return store.GuessCategory(rec.Desc)
+}
+
+/*
+Shows imported transactions for review before they are inserted.
+c/enter: choose category, x: skip/unskip, A: use this category for all
+uncategorised rows, ctrl+enter: import, q: cancel
+*/
+func showImportPreview(parent updatablePrim, store *backend.Store, rows []importRow, invs []backend.Investment) {
+	table := newUpdatableTable(store, strings.Split("#:Date:Category:Account:Description:Amount", ":"), nil)
+	preview := &table
+	preview.title = "Import Preview"
+	preview.fGetMaxPage = func() (int, error) { return 0, nil }
+	title := fmt.Sprintf("Import Preview (%d transactions", len(rows))
+	if len(invs) > 0 {
+		title += fmt.Sprintf(", %d investments", len(invs))
+	}
+	preview.SetTitle(title + ")")
+
+	/* ===== Helper Functions ===== */
+
+	redraw := func() {
+		dataRows := make([]backend.DataRow, len(rows))
+		for i, r := range rows {
+			dataRows[i] = r
+		}
+		preview.update(dataRows)
+	}
+
+	closePreview := func() {
+		flex.RemoveItem(preview)
+		app.SetFocus(parent)
+	}
+
+	chooseCategory := func(i int) {
+		cats, err := store.GetCategories(0)
+		if err != nil {
+			showError(err)
+			return
+		}
+		names := make([]string, len(cats))
+		current := 0
+		for j, cat := range cats {
+			names[j] = cat.(backend.Category).Name
+			if cat.(backend.Category).Id == rows[i].rec.CatId {
+				current = j
+			}
+		}
+		showPicker("Category", names, current, func(choice int) {
+			cat := cats[choice].(backend.Category)
+			rows[i].rec.CatId, rows[i].rec.CatName = cat.Id, cat.Name
+			rows[i].skip = false
+			redraw()
+		})
+	}
+
+	applyToUncategorised := func(i int) {
+		for j := range rows {
+			if rows[j].rec.CatId == 0 {
+				rows[j].rec.CatId, rows[j].rec.CatName = rows[i].rec.CatId, rows[i].rec.CatName
+			}
+		}
+		redraw()
+	}
+
+	importRows := func() {
+		var recs []backend.Record
+		for _, r := range rows {
+			if r.skip {
+				continue
+			} else if r.rec.CatId == 0 {
+				showMessage(fmt.Sprintf("Transaction %d has no category, choose one or skip it (x)", r.line))
+				return
+			}
+			recs = append(recs, r.rec)
+		}
+		nRecs, err := store.InsertRecords(recs)
+		if err != nil {
+			showError(err)
+			return
+		}
+		nInvs, err := store.InsertInvestments(invs)
+		if err != nil {
+			showError(err)
+			return
+		}
+
+		msg := fmt.Sprintf("Imported %d records", nRecs)
+		if len(invs) > 0 {
+			msg += fmt.Sprintf(" and %d investments", nInvs)
+		}
+		if skipped := len(recs) + len(invs) - nRecs - nInvs; skipped > 0 {
+			msg += fmt.Sprintf(", %d were already imported", skipped)
+		}
+		closePreview()
+		refresh(parent)
+		showMessage(msg)
+	}
+
+	/* ===== Function Body ===== */
+
+	preview.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		row, _ := preview.GetSelection()
+		i := row - 1
+		if isBackKey(event) {
+			closePreview()
+		} else if event.Key() == tcell.KeyEnter && event.Modifiers() == tcell.ModCtrl {
+			importRows()
+		} else if i < 0 || i >= len(rows) {
+			return event
+		} else if event.Key() == tcell.KeyEnter || event.Rune() == 'c' {
+			chooseCategory(i)
+		} else if event.Rune() == 'x' {
+			rows[i].skip = !rows[i].skip
+			redraw()
+		} else if event.Rune() == 'A' {
+			if rows[i].rec.CatId != 0 {
+				applyToUncategorised(i)
+			}
+		} else {
+			return event
+		}
+		return nil
+	})
+
+	redraw()
+	flex.AddItem(preview, 0, 2, true)
+	app.SetFocus(preview)
+	preview.Select(1, 0)
+}
@@ -0,0 +1,257 @@
+package frontend
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/shen-kit/finance-tracker/backend"
+	"github.com/shen-kit/finance-tracker/importer"
+)
+
+type importProfileForm struct {
+	store       *backend.Store
+	form        *tview.Form
+	iName       *tview.InputField
+	iDelimiter  *tview.InputField
+	iSkipRows   *tview.InputField
+	iDateCol    *tview.InputField
+	iDateFormat *tview.InputField
+	iDescCols   *tview.InputField
+	iAmtCol     *tview.InputField
+	iDebitCol   *tview.InputField
+	iNegate     *tview.Checkbox
+	tvMsg       *tview.TextView
+}
+
+func createImportProfilesTable(store *backend.Store) *updatableTable {
+	table := newUpdatableTable(store, strings.Split("ID:Name:Delimiter:Skip Rows:Date Col:Date Format:Desc Cols:Amount Col:Debit Col:Negate", ":"), nil)
+	table.title = "Import Profiles"
+	table.fGetMaxPage = func() (int, error) { return 0, nil }
+	return &table
+}
+
+func setImportProfilesTableKeybinds(t *updatableTable, pf importProfileForm) {
+	t.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if res := t.defaultInputCapture(event); res == nil {
+			return nil
+		}
+
+		if event.Rune() == 'a' {
+			showImportProfileForm(t, pf, -1, backend.ImportProfile{Delimiter: ",", DateFormat: "DD/MM/YYYY"})
+		} else if event.Rune() == 'd' { // delete profile
+			row, _ := t.GetSelection()
+			id := t.getCellInt(row, 0)
+			showModal("Delete this import profile? (y/n)", func() {
+				if err := t.store.DeleteImportProfile(id); err != nil {
+					showError(err)
+					return
+				}
+				refresh(t)
+				// set focus if deleted last row
+				if row > t.GetRowCount()-1 {
+					t.Select(max(0, row-1), 0)
+				}
+			}, t)
+		} else if event.Rune() == 'e' { // edit profile
+			row, _ := t.GetSelection()
+			id := t.getCellInt(row, 0)
+			p, err := t.store.GetImportProfile(t.getCellString(row, 1))
+			if err != nil {
+				showError(err)
+				return nil
+			}
+			showImportProfileForm(t, pf, id, p)
+		} else {
+			return event
+		}
+		return nil
+	})
+}
+
+func createImportProfileForm(store *backend.Store) importProfileForm {
+	intField := func(label string) *tview.InputField {
+		return tview.NewInputField().
+			SetLabel(label).
+			SetFieldWidth(4).
+			SetAcceptanceFunc(tview.InputFieldInteger)
+	}
+
+	inName := tview.NewInputField().
+		SetLabel("Name").
+		SetFieldWidth(30)
+
+	inDelimiter := tview.NewInputField().
+		SetLabel("Delimiter").
+		SetFieldWidth(2).
+		SetAcceptanceFunc(tview.InputFieldMaxLength(1))
+
+	inDateFormat := tview.NewInputField().
+		SetLabel("Date Format").
+		SetFieldWidth(12).
+		SetPlaceholder("DD/MM/YYYY")
+
+	inDescCols := tview.NewInputField().
+		SetLabel("Description Columns").
+		SetFieldWidth(10).
+		SetPlaceholder("e.g. 3,4")
+
+	inNegate := tview.NewCheckbox().
+		SetLabel("Spending is Positive?")
+
+	formMsg := tview.NewTextView().
+		SetSize(1, 35).
+		SetDynamicColors(true).
+		SetScrollable(false)
+
+	pf := importProfileForm{
+		store:       store,
+		iName:       inName,
+		iDelimiter:  inDelimiter,
+		iSkipRows:   intField("Header Rows to Skip"),
+		iDateCol:    intField("Date Column"),
+		iDateFormat: inDateFormat,
+		iDescCols:   inDescCols,
+		iAmtCol:     intField("Amount Column"),
+		iDebitCol:   intField("Debit Column (optional)"),
+		iNegate:     inNegate,
+		tvMsg:       formMsg,
+	}
+
+	pf.form = tview.NewForm().
+		AddFormItem(pf.iName).
+		AddFormItem(pf.iDelimiter).
+		AddFormItem(pf.iSkipRows).
+		AddFormItem(pf.iDateCol).
+		AddFormItem(pf.iDateFormat).
+		AddFormItem(pf.iDescCols).
+		AddFormItem(pf.iAmtCol).
+		AddFormItem(pf.iDebitCol).
+		AddFormItem(pf.iNegate).
+		AddFormItem(pf.tvMsg).
+		AddButton("Save", nil).
+		AddButton("Cancel", nil).
+		SetFieldBackgroundColor(tview.Styles.MoreContrastBackgroundColor).
+		SetButtonBackgroundColor(tview.Styles.MoreContrastBackgroundColor)
+
+	pf.form.SetBorder(true).
+		SetBorderColor(tview.Styles.TertiaryTextColor)
+
+	return pf
+}
+
+func showImportProfileForm(t *updatableTable, pf importProfileForm, id int, p backend.ImportProfile) {
+
+	/* ===== Helper Functions ===== */
+
+	intToField := func(n int) string {
+		if n == 0 {
+			return ""
+		}
+		return strconv.Itoa(n)
+	}
+
+	setInputFieldValues := func() {
+		descCols := make([]string, len(p.DescCols))
+		for i, c := range p.DescCols {
+			descCols[i] = strconv.Itoa(c)
+		}
+		pf.iName.SetText(p.Name)
+		pf.iDelimiter.SetText(p.Delimiter)
+		pf.iSkipRows.SetText(strconv.Itoa(p.SkipRows))
+		pf.iDateCol.SetText(intToField(p.DateCol))
+		pf.iDateFormat.SetText(p.DateFormat)
+		pf.iDescCols.SetText(strings.Join(descCols, ","))
+		pf.iAmtCol.SetText(intToField(p.AmtCol))
+		pf.iDebitCol.SetText(intToField(p.DebitCol))
+		pf.iNegate.SetChecked(p.Negate)
+		pf.tvMsg.SetText("")
+	}
+
+	closeForm := func() {
+		flex.RemoveItem(pf.form)
+		app.SetFocus(t)
+	}
+
+	onSubmit := func() {
+		profile, err := parseImportProfileForm(pf)
+		if err != nil {
+			pf.tvMsg.SetText("[red]" + err.Error())
+			return
+		}
+
+		if id == -1 {
+			err = pf.store.InsertImportProfile(profile)
+		} else {
+			err = pf.store.UpdateImportProfile(id, profile)
+		}
+		if err != nil {
+			pf.tvMsg.SetText("[red]" + err.Error())
+			return
+		}
+		refresh(t)
+		closeForm()
+	}
+
+	/* ===== Function Body ===== */
+
+	if id == -1 {
+		pf.form.SetTitle("Add Import Profile")
+	} else {
+		pf.form.SetTitle("Edit Import Profile")
+	}
+
+	setInputFieldValues()
+
+	pf.form.SetInputCapture(formInputCapture(closeForm, onSubmit))
+	pf.form.GetButton(pf.form.GetButtonIndex("Cancel")).SetSelectedFunc(closeForm)
+	pf.form.GetButton(pf.form.GetButtonIndex("Save")).SetSelectedFunc(onSubmit)
+
+	flex.AddItem(pf.form, 55, 0, true)
+	pf.form.SetFocus(0)
+	app.SetFocus(pf.form)
+}
+
+/* Takes input from the form and returns an ImportProfile object */
+func parseImportProfileForm(pf importProfileForm) (backend.ImportProfile, error) {
+
+	fail := func(msg string) (backend.ImportProfile, error) {
+		return backend.ImportProfile{}, errors.New(msg)
+	}
+
+	// empty optional fields are 0
+	fieldInt := func(field *tview.InputField) int {
+		n, _ := strconv.Atoi(strings.TrimSpace(field.GetText()))
+		return n
+	}
+
+	var descCols []int
+	for _, c := range strings.Split(pf.iDescCols.GetText(), ",") {
+		if strings.TrimSpace(c) == "" {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(c))
+		if err != nil {
+			return fail("Description columns must be numbers, e.g. 3,4")
+		}
+		descCols = append(descCols, n)
+	}
+
+	p := backend.ImportProfile{
+		Name:       strings.TrimSpace(pf.iName.GetText()),
+		Delimiter:  pf.iDelimiter.GetText(),
+		SkipRows:   fieldInt(pf.iSkipRows),
+		DateCol:    fieldInt(pf.iDateCol),
+		DateFormat: strings.TrimSpace(pf.iDateFormat.GetText()),
+		DescCols:   descCols,
+		AmtCol:     fieldInt(pf.iAmtCol),
+		DebitCol:   fieldInt(pf.iDebitCol),
+		Negate:     pf.iNegate.IsChecked(),
+	}
+	if err := importer.ValidateProfile(p); err != nil {
+		return fail(err.Error())
+	}
+	return p, nil
+}
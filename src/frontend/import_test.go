@@ -0,0 +1,136 @@
+package frontend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+func seedImportProfile(t *testing.T) func(*backend.Store) {
+	return func(s *backend.Store) {
+		seedCategories(t)(s)
+		err := s.InsertRecord(backend.Record{Date: time.Now(), Desc: "COLES 0123 SYDNEY", Amt: -1000, CatId: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = s.InsertImportProfile(backend.ImportProfile{
+			Name: "CommBank", Delimiter: ",", DateCol: 1, DateFormat: "DD/MM/YYYY", DescCols: []int{3}, AmtCol: 2,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "statement.csv")
+	statement := `01/10/2026,"-42.50","COLES 0123 SYDNEY","+1234.56"
+02/10/2026,"+3,000.00","SALARY ACME PTY LTD","+4234.56"
+03/10/2026,"-1,500.00","RENT PAYMENT","+2734.56"
+`
+	if err := os.WriteFile(path, []byte(statement), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	h := startTUI(t, seedImportProfile(t))
+
+	h.typeText("rI")
+	h.waitFor("Import Statement")
+	h.typeText(path)
+	h.submit()
+	h.waitFor("Import Preview (3 transactions)")
+	h.waitFor("(none)")
+
+	// importing with uncategorised rows is refused
+	h.submit()
+	h.waitFor("Transaction 2 has no category")
+	h.typeText("q")
+
+	h.press(tcell.KeyDown)
+	h.typeText("c")
+	h.waitFor("Work")
+	h.typeText("jl") // second category
+	h.press(tcell.KeyDown)
+	h.typeText("x")
+	h.waitFor("(skipped)")
+	h.submit()
+	h.waitFor("Imported 2 records")
+
+	recs := getRecords(t, h.store)
+	if len(recs) != 3 {
+		t.Fatalf("got %d records, want 3", len(recs))
+	}
+	got := map[string]string{}
+	for _, r := range recs {
+		got[r.Desc] = r.CatName
+	}
+	want := map[string]string{"COLES 0123 SYDNEY": "Groceries", "SALARY ACME PTY LTD": "Work"}
+	for desc, cat := range want {
+		if got[desc] != cat {
+			t.Errorf("%s: got category %q, want %q", desc, got[desc], cat)
+		}
+	}
+	if _, ok := got["RENT PAYMENT"]; ok {
+		t.Error("skipped transaction was imported")
+	}
+}
+
+func TestImportProfileForm(t *testing.T) {
+	h := startTUI(t, nil)
+
+	h.openOption("profiles")
+	h.typeText("a")
+	h.waitFor("Add Import Profile")
+	h.typeText("Westpac")
+	h.submit()
+	h.waitFor("column numbers start from 1")
+
+	h.press(tcell.KeyTab, tcell.KeyTab, tcell.KeyTab)
+	h.typeText("1")
+	h.press(tcell.KeyTab, tcell.KeyTab)
+	h.typeText("3")
+	h.press(tcell.KeyTab)
+	h.typeText("2")
+	h.submit()
+	h.waitForGone("Add Import Profile")
+	h.waitFor("Westpac")
+
+	p, err := h.store.GetImportProfile("Westpac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.DateCol != 1 || p.AmtCol != 2 || len(p.DescCols) != 1 || p.DescCols[0] != 3 || p.DateFormat != "DD/MM/YYYY" {
+		t.Errorf("unexpected profile %+v", p)
+	}
+}
+
+func TestImportOFXSkipsDuplicates(t *testing.T) {
+	h := startTUI(t, seedImportProfile(t))
+	importStatement := func() {
+		h.typeText("I")
+		h.waitFor("Import Statement")
+		h.replaceText("../importer/testdata/bank.qfx")
+		h.submit()
+		h.waitFor("Import Preview (3 transactions)")
+	}
+
+	h.typeText("r")
+	importStatement()
+	h.press(tcell.KeyDown)
+	h.typeText("x")
+	h.press(tcell.KeyDown)
+	h.typeText("x")
+	h.submit()
+	h.waitFor("Imported 1 records")
+	h.typeText("q")
+
+	// the transaction imported the first time is skipped
+	importStatement()
+	h.waitFor("(duplicate)")
+	if recs := getRecords(t, h.store); len(recs) != 2 {
+		t.Errorf("got %d records, want 2", len(recs))
+	}
+}
@@ -4,12 +4,14 @@ import (
 	"strings"
 
 	"github.com/gdamore/tcell/v2"
+	"github.com/shen-kit/finance-tracker/backend"
 )
 
-func createInvSummaryTable() *updatableTable {
-	table := newUpdatableTable(strings.Split("Code:Qty:Avg Buy Price:Current Price:Total In:Current Value:P/L:%P/L", ":"), nil)
+func createInvSummaryTable(store *backend.Store) *updatableTable {
+	table := newUpdatableTable(store, strings.Split("Code:Qty:Avg Buy Price:Current Price:Total In:Current Value:Unrealised P/L:%P/L:Realised P/L:Dividends:Yield:Total Return:Fees", ":"), nil)
 	table.title = "Investment Summary"
-	table.fGetMaxPage = func() int { return 0 }
+	table.fGetMaxPage = func() (int, error) { return 0, nil }
+	table.sortable = true
 	return &table
 }
 
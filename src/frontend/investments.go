@@ -2,6 +2,8 @@ package frontend
 
 import (
 	"errors"
+	"math"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -12,35 +14,50 @@ import (
 )
 
 type investmentForm struct {
+	store      *backend.Store
 	form       *tview.Form
 	iDate      *tview.InputField
+	iType      *tview.DropDown
 	iCode      *tview.InputField
 	iQty       *tview.InputField
 	iUnitprice *tview.InputField
+	iFee       *tview.InputField // brokerage, optional
+	iMethod    *tview.DropDown   // how a sell is matched to lots
+	iLot       *tview.InputField
 	tvMsg      *tview.TextView
 }
 
-func createInvestmentsTable() *updatableTable {
-	table := newUpdatableTable(strings.Split("ID:Date:Code:Unitprice:Qty:Total", ":"), nil)
+// options of the type dropdown
+const (
+	invBuy  = "Buy"
+	invSell = "Sell"
+)
+
+func createInvestmentsTable(store *backend.Store) *updatableTable {
+	table := newUpdatableTable(store, strings.Split("ID:Date:Type:Code:Unitprice:Qty:Total:Fee", ":"), nil)
 	table.title = "Investments"
-	table.fGetMaxPage = backend.GetInvestmentsMaxPage
+	table.fGetMaxPage = func() (int, error) { return store.GetInvestmentsFilterMaxPage(table.getFilter()) }
+	table.sortable = true
 	return &table
 }
 
-func setInvTableKeybinds(t *updatableTable, inf investmentForm) {
+func setInvTableKeybinds(t *updatableTable, inf investmentForm, ef exportForm, ff filterForm) {
 	t.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		if res := t.defaultInputCapture(event); res == nil {
 			return nil
 		}
 
 		if event.Rune() == 'a' {
-			showInvestmentForm(t, inf, -1, "", "", "", "")
+			showInvestmentForm(t, inf, -1, backend.Investment{})
 		} else if event.Rune() == 'd' { // delete investment
 			row, _ := t.GetSelection()
 			id := t.getCellInt(row, 0)
 			showModal("Delete this investment record? (y/n)", func() {
-				backend.DeleteInvestment(id)
-				t.update(t.fGetData(t.curPage))
+				if err := t.store.DeleteInvestment(id); err != nil {
+					showError(err)
+					return
+				}
+				refresh(t)
 				// set focus if deleted last row
 				if row > t.GetRowCount()-1 {
 					t.Select(max(0, row-1), 0)
@@ -48,12 +65,18 @@ func setInvTableKeybinds(t *updatableTable, inf investmentForm) {
 			}, t)
 		} else if event.Rune() == 'e' { // edit investment
 			row, _ := t.GetSelection()
-			id := t.getCellInt(row, 0)
-			date := t.getCellString(row, 1)
-			code := t.getCellString(row, 2)
-			unitprice := t.getCellString(row, 3)
-			qty := t.getCellString(row, 4)
-			showInvestmentForm(t, inf, id, date, code, unitprice, qty)
+			inv, err := t.store.GetInvestment(t.getCellInt(row, 0))
+			if err != nil {
+				showError(err)
+				return nil
+			}
+			showInvestmentForm(t, inf, inv.Id, inv)
+		} else if event.Rune() == 'X' { // export
+			showExportForm(t, ef, "Investments", "", "")
+		} else if event.Rune() == 'f' { // filter
+			showFilterForm(t, ff)
+		} else if event.Rune() == 'F' { // clear the filter
+			clearFilter(t, ff)
 		} else {
 			return event
 		}
@@ -61,10 +84,11 @@ func setInvTableKeybinds(t *updatableTable, inf investmentForm) {
 	})
 }
 
-func createInvestmentForm() investmentForm {
+func createInvestmentForm(store *backend.Store) investmentForm {
 
 	var form *tview.Form
-	var inDate, inCode, inUnitprice, inQty *tview.InputField
+	var inDate, inCode, inUnitprice, inQty, inFee, inLot *tview.InputField
+	var inType, inMethod *tview.DropDown
 	var formMsg *tview.TextView
 
 	inDate = tview.NewInputField().
@@ -73,6 +97,10 @@ func createInvestmentForm() investmentForm {
 		SetPlaceholder("YYYY-MM-DD").
 		SetAcceptanceFunc(isPartialDate)
 
+	inType = tview.NewDropDown().
+		SetLabel("Type").
+		SetOptions([]string{invBuy, invSell}, nil)
+
 	inCode = tview.NewInputField().
 		SetLabel("Stock Code").
 		SetFieldWidth(10)
@@ -87,17 +115,30 @@ func createInvestmentForm() investmentForm {
 		SetFieldWidth(7).
 		SetAcceptanceFunc(tview.InputFieldFloat)
 
+	inFee = tview.NewInputField().
+		SetLabel("Brokerage Fee").
+		SetFieldWidth(7).
+		SetAcceptanceFunc(tview.InputFieldFloat)
+
+	methods := make([]string, len(backend.LotMethods))
+	for i, m := range backend.LotMethods {
+		methods[i] = m.String()
+	}
+	inMethod = tview.NewDropDown().
+		SetLabel("Sell From").
+		SetOptions(methods, nil)
+
+	inLot = tview.NewInputField().
+		SetLabel("Lot (buy ID)").
+		SetFieldWidth(7).
+		SetAcceptanceFunc(tview.InputFieldInteger)
+
 	formMsg = tview.NewTextView().
-		SetSize(1, 35).
+		SetSize(3, 45).
 		SetDynamicColors(true).
 		SetScrollable(false)
 
 	form = tview.NewForm().
-		AddFormItem(inDate).
-		AddFormItem(inCode).
-		AddFormItem(inUnitprice).
-		AddFormItem(inQty).
-		AddFormItem(formMsg).
 		AddButton("Save", nil).
 		AddButton("Cancel", nil).
 		SetFieldBackgroundColor(tview.Styles.MoreContrastBackgroundColor).
@@ -107,23 +148,72 @@ func createInvestmentForm() investmentForm {
 		SetBorderColor(tview.Styles.TertiaryTextColor)
 
 	return investmentForm{
-		form: form, iDate: inDate, iCode: inCode, iUnitprice: inUnitprice, iQty: inQty, tvMsg: formMsg,
+		store: store, form: form, iDate: inDate, iType: inType, iCode: inCode, iUnitprice: inUnitprice, iQty: inQty,
+		iFee: inFee, iMethod: inMethod, iLot: inLot, tvMsg: formMsg,
+	}
+}
+
+/* Adds the form's items, with how a sell is matched to lots only shown for sells, keeping the focused item */
+func layoutInvForm(inf investmentForm) {
+	focused := inf.form.GetFormItemCount()
+	for i := range inf.form.GetFormItemCount() {
+		if inf.form.GetFormItem(i).HasFocus() {
+			focused = i
+		}
+	}
+
+	inf.form.Clear(false).
+		AddFormItem(inf.iDate).
+		AddFormItem(inf.iType).
+		AddFormItem(inf.iCode).
+		AddFormItem(inf.iUnitprice).
+		AddFormItem(inf.iQty).
+		AddFormItem(inf.iFee)
+	if _, typ := inf.iType.GetCurrentOption(); typ == invSell {
+		inf.form.AddFormItem(inf.iMethod)
+		if i, _ := inf.iMethod.GetCurrentOption(); i >= 0 && backend.LotMethods[i] == backend.SpecificLot {
+			inf.form.AddFormItem(inf.iLot)
+		}
+	}
+	inf.form.AddFormItem(inf.tvMsg)
+
+	if focused < inf.form.GetFormItemCount() {
+		inf.form.SetFocus(focused)
 	}
 }
 
-func showInvestmentForm(t *updatableTable, inf investmentForm, id int, date, code, unitprice, qty string) {
+/* Shows the form to add (id -1) or edit an investment */
+func showInvestmentForm(t *updatableTable, inf investmentForm, id int, inv backend.Investment) {
 
 	/* ===== Helper Functions ===== */
 
 	setInputFieldValues := func() {
-		if date == "" {
-			date = time.Now().Format("2006-01-02")
+		date, qty, unitprice, fee, lot := time.Now().Format("2006-01-02"), "", "", "", ""
+		if id != -1 {
+			date = inv.Date.Format("2006-01-02")
+			qty = strconv.FormatFloat(math.Abs(float64(inv.Qty)), 'f', -1, 32)
+			unitprice = strconv.FormatFloat(float64(inv.Unitprice)/100, 'f', 2, 64)
+		}
+		if inv.Fee != 0 {
+			fee = strconv.FormatFloat(float64(inv.Fee)/100, 'f', 2, 64)
+		}
+		if inv.LotId > 0 {
+			lot = strconv.Itoa(inv.LotId)
 		}
 		inf.iDate.SetText(date)
-		inf.iCode.SetText(code)
+		inf.iCode.SetText(inv.Code)
 		inf.iQty.SetText(qty)
 		inf.iUnitprice.SetText(unitprice)
+		inf.iFee.SetText(fee)
+		inf.iLot.SetText(lot)
 		inf.tvMsg.SetText("")
+
+		typ, method := 0, max(0, slices.Index(backend.LotMethods, inv.Method))
+		if inv.IsSell() {
+			typ = 1
+		}
+		inf.iType.SetCurrentOption(typ)
+		inf.iMethod.SetCurrentOption(method)
 	}
 
 	closeForm := func() {
@@ -139,12 +229,16 @@ func showInvestmentForm(t *updatableTable, inf investmentForm, id int, date, cod
 		}
 
 		if id == -1 {
-			backend.InsertInvestment(inv)
+			err = inf.store.InsertInvestment(inv)
 		} else {
-			backend.UpdateInvestment(id, inv)
+			err = inf.store.UpdateInvestment(id, inv)
+		}
+		if err != nil {
+			inf.tvMsg.SetText("[red]" + err.Error())
+			return
 		}
 
-		t.update(t.fGetData(t.curPage))
+		refresh(t)
 		closeForm()
 	}
 
@@ -156,7 +250,13 @@ func showInvestmentForm(t *updatableTable, inf investmentForm, id int, date, cod
 		inf.form.SetTitle("Edit Investment Details")
 	}
 
+	inf.iType.SetSelectedFunc(nil)
+	inf.iMethod.SetSelectedFunc(nil)
 	setInputFieldValues()
+	layoutInvForm(inf)
+	relayout := func(string, int) { layoutInvForm(inf) }
+	inf.iType.SetSelectedFunc(relayout)
+	inf.iMethod.SetSelectedFunc(relayout)
 
 	inf.form.SetInputCapture(formInputCapture(closeForm, onSubmit))
 	inf.form.GetButton(inf.form.GetButtonIndex("Cancel")).SetSelectedFunc(closeForm)
@@ -167,7 +267,7 @@ func showInvestmentForm(t *updatableTable, inf investmentForm, id int, date, cod
 	app.SetFocus(inf.form)
 }
 
-/* Returns (Investment, success?) */
+/* Returns the investment in the form, sells having a negative quantity and no fee being 0 */
 func parseInvForm(inf investmentForm) (backend.Investment, error) {
 
 	fail := func(msg string) (backend.Investment, error) {
@@ -183,8 +283,8 @@ func parseInvForm(inf investmentForm) (backend.Investment, error) {
 	code := inf.iCode.GetText()
 
 	qty, err := strconv.ParseFloat(inf.iQty.GetText(), 32)
-	if err != nil || qty == 0 {
-		return fail("Quantity is invalid")
+	if err != nil || qty <= 0 {
+		return fail("Quantity is invalid, it must be positive")
 	}
 
 	unitprice, err := strconv.ParseFloat(inf.iUnitprice.GetText(), 32)
@@ -192,12 +292,27 @@ func parseInvForm(inf investmentForm) (backend.Investment, error) {
 		return fail("Unitprice is invalid")
 	}
 
+	fee := 0.0
+	if inf.iFee.GetText() != "" {
+		if fee, err = strconv.ParseFloat(inf.iFee.GetText(), 32); err != nil || fee < 0 {
+			return fail("Brokerage fee is invalid, it can't be negative")
+		}
+	}
+
 	date, err := time.Parse("2006-01-02", inf.iDate.GetText())
 	if err != nil {
 		return fail("Date must be in YYYY-MM-DD format")
 	}
 
-	return backend.Investment{
-			Date: date, Code: code, Qty: float32(qty), Unitprice: int(unitprice * 100)},
-		nil
+	inv := backend.Investment{Date: date, Code: code, Qty: float32(qty), Unitprice: int(math.Round(unitprice * 100)), Fee: int(math.Round(fee * 100))}
+	if _, typ := inf.iType.GetCurrentOption(); typ == invSell {
+		i, _ := inf.iMethod.GetCurrentOption()
+		inv.Qty, inv.Method = -inv.Qty, backend.LotMethods[max(i, 0)]
+		if inv.Method == backend.SpecificLot {
+			if inv.LotId, err = strconv.Atoi(inf.iLot.GetText()); err != nil {
+				return fail("Choose the lot to sell from by its buy's ID")
+			}
+		}
+	}
+	return inv, nil
 }
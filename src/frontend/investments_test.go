@@ -0,0 +1,142 @@
+package frontend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+func getInvestments(t *testing.T, s *backend.Store) []backend.Investment {
+	t.Helper()
+	rows, err := s.GetInvestmentsRecent(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	invs := make([]backend.Investment, len(rows))
+	for i, r := range rows {
+		invs[i] = r.(backend.Investment)
+	}
+	return invs
+}
+
+func seedInvestment(t *testing.T) func(*backend.Store) {
+	return func(s *backend.Store) {
+		err := s.InsertInvestment(backend.Investment{Date: time.Now(), Code: "IVV", Unitprice: 55000, Qty: 3})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAddInvestment(t *testing.T) {
+	h := startTUI(t, nil)
+
+	h.typeText("ia")
+	h.waitFor("Add Investment")
+	h.press(tcell.KeyTab, tcell.KeyTab) // keep the date, buy
+	h.typeText("VGS.AX")
+	h.press(tcell.KeyTab)
+	h.typeText("120.5")
+	h.press(tcell.KeyTab)
+	h.typeText("10")
+	h.submit()
+
+	h.waitForGone("Add Investment")
+	h.waitFor("VGS.AX")
+	h.waitFor("$1205.00")
+
+	invs := getInvestments(t, h.store)
+	if len(invs) != 1 || invs[0].Code != "VGS.AX" || invs[0].Unitprice != 12050 || invs[0].Qty != 10 {
+		t.Errorf("unexpected investments %+v", invs)
+	}
+}
+
+func TestEditInvestment(t *testing.T) {
+	h := startTUI(t, seedInvestment(t))
+
+	h.typeText("i")
+	h.waitFor("IVV")
+	h.typeText("e")
+	h.waitFor("Edit Investment Details")
+	h.press(tcell.KeyTab, tcell.KeyTab, tcell.KeyTab, tcell.KeyTab)
+	h.replaceText("5")
+	h.submit()
+
+	h.waitForGone("Edit Investment Details")
+	h.waitFor("$2750.00")
+
+	if invs := getInvestments(t, h.store); invs[0].Qty != 5 || invs[0].Unitprice != 55000 {
+		t.Errorf("unexpected investments %+v", invs)
+	}
+}
+
+func TestDeleteInvestment(t *testing.T) {
+	h := startTUI(t, seedInvestment(t))
+
+	h.typeText("i")
+	h.waitFor("IVV")
+	h.typeText("dy")
+	h.waitForGone("IVV")
+
+	if invs := getInvestments(t, h.store); len(invs) != 0 {
+		t.Errorf("investment not deleted: %+v", invs)
+	}
+}
+
+func TestInvestmentSummary(t *testing.T) {
+	h := startTUI(t, seedInvestment(t))
+
+	h.typeText("jjjj") // from month summary down to investment summary
+	h.press(tcell.KeyEnter)
+	h.waitFor("Avg Buy Price")
+	h.waitFor("IVV")
+	h.waitFor("$300.00") // current value: 3 at the stubbed price of $100
+}
+
+func TestSellInvestment(t *testing.T) {
+	h := startTUI(t, func(s *backend.Store) {
+		seedInvestment(t)(s) // 3 IVV at $550, id 1
+		if err := s.InsertInvestment(backend.Investment{Date: time.Now(), Code: "IVV", Unitprice: 60000, Qty: 2}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	h.typeText("ia")
+	h.waitFor("Add Investment")
+	h.press(tcell.KeyTab, tcell.KeyEnter, tcell.KeyDown, tcell.KeyEnter) // sell
+	h.waitFor("Sell From")
+	h.press(tcell.KeyTab)
+	h.typeText("IVV")
+	h.press(tcell.KeyTab)
+	h.typeText("700")
+	h.press(tcell.KeyTab)
+	h.typeText("4")
+	h.press(tcell.KeyTab)
+	h.typeText("10")                                                                    // brokerage
+	h.press(tcell.KeyTab, tcell.KeyEnter, tcell.KeyDown, tcell.KeyDown, tcell.KeyEnter) // from a specific lot
+	h.waitFor("Lot (buy ID)")
+	h.press(tcell.KeyTab)
+	h.typeText("1")
+	h.submit()
+	h.waitFor("of it are held") // only 3 are left in lot 1
+
+	h.press(tcell.KeyBacktab, tcell.KeyBacktab, tcell.KeyBacktab) // qty
+	h.replaceText("2")
+	h.submit()
+	h.waitForGone("Add Investment")
+	h.waitFor("Sell lot 1")
+
+	invs := getInvestments(t, h.store)
+	if sell := invs[len(invs)-1]; len(invs) != 3 || sell.Qty != -2 || sell.Fee != 1000 || sell.Method != backend.SpecificLot || sell.LotId != 1 {
+		t.Errorf("unexpected investments %+v", invs)
+	}
+	_, disposals, err := h.store.GetLots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(disposals) != 1 || disposals[0].Gain() != 29000 {
+		t.Errorf("got disposals %+v, want $290 gained on lot 1 after the brokerage", disposals)
+	}
+}
@@ -2,6 +2,7 @@ package frontend
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/gdamore/tcell/v2"
@@ -9,42 +10,81 @@ import (
 	"github.com/shen-kit/finance-tracker/backend"
 )
 
-func createMonthSummary() *monthGridView {
+func createMonthSummary(store *backend.Store) *monthGridView {
 	tvTitle := tview.NewTextView().
-		SetTextAlign(tview.AlignCenter)
+		SetTextAlign(tview.AlignCenter).
+		SetDynamicColors(true)
 	tvTitle.SetBorderPadding(1, 1, 3, 3)
 
 	tvSummary := tview.NewTextView()
 	tvSummary.SetBorderPadding(0, 0, 3, 3)
 
+	// balance of each account at the end of the month, and the change during it
+	tvAccounts := tview.NewTextView()
+	tvAccounts.SetBorderPadding(0, 0, 3, 3)
+
 	msGrid := tview.NewGrid().
 		SetRows(3, 3, 0).
-		SetBorders(true).
-		AddItem(tvTitle, 0, 0, 1, 1, 0, 0, false).
-		AddItem(tvSummary, 1, 0, 1, 1, 0, 0, false)
+		SetColumns(0, 0, 40).
+		SetBorders(true)
+
+	// totals for each category, beside the records
+	catTable := newUpdatableTable(store, []string{"Category", "Total"}, msGrid)
+	catTable.SetBorder(false)
+	catTable.fGetMaxPage = func() (int, error) { return 0, nil }
+
+	msGrid.AddItem(tvTitle, 0, 0, 1, 3, 0, 0, false).
+		AddItem(tvSummary, 1, 0, 1, 1, 0, 0, false).
+		AddItem(tvAccounts, 1, 1, 1, 1, 0, 0, false).
+		AddItem(catTable, 1, 2, 2, 1, 0, 0, false)
 
 	msGrid.SetBorder(true).
 		SetTitle("Month Summary")
 
 	return &monthGridView{
-		Grid:      msGrid,
-		tvTitle:   tvTitle,
-		tvSummary: tvSummary,
+		store:      store,
+		Grid:       msGrid,
+		tvTitle:    tvTitle,
+		tvSummary:  tvSummary,
+		tvAccounts: tvAccounts,
+		catTable:   &catTable,
+		collapsed:  map[int]bool{},
 	}
 }
 
-func setMonthGridKeybinds(mv *monthGridView, rf recordForm) {
+func setMonthGridKeybinds(mv *monthGridView, rf recordForm, tf transferForm, imf importForm, ef exportForm, sf searchForm) {
 	mv.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyTab { // switch between the records and category totals
+			if mv.catTable.HasFocus() {
+				app.SetFocus(mv.table)
+			} else {
+				app.SetFocus(mv.catTable)
+			}
+			return nil
+		} else if mv.catTable.HasFocus() {
+			if toggleCategoryTree(mv.catTable, mv.collapsed, event) {
+				refresh(mv)
+				return nil
+			} else if event.Rune() == 'e' || event.Rune() == 'd' { // no record is selected
+				return event
+			}
+		}
+
 		if isBackKey(event) {
 			app.SetFocus(flex)
 		} else if event.Rune() == 'a' { // add record
-			showRecordForm(mv, rf, -1, "", "", "", "")
+			showRecordForm(mv, rf, -1, "", "", "", "", "", "")
+		} else if event.Rune() == 't' { // add transfer
+			showTransferForm(mv, tf, -1, backend.Transfer{})
 		} else if event.Rune() == 'd' { // delete record
 			row, _ := mv.table.GetSelection()
 			id := mv.table.getCellInt(row, 0)
-			showModal("Delete this record? (y/n)", func() {
-				backend.DeleteRecord(id)
-				mv.update(mv.fGetData(mv.getCurPage()))
+			showModal(deleteRecordPrompt(mv.store, id), func() {
+				if err := mv.store.DeleteRecord(id); err != nil {
+					showError(err)
+					return
+				}
+				refresh(mv)
 				// set focus if deleted last row
 				if row > mv.table.GetRowCount()-1 {
 					mv.table.Select(max(0, row-1), 0)
@@ -53,11 +93,50 @@ func setMonthGridKeybinds(mv *monthGridView, rf recordForm) {
 		} else if event.Rune() == 'e' { // edit record
 			row, _ := mv.table.GetSelection()
 			id := mv.table.getCellInt(row, 0)
+			if tr, err := mv.store.GetTransfer(id); err == nil {
+				showTransferForm(mv, tf, id, tr)
+				return nil
+			}
 			date := mv.table.getCellString(row, 1)
 			catName := mv.table.getCellString(row, 2)
-			desc := mv.table.getCellString(row, 3)
-			amt := mv.table.getCellString(row, 4)
-			showRecordForm(mv, rf, id, date, desc, amt, catName)
+			accName := mv.table.getCellString(row, 3)
+			desc := mv.table.getCellString(row, 4)
+			amt := mv.table.getCellString(row, 5)
+			tags := mv.table.getCellString(row, 6)
+			showRecordForm(mv, rf, id, date, desc, amt, catName, accName, tags)
+		} else if event.Rune() == 'I' { // import statement
+			showImportForm(mv, imf)
+		} else if event.Rune() == 'X' { // export this month's records
+			month := time.Now().AddDate(0, mv.monthOffset, 0)
+			start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+			end := start.AddDate(0, 1, -1)
+			showExportForm(mv, ef, "Records", start.Format("2006-01-02"), end.Format("2006-01-02"))
+		} else if event.Rune() == '/' { // search this month's descriptions
+			showSearchForm(mv, sf, mv.table.search, func(query string) error {
+				prev := mv.table.search
+				mv.table.search = query
+				refresh(mv)
+				if query == "" {
+					return nil
+				}
+				err := jumpToMonthSearchMatch(mv, 0)
+				if err != nil {
+					mv.table.search = prev
+					refresh(mv)
+				}
+				return err
+			})
+		} else if (event.Rune() == 'n' || event.Rune() == 'N') && mv.table.search != "" { // next/previous match
+			by := 1
+			if event.Rune() == 'N' {
+				by = -1
+			}
+			app.SetFocus(mv.table)
+			if err := jumpToMonthSearchMatch(mv, by); err != nil {
+				showError(err)
+			}
+		} else if strings.ContainsRune("fFsS", event.Rune()) { // the records table's filter and sort don't apply to a month
+			return nil
 		} else if event.Rune() == 'H' {
 			mv.changeMonth(-1)
 		} else if event.Rune() == 'L' {
@@ -71,17 +150,37 @@ func setMonthGridKeybinds(mv *monthGridView, rf recordForm) {
 
 func showMonthSummary(monthView *monthGridView) {
 	monthView.SetBorder(false)
-	monthView.update(monthView.fGetData(monthView.getCurPage()))
+	refresh(monthView)
 	flex.AddItem(monthView, 0, 1, true)
 	app.SetFocus(monthView)
 }
 
 func (mv monthGridView) update(recs []backend.DataRow) {
 	t := time.Now().AddDate(0, mv.monthOffset, 0)
-	_, income, expenditure := backend.GetMonthInfo(t)
+	_, income, expenditure, err := mv.store.GetMonthInfo(t)
+	if err != nil {
+		showError(err)
+	}
 
-	// set title text
-	mv.tvTitle.SetText(fmt.Sprintf("%s %d", t.Month().String(), t.Year()))
+	// set title text, with any categories over budget in red
+	title := fmt.Sprintf("%s %d", t.Month().String(), t.Year())
+	budgets, err := mv.store.GetBudgets(t)
+	if err != nil {
+		showError(err)
+	}
+	var over []string
+	for _, row := range budgets {
+		if br := row.(backend.BudgetRow); br.Remaining() < 0 {
+			over = append(over, fmt.Sprintf("%s -$%.0f", br.CatName, float32(-br.Remaining())/100))
+		}
+	}
+	if len(over) > 0 {
+		title += "   [red]Over budget: " + strings.Join(over, ", ")
+	}
+	if mv.table.search != "" {
+		title += "   [-]/" + mv.table.search
+	}
+	mv.tvTitle.SetText(title)
 
 	// set summary text
 	incomeStr := fmt.Sprintf("$%.0f", income/100)
@@ -89,11 +188,45 @@ func (mv monthGridView) update(recs []backend.DataRow) {
 	netStr := fmt.Sprintf("$%.0f", (income-expenditure)/100)
 	mv.tvSummary.SetText(fmt.Sprintf("Income:      %8s\nExpenditure: %8s\nNet Change:  %8s", incomeStr, expenditureStr, netStr))
 
-	// update table data
+	// set account balances text
+	mStart := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	before, err := mv.store.GetAccountBalances(mStart)
+	if err != nil {
+		showError(err)
+	}
+	after, err := mv.store.GetAccountBalances(mStart.AddDate(0, 1, 0))
+	if err != nil {
+		showError(err)
+	}
+	var lines []string
+	for i, row := range after {
+		acc := row.(backend.Account)
+		change := acc.Balance
+		if i < len(before) {
+			change -= before[i].(backend.Account).Balance
+		}
+		lines = append(lines, fmt.Sprintf("%-15s %9s (%s)", acc.Name+":",
+			fmt.Sprintf("$%.0f", float32(acc.Balance)/100), fmt.Sprintf("%+.0f", float32(change)/100)))
+	}
+	mv.tvAccounts.SetText(strings.Join(lines, "\n"))
+	mv.SetRows(3, max(3, len(lines)), 0)
+
+	// set category totals
+	totals, err := mv.store.GetMonthCategoryTotals(t)
+	if err != nil {
+		showError(err)
+	}
+	updateCategoryTree(mv.catTable, totals, mv.collapsed)
+
+	// update table data, the month's records are in date order however the records view is sorted
+	sort := mv.table.sort
+	mv.table.sort = backend.SortOpts{}
 	mv.table.update(recs)
+	mv.table.sort = sort
 }
 
 func (mv *monthGridView) reset() {
+	mv.table.search = ""
 	mv.changeMonth(-mv.monthOffset)
 	mv.table.SetBorder(false)
 }
@@ -2,6 +2,9 @@ package frontend
 
 import (
 	"errors"
+	"fmt"
+	"math"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -12,35 +15,78 @@ import (
 )
 
 type recordForm struct {
+	store *backend.Store
 	form  *tview.Form
 	iDate *tview.InputField
 	iCat  *tview.DropDown
+	iAcc  *tview.DropDown
 	iAmt  *tview.InputField
 	iDesc *tview.TextArea
+	iTags *tview.InputField
 	tvMsg *tview.TextView
+
+	splits *[]splitLine // replace iCat when the record is split between categories
+}
+
+/* The inputs for one line of a split record */
+type splitLine struct {
+	iCat  *tview.DropDown
+	iAmt  *tview.InputField
+	iNote *tview.InputField
 }
 
-func createRecordsTable(monthGrid borderColorChanger) *updatableTable {
-	table := newUpdatableTable(strings.Split("ID:Date:Category:Description:Amount", ":"), monthGrid)
+func newSplitLine(n int, catNames []string) splitLine {
+	sl := splitLine{
+		iCat: tview.NewDropDown().
+			SetLabel(fmt.Sprintf("Split %d", n)).
+			SetOptions(catNames, nil),
+		iAmt: tview.NewInputField().
+			SetLabel("  Amount").
+			SetFieldWidth(7).
+			SetAcceptanceFunc(tview.InputFieldFloat),
+		iNote: tview.NewInputField().
+			SetLabel("  Note").
+			SetFieldWidth(35).
+			SetAcceptanceFunc(tview.InputFieldMaxLength(50)),
+	}
+	sl.iCat.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Rune() == 'j' || event.Key() == tcell.KeyCtrlN {
+			return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+		} else if event.Rune() == 'k' || event.Key() == tcell.KeyCtrlP {
+			return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+		}
+		return event
+	})
+	return sl
+}
+
+func createRecordsTable(store *backend.Store, monthGrid borderColorChanger) *updatableTable {
+	table := newUpdatableTable(store, strings.Split("ID:Date:Category:Account:Description:Amount:Tags", ":"), monthGrid)
 	table.title = "Records"
-	table.fGetMaxPage = backend.GetRecordsMaxPage
+	table.fGetMaxPage = func() (int, error) { return store.GetRecordsFilterMaxPage(table.getFilter()) }
+	table.sortable = true
 	return &table
 }
 
-func setRecTableKeybinds(t *updatableTable, rf recordForm) {
+func setRecTableKeybinds(t *updatableTable, rf recordForm, tf transferForm, imf importForm, ef exportForm, sf searchForm, ff filterForm) {
 	t.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		if res := t.defaultInputCapture(event); res == nil {
 			return nil
 		}
 
 		if event.Rune() == 'a' {
-			showRecordForm(t, rf, -1, "", "", "", "")
+			showRecordForm(t, rf, -1, "", "", "", "", "", "")
+		} else if event.Rune() == 't' { // add transfer
+			showTransferForm(t, tf, -1, backend.Transfer{})
 		} else if event.Rune() == 'd' { // delete record
 			row, _ := t.GetSelection()
 			id := t.getCellInt(row, 0)
-			showModal("Delete this record? (y/n)", func() {
-				backend.DeleteRecord(id)
-				t.update(t.fGetData(t.curPage))
+			showModal(deleteRecordPrompt(t.store, id), func() {
+				if err := t.store.DeleteRecord(id); err != nil {
+					showError(err)
+					return
+				}
+				refresh(t)
 				// set focus if deleted last row
 				if row > t.GetRowCount()-1 {
 					t.Select(max(0, row-1), 0)
@@ -49,11 +95,47 @@ func setRecTableKeybinds(t *updatableTable, rf recordForm) {
 		} else if event.Rune() == 'e' { // edit record
 			row, _ := t.GetSelection()
 			id := t.getCellInt(row, 0)
+			if tr, err := t.store.GetTransfer(id); err == nil {
+				showTransferForm(t, tf, id, tr)
+				return nil
+			}
 			date := t.getCellString(row, 1)
 			catName := t.getCellString(row, 2)
-			desc := t.getCellString(row, 3)
-			amt := t.getCellString(row, 4)
-			showRecordForm(t, rf, id, date, desc, amt, catName)
+			accName := t.getCellString(row, 3)
+			desc := t.getCellString(row, 4)
+			amt := t.getCellString(row, 5)
+			tags := t.getCellString(row, 6)
+			showRecordForm(t, rf, id, date, desc, amt, catName, accName, tags)
+		} else if event.Rune() == 'I' { // import statement
+			showImportForm(t, imf)
+		} else if event.Rune() == 'X' { // export
+			showExportForm(t, ef, "Records", "", "")
+		} else if event.Rune() == 'f' { // filter
+			showFilterForm(t, ff)
+		} else if event.Rune() == 'F' { // clear the filter
+			clearFilter(t, ff)
+		} else if event.Rune() == '/' { // search descriptions
+			showSearchForm(t, sf, t.search, func(query string) error {
+				prev := t.search
+				t.search = query
+				if query == "" {
+					t.changePage(0)
+					return nil
+				}
+				err := jumpToSearchMatch(t, 0)
+				if err != nil {
+					t.search = prev
+				}
+				return err
+			})
+		} else if (event.Rune() == 'n' || event.Rune() == 'N') && t.search != "" { // next/previous match
+			by := 1
+			if event.Rune() == 'N' {
+				by = -1
+			}
+			if err := jumpToSearchMatch(t, by); err != nil {
+				showError(err)
+			}
 		} else {
 			return event
 		}
@@ -61,11 +143,11 @@ func setRecTableKeybinds(t *updatableTable, rf recordForm) {
 	})
 }
 
-func createRecordForm() recordForm {
+func createRecordForm(store *backend.Store) recordForm {
 	var form *tview.Form
-	var inDate, inAmt *tview.InputField
+	var inDate, inAmt, inTags *tview.InputField
 	var inDesc *tview.TextArea
-	var inCat *tview.DropDown
+	var inCat, inAcc *tview.DropDown
 	var formMsg *tview.TextView
 
 	inDate = tview.NewInputField().
@@ -77,14 +159,19 @@ func createRecordForm() recordForm {
 	inCat = tview.NewDropDown().
 		SetLabel("Category")
 
-	inCat.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+	inAcc = tview.NewDropDown().
+		SetLabel("Account")
+
+	dropDownCapture := func(event *tcell.EventKey) *tcell.EventKey {
 		if event.Rune() == 'j' || event.Key() == tcell.KeyCtrlN {
 			return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
 		} else if event.Rune() == 'k' || event.Key() == tcell.KeyCtrlP {
 			return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
 		}
 		return event
-	})
+	}
+	inCat.SetInputCapture(dropDownCapture)
+	inAcc.SetInputCapture(dropDownCapture)
 
 	inAmt = tview.NewInputField().
 		SetLabel("Amount").
@@ -95,6 +182,31 @@ func createRecordForm() recordForm {
 		SetLabel("Description").
 		SetSize(4, 35)
 
+	inTags = tview.NewInputField().
+		SetLabel("Tags").
+		SetFieldWidth(35).
+		SetPlaceholder("e.g. #tax-deductible #holiday")
+
+	// complete the last tag being typed from the existing tags
+	inTags.SetAutocompleteFunc(func(text string) []string {
+		i := strings.LastIndexAny(text, " ,") + 1
+		prefix := strings.TrimPrefix(text[i:], "#")
+		if prefix == "" {
+			return nil
+		}
+		names, err := store.GetTagNames()
+		if err != nil {
+			return nil
+		}
+		var entries []string
+		for _, name := range names {
+			if strings.HasPrefix(name, strings.ToLower(prefix)) {
+				entries = append(entries, text[:i]+"#"+name)
+			}
+		}
+		return entries
+	})
+
 	formMsg = tview.NewTextView().
 		SetSize(1, 35).
 		SetDynamicColors(true).
@@ -103,11 +215,15 @@ func createRecordForm() recordForm {
 	form = tview.NewForm().
 		AddFormItem(inDate).
 		AddFormItem(inCat).
+		AddFormItem(inAcc).
 		AddFormItem(inAmt).
 		AddFormItem(inDesc).
+		AddFormItem(inTags).
 		AddFormItem(formMsg).
 		AddButton("Save", nil).
 		AddButton("Cancel", nil).
+		AddButton("Add Split", nil).
+		AddButton("Remove Split", nil).
 		SetFieldBackgroundColor(tview.Styles.MoreContrastBackgroundColor).
 		SetButtonBackgroundColor(tview.Styles.MoreContrastBackgroundColor)
 
@@ -115,19 +231,46 @@ func createRecordForm() recordForm {
 		SetBorderColor(tview.Styles.TertiaryTextColor)
 
 	return recordForm{
-		form: form, iDate: inDate, iAmt: inAmt, iCat: inCat, iDesc: inDesc, tvMsg: formMsg,
+		store: store, form: form, iDate: inDate, iAmt: inAmt, iCat: inCat, iAcc: inAcc, iDesc: inDesc, iTags: inTags, tvMsg: formMsg,
+		splits: &[]splitLine{},
+	}
+}
+
+/* Rebuilds the form's items, showing the split lines in place of the category if there are any */
+func layoutRecForm(rf recordForm) {
+	rf.form.Clear(false).AddFormItem(rf.iDate)
+	if len(*rf.splits) == 0 {
+		rf.form.AddFormItem(rf.iCat)
 	}
+	rf.form.AddFormItem(rf.iAcc).
+		AddFormItem(rf.iAmt).
+		AddFormItem(rf.iDesc).
+		AddFormItem(rf.iTags)
+	for _, sl := range *rf.splits {
+		rf.form.AddFormItem(sl.iCat).
+			AddFormItem(sl.iAmt).
+			AddFormItem(sl.iNote)
+	}
+	rf.form.AddFormItem(rf.tvMsg)
 }
 
-func showRecordForm(t updatablePrim, rf recordForm, id int, date, desc, amt, catName string) {
+/*
+Shows the form to add (id -1) or edit a record, an empty accName selects the
+first account. tags are separated by spaces or commas.
+*/
+func showRecordForm(t updatablePrim, rf recordForm, id int, date, desc, amt, catName, accName, tags string) {
 
 	/* ===== Helper Functions ===== */
 	catOpt := 0
+	var catNames []string
 	setCategoryOptions := func() {
-		cats := backend.GetCategories(0)
-		catNames := make([]string, len(cats))
+		cats, err := rf.store.GetCategories(0)
+		if err != nil {
+			rf.tvMsg.SetText("[red]" + err.Error())
+		}
+		catNames = make([]string, len(cats))
 		for i, cat := range cats {
-			catNames[i] = cat.SpreadToStrings()[1]
+			catNames[i] = cat.(backend.Category).Name
 			if catNames[i] == catName {
 				catOpt = i
 			}
@@ -135,6 +278,22 @@ func showRecordForm(t updatablePrim, rf recordForm, id int, date, desc, amt, cat
 		rf.iCat.SetOptions(catNames, nil)
 	}
 
+	accOpt := 0
+	setAccountOptions := func() {
+		accs, err := rf.store.GetAccounts()
+		if err != nil {
+			rf.tvMsg.SetText("[red]" + err.Error())
+		}
+		accNames := make([]string, len(accs))
+		for i, acc := range accs {
+			accNames[i] = acc.(backend.Account).Name
+			if accNames[i] == accName {
+				accOpt = i
+			}
+		}
+		rf.iAcc.SetOptions(accNames, nil)
+	}
+
 	setInputFieldValues := func() {
 		if date == "" {
 			date = time.Now().Format("2006-01-02")
@@ -142,8 +301,62 @@ func showRecordForm(t updatablePrim, rf recordForm, id int, date, desc, amt, cat
 		rf.iDate.SetText(date)
 		rf.iDesc.SetText(desc, true)
 		rf.iAmt.SetText(amt)
+		rf.iTags.SetText(tags)
 		rf.iCat.SetCurrentOption(catOpt)
-		rf.tvMsg.SetText("")
+		rf.iAcc.SetCurrentOption(accOpt)
+	}
+
+	// a new split line, optionally with a category (-1 for none) and amount
+	addSplitLine := func(opt int, amt, note string) {
+		sl := newSplitLine(len(*rf.splits)+1, catNames)
+		sl.iCat.SetCurrentOption(opt)
+		sl.iAmt.SetText(amt)
+		sl.iNote.SetText(note)
+		*rf.splits = append(*rf.splits, sl)
+	}
+
+	setSplitLines := func() {
+		*rf.splits = nil
+		if id != -1 {
+			splits, err := rf.store.GetRecordSplits(id)
+			if err != nil {
+				rf.tvMsg.SetText("[red]" + err.Error())
+			}
+			for _, spl := range splits {
+				addSplitLine(slices.Index(catNames, spl.CatName), fmt.Sprintf("%.2f", float64(spl.Amt)/100), spl.Note)
+			}
+		}
+		layoutRecForm(rf)
+	}
+
+	// splitting a record starts with its category and amount on the first line
+	onAddSplit := func() {
+		if len(*rf.splits) == 0 {
+			opt, _ := rf.iCat.GetCurrentOption()
+			addSplitLine(opt, strings.TrimSpace(rf.iAmt.GetText()), "")
+		}
+		addSplitLine(-1, "", "")
+		layoutRecForm(rf)
+		rf.form.SetFocus(rf.form.GetFormItemCount() - 4)
+		app.SetFocus(rf.form)
+	}
+
+	// removing the second last line leaves the record in the remaining line's category
+	onRemoveSplit := func() {
+		lines := *rf.splits
+		if len(lines) == 0 {
+			return
+		} else if len(lines) == 2 {
+			opt, _ := lines[0].iCat.GetCurrentOption()
+			rf.iCat.SetCurrentOption(max(opt, 0))
+			lines = nil
+		} else {
+			lines = lines[:len(lines)-1]
+		}
+		*rf.splits = lines
+		layoutRecForm(rf)
+		rf.form.SetFocus(rf.form.GetFormItemCount() + rf.form.GetButtonIndex("Remove Split"))
+		app.SetFocus(rf.form)
 	}
 
 	closeForm := func() {
@@ -159,12 +372,16 @@ func showRecordForm(t updatablePrim, rf recordForm, id int, date, desc, amt, cat
 		}
 
 		if id == -1 {
-			backend.InsertRecord(rec)
+			err = rf.store.InsertRecord(rec)
 		} else {
-			backend.UpdateRecord(id, rec)
+			err = rf.store.UpdateRecord(id, rec)
+		}
+		if err != nil {
+			rf.tvMsg.SetText("[red]" + err.Error())
+			return
 		}
 
-		t.update(t.fGetData(t.getCurPage()))
+		refresh(t)
 		closeForm()
 	}
 
@@ -177,12 +394,17 @@ func showRecordForm(t updatablePrim, rf recordForm, id int, date, desc, amt, cat
 		rf.form.SetTitle("Edit Record Details")
 	}
 
+	rf.tvMsg.SetText("")
 	setCategoryOptions()
+	setAccountOptions()
 	setInputFieldValues()
+	setSplitLines()
 
 	rf.form.SetInputCapture(formInputCapture(closeForm, onSubmit))
 	rf.form.GetButton(rf.form.GetButtonIndex("Cancel")).SetSelectedFunc(closeForm)
 	rf.form.GetButton(rf.form.GetButtonIndex("Save")).SetSelectedFunc(onSubmit)
+	rf.form.GetButton(rf.form.GetButtonIndex("Add Split")).SetSelectedFunc(onAddSplit)
+	rf.form.GetButton(rf.form.GetButtonIndex("Remove Split")).SetSelectedFunc(onRemoveSplit)
 
 	// display + focus form
 	flex.AddItem(rf.form, 55, 0, true)
@@ -206,18 +428,52 @@ func parseRecForm(rf recordForm) (backend.Record, error) {
 		return fail("Date musy be in YYYY-MM-DD format")
 	}
 
-	_, cname := rf.iCat.GetCurrentOption()
-	if cname == "" {
-		return fail("Please choose a category")
-	}
-	catId := backend.GetCategoryIdFromName(cname)
-
-	desc := rf.iDesc.GetText()
-
 	amt, err := strconv.ParseFloat(rf.iAmt.GetText(), 32)
 	if err != nil || amt == 0 {
 		return fail("Invalid amount entered")
 	}
+	rec := backend.Record{Date: date, Amt: int(math.Round(amt * 100)), Desc: rf.iDesc.GetText()}
+
+	if len(*rf.splits) == 0 {
+		_, cname := rf.iCat.GetCurrentOption()
+		if cname == "" {
+			return fail("Please choose a category")
+		}
+		if rec.CatId, err = rf.store.GetCategoryIdFromName(cname); err != nil {
+			return fail(err.Error())
+		}
+	}
+
+	sum := 0
+	for i, sl := range *rf.splits {
+		_, cname := sl.iCat.GetCurrentOption()
+		if cname == "" {
+			return fail(fmt.Sprintf("Please choose a category for split %d", i+1))
+		}
+		catId, err := rf.store.GetCategoryIdFromName(cname)
+		if err != nil {
+			return fail(err.Error())
+		}
+		amt, err := strconv.ParseFloat(sl.iAmt.GetText(), 32)
+		if err != nil || amt == 0 {
+			return fail(fmt.Sprintf("Invalid amount for split %d", i+1))
+		}
+		spl := backend.Split{CatId: catId, Amt: int(math.Round(amt * 100)), Note: sl.iNote.GetText()}
+		rec.Splits = append(rec.Splits, spl)
+		sum += spl.Amt
+	}
+	if len(rec.Splits) > 0 && sum != rec.Amt {
+		return fail(fmt.Sprintf("Splits add up to %.2f, not %.2f", float64(sum)/100, float64(rec.Amt)/100))
+	}
+
+	_, aname := rf.iAcc.GetCurrentOption()
+	if aname == "" {
+		return fail("Please add an account first")
+	}
+	if rec.AccId, err = rf.store.GetAccountIdFromName(aname); err != nil {
+		return fail(err.Error())
+	}
 
-	return backend.Record{Date: date, Amt: int(amt * 100), Desc: desc, CatId: catId}, nil
+	rec.Tags = strings.FieldsFunc(rf.iTags.GetText(), func(r rune) bool { return r == ' ' || r == ',' })
+	return rec, nil
 }
@@ -0,0 +1,205 @@
+package frontend
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+func seedRecord(t *testing.T) func(*backend.Store) {
+	return func(s *backend.Store) {
+		seedCategories(t)(s)
+		err := s.InsertRecord(backend.Record{Date: time.Now(), Desc: "weekly shop", Amt: -4250, CatId: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func getRecords(t *testing.T, s *backend.Store) []backend.Record {
+	t.Helper()
+	rows, err := s.GetRecordsRecent(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recs := make([]backend.Record, len(rows))
+	for i, r := range rows {
+		recs[i] = r.(backend.Record)
+	}
+	return recs
+}
+
+func TestAddRecordFromMonthView(t *testing.T) {
+	h := startTUI(t, seedCategories(t))
+
+	h.typeText("ma")
+	h.waitFor("Add Record")
+	h.press(tcell.KeyTab, tcell.KeyTab, tcell.KeyTab) // date is prefilled, keep the first category and account
+	h.typeText("-42.5")
+	h.press(tcell.KeyTab)
+	h.typeText("Coles")
+	h.submit()
+
+	h.waitForGone("Add Record")
+	h.waitFor("Coles")
+	h.waitFor("-$42.50")
+
+	recs := getRecords(t, h.store)
+	if len(recs) != 1 || recs[0].Desc != "Coles" || recs[0].Amt != -4250 || recs[0].CatName != "Groceries" {
+		t.Errorf("unexpected records %+v", recs)
+	}
+}
+
+func TestAddRecordValidation(t *testing.T) {
+	h := startTUI(t, seedCategories(t))
+
+	h.typeText("ra")
+	h.waitFor("Add Record")
+	h.submit()
+	h.waitFor("All fields are required")
+
+	if recs := getRecords(t, h.store); len(recs) != 0 {
+		t.Errorf("invalid record was saved: %+v", recs)
+	}
+}
+
+func TestEditRecord(t *testing.T) {
+	h := startTUI(t, seedRecord(t))
+
+	h.typeText("r")
+	h.waitFor("weekly shop")
+	h.typeText("e")
+	h.waitFor("Edit Record Details")
+	h.press(tcell.KeyTab, tcell.KeyTab, tcell.KeyTab, tcell.KeyTab)
+	h.replaceText("big shop")
+	h.submit()
+
+	h.waitForGone("Edit Record Details")
+	h.waitFor("big shop")
+
+	recs := getRecords(t, h.store)
+	if len(recs) != 1 || recs[0].Desc != "big shop" || recs[0].Amt != -4250 {
+		t.Errorf("unexpected records %+v", recs)
+	}
+}
+
+func TestDeleteRecord(t *testing.T) {
+	h := startTUI(t, seedRecord(t))
+
+	h.typeText("r")
+	h.waitFor("weekly shop")
+
+	// cancelling keeps the record
+	h.typeText("d")
+	h.waitFor("Delete this record?")
+	h.typeText("n")
+	h.waitForGone("Delete this record?")
+	if recs := getRecords(t, h.store); len(recs) != 1 {
+		t.Fatalf("record deleted after cancelling")
+	}
+
+	h.typeText("d")
+	h.waitFor("Delete this record?")
+	h.typeText("y")
+	h.waitForGone("weekly shop")
+	if recs := getRecords(t, h.store); len(recs) != 0 {
+		t.Errorf("record not deleted: %+v", recs)
+	}
+}
+
+func TestDeleteRecordFromMonthView(t *testing.T) {
+	h := startTUI(t, seedRecord(t))
+
+	h.typeText("m")
+	h.waitFor("weekly shop")
+	h.waitFor("Expenditure:      $42")
+	h.typeText("dy")
+	h.waitForGone("weekly shop")
+	h.waitFor("Expenditure:       $0")
+}
+
+func TestSplitRecord(t *testing.T) {
+	h := startTUI(t, seedRecord(t))
+
+	h.typeText("re")
+	h.waitFor("Edit Record Details")
+	h.press(tcell.KeyBacktab, tcell.KeyBacktab, tcell.KeyEnter) // Add Split
+	h.waitFor("Split 2")
+
+	// the first line has the record's category and amount, the new line is focused
+	h.press(tcell.KeyEnter)
+	h.typeText("Work")
+	h.press(tcell.KeyEnter, tcell.KeyTab)
+	h.typeText("-10")
+	h.submit()
+	h.waitFor("Splits add up to -52.50, not -42.50")
+
+	h.press(tcell.KeyBacktab, tcell.KeyBacktab, tcell.KeyBacktab)
+	h.replaceText("-32.50")
+	h.submit()
+	h.waitForGone("Edit Record Details")
+	h.waitFor("Split: Groceries, Work")
+
+	recs := getRecords(t, h.store)
+	if len(recs) != 1 || len(recs[0].Splits) != 2 || recs[0].Splits[0].Amt != -3250 || recs[0].Splits[1].CatName != "Work" {
+		t.Fatalf("unexpected records %+v", recs)
+	}
+
+	// removing the second line leaves the record in the first line's category
+	h.typeText("e")
+	h.waitFor("Split 2")
+	h.press(tcell.KeyBacktab, tcell.KeyEnter) // Remove Split
+	h.waitForGone("Split 1")
+	h.submit()
+	h.waitForGone("Edit Record Details")
+	h.waitForGone("Split:")
+
+	if recs := getRecords(t, h.store); recs[0].Splits != nil || recs[0].CatName != "Groceries" {
+		t.Errorf("unexpected record %+v after removing the split", recs[0])
+	}
+}
+
+func TestSortRecords(t *testing.T) {
+	h := startTUI(t, func(s *backend.Store) {
+		seedCategories(t)(s)
+		recs := []backend.Record{
+			{Date: time.Now(), Desc: "weekly shop", Amt: -4250, CatId: 1},
+			{Date: time.Now().AddDate(0, 0, -1), Desc: "big shop", Amt: -25000, CatId: 1},
+			{Date: time.Now().AddDate(0, 0, -2), Desc: "pay", Amt: 300000, CatId: 2},
+		}
+		if _, err := s.InsertRecords(recs); err != nil {
+			t.Fatal(err)
+		}
+	})
+	inOrder := func(descs ...string) func(string) bool {
+		return func(screen string) bool {
+			last := -1
+			for _, d := range descs {
+				i := strings.Index(screen, d)
+				if i <= last {
+					return false
+				}
+				last = i
+			}
+			return true
+		}
+	}
+
+	h.typeText("r")
+	h.waitUntil("newest first", inOrder("weekly shop", "big shop", "pay"))
+
+	h.typeText("ssssss") // ID, Date, Category, Account, Description, Amount
+	h.waitFor("Amount ▲")
+	h.waitUntil("smallest amount first", inOrder("big shop", "weekly shop", "pay"))
+	h.typeText("S")
+	h.waitFor("Amount ▼")
+	h.waitUntil("biggest amount first", inOrder("pay", "weekly shop", "big shop"))
+
+	// back to the default order after the last column
+	h.typeText("ss")
+	h.waitForGone("▲")
+	h.waitUntil("newest first", inOrder("weekly shop", "big shop", "pay"))
+}
@@ -0,0 +1,339 @@
+package frontend
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+type recurringForm struct {
+	store  *backend.Store
+	form   *tview.Form
+	iDesc  *tview.InputField
+	iCat   *tview.DropDown
+	iAcc   *tview.DropDown
+	iAmt   *tview.InputField
+	iFreq  *tview.DropDown
+	iDay   *tview.InputField
+	iStart *tview.InputField
+	iEnd   *tview.InputField
+	tvMsg  *tview.TextView
+}
+
+// labels for backend.Frequencies, in the same order
+var frequencyLabels = []string{"Weekly", "Fortnightly", "Monthly (on day)", "Last business day", "Yearly"}
+
+func createRecurringTable(store *backend.Store) *updatableTable {
+	table := newUpdatableTable(store, strings.Split("ID:Description:Category:Account:Amount:Schedule:Start:End:Next", ":"), nil)
+	table.title = "Recurring Rules"
+	table.fGetMaxPage = func() (int, error) { return 0, nil }
+	return &table
+}
+
+func setRecurringTableKeybinds(t *updatableTable, rrf recurringForm) {
+	t.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if res := t.defaultInputCapture(event); res == nil {
+			return nil
+		}
+
+		row, _ := t.GetSelection()
+		id := t.getCellInt(row, 0)
+		if event.Rune() == 'a' {
+			showRecurringForm(t, rrf, -1, backend.RecurringRule{})
+		} else if event.Rune() == 'd' { // delete rule
+			showModal("Delete this rule? Records it created are kept (y/n)", func() {
+				if err := t.store.DeleteRecurringRule(id); err != nil {
+					showError(err)
+					return
+				}
+				refresh(t)
+				// set focus if deleted last row
+				if row > t.GetRowCount()-1 {
+					t.Select(max(0, row-1), 0)
+				}
+			}, t)
+		} else if event.Rune() == 'e' { // edit rule
+			rr, err := t.store.GetRecurringRule(id)
+			if err != nil {
+				showError(err)
+				return nil
+			}
+			showRecurringForm(t, rrf, id, rr)
+		} else if event.Rune() == 'p' { // pause/resume rule
+			paused := t.getCellString(row, 8) != "Paused"
+			if err := t.store.SetRecurringRulePaused(id, paused, time.Now()); err != nil {
+				showError(err)
+				return nil
+			}
+			refresh(t)
+		} else {
+			return event
+		}
+		return nil
+	})
+}
+
+func createRecurringForm(store *backend.Store) recurringForm {
+	rrf := recurringForm{
+		store: store,
+		iDesc: tview.NewInputField().
+			SetLabel("Description").
+			SetFieldWidth(35),
+		iCat: tview.NewDropDown().
+			SetLabel("Category"),
+		iAcc: tview.NewDropDown().
+			SetLabel("Account"),
+		iAmt: tview.NewInputField().
+			SetLabel("Amount").
+			SetFieldWidth(10).
+			SetAcceptanceFunc(tview.InputFieldFloat),
+		iFreq: tview.NewDropDown().
+			SetLabel("Frequency").
+			SetOptions(frequencyLabels, nil),
+		iDay: tview.NewInputField().
+			SetLabel("Day of Month").
+			SetFieldWidth(3).
+			SetAcceptanceFunc(tview.InputFieldInteger),
+		iStart: tview.NewInputField().
+			SetLabel("Start").
+			SetFieldWidth(11).
+			SetPlaceholder("YYYY-MM-DD").
+			SetAcceptanceFunc(isPartialDate),
+		iEnd: tview.NewInputField().
+			SetLabel("End (optional)").
+			SetFieldWidth(11).
+			SetPlaceholder("YYYY-MM-DD").
+			SetAcceptanceFunc(isPartialDate),
+		tvMsg: tview.NewTextView().
+			SetSize(1, 35).
+			SetDynamicColors(true).
+			SetScrollable(false),
+	}
+
+	dropDownCapture := func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Rune() == 'j' || event.Key() == tcell.KeyCtrlN {
+			return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+		} else if event.Rune() == 'k' || event.Key() == tcell.KeyCtrlP {
+			return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+		}
+		return event
+	}
+	rrf.iCat.SetInputCapture(dropDownCapture)
+	rrf.iAcc.SetInputCapture(dropDownCapture)
+	rrf.iFreq.SetInputCapture(dropDownCapture)
+
+	rrf.form = tview.NewForm().
+		AddFormItem(rrf.iDesc).
+		AddFormItem(rrf.iCat).
+		AddFormItem(rrf.iAcc).
+		AddFormItem(rrf.iAmt).
+		AddFormItem(rrf.iFreq).
+		AddFormItem(rrf.iDay).
+		AddFormItem(rrf.iStart).
+		AddFormItem(rrf.iEnd).
+		AddFormItem(rrf.tvMsg).
+		AddButton("Save", nil).
+		AddButton("Cancel", nil).
+		SetFieldBackgroundColor(tview.Styles.MoreContrastBackgroundColor).
+		SetButtonBackgroundColor(tview.Styles.MoreContrastBackgroundColor)
+
+	rrf.form.SetBorder(true).
+		SetBorderColor(tview.Styles.TertiaryTextColor)
+
+	return rrf
+}
+
+/*
+Shows the form to add (id -1) or edit a rule. After saving, records for any
+dates the rule is already due are created and shown.
+*/
+func showRecurringForm(t *updatableTable, rrf recurringForm, id int, rr backend.RecurringRule) {
+
+	/* ===== Helper Functions ===== */
+
+	setOptions := func() {
+		cats, err := rrf.store.GetCategories(0)
+		if err != nil {
+			rrf.tvMsg.SetText("[red]" + err.Error())
+		}
+		catOpt, catNames := 0, make([]string, len(cats))
+		for i, row := range cats {
+			catNames[i] = row.(backend.Category).Name
+			if catNames[i] == rr.CatName {
+				catOpt = i
+			}
+		}
+		rrf.iCat.SetOptions(catNames, nil).SetCurrentOption(catOpt)
+
+		accs, err := rrf.store.GetAccounts()
+		if err != nil {
+			rrf.tvMsg.SetText("[red]" + err.Error())
+		}
+		accOpt, accNames := 0, make([]string, len(accs))
+		for i, row := range accs {
+			accNames[i] = row.(backend.Account).Name
+			if accNames[i] == rr.AccName {
+				accOpt = i
+			}
+		}
+		rrf.iAcc.SetOptions(accNames, nil).SetCurrentOption(accOpt)
+
+		freqOpt := 0
+		for i, f := range backend.Frequencies {
+			if f == rr.Freq {
+				freqOpt = i
+			}
+		}
+		rrf.iFreq.SetCurrentOption(freqOpt)
+	}
+
+	closeForm := func() {
+		flex.RemoveItem(rrf.form)
+		app.SetFocus(t)
+	}
+
+	onSubmit := func() {
+		rr, err := parseRecurringForm(rrf)
+		if err != nil {
+			rrf.tvMsg.SetText("[red]" + err.Error())
+			return
+		}
+
+		if id == -1 {
+			err = rrf.store.InsertRecurringRule(rr)
+		} else {
+			err = rrf.store.UpdateRecurringRule(id, rr)
+		}
+		if err != nil {
+			rrf.tvMsg.SetText("[red]" + err.Error())
+			return
+		}
+		closeForm()
+
+		created, err := rrf.store.MaterialiseRecurring(time.Now())
+		refresh(t)
+		if err != nil {
+			showError(err)
+		} else if len(created) > 0 {
+			showCreatedRecords(created)
+		}
+	}
+
+	/* ===== Function Body ===== */
+
+	if id == -1 {
+		rrf.form.SetTitle("Add Recurring Rule")
+		rr.Start = time.Now()
+	} else {
+		rrf.form.SetTitle("Edit Recurring Rule")
+	}
+
+	rrf.tvMsg.SetText("")
+	setOptions()
+	rrf.iDesc.SetText(rr.Desc)
+	rrf.iAmt.SetText("")
+	if rr.Amt != 0 {
+		rrf.iAmt.SetText(strconv.FormatFloat(float64(rr.Amt)/100, 'f', 2, 64))
+	}
+	rrf.iDay.SetText("")
+	if rr.Day != 0 {
+		rrf.iDay.SetText(strconv.Itoa(rr.Day))
+	}
+	rrf.iStart.SetText(rr.Start.Format("2006-01-02"))
+	rrf.iEnd.SetText("")
+	if !rr.End.IsZero() {
+		rrf.iEnd.SetText(rr.End.Format("2006-01-02"))
+	}
+
+	rrf.form.SetInputCapture(formInputCapture(closeForm, onSubmit))
+	rrf.form.GetButton(rrf.form.GetButtonIndex("Cancel")).SetSelectedFunc(closeForm)
+	rrf.form.GetButton(rrf.form.GetButtonIndex("Save")).SetSelectedFunc(onSubmit)
+
+	// display + focus form
+	flex.AddItem(rrf.form, 55, 0, true)
+	rrf.form.SetFocus(0)
+	app.SetFocus(rrf.form)
+}
+
+/* Takes input from the form and returns a RecurringRule object */
+func parseRecurringForm(rrf recurringForm) (backend.RecurringRule, error) {
+
+	fail := func(msg string) (backend.RecurringRule, error) {
+		return backend.RecurringRule{}, errors.New(msg)
+	}
+
+	desc := strings.TrimSpace(rrf.iDesc.GetText())
+	if desc == "" || rrf.iAmt.GetText() == "" || rrf.iStart.GetText() == "" {
+		return fail("Description, amount and start are required")
+	}
+
+	_, cname := rrf.iCat.GetCurrentOption()
+	if cname == "" {
+		return fail("Please choose a category")
+	}
+	catId, err := rrf.store.GetCategoryIdFromName(cname)
+	if err != nil {
+		return fail(err.Error())
+	}
+	_, aname := rrf.iAcc.GetCurrentOption()
+	if aname == "" {
+		return fail("Please add an account first")
+	}
+	accId, err := rrf.store.GetAccountIdFromName(aname)
+	if err != nil {
+		return fail(err.Error())
+	}
+
+	amt, err := strconv.ParseFloat(rrf.iAmt.GetText(), 64)
+	if err != nil || amt == 0 {
+		return fail("Invalid amount entered")
+	}
+
+	freqOpt, _ := rrf.iFreq.GetCurrentOption()
+	freq := backend.Frequencies[max(0, freqOpt)]
+	day := 0
+	if freq == backend.Monthly {
+		if day, err = strconv.Atoi(rrf.iDay.GetText()); err != nil || day < 1 || day > 31 {
+			return fail("Day of month must be from 1 to 31")
+		}
+	}
+
+	start, err := time.Parse("2006-01-02", rrf.iStart.GetText())
+	if err != nil {
+		return fail("Dates must be in YYYY-MM-DD format")
+	}
+	var end time.Time
+	if rrf.iEnd.GetText() != "" {
+		if end, err = time.Parse("2006-01-02", rrf.iEnd.GetText()); err != nil {
+			return fail("Dates must be in YYYY-MM-DD format")
+		}
+	}
+
+	return backend.RecurringRule{
+		Desc:  desc,
+		Amt:   int(math.Round(amt * 100)),
+		CatId: catId,
+		AccId: accId,
+		Freq:  freq,
+		Day:   day,
+		Start: start,
+		End:   end,
+	}, nil
+}
+
+/* Shows the records created by recurring rules */
+func showCreatedRecords(recs []backend.Record) {
+	lines := make([]string, len(recs))
+	for i, rec := range recs {
+		lines[i] = fmt.Sprintf("%s  %-30s %-15s %10s", rec.Date.Format("2006-01-02"), rec.Desc, rec.CatName,
+			strings.Replace(fmt.Sprintf("$%.2f", float32(rec.Amt)/100), "$-", "-$", 1))
+	}
+	showReport(fmt.Sprintf("Created %d recurring records", len(recs)), strings.Join(lines, "\n"))
+}
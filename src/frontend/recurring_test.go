@@ -0,0 +1,37 @@
+package frontend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestAddAndPauseRecurringRule(t *testing.T) {
+	h := startTUI(t, seedCategories(t))
+
+	h.openOption("recurring")
+	h.typeText("a")
+	h.waitFor("Add Recurring Rule")
+	h.typeText("gym")
+	h.press(tcell.KeyTab, tcell.KeyTab, tcell.KeyTab)
+	h.typeText("-20")
+	h.press(tcell.KeyTab, tcell.KeyTab, tcell.KeyTab)
+	h.replaceText(time.Now().AddDate(0, 0, -14).Format("2006-01-02"))
+	h.submit()
+
+	// weekly from a fortnight ago, including today
+	h.waitFor("Created 3 recurring records")
+	h.press(tcell.KeyEnter)
+	h.waitForGone("Created 3 recurring records")
+	h.waitFor("Weekly on")
+	if n := len(getRecords(t, h.store)); n != 3 {
+		t.Errorf("got %d records, want 3", n)
+	}
+
+	h.typeText("p")
+	h.waitFor("Paused")
+	h.typeText("p")
+	h.waitForGone("Paused")
+	h.waitFor(time.Now().AddDate(0, 0, 7).Format("2006-01-02"))
+}
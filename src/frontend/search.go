@@ -0,0 +1,188 @@
+package frontend
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+type searchForm struct {
+	store  *backend.Store
+	form   *tview.Form
+	iQuery *tview.InputField
+	tvMsg  *tview.TextView
+}
+
+var errNoMatches = errors.New("No records match")
+
+func createSearchForm(store *backend.Store) searchForm {
+	sf := searchForm{
+		store: store,
+		iQuery: tview.NewInputField().
+			SetLabel("Search").
+			SetFieldWidth(35).
+			SetPlaceholder("words in the description"),
+		tvMsg: tview.NewTextView().
+			SetSize(1, 35).
+			SetDynamicColors(true).
+			SetScrollable(false),
+	}
+
+	sf.form = tview.NewForm().
+		AddFormItem(sf.iQuery).
+		AddFormItem(sf.tvMsg).
+		AddButton("Search", nil).
+		AddButton("Cancel", nil).
+		SetFieldBackgroundColor(tview.Styles.MoreContrastBackgroundColor).
+		SetButtonBackgroundColor(tview.Styles.MoreContrastBackgroundColor)
+
+	sf.form.SetBorder(true).
+		SetBorderColor(tview.Styles.TertiaryTextColor).
+		SetTitle("Search Records")
+
+	return sf
+}
+
+/*
+Shows the search prompt, starting with the current search. onSearch is called
+with the new search (empty to clear it), the prompt stays open if it fails.
+*/
+func showSearchForm(prev tview.Primitive, sf searchForm, query string, onSearch func(query string) error) {
+
+	/* ===== Helper Functions ===== */
+
+	closeForm := func() {
+		flex.RemoveItem(sf.form)
+		app.SetFocus(prev)
+	}
+
+	onSubmit := func() {
+		q := strings.TrimSpace(sf.iQuery.GetText())
+		if len(backend.SearchTerms(q)) == 0 {
+			q = ""
+		}
+		if err := onSearch(q); err != nil {
+			sf.tvMsg.SetText("[red]" + err.Error())
+			return
+		}
+		closeForm()
+	}
+
+	/* ===== Function Body ===== */
+
+	sf.tvMsg.SetText("")
+	if !sf.store.FullTextSearch() {
+		sf.tvMsg.SetText("[yellow]Built without FTS5, slower search")
+	}
+	sf.iQuery.SetText(query)
+
+	// enter in the search field searches straight away
+	capture := formInputCapture(closeForm, onSubmit)
+	sf.form.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEnter && sf.iQuery.HasFocus() {
+			onSubmit()
+			return nil
+		}
+		return capture(event)
+	})
+	sf.form.GetButton(sf.form.GetButtonIndex("Cancel")).SetSelectedFunc(closeForm)
+	sf.form.GetButton(sf.form.GetButtonIndex("Search")).SetSelectedFunc(onSubmit)
+
+	// display + focus form
+	flex.AddItem(sf.form, 55, 0, true)
+	sf.form.SetFocus(0)
+	app.SetFocus(sf.form)
+}
+
+/* Wraps the words of text starting with any of the search terms in reverse video */
+func highlightMatches(text string, terms []string) string {
+	if len(terms) == 0 {
+		return text
+	}
+	quoted := make([]string, len(terms))
+	for i, t := range terms {
+		quoted[i] = regexp.QuoteMeta(t)
+	}
+	re := regexp.MustCompile(`(?i)(^|[^\pL\pN])(` + strings.Join(quoted, "|") + `)`)
+	return re.ReplaceAllString(text, "${1}[::r]${2}[::-]")
+}
+
+/*
+Selects the records table row of the next search match after (by 1) or before
+(by -1) the selected one, wrapping around, or the first match from the
+selected one for by 0. Matches on other pages, among the records the table
+is filtered to, are found with SearchRecords.
+*/
+func jumpToSearchMatch(t *updatableTable, by int) error {
+	positions, err := t.store.SearchRecords(t.getFilter(), t.sort, t.search)
+	if err != nil {
+		return err
+	} else if len(positions) == 0 {
+		return errNoMatches
+	}
+
+	row, _ := t.GetSelection()
+	cur := t.curPage*t.store.PageRows + max(row, 1) - 1
+	var pos int
+	if by < 0 {
+		pos = positions[len(positions)-1]
+		for i := len(positions) - 1; i >= 0; i-- {
+			if positions[i] < cur {
+				pos = positions[i]
+				break
+			}
+		}
+	} else {
+		pos = positions[0]
+		for _, p := range positions {
+			if p > cur || (by == 0 && p == cur) {
+				pos = p
+				break
+			}
+		}
+	}
+
+	t.changePage(pos/t.store.PageRows - t.curPage)
+	t.Select(pos%t.store.PageRows+1, 0)
+	return nil
+}
+
+/*
+Selects the row of the month view's next search match, as in
+jumpToSearchMatch, searching only the month's records.
+*/
+func jumpToMonthSearchMatch(mv *monthGridView, by int) error {
+	month := time.Now().AddDate(0, mv.monthOffset, 0)
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	recs, err := mv.store.GetRecordsFilter(backend.NewFilterOpts().WithText(mv.table.search).WithStartDate(start).WithEndDate(start.AddDate(0, 1, 0)))
+	if err != nil {
+		return err
+	} else if len(recs) == 0 {
+		return errNoMatches
+	}
+	ids := map[int]bool{}
+	for _, r := range recs {
+		ids[r.(backend.Record).Id] = true
+	}
+
+	rows := mv.table.GetRowCount() - 1
+	row, _ := mv.table.GetSelection()
+	step := by
+	if by == 0 {
+		step = 1
+	}
+	for i := 0; i < rows; i++ {
+		// rows 1 to rows, starting from the selected row (by 0) or the one after/before it
+		r := ((max(row, 1)-1+by+i*step)%rows+rows)%rows + 1
+		if ids[mv.table.getCellInt(r, 0)] {
+			mv.table.Select(r, 0)
+			return nil
+		}
+	}
+	return errNoMatches
+}
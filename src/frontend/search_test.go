@@ -0,0 +1,78 @@
+package frontend
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+func TestSearchRecords(t *testing.T) {
+	h := startTUI(t, func(s *backend.Store) {
+		seedCategories(t)(s)
+		// a page of shops between a new and an old match
+		recs := []backend.Record{{Date: time.Now(), Desc: "new Bunnings", Amt: -100, CatId: 1}}
+		for i := range 60 {
+			recs = append(recs, backend.Record{Date: time.Now().AddDate(0, 0, -i-1), Desc: fmt.Sprintf("shop %d", i), Amt: -100, CatId: 1})
+		}
+		recs = append(recs, backend.Record{Date: time.Now().AddDate(-1, 0, 0), Desc: "old bunnings", Amt: -100, CatId: 1})
+		if _, err := s.InsertRecords(recs); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	h.typeText("r/")
+	h.waitFor("Search Records")
+	if !h.store.FullTextSearch() {
+		h.waitFor("Built without FTS5")
+	}
+	h.typeText("zzz")
+	h.press(tcell.KeyEnter)
+	h.waitFor("No records match")
+
+	h.replaceText("BUNN")
+	h.press(tcell.KeyEnter)
+	h.waitForGone("Search Records")
+	h.waitFor("Records /BUNN (1/2)")
+
+	h.typeText("n")
+	h.waitFor("Records /BUNN (2/2)")
+	h.waitFor("old bunnings")
+	h.typeText("n") // wraps around
+	h.waitFor("Records /BUNN (1/2)")
+	h.typeText("N")
+	h.waitFor("Records /BUNN (2/2)")
+
+	// the selected match is edited without its highlighting
+	h.typeText("e")
+	h.waitFor("Edit Record Details")
+	h.submit()
+	h.waitForGone("Edit Record Details")
+	rows, err := h.store.GetRecordsFilter(backend.NewFilterOpts().WithText("bunnings"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if desc := rows[0].(backend.Record).Desc; len(rows) != 2 || desc != "old bunnings" {
+		t.Errorf("got %d matches, the old one described %q", len(rows), desc)
+	}
+
+	// an empty search clears it
+	h.typeText("/")
+	h.waitFor("Search Records")
+	h.replaceText("")
+	h.press(tcell.KeyEnter)
+	h.waitForGone("/BUNN")
+}
+
+func TestSearchMonth(t *testing.T) {
+	h := startTUI(t, seedRecord(t))
+
+	h.typeText("m/")
+	h.waitFor("Search Records")
+	h.typeText("shop")
+	h.press(tcell.KeyEnter)
+	h.waitForGone("Search Records")
+	h.waitFor("/shop")
+}
@@ -0,0 +1,247 @@
+package frontend
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+type tagForm struct {
+	store *backend.Store
+	form  *tview.Form
+	iName *tview.InputField
+	tvMsg *tview.TextView
+}
+
+type dateRangeForm struct {
+	form  *tview.Form
+	iFrom *tview.InputField
+	iTo   *tview.InputField
+	tvMsg *tview.TextView
+}
+
+/* Totals for each tag over a date range, defaulting to the current financial year */
+func createTagsTable(store *backend.Store) *updatableTable {
+	table := newUpdatableTable(store, strings.Split("Tag:Records:Income:Expenditure:Net", ":"), nil)
+	table.title = "Tags"
+	table.from, table.to = backend.FinancialYear(backend.FinancialYearOf(time.Now()))
+	table.fGetMaxPage = func() (int, error) { return 0, nil }
+	return &table
+}
+
+/* The records with a single tag (set by tag) over the tags table's date range */
+func createTaggedRecordsTable(store *backend.Store) *updatableTable {
+	table := newUpdatableTable(store, strings.Split("ID:Date:Category:Account:Description:Amount:Tags", ":"), nil)
+	table.title = "Tagged Records"
+	table.fGetMaxPage = func() (int, error) { return 0, nil }
+	return &table
+}
+
+func setTagsTableKeybinds(t *updatableTable, tagged *updatableTable, tf tagForm, drf dateRangeForm) {
+	t.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Rune() == 'H' || event.Rune() == 'L' { // previous/next year
+			by := 1
+			if event.Rune() == 'H' {
+				by = -1
+			}
+			t.from, t.to = t.from.AddDate(by, 0, 0), t.to.AddDate(by, 0, 0)
+			t.changePage(0)
+			return nil
+		} else if res := t.defaultInputCapture(event); res == nil {
+			return nil
+		}
+
+		row, _ := t.GetSelection()
+		name := strings.TrimPrefix(t.getCellString(row, 0), "#")
+		if event.Rune() == 'R' { // choose the date range
+			showDateRangeForm(t, drf)
+		} else if event.Rune() == 'e' { // rename tag
+			showTagForm(t, tf, name)
+		} else if event.Rune() == 'd' { // delete tag
+			showModal(fmt.Sprintf("Delete #%s? It will be removed from every record (y/n)", name), func() {
+				if err := t.store.DeleteTag(name); err != nil {
+					showError(err)
+					return
+				}
+				refresh(t)
+				// set focus if deleted last row
+				if row > t.GetRowCount()-1 {
+					t.Select(max(0, row-1), 0)
+				}
+			}, t)
+		} else if event.Key() == tcell.KeyEnter || event.Rune() == 'l' { // show the tag's records
+			showTaggedRecords(t, tagged, name)
+		} else {
+			return event
+		}
+		return nil
+	})
+}
+
+/* Replaces the tags table with the records of one tag, back keys return to the tags */
+func showTaggedRecords(tags, tagged *updatableTable, name string) {
+	tagged.tag = name
+	tagged.from, tagged.to = tags.from, tags.to
+	flex.RemoveItem(tags)
+	showUpdatablePrim(tagged)
+	app.SetFocus(tagged)
+
+	tagged.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if isBackKey(event) {
+			flex.RemoveItem(tagged)
+			showUpdatablePrim(tags)
+			app.SetFocus(tags)
+			return nil
+		}
+		return tagged.defaultInputCapture(event)
+	})
+}
+
+func createTagForm(store *backend.Store) tagForm {
+	tf := tagForm{
+		store: store,
+		iName: tview.NewInputField().
+			SetLabel("Name").
+			SetFieldWidth(31),
+		tvMsg: tview.NewTextView().
+			SetSize(1, 35).
+			SetDynamicColors(true).
+			SetScrollable(false),
+	}
+
+	tf.form = tview.NewForm().
+		AddFormItem(tf.iName).
+		AddFormItem(tf.tvMsg).
+		AddButton("Save", nil).
+		AddButton("Cancel", nil).
+		SetFieldBackgroundColor(tview.Styles.MoreContrastBackgroundColor).
+		SetButtonBackgroundColor(tview.Styles.MoreContrastBackgroundColor)
+
+	tf.form.SetBorder(true).
+		SetBorderColor(tview.Styles.TertiaryTextColor)
+
+	return tf
+}
+
+/* Shows the form to rename a tag on every record */
+func showTagForm(t *updatableTable, tf tagForm, name string) {
+
+	/* ===== Helper Functions ===== */
+
+	closeForm := func() {
+		flex.RemoveItem(tf.form)
+		app.SetFocus(t)
+	}
+
+	onSubmit := func() {
+		newName := strings.TrimSpace(tf.iName.GetText())
+		if newName == "" {
+			tf.tvMsg.SetText("[red]Name is required")
+			return
+		}
+		if err := tf.store.RenameTag(name, newName); errors.Is(err, backend.ErrConstraint) {
+			tf.tvMsg.SetText("[red]Invalid tag, or a tag with that name already exists")
+			return
+		} else if err != nil {
+			tf.tvMsg.SetText("[red]" + err.Error())
+			return
+		}
+		refresh(t)
+		closeForm()
+	}
+
+	/* ===== Function Body ===== */
+
+	tf.form.SetTitle("Rename #" + name)
+	tf.tvMsg.SetText("")
+	tf.iName.SetText(name)
+
+	tf.form.SetInputCapture(formInputCapture(closeForm, onSubmit))
+	tf.form.GetButton(tf.form.GetButtonIndex("Cancel")).SetSelectedFunc(closeForm)
+	tf.form.GetButton(tf.form.GetButtonIndex("Save")).SetSelectedFunc(onSubmit)
+
+	// display + focus form
+	flex.AddItem(tf.form, 55, 0, true)
+	tf.form.SetFocus(0)
+	app.SetFocus(tf.form)
+}
+
+func createDateRangeForm() dateRangeForm {
+	drf := dateRangeForm{
+		iFrom: tview.NewInputField().
+			SetLabel("From").
+			SetFieldWidth(11).
+			SetPlaceholder("YYYY-MM-DD").
+			SetAcceptanceFunc(isPartialDate),
+		iTo: tview.NewInputField().
+			SetLabel("To").
+			SetFieldWidth(11).
+			SetPlaceholder("YYYY-MM-DD").
+			SetAcceptanceFunc(isPartialDate),
+		tvMsg: tview.NewTextView().
+			SetSize(1, 35).
+			SetDynamicColors(true).
+			SetScrollable(false),
+	}
+
+	drf.form = tview.NewForm().
+		AddFormItem(drf.iFrom).
+		AddFormItem(drf.iTo).
+		AddFormItem(drf.tvMsg).
+		AddButton("Save", nil).
+		AddButton("Cancel", nil).
+		SetFieldBackgroundColor(tview.Styles.MoreContrastBackgroundColor).
+		SetButtonBackgroundColor(tview.Styles.MoreContrastBackgroundColor)
+
+	drf.form.SetBorder(true).
+		SetBorderColor(tview.Styles.TertiaryTextColor).
+		SetTitle("Date Range")
+
+	return drf
+}
+
+/* Shows the form to choose the (inclusive) date range of a table */
+func showDateRangeForm(t *updatableTable, drf dateRangeForm) {
+
+	/* ===== Helper Functions ===== */
+
+	closeForm := func() {
+		flex.RemoveItem(drf.form)
+		app.SetFocus(t)
+	}
+
+	onSubmit := func() {
+		from, err1 := time.Parse("2006-01-02", drf.iFrom.GetText())
+		to, err2 := time.Parse("2006-01-02", drf.iTo.GetText())
+		if err1 != nil || err2 != nil {
+			drf.tvMsg.SetText("[red]Dates must be in YYYY-MM-DD format")
+			return
+		} else if to.Before(from) {
+			drf.tvMsg.SetText("[red]The range must end after it starts")
+			return
+		}
+		t.from, t.to = from, to.AddDate(0, 0, 1)
+		closeForm()
+		t.changePage(0)
+	}
+
+	/* ===== Function Body ===== */
+
+	drf.tvMsg.SetText("")
+	drf.iFrom.SetText(t.from.Format("2006-01-02"))
+	drf.iTo.SetText(t.to.AddDate(0, 0, -1).Format("2006-01-02"))
+
+	drf.form.SetInputCapture(formInputCapture(closeForm, onSubmit))
+	drf.form.GetButton(drf.form.GetButtonIndex("Cancel")).SetSelectedFunc(closeForm)
+	drf.form.GetButton(drf.form.GetButtonIndex("Save")).SetSelectedFunc(onSubmit)
+
+	// display + focus form
+	flex.AddItem(drf.form, 55, 0, true)
+	drf.form.SetFocus(0)
+	app.SetFocus(drf.form)
+}
@@ -0,0 +1,57 @@
+package frontend
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+func TestTagRecordAndTagTotals(t *testing.T) {
+	h := startTUI(t, seedRecord(t))
+
+	h.typeText("re")
+	h.waitFor("Edit Record Details")
+	h.press(tcell.KeyTab, tcell.KeyTab, tcell.KeyTab, tcell.KeyTab, tcell.KeyTab)
+	h.typeText("#Tax-Deductible, holiday")
+	h.submit()
+	h.waitForGone("Edit Record Details")
+	h.waitFor("#holiday #tax-deductible")
+
+	if recs := getRecords(t, h.store); len(recs) != 1 || !slices.Equal(recs[0].Tags, []string{"holiday", "tax-deductible"}) {
+		t.Fatalf("unexpected records %+v", recs)
+	}
+
+	// back to the options list, then to tags, showing this financial year
+	from, _ := backend.FinancialYear(backend.FinancialYearOf(time.Now()))
+	h.typeText("q")
+	h.openOption("tags")
+	h.waitFor("Tags " + from.Format("2006-01-02"))
+	h.waitFor("-$42.50")
+
+	// the records with a tag
+	h.typeText("j")
+	h.press(tcell.KeyEnter)
+	h.waitFor("Tagged Records #tax-deductible")
+	h.waitFor("weekly shop")
+	h.typeText("q")
+	h.waitForGone("Tagged Records")
+
+	h.typeText("e")
+	h.waitFor("Rename #tax-deductible")
+	h.replaceText("deductible")
+	h.submit()
+	h.waitForGone("Rename")
+	h.waitFor("#deductible")
+
+	h.typeText("kd") // the renamed tag is now sorted first
+	h.waitFor("Delete #deductible?")
+	h.typeText("y")
+	h.waitForGone("#deductible")
+
+	if recs := getRecords(t, h.store); !slices.Equal(recs[0].Tags, []string{"holiday"}) {
+		t.Errorf("got tags %v after deleting #deductible, want [holiday]", recs[0].Tags)
+	}
+}
@@ -0,0 +1,208 @@
+package frontend
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+type transferForm struct {
+	store *backend.Store
+	form  *tview.Form
+	iDate *tview.InputField
+	iFrom *tview.DropDown
+	iTo   *tview.DropDown
+	iAmt  *tview.InputField
+	iDesc *tview.InputField
+	tvMsg *tview.TextView
+}
+
+func createTransferForm(store *backend.Store) transferForm {
+	tf := transferForm{
+		store: store,
+		iDate: tview.NewInputField().
+			SetLabel("Date").
+			SetFieldWidth(11).
+			SetPlaceholder("YYYY-MM-DD").
+			SetAcceptanceFunc(isPartialDate),
+		iFrom: tview.NewDropDown().
+			SetLabel("From"),
+		iTo: tview.NewDropDown().
+			SetLabel("To"),
+		iAmt: tview.NewInputField().
+			SetLabel("Amount").
+			SetFieldWidth(10).
+			SetAcceptanceFunc(tview.InputFieldFloat),
+		iDesc: tview.NewInputField().
+			SetLabel("Description").
+			SetFieldWidth(35),
+		tvMsg: tview.NewTextView().
+			SetSize(1, 35).
+			SetDynamicColors(true).
+			SetScrollable(false),
+	}
+
+	dropDownCapture := func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Rune() == 'j' || event.Key() == tcell.KeyCtrlN {
+			return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+		} else if event.Rune() == 'k' || event.Key() == tcell.KeyCtrlP {
+			return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+		}
+		return event
+	}
+	tf.iFrom.SetInputCapture(dropDownCapture)
+	tf.iTo.SetInputCapture(dropDownCapture)
+
+	tf.form = tview.NewForm().
+		AddFormItem(tf.iDate).
+		AddFormItem(tf.iFrom).
+		AddFormItem(tf.iTo).
+		AddFormItem(tf.iAmt).
+		AddFormItem(tf.iDesc).
+		AddFormItem(tf.tvMsg).
+		AddButton("Save", nil).
+		AddButton("Cancel", nil).
+		SetFieldBackgroundColor(tview.Styles.MoreContrastBackgroundColor).
+		SetButtonBackgroundColor(tview.Styles.MoreContrastBackgroundColor)
+
+	tf.form.SetBorder(true).
+		SetBorderColor(tview.Styles.TertiaryTextColor)
+
+	return tf
+}
+
+/*
+Shows the form to add (id -1) or edit a transfer, id is the id of either leg.
+A new transfer defaults to moving money from the first account to the second.
+*/
+func showTransferForm(t updatablePrim, tf transferForm, id int, tr backend.Transfer) {
+
+	/* ===== Helper Functions ===== */
+
+	setAccountOptions := func() {
+		accs, err := tf.store.GetAccounts()
+		if err != nil {
+			tf.tvMsg.SetText("[red]" + err.Error())
+		}
+		fromOpt, toOpt := 0, min(1, len(accs)-1)
+		accNames := make([]string, len(accs))
+		for i, row := range accs {
+			acc := row.(backend.Account)
+			accNames[i] = acc.Name
+			if acc.Id == tr.FromAccId {
+				fromOpt = i
+			}
+			if acc.Id == tr.ToAccId {
+				toOpt = i
+			}
+		}
+		tf.iFrom.SetOptions(accNames, nil).SetCurrentOption(fromOpt)
+		tf.iTo.SetOptions(accNames, nil).SetCurrentOption(toOpt)
+	}
+
+	closeForm := func() {
+		flex.RemoveItem(tf.form)
+		app.SetFocus(t)
+	}
+
+	onSubmit := func() {
+		tr, err := parseTransferForm(tf)
+		if err != nil {
+			tf.tvMsg.SetText("[red]" + err.Error())
+			return
+		}
+
+		if id == -1 {
+			err = tf.store.InsertTransfer(tr)
+		} else {
+			err = tf.store.UpdateTransfer(id, tr)
+		}
+		if err != nil {
+			tf.tvMsg.SetText("[red]" + err.Error())
+			return
+		}
+
+		refresh(t)
+		closeForm()
+	}
+
+	/* ===== Function Body ===== */
+
+	if id == -1 {
+		tf.form.SetTitle("Add Transfer")
+		tr.Date = time.Now()
+	} else {
+		tf.form.SetTitle("Edit Transfer Details")
+	}
+
+	tf.tvMsg.SetText("")
+	setAccountOptions()
+	tf.iDate.SetText(tr.Date.Format("2006-01-02"))
+	tf.iAmt.SetText("")
+	if tr.Amt != 0 {
+		tf.iAmt.SetText(strconv.FormatFloat(float64(tr.Amt)/100, 'f', 2, 64))
+	}
+	tf.iDesc.SetText(tr.Desc)
+
+	tf.form.SetInputCapture(formInputCapture(closeForm, onSubmit))
+	tf.form.GetButton(tf.form.GetButtonIndex("Cancel")).SetSelectedFunc(closeForm)
+	tf.form.GetButton(tf.form.GetButtonIndex("Save")).SetSelectedFunc(onSubmit)
+
+	// display + focus form
+	flex.AddItem(tf.form, 55, 0, true)
+	tf.form.SetFocus(0)
+	app.SetFocus(tf.form)
+}
+
+/* Takes input from the form and returns a Transfer object */
+func parseTransferForm(tf transferForm) (backend.Transfer, error) {
+
+	fail := func(msg string) (backend.Transfer, error) {
+		return backend.Transfer{}, errors.New(msg)
+	}
+
+	desc := strings.TrimSpace(tf.iDesc.GetText())
+	if tf.iDate.GetText() == "" || tf.iAmt.GetText() == "" || desc == "" {
+		return fail("All fields are required")
+	}
+
+	date, err := time.Parse("2006-01-02", tf.iDate.GetText())
+	if err != nil {
+		return fail("Date must be in YYYY-MM-DD format")
+	}
+
+	_, from := tf.iFrom.GetCurrentOption()
+	_, to := tf.iTo.GetCurrentOption()
+	if from == "" || to == "" || from == to {
+		return fail("Choose two different accounts")
+	}
+	fromId, err := tf.store.GetAccountIdFromName(from)
+	if err != nil {
+		return fail(err.Error())
+	}
+	toId, err := tf.store.GetAccountIdFromName(to)
+	if err != nil {
+		return fail(err.Error())
+	}
+
+	amt, err := strconv.ParseFloat(tf.iAmt.GetText(), 64)
+	if err != nil || amt <= 0 {
+		return fail("Amount must be a positive number")
+	}
+
+	return backend.Transfer{Date: date, FromAccId: fromId, ToAccId: toId, Desc: desc, Amt: int(math.Round(amt * 100))}, nil
+}
+
+/* Returns the prompt shown before deleting a record, which deletes both legs of a transfer */
+func deleteRecordPrompt(store *backend.Store, id int) string {
+	if _, err := store.GetTransfer(id); err == nil {
+		return "Delete both records of this transfer? (y/n)"
+	}
+	return "Delete this record? (y/n)"
+}
@@ -0,0 +1,71 @@
+package frontend
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+func seedSavings(t *testing.T) func(*backend.Store) {
+	return func(s *backend.Store) {
+		seedRecord(t)(s)
+		if err := s.InsertAccount(backend.Account{Name: "Savings", Type: "Bank"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAddTransfer(t *testing.T) {
+	h := startTUI(t, seedSavings(t))
+
+	h.typeText("mt")
+	h.waitFor("Add Transfer")
+	h.press(tcell.KeyTab, tcell.KeyTab, tcell.KeyTab) // Everyday to Savings is the default
+	h.typeText("500")
+	h.press(tcell.KeyTab)
+	h.typeText("to savings")
+	h.submit()
+
+	h.waitForGone("Add Transfer")
+	h.waitFor("Savings:             $500 (+500)")
+	h.waitFor("Expenditure:      $42") // not counted as income or expenditure
+
+	var legs []backend.Record
+	for _, rec := range getRecords(t, h.store) {
+		if rec.XferId != 0 {
+			legs = append(legs, rec)
+		}
+	}
+	if len(legs) != 2 || legs[0].Amt+legs[1].Amt != 0 || legs[0].Desc != "to savings" {
+		t.Errorf("unexpected transfer legs %+v", legs)
+	}
+}
+
+func TestEditAndDeleteTransfer(t *testing.T) {
+	h := startTUI(t, func(s *backend.Store) {
+		seedSavings(t)(s)
+		tr := backend.Transfer{Date: getRecords(t, s)[0].Date, FromAccId: 1, ToAccId: 2, Desc: "to savings", Amt: 10000}
+		if err := s.InsertTransfer(tr); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	h.typeText("r")
+	h.waitFor("to savings")
+	h.typeText("jje") // the Savings leg
+	h.waitFor("Edit Transfer Details")
+	h.press(tcell.KeyTab, tcell.KeyTab, tcell.KeyTab)
+	h.replaceText("250")
+	h.submit()
+	h.waitForGone("Edit Transfer Details")
+	h.waitFor("-$250.00")
+
+	h.typeText("d")
+	h.waitFor("Delete both records of this transfer?")
+	h.typeText("y")
+	h.waitForGone("to savings")
+	if recs := getRecords(t, h.store); len(recs) != 1 {
+		t.Errorf("transfer legs left after deleting %+v", recs)
+	}
+}
@@ -13,7 +13,7 @@ import (
 )
 
 type updatable interface {
-	fGetData(int) []backend.DataRow
+	fGetData(int) ([]backend.DataRow, error)
 	getCurPage() int
 	update([]backend.DataRow)
 	reset()
@@ -38,25 +38,62 @@ func focusUpdatablePrim(p updatablePrim) {
 	app.SetFocus(p)
 }
 
+/* Reloads the data displayed by u, showing an error message if the data couldn't be loaded */
+func refresh(u updatable) {
+	rows, err := u.fGetData(u.getCurPage())
+	if err != nil {
+		showError(err)
+		return
+	}
+	u.update(rows)
+}
+
 type updatableTable struct {
 	*tview.Table
+	store       *backend.Store
 	title       string
 	headers     []string
 	curPage     int `default:"0"`
 	maxPage     int `default:"0"`
-	fGetMaxPage func() int
+	fGetMaxPage func() (int, error)
+	accId       int                 // account shown by the "Account Ledger" table
+	tag         string              // tag shown by the "Tagged Records" table
+	from, to    time.Time           // date range of the "Tags" and "Tagged Records" tables, to is exclusive
+	search      string              // matches are highlighted in the Description column, set with '/'
+	filter      *backend.FilterOpts // rows shown by the "Records" and "Investments" tables, nil for all
+	filterDesc  string              // the filter in the title
+	sortable    bool                // the rows can be sorted by a column with s/S
+	sort        backend.SortOpts    // order of the rows, shown by an arrow in the header
 }
 
-func (t *updatableTable) fGetData(int) []backend.DataRow {
+func (t *updatableTable) fGetData(int) ([]backend.DataRow, error) {
 	switch t.title {
 	case "Records":
-		return backend.GetRecordsRecent(t.curPage)
+		return t.store.GetRecordsFilterPage(t.getFilter(), t.sort, t.curPage)
 	case "Categories":
-		return backend.GetCategories(t.curPage)
+		return t.store.GetCategoriesSorted(t.sort)
 	case "Investments":
-		return backend.GetInvestmentsRecent(t.curPage)
+		return t.store.GetInvestmentsFilterPage(t.getFilter(), t.sort, t.curPage)
 	case "Investment Summary":
-		return backend.GetInvestmentSummary()
+		return t.store.GetInvestmentSummary(t.sort)
+	case "Dividends":
+		return t.store.GetDividends()
+	case "Corporate Actions":
+		return t.store.GetCorporateActions()
+	case "Import Profiles":
+		return t.store.GetImportProfiles()
+	case "Accounts":
+		return t.store.GetAccounts()
+	case "Account Ledger":
+		return t.store.GetAccountLedger(t.accId, t.curPage)
+	case "Budgets":
+		return t.store.GetBudgets(time.Now())
+	case "Recurring Rules":
+		return t.store.GetRecurringRules()
+	case "Tags":
+		return t.store.GetTagTotals(t.from, t.to)
+	case "Tagged Records":
+		return t.store.GetRecordsFilter(backend.NewFilterOpts().WithTags([]string{t.tag}).WithStartDate(t.from).WithEndDate(t.to))
 	default:
 		panic("fGetData encountered an unknown title: " + t.title)
 	}
@@ -64,6 +101,14 @@ func (t *updatableTable) fGetData(int) []backend.DataRow {
 
 func (t *updatableTable) getCurPage() int { return t.curPage }
 
+/* The filter of the rows shown, or one matching every row */
+func (t *updatableTable) getFilter() backend.FilterOpts {
+	if t.filter == nil {
+		return backend.NewFilterOpts()
+	}
+	return *t.filter
+}
+
 /* handles keys common to all tables (back, prev/next page) */
 func (t *updatableTable) defaultInputCapture(event *tcell.EventKey) *tcell.EventKey {
 	if isBackKey(event) {
@@ -72,15 +117,41 @@ func (t *updatableTable) defaultInputCapture(event *tcell.EventKey) *tcell.Event
 		t.changePage(1)
 	} else if event.Rune() == 'H' { // previous page
 		t.changePage(-1)
+	} else if event.Rune() == 's' && t.sortable { // sort by the next column
+		t.cycleSort(false)
+	} else if event.Rune() == 'S' && t.sortable { // reverse the sort
+		t.cycleSort(true)
 	} else {
 		return event
 	}
 	return nil
 }
 
+/*
+Sorts the rows by the next column, going back to the default order after the
+last one, or reverses the sort (sorting by the first column if there isn't one)
+*/
+func (t *updatableTable) cycleSort(reverse bool) {
+	col := t.sort.Col()
+	if reverse {
+		t.sort = backend.SortBy(max(col, 0), col >= 0 && !t.sort.Desc())
+	} else if col+1 < len(t.headers) {
+		t.sort = backend.SortBy(col+1, false)
+	} else {
+		t.sort = backend.SortOpts{}
+	}
+	t.changePage(-t.curPage)
+	t.Select(1, 0)
+}
+
 func (t *updatableTable) update(rows []backend.DataRow) {
-	t.maxPage = t.fGetMaxPage()
+	maxPage, err := t.fGetMaxPage()
+	if err != nil {
+		showError(err)
+	}
+	t.maxPage = maxPage
 	t.createHeaders()
+	terms := backend.SearchTerms(t.search)
 
 	// create table body
 	for i, row := range rows {
@@ -99,6 +170,9 @@ func (t *updatableTable) update(rows []backend.DataRow) {
 					newCell.SetText(strings.Replace(newCell.Text, "$-", "-$", 1))
 				}
 			}
+			if len(terms) > 0 && j < len(t.headers) && t.headers[j] == "Description" {
+				newCell.SetText(highlightMatches(newCell.Text, terms))
+			}
 			t.SetCell(i+1, j, newCell)
 		}
 	}
@@ -106,8 +180,9 @@ func (t *updatableTable) update(rows []backend.DataRow) {
 
 func (t *updatableTable) reset() {
 	t.SetBorder(true)
+	t.search = ""
 	t.changePage(-t.curPage)
-	t.update(t.fGetData(t.curPage))
+	refresh(t)
 }
 
 func (t *updatableTable) changePage(by int) {
@@ -115,9 +190,21 @@ func (t *updatableTable) changePage(by int) {
 		return
 	}
 	t.curPage += by
-	t.update(t.fGetData(t.curPage))
+	refresh(t)
 
 	title := t.title
+	if t.tag != "" {
+		title += " #" + t.tag
+	}
+	if t.filterDesc != "" {
+		title += " filtered: " + t.filterDesc
+	}
+	if t.search != "" {
+		title += " /" + t.search
+	}
+	if !t.from.IsZero() {
+		title += fmt.Sprintf(" %s to %s", t.from.Format("2006-01-02"), t.to.AddDate(0, 0, -1).Format("2006-01-02"))
+	}
 	if t.maxPage > 0 {
 		title += fmt.Sprintf(" (%d/%d)", t.curPage+1, t.maxPage+1)
 	}
@@ -127,6 +214,13 @@ func (t *updatableTable) changePage(by int) {
 func (t *updatableTable) createHeaders() {
 	t.Clear()
 	for i, h := range t.headers {
+		if t.sortable && i == t.sort.Col() {
+			if t.sort.Desc() {
+				h += " ▼"
+			} else {
+				h += " ▲"
+			}
+		}
 		newCell := tview.NewTableCell(" " + h + " ").
 			SetSelectable(false).
 			SetStyle(tcell.StyleDefault.Bold(true))
@@ -140,12 +234,13 @@ func (t *updatableTable) getCellInt(row, col int) int {
 	return int(res)
 }
 
-// get the text of a cell, remove padding spaces + '$' symbol
+// get the text of a cell, remove padding spaces, '$' symbol + search highlighting
 func (t *updatableTable) getCellString(row, col int) string {
-	return strings.Replace(strings.TrimSpace(t.GetCell(row, col).Text), "$", "", 1)
+	text := strings.NewReplacer("[::r]", "", "[::-]", "").Replace(t.GetCell(row, col).Text)
+	return strings.Replace(strings.TrimSpace(text), "$", "", 1)
 }
 
-func newUpdatableTable(headers []string, parent borderColorChanger) updatableTable {
+func newUpdatableTable(store *backend.Store, headers []string, parent borderColorChanger) updatableTable {
 	t := tview.NewTable().
 		SetBorders(false).
 		SetSeparator(tview.Borders.Vertical).
@@ -168,6 +263,7 @@ func newUpdatableTable(headers []string, parent borderColorChanger) updatableTab
 	})
 
 	return updatableTable{
+		store:   store,
 		Table:   t,
 		headers: headers,
 	}
@@ -175,41 +271,81 @@ func newUpdatableTable(headers []string, parent borderColorChanger) updatableTab
 
 type monthGridView struct {
 	*tview.Grid
+	store       *backend.Store
 	table       *updatableTable
 	monthOffset int `default:"0"`
 	tvTitle     *tview.TextView
 	tvSummary   *tview.TextView
+	tvAccounts  *tview.TextView
+	catTable    *updatableTable // totals for each category
+	collapsed   map[int]bool    // categories with their subcategories hidden
 }
 
-func (mv *monthGridView) fGetData(offset int) []backend.DataRow {
+func (mv *monthGridView) fGetData(offset int) ([]backend.DataRow, error) {
 	t := time.Now().AddDate(0, mv.monthOffset, 0)
-	records, _, _ := backend.GetMonthInfo(t)
-	return records
+	records, _, _, err := mv.store.GetMonthInfo(t)
+	return records, err
 }
 
 func (mv *monthGridView) getCurPage() int { return mv.monthOffset }
 
 func (mv *monthGridView) changeMonth(by int) {
 	mv.monthOffset += by
-	mv.update(mv.fGetData(mv.monthOffset))
+	refresh(mv)
 }
 
 type yearView struct {
 	*tview.Grid
+	store      *backend.Store
 	invTable   *updatableTable
 	recTable   *updatableTable
 	yearOffset int `default:"0"`
 	tvTitle    *tview.TextView
+	collapsed  map[int]bool // categories with their subcategories hidden
 }
 
 func (yv *yearView) changeYear(by int) {
 	yv.yearOffset += by
-	yv.update(yv.fGetData(yv.yearOffset))
+	refresh(yv)
 }
 
-func (yv *yearView) fGetData(offset int) []backend.DataRow {
+func (yv *yearView) fGetData(offset int) ([]backend.DataRow, error) {
 	year := time.Now().Year() + offset
-	return backend.GetYearSummary(year)
+	return yv.store.GetYearSummary(year)
 }
 
 func (yv *yearView) getCurPage() int { return yv.yearOffset }
+
+type cgtView struct {
+	*tview.Grid
+	store      *backend.Store
+	table      *updatableTable // the year's disposals
+	yearOffset int             `default:"0"` // financial years from the current one
+	tvTitle    *tview.TextView
+	tvSummary  *tview.TextView // gains and losses
+	tvNet      *tview.TextView // the net capital gain, what's carried forward and the brokerage paid
+}
+
+/* The financial year shown, by the year it ends in */
+func (cv *cgtView) year() int {
+	return backend.FinancialYearOf(time.Now()) + cv.yearOffset
+}
+
+func (cv *cgtView) changeYear(by int) {
+	cv.yearOffset += by
+	refresh(cv)
+}
+
+func (cv *cgtView) fGetData(offset int) ([]backend.DataRow, error) {
+	report, err := cv.store.GetCapitalGains(cv.year())
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]backend.DataRow, len(report.Events))
+	for i, e := range report.Events {
+		rows[i] = e
+	}
+	return rows, nil
+}
+
+func (cv *cgtView) getCurPage() int { return cv.yearOffset }
@@ -9,7 +9,7 @@ import (
 	"github.com/shen-kit/finance-tracker/backend"
 )
 
-func createYearView() *yearView {
+func createYearView(store *backend.Store) *yearView {
 	tvTitle := tview.NewTextView().SetTextAlign(tview.AlignCenter)
 	tvTitle.SetBorderPadding(1, 1, 3, 3)
 
@@ -17,9 +17,9 @@ func createYearView() *yearView {
 		SetRows(3, 0).
 		SetBorders(true)
 
-	yearRecTable := newUpdatableTable(strings.Split(":Jan:Feb:Mar:Apr:May:Jun:Jul:Aug:Sep:Oct:Nov:Dec", ":"), yearGrid)
+	yearRecTable := newUpdatableTable(store, strings.Split(":Jan:Feb:Mar:Apr:May:Jun:Jul:Aug:Sep:Oct:Nov:Dec", ":"), yearGrid)
 	yearRecTable.SetBorder(false)
-	yearRecTable.fGetMaxPage = func() int { return 0 }
+	yearRecTable.fGetMaxPage = func() (int, error) { return 0, nil }
 
 	yearGrid.AddItem(tvTitle, 0, 0, 1, 1, 0, 0, false).
 		AddItem(yearRecTable, 1, 0, 1, 1, 0, 0, true).
@@ -27,10 +27,12 @@ func createYearView() *yearView {
 		SetTitle("Year Overview")
 
 	return &yearView{
+		store:      store,
 		Grid:       yearGrid,
 		recTable:   &yearRecTable,
 		yearOffset: 0,
 		tvTitle:    tvTitle,
+		collapsed:  map[int]bool{},
 	}
 }
 
@@ -42,6 +44,8 @@ func setYearViewKeybinds(yv *yearView) {
 			yv.changeYear(-1)
 		} else if event.Rune() == 'L' {
 			yv.changeYear(1)
+		} else if toggleCategoryTree(yv.recTable, yv.collapsed, event) {
+			refresh(yv)
 		} else {
 			return event
 		}
@@ -55,10 +59,9 @@ func (yv *yearView) update(data []backend.DataRow) {
 	yv.tvTitle.SetText(t.Format("2006"))
 
 	// update table data
-	yv.recTable.update(data)
+	updateCategoryTree(yv.recTable, data, yv.collapsed)
 }
 
 func (yv *yearView) reset() {
 	yv.changeYear(-yv.yearOffset)
-	yv.update(yv.fGetData(0))
 }
@@ -2,14 +2,16 @@ module github.com/shen-kit/finance-tracker
 
 go 1.23.5
 
-require github.com/mattn/go-sqlite3 v1.14.24
+require (
+	github.com/gdamore/tcell/v2 v2.7.1
+	github.com/mattn/go-sqlite3 v1.14.24
+	github.com/rivo/tview v0.0.0-20241227133733-17b7edb88c57
+)
 
 require (
 	github.com/gdamore/encoding v1.0.0 // indirect
-	github.com/gdamore/tcell/v2 v2.7.1 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
 	github.com/mattn/go-runewidth v0.0.15 // indirect
-	github.com/rivo/tview v0.0.0-20241227133733-17b7edb88c57 // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
 	golang.org/x/sys v0.17.0 // indirect
 	golang.org/x/term v0.17.0 // indirect
@@ -0,0 +1,205 @@
+/*
+Parsers for bank and broker statements, producing backend values which can be
+reviewed and then inserted into the database.
+*/
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+// date format tokens, longest first so "MMM" is replaced before "MM"
+var dateTokens = []struct{ token, layout string }{
+	{"YYYY", "2006"},
+	{"YY", "06"},
+	{"MMM", "Jan"},
+	{"MM", "01"},
+	{"M", "1"},
+	{"DD", "02"},
+	{"D", "2"},
+}
+
+/*
+Converts a human readable date format such as DD/MM/YYYY or D MMM YY into a
+layout for time.Parse.
+*/
+func DateLayout(format string) string {
+	var sb strings.Builder
+outer:
+	for i := 0; i < len(format); {
+		for _, t := range dateTokens {
+			if strings.HasPrefix(format[i:], t.token) {
+				sb.WriteString(t.layout)
+				i += len(t.token)
+				continue outer
+			}
+		}
+		sb.WriteByte(format[i])
+		i++
+	}
+	return sb.String()
+}
+
+/*
+Parses an amount in dollars as written in a statement, returning cents.
+Accepts currency symbols, thousands separators, (brackets) for negative
+amounts and CR/DR suffixes.
+*/
+func ParseAmount(s string) (int, error) {
+	orig := s
+	s = strings.TrimSpace(s)
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative = true
+		s = s[1 : len(s)-1]
+	}
+	if upper := strings.ToUpper(s); strings.HasSuffix(upper, "DR") {
+		negative = !negative
+		s = s[:len(s)-2]
+	} else if strings.HasSuffix(upper, "CR") {
+		s = s[:len(s)-2]
+	}
+	s = strings.NewReplacer("$", "", ",", "", " ", "").Replace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", orig)
+	}
+	cents := int(math.Round(f * 100))
+	if negative {
+		cents = -cents
+	}
+	return cents, nil
+}
+
+/* Returns the (1-based) column of a CSV row, or an error if the row is too short */
+func column(row []string, col int) (string, error) {
+	if col < 1 || col > len(row) {
+		return "", fmt.Errorf("no column %d, row has %d columns", col, len(row))
+	}
+	return strings.TrimSpace(row[col-1]), nil
+}
+
+/* Checks a profile is usable before parsing a file with it */
+func ValidateProfile(p backend.ImportProfile) error {
+	switch {
+	case p.Name == "":
+		return errors.New("profile name is required")
+	case len([]rune(p.Delimiter)) != 1:
+		return errors.New("delimiter must be a single character")
+	case p.SkipRows < 0:
+		return errors.New("rows to skip can't be negative")
+	case p.DateCol < 1 || p.AmtCol < 1 || p.DebitCol < 0:
+		return errors.New("column numbers start from 1")
+	case len(p.DescCols) == 0:
+		return errors.New("at least one description column is required")
+	case p.DateFormat == "":
+		return errors.New("date format is required")
+	}
+	for _, c := range p.DescCols {
+		if c < 1 {
+			return errors.New("column numbers start from 1")
+		}
+	}
+	return nil
+}
+
+/*
+Parses a CSV statement using the column mapping in profile p. The records
+returned have no category (CatId 0), blank lines and rows with no amount are
+skipped.
+*/
+func ParseCSV(r io.Reader, p backend.ImportProfile) ([]backend.Record, error) {
+	if err := ValidateProfile(p); err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(r)
+	reader.Comma = []rune(p.Delimiter)[0]
+	reader.FieldsPerRecord = -1 // some banks add trailing columns to some rows
+	reader.TrimLeadingSpace = true
+
+	layout := DateLayout(p.DateFormat)
+	var recs []backend.Record
+	for line := 1; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if line <= p.SkipRows || (len(row) == 1 && strings.TrimSpace(row[0]) == "") {
+			continue
+		}
+
+		rec, err := parseCSVRow(row, p, layout)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if rec.Amt == 0 {
+			continue
+		}
+		recs = append(recs, rec)
+	}
+	return recs, nil
+}
+
+func parseCSVRow(row []string, p backend.ImportProfile, layout string) (backend.Record, error) {
+	var rec backend.Record
+
+	dateStr, err := column(row, p.DateCol)
+	if err != nil {
+		return rec, err
+	}
+	if rec.Date, err = time.Parse(layout, dateStr); err != nil {
+		return rec, fmt.Errorf("date %q doesn't match format %s", dateStr, p.DateFormat)
+	}
+
+	var desc []string
+	for _, c := range p.DescCols {
+		d, err := column(row, c)
+		if err != nil {
+			return rec, err
+		}
+		if d != "" {
+			desc = append(desc, d)
+		}
+	}
+	rec.Desc = strings.Join(desc, " ")
+
+	amtStr, err := column(row, p.AmtCol)
+	if err != nil {
+		return rec, err
+	}
+	if rec.Amt, err = ParseAmount(amtStr); err != nil {
+		return rec, err
+	}
+	// separate debit column, debits are written as positive amounts
+	if p.DebitCol > 0 {
+		debitStr, err := column(row, p.DebitCol)
+		if err != nil {
+			return rec, err
+		}
+		debit, err := ParseAmount(debitStr)
+		if err != nil {
+			return rec, err
+		}
+		rec.Amt -= int(math.Abs(float64(debit)))
+	}
+	if p.Negate {
+		rec.Amt = -rec.Amt
+	}
+	return rec, nil
+}
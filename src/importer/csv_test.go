@@ -0,0 +1,138 @@
+package importer
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+func TestDateLayout(t *testing.T) {
+	tests := []struct{ format, layout string }{
+		{"DD/MM/YYYY", "02/01/2006"},
+		{"YYYY-MM-DD", "2006-01-02"},
+		{"D/M/YY", "2/1/06"},
+		{"DD MMM YYYY", "02 Jan 2006"},
+		{"MM/DD/YYYY", "01/02/2006"},
+	}
+	for _, tt := range tests {
+		if got := DateLayout(tt.format); got != tt.layout {
+			t.Errorf("DateLayout(%q) = %q, want %q", tt.format, got, tt.layout)
+		}
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"42.50", 4250},
+		{"-42.50", -4250},
+		{"+3,000.00", 300000},
+		{"$1,234.5", 123450},
+		{"(12.34)", -1234},
+		{"12.34 DR", -1234},
+		{"12.34CR", 1234},
+		{"0.1", 10},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		got, err := ParseAmount(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseAmount(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+	if _, err := ParseAmount("abc"); err == nil {
+		t.Error("expected an error for an invalid amount")
+	}
+}
+
+func parseFile(t *testing.T, path string, p backend.ImportProfile) []backend.Record {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	recs, err := ParseCSV(f, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return recs
+}
+
+func checkRecords(t *testing.T, got []backend.Record, want []backend.Record) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d records, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if !got[i].Date.Equal(want[i].Date) || got[i].Desc != want[i].Desc || got[i].Amt != want[i].Amt ||
+			got[i].ExtId != want[i].ExtId || got[i].CatName != want[i].CatName {
+			t.Errorf("record %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func day(s string) time.Time {
+	d, _ := time.Parse("2006-01-02", s)
+	return d
+}
+
+func TestParseCSVSingleAmountColumn(t *testing.T) {
+	recs := parseFile(t, "testdata/commbank.csv", backend.ImportProfile{
+		Name: "CommBank", Delimiter: ",", DateCol: 1, DateFormat: "DD/MM/YYYY", DescCols: []int{3}, AmtCol: 2,
+	})
+	checkRecords(t, recs, []backend.Record{
+		{Date: day("2026-10-01"), Desc: "COLES 0123 SYDNEY", Amt: -4250},
+		{Date: day("2026-10-02"), Desc: "SALARY ACME PTY LTD", Amt: 300000},
+		{Date: day("2026-10-03"), Desc: "RENT PAYMENT", Amt: -150000},
+	})
+}
+
+func TestParseCSVDebitCreditColumns(t *testing.T) {
+	recs := parseFile(t, "testdata/debit-credit.csv", backend.ImportProfile{
+		Name: "Other bank", Delimiter: ";", SkipRows: 1, DateCol: 1, DateFormat: "YYYY-MM-DD",
+		DescCols: []int{2, 3}, AmtCol: 5, DebitCol: 4,
+	})
+	// the zero amount bank fee is skipped
+	checkRecords(t, recs, []backend.Record{
+		{Date: day("2026-10-01"), Desc: "Coles 0123", Amt: -4250},
+		{Date: day("2026-10-02"), Desc: "Salary", Amt: 300000},
+	})
+}
+
+func TestParseCSVNegate(t *testing.T) {
+	recs := parseFile(t, "testdata/commbank.csv", backend.ImportProfile{
+		Name: "Card", Delimiter: ",", DateCol: 1, DateFormat: "DD/MM/YYYY", DescCols: []int{3}, AmtCol: 2, Negate: true,
+	})
+	if recs[0].Amt != 4250 {
+		t.Errorf("got %d, want 4250", recs[0].Amt)
+	}
+}
+
+func TestParseCSVErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile backend.ImportProfile
+	}{
+		{"wrong date format", backend.ImportProfile{Name: "x", Delimiter: ",", DateCol: 1, DateFormat: "YYYY-MM-DD", DescCols: []int{3}, AmtCol: 2}},
+		{"missing column", backend.ImportProfile{Name: "x", Delimiter: ",", DateCol: 1, DateFormat: "DD/MM/YYYY", DescCols: []int{9}, AmtCol: 2}},
+		{"amount isn't a number", backend.ImportProfile{Name: "x", Delimiter: ",", DateCol: 1, DateFormat: "DD/MM/YYYY", DescCols: []int{3}, AmtCol: 3}},
+		{"invalid profile", backend.ImportProfile{Name: "x", Delimiter: ",", DateCol: 0, DateFormat: "DD/MM/YYYY", DescCols: []int{3}, AmtCol: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.Open("testdata/commbank.csv")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			if _, err := ParseCSV(f, tt.profile); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
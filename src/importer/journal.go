@@ -0,0 +1,399 @@
+package importer
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shen-kit/finance-tracker/backend"
+	"github.com/shen-kit/finance-tracker/exporter"
+)
+
+// beancount directives which start with a date but aren't transactions
+var beancountDirectives = map[string]bool{
+	"open": true, "close": true, "balance": true, "pad": true, "price": true, "note": true,
+	"document": true, "event": true, "commodity": true, "custom": true, "query": true,
+}
+
+type journalPosting struct {
+	account   string
+	qty       float64
+	commodity string
+	hasAmt    bool
+	price     float64 // unit price in exporter.Currency, from @ or {cost}
+	hasPrice  bool
+}
+
+/* Is the commodity the journal's currency rather than a security? */
+func isCurrency(commodity string) bool {
+	return commodity == "" || commodity == "$" || strings.EqualFold(commodity, exporter.Currency)
+}
+
+/* The posting's value in exporter.Currency, false if it can't be worked out */
+func (p journalPosting) value() (float64, bool) {
+	switch {
+	case !p.hasAmt:
+		return 0, false
+	case isCurrency(p.commodity):
+		return p.qty, true
+	case p.hasPrice:
+		return p.qty * p.price, true
+	}
+	return 0, false
+}
+
+/*
+Splits an amount such as "-42.50 AUD", "$42.50" or `3 "IVV.AX"` into its
+quantity and commodity.
+*/
+func parseCommodityAmount(s string) (float64, string, error) {
+	orig := s
+	fail := func() (float64, string, error) {
+		return 0, "", fmt.Errorf("invalid amount %q", orig)
+	}
+	isNum := func(c byte) bool { return c >= '0' && c <= '9' || c == '.' || c == ',' }
+
+	// reads a commodity, which is quoted or runs until a space (or for a prefix like $10, a number)
+	readCommodity := func(s string, prefix bool) (string, string) {
+		if strings.HasPrefix(s, `"`) {
+			if end := strings.IndexByte(s[1:], '"'); end != -1 {
+				return s[1 : end+1], s[end+2:]
+			}
+		}
+		i := 0
+		for i < len(s) && s[i] != ' ' && s[i] != '\t' && !(prefix && (isNum(s[i]) || s[i] == '-')) {
+			i++
+		}
+		return s[:i], s[i:]
+	}
+
+	s = strings.TrimSpace(s)
+	negative := false
+	if strings.HasPrefix(s, "-") {
+		negative, s = true, strings.TrimSpace(s[1:])
+	}
+	var commodity string
+	if s != "" && !isNum(s[0]) {
+		commodity, s = readCommodity(s, true)
+		if s = strings.TrimSpace(s); strings.HasPrefix(s, "-") {
+			negative, s = !negative, s[1:]
+		}
+	}
+	i := 0
+	for i < len(s) && isNum(s[i]) {
+		i++
+	}
+	num, rest := s[:i], strings.TrimSpace(s[i:])
+	if commodity == "" && rest != "" {
+		commodity, rest = readCommodity(rest, false)
+	}
+	if num == "" || strings.TrimSpace(rest) != "" {
+		return fail()
+	}
+	qty, err := strconv.ParseFloat(strings.ReplaceAll(num, ",", ""), 64)
+	if err != nil {
+		return fail()
+	}
+	if negative {
+		qty = -qty
+	}
+	return qty, commodity, nil
+}
+
+/* Parses a posting line (without its indent), e.g. "Assets:Investments  3 IVV {550.10 AUD}" */
+func parsePosting(line string) (journalPosting, error) {
+	var p journalPosting
+	// beancount postings may be flagged
+	if strings.HasPrefix(line, "* ") || strings.HasPrefix(line, "! ") {
+		line = strings.TrimSpace(line[2:])
+	}
+
+	// the account ends at two spaces or a tab, or in beancount (where accounts have no spaces) at a space
+	end := len(line)
+	for _, sep := range []string{"\t", "  "} {
+		if i := strings.Index(line, sep); i != -1 && i < end {
+			end = i
+		}
+	}
+	if end == len(line) {
+		if i := strings.IndexByte(line, ' '); i != -1 {
+			end = i
+		}
+	}
+	p.account = strings.Trim(line[:end], "[]")
+	amount := strings.TrimSpace(line[end:])
+	// balance assertions aren't needed
+	if i := strings.Index(amount, "="); i != -1 {
+		amount = strings.TrimSpace(amount[:i])
+	}
+	if amount == "" {
+		return p, nil
+	}
+
+	// cost {...} and price @ or @@ (total)
+	var cost, price string
+	totalPrice := false
+	if open := strings.IndexByte(amount, '{'); open != -1 {
+		close := strings.IndexByte(amount, '}')
+		if close < open {
+			return p, fmt.Errorf("invalid cost in %q", amount)
+		}
+		cost = strings.Trim(amount[open+1:close], "{} ") // {{total}} is treated as a unit cost
+		amount = amount[:open] + amount[close+1:]
+	}
+	if i := strings.Index(amount, "@@"); i != -1 {
+		price, totalPrice, amount = amount[i+2:], true, amount[:i]
+	} else if i := strings.IndexByte(amount, '@'); i != -1 {
+		price, amount = amount[i+1:], amount[:i]
+	}
+
+	var err error
+	if p.qty, p.commodity, err = parseCommodityAmount(amount); err != nil {
+		return p, err
+	}
+	p.hasAmt = true
+
+	unitPrice := func(s string, total bool) (float64, error) {
+		v, _, err := parseCommodityAmount(s)
+		if total && p.qty != 0 {
+			v = math.Abs(v / p.qty)
+		}
+		return v, err
+	}
+	// buys are valued at cost, sells at the sale price
+	if cost != "" && (p.qty > 0 || price == "") {
+		p.price, err = unitPrice(cost, false)
+		p.hasPrice = true
+	} else if price != "" {
+		p.price, err = unitPrice(price, totalPrice)
+		p.hasPrice = true
+	}
+	return p, err
+}
+
+/* Parses a transaction's first line, returning its date and description */
+func parseJournalHeader(line string) (time.Time, string, bool) {
+	dateStr, rest, _ := strings.Cut(line, " ")
+	dateStr, _, _ = strings.Cut(dateStr, "=") // secondary date
+	dateStr = strings.NewReplacer("/", "-", ".", "-").Replace(dateStr)
+	date, err := time.Parse("2006-01-02", dateStr)
+	if err != nil {
+		return date, "", false
+	}
+
+	rest = strings.TrimSpace(rest)
+	if first, _, _ := strings.Cut(rest, " "); beancountDirectives[first] {
+		return date, "", false
+	}
+	for _, flag := range []string{"txn ", "* ", "! "} {
+		rest = strings.TrimSpace(strings.TrimPrefix(rest, flag))
+	}
+	if rest == "*" || rest == "!" || rest == "txn" {
+		rest = ""
+	}
+	// transaction code, e.g. (1234)
+	if strings.HasPrefix(rest, "(") {
+		if end := strings.IndexByte(rest, ')'); end != -1 {
+			rest = strings.TrimSpace(rest[end+1:])
+		}
+	}
+
+	// beancount has a quoted payee and/or narration, then tags and links
+	if strings.HasPrefix(rest, `"`) {
+		var parts []string
+		for strings.HasPrefix(rest, `"`) {
+			s, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				break
+			}
+			if unq, _ := strconv.Unquote(s); unq != "" {
+				parts = append(parts, unq)
+			}
+			rest = strings.TrimSpace(rest[len(s):])
+		}
+		return date, strings.Join(parts, " "), true
+	}
+	if i := strings.Index(rest, ";"); i != -1 { // ledger comment
+		rest = strings.TrimSpace(rest[:i])
+	}
+	return date, rest, true
+}
+
+/*
+Parses a ledger, hledger or beancount journal. Postings to Income: and
+Expenses: accounts become records, and postings of commodities other than
+exporter.Currency become investments. Transactions only between other accounts
+(e.g. transfers between bank accounts) are skipped, as are income and expense
+postings in investment transactions (e.g. capital gains), other than brokerage
+posted to exporter.BrokerageAccount, which is the trade's fee.
+
+Records are given the name of an existing category if its account
+(exporter.CategoryAccount) matches, otherwise the categories they need are
+returned in Statement.Categories. ExtIds are a hash of each transaction, so
+re-importing the journal after adding to it only imports the new transactions.
+*/
+func ParseJournal(r io.Reader, existing []backend.Category) (Statement, error) {
+	var st Statement
+
+	catNames := map[string]string{} // account -> category name
+	for _, c := range existing {
+		catNames[exporter.CategoryAccount(c.Name, c.IsIncome)] = c.Name
+	}
+	category := func(account string) (string, bool, bool) {
+		top, rest, _ := strings.Cut(account, ":")
+		isIncome := strings.EqualFold(top, "income")
+		if !isIncome && !strings.EqualFold(top, "expenses") {
+			return "", false, false
+		}
+		if name, ok := catNames[exporter.CategoryAccount(rest, isIncome)]; ok {
+			return name, isIncome, true
+		}
+		name := strings.ReplaceAll(rest, "-", " ")
+		catNames[exporter.CategoryAccount(rest, isIncome)] = name
+		st.Categories = append(st.Categories, backend.Category{Name: name, IsIncome: isIncome})
+		return name, isIncome, true
+	}
+
+	// identical transactions are numbered so they get different ids
+	seen := map[string]int{}
+	extId := func(parts ...any) string {
+		key := fmt.Sprint(parts...)
+		seen[key]++
+		sum := sha1.Sum([]byte(fmt.Sprint(key, seen[key])))
+		return "journal/" + hex.EncodeToString(sum[:8])
+	}
+
+	var (
+		inTxn    bool
+		date     time.Time
+		desc     string
+		postings []journalPosting
+		errs     []error
+	)
+	finish := func(line int) {
+		if !inTxn {
+			return
+		}
+		inTxn = false
+		if err := addJournalTransaction(&st, date, desc, postings, category, extId); err != nil {
+			errs = append(errs, fmt.Errorf("transaction ending line %d: %w", line, err))
+		}
+		postings = nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" {
+			finish(line)
+			continue
+		}
+		if strings.ContainsRune(";#%|*", rune(trimmed[0])) && (text[0] != ' ' && text[0] != '\t' || trimmed[0] == ';' || trimmed[0] == '#') {
+			continue // comment
+		}
+
+		if text[0] != ' ' && text[0] != '\t' { // new transaction or directive
+			finish(line)
+			date, desc, inTxn = parseJournalHeader(trimmed)
+			continue
+		}
+		if !inTxn {
+			continue // directive contents, e.g. a ledger account declaration
+		}
+
+		if i := strings.Index(trimmed, ";"); i != -1 {
+			trimmed = strings.TrimSpace(trimmed[:i])
+		}
+		// metadata ("key: value") and unbalanced virtual postings aren't needed
+		if first, _, _ := strings.Cut(trimmed, " "); trimmed == "" || strings.HasSuffix(first, ":") || strings.HasPrefix(trimmed, "(") {
+			continue
+		}
+		p, err := parsePosting(trimmed)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", line, err))
+			continue
+		}
+		postings = append(postings, p)
+	}
+	finish(line + 1)
+	if err := scanner.Err(); err != nil {
+		return Statement{}, err
+	}
+	if err := errors.Join(errs...); err != nil {
+		return Statement{}, err
+	}
+	return st, nil
+}
+
+func addJournalTransaction(st *Statement, date time.Time, desc string, postings []journalPosting,
+	category func(string) (string, bool, bool), extId func(...any) string) error {
+
+	// fill in the amount of the posting without one, from the others
+	missing, sum := -1, 0.0
+	for i, p := range postings {
+		if !p.hasAmt {
+			if missing != -1 {
+				return errors.New("more than one posting without an amount")
+			}
+			missing = i
+			continue
+		}
+		v, ok := p.value()
+		if !ok {
+			return fmt.Errorf("no cost or price for %s", p.commodity)
+		}
+		sum += v
+	}
+	if missing != -1 {
+		postings[missing].qty, postings[missing].hasAmt = -sum, true
+	}
+
+	first := len(st.Investments)
+	fee := 0
+	for _, p := range postings {
+		if p.account == exporter.BrokerageAccount && isCurrency(p.commodity) {
+			fee += int(math.Round(p.qty * 100))
+		}
+		if isCurrency(p.commodity) {
+			continue
+		}
+		st.Investments = append(st.Investments, backend.Investment{
+			Date:      date,
+			Code:      p.commodity,
+			Unitprice: int(math.Round(p.price * 100)),
+			Qty:       float32(p.qty),
+			ExtId:     extId(date, p.account, p.commodity, p.qty, p.price),
+		})
+	}
+	if len(st.Investments) > first {
+		// brokerage is paid on the trade, see exporter.WriteJournal
+		st.Investments[first].Fee = fee
+		return nil
+	}
+
+	for _, p := range postings {
+		name, _, ok := category(p.account)
+		if !ok {
+			continue
+		}
+		amt := -int(math.Round(p.qty * 100))
+		st.Records = append(st.Records, backend.Record{
+			Date:    date,
+			Desc:    desc,
+			Amt:     amt,
+			CatName: name,
+			ExtId:   extId(date, desc, p.account, amt),
+		})
+	}
+	return nil
+}
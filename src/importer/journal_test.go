@@ -0,0 +1,138 @@
+package importer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/shen-kit/finance-tracker/backend"
+	"github.com/shen-kit/finance-tracker/exporter"
+)
+
+/* Checks ExtIds are set and unique, then clears them so records can be compared */
+func clearJournalIds(t *testing.T, st *Statement) {
+	t.Helper()
+	seen := map[string]bool{}
+	check := func(id string) {
+		if !strings.HasPrefix(id, "journal/") || seen[id] {
+			t.Errorf("bad or duplicate id %q", id)
+		}
+		seen[id] = true
+	}
+	for i := range st.Records {
+		check(st.Records[i].ExtId)
+		st.Records[i].ExtId = ""
+	}
+	for i := range st.Investments {
+		check(st.Investments[i].ExtId)
+		st.Investments[i].ExtId = ""
+	}
+}
+
+func TestJournalRoundTrip(t *testing.T) {
+	cats := []backend.Category{
+		{Id: 1, Name: "Groceries"},
+		{Id: 2, Name: "Work", IsIncome: true},
+		{Id: 3, Name: "Eating Out"},
+	}
+	j := exporter.Journal{
+		Categories: cats,
+		Records: []backend.Record{
+			{Date: day("2026-10-01"), Desc: "Coles", Amt: -4250, CatId: 1},
+			{Date: day("2026-09-15"), Desc: "pay", Amt: 300000, CatId: 2},
+			{Date: day("2026-09-20"), Desc: `Dinner "Thai"`, Amt: -6001, CatId: 3},
+			{Date: day("2026-09-20"), Desc: `Dinner "Thai"`, Amt: -6001, CatId: 3},
+		},
+		Investments: []backend.Investment{
+			{Date: day("2026-01-05"), Code: "IVV", Unitprice: 55010, Qty: 3, Fee: 995},
+			{Date: day("2026-02-05"), Code: "IVV", Unitprice: 60000, Qty: -1},
+		},
+	}
+
+	for _, f := range exporter.JournalFormats {
+		t.Run(string(f), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := exporter.WriteJournal(&buf, f, j); err != nil {
+				t.Fatal(err)
+			}
+			// only Groceries exists, the others are created
+			st, err := ParseJournal(&buf, cats[:1])
+			if err != nil {
+				t.Fatalf("%v\n%s", err, buf.String())
+			}
+			clearJournalIds(t, &st)
+
+			checkRecords(t, st.Records, []backend.Record{
+				{Date: day("2026-09-15"), Desc: "pay", Amt: 300000, CatName: "Work"},
+				{Date: day("2026-09-20"), Desc: `Dinner "Thai"`, Amt: -6001, CatName: "Eating Out"},
+				{Date: day("2026-09-20"), Desc: `Dinner "Thai"`, Amt: -6001, CatName: "Eating Out"},
+				{Date: day("2026-10-01"), Desc: "Coles", Amt: -4250, CatName: "Groceries"},
+			})
+			if len(st.Categories) != 2 || st.Categories[0] != (backend.Category{Name: "Work", IsIncome: true}) ||
+				st.Categories[1] != (backend.Category{Name: "Eating Out"}) {
+				t.Errorf("unexpected new categories %+v", st.Categories)
+			}
+			if len(st.Investments) != 2 {
+				t.Fatalf("got %d investments, want 2", len(st.Investments))
+			}
+			for i, want := range j.Investments {
+				if got := st.Investments[i]; !got.Date.Equal(want.Date) || got.Code != want.Code ||
+					got.Unitprice != want.Unitprice || got.Qty != want.Qty || got.Fee != want.Fee {
+					t.Errorf("investment %d: got %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseJournalSyntax(t *testing.T) {
+	journal := `; written by hand
+account Assets:Bank
+    note the bank
+
+2026/10/01 * (123) Woolworths  ; weekly shop
+    Expenses:Groceries    $42.50
+    Expenses:Household     AUD 7.50
+    Assets:Bank
+
+2026-10-02=2026-10-04 ! Transfer to savings
+    Assets:Savings         100 AUD
+    Assets:Bank           -100 AUD
+
+2026-10-03 txn "Employer" "October pay" #work
+  memo: "ignored"
+  Income:Salary  -1,000.00 AUD
+  Assets:Bank
+
+2026-10-04 "Broker" "Buy"
+  Assets:Broker  10 "VGS.AX" @@ 1200 AUD
+  Expenses:Brokerage  9.50 AUD
+  Assets:Bank
+
+2026-10-05 price VGS.AX 121.00 AUD
+`
+	st, err := ParseJournal(strings.NewReader(journal), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clearJournalIds(t, &st)
+	checkRecords(t, st.Records, []backend.Record{
+		{Date: day("2026-10-01"), Desc: "Woolworths", Amt: -4250, CatName: "Groceries"},
+		{Date: day("2026-10-01"), Desc: "Woolworths", Amt: -750, CatName: "Household"},
+		{Date: day("2026-10-03"), Desc: "Employer October pay", Amt: 100000, CatName: "Salary"},
+	})
+	want := backend.Investment{Date: day("2026-10-04"), Code: "VGS.AX", Unitprice: 12000, Qty: 10, Fee: 950}
+	if len(st.Investments) != 1 || st.Investments[0] != want {
+		t.Errorf("got investments %+v, want %+v", st.Investments, want)
+	}
+
+	for _, bad := range []string{
+		"2026-10-01 x\n  Expenses:A\n  Assets:Bank\n",
+		"2026-10-01 x\n  Expenses:A  ten AUD\n  Assets:Bank\n",
+		"2026-10-01 x\n  Assets:Broker  3 IVV\n  Assets:Bank\n",
+	} {
+		if _, err := ParseJournal(strings.NewReader(bad), nil); err == nil {
+			t.Errorf("expected an error parsing %q", bad)
+		}
+	}
+}
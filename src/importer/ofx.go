@@ -0,0 +1,285 @@
+package importer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+/* Transactions read from a statement which can hold both bank and investment transactions */
+type Statement struct {
+	Records     []backend.Record
+	Investments []backend.Investment
+	Categories  []backend.Category // categories records need which don't exist yet (journals only)
+}
+
+/* An OFX element, either an aggregate with children or a leaf with a value */
+type ofxNode struct {
+	name     string
+	value    string
+	children []*ofxNode
+}
+
+/* Returns the first descendant with the given path of element names, e.g. "INVTRAN", "FITID" */
+func (n *ofxNode) find(path ...string) *ofxNode {
+	if len(path) == 0 {
+		return n
+	}
+	for _, c := range n.children {
+		if c.name == path[0] {
+			if res := c.find(path[1:]...); res != nil {
+				return res
+			}
+		}
+	}
+	return nil
+}
+
+/* Returns the value of the element at path, or "" if there isn't one */
+func (n *ofxNode) get(path ...string) string {
+	if res := n.find(path...); res != nil {
+		return res.value
+	}
+	return ""
+}
+
+/* Calls f for every descendant with one of the given names, in document order */
+func (n *ofxNode) walk(f func(*ofxNode), names ...string) {
+	for _, c := range n.children {
+		for _, name := range names {
+			if c.name == name {
+				f(c)
+			}
+		}
+		c.walk(f, names...)
+	}
+}
+
+/*
+Parses the body of an OFX document into a tree. Handles both OFX 1.x (SGML,
+where leaf elements have no closing tag) and OFX 2.x (XML) documents.
+*/
+func parseOFXTree(r io.Reader) (*ofxNode, error) {
+	data, err := io.ReadAll(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+	doc := string(data)
+	start := strings.Index(strings.ToUpper(doc), "<OFX>")
+	if start == -1 {
+		return nil, errors.New("not an OFX file, no <OFX> element")
+	}
+	doc = doc[start:]
+
+	// SGML leaves are never closed, so an element with no value is only an aggregate if it's closed somewhere
+	closed := map[string]bool{}
+	for _, part := range strings.Split(doc, "</")[1:] {
+		if end := strings.IndexByte(part, '>'); end != -1 {
+			closed[strings.ToUpper(strings.TrimSpace(part[:end]))] = true
+		}
+	}
+
+	root := &ofxNode{}
+	stack := []*ofxNode{root}
+	for len(doc) > 0 {
+		open := strings.IndexByte(doc, '<')
+		if open == -1 {
+			break
+		}
+		end := strings.IndexByte(doc[open:], '>')
+		if end == -1 {
+			return nil, errors.New("unterminated OFX tag")
+		}
+		tag := strings.ToUpper(strings.TrimSpace(doc[open+1 : open+end]))
+		doc = doc[open+end+1:]
+
+		// text up to the next tag is the value of a leaf element
+		next := strings.IndexByte(doc, '<')
+		if next == -1 {
+			next = len(doc)
+		}
+		value := strings.TrimSpace(doc[:next])
+
+		switch {
+		case strings.HasPrefix(tag, "?"), strings.HasPrefix(tag, "!"): // XML declaration, comments
+		case strings.HasPrefix(tag, "/"):
+			name := tag[1:]
+			// pop up to and including the matching element, leaves in SGML are never closed
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].name == name {
+					stack = stack[:i]
+					break
+				}
+			}
+		case strings.HasSuffix(tag, "/"): // empty XML element
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, &ofxNode{name: strings.TrimSpace(tag[:len(tag)-1])})
+		default:
+			node := &ofxNode{name: tag, value: unescapeOFX(value)}
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, node)
+			if value == "" && closed[tag] {
+				stack = append(stack, node)
+			}
+		}
+	}
+	return root, nil
+}
+
+func unescapeOFX(s string) string {
+	return strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&", "&nbsp;", " ").Replace(s)
+}
+
+/* Parses an OFX date, YYYYMMDD optionally followed by a time and timezone which are ignored */
+func parseOFXDate(s string) (time.Time, error) {
+	if len(s) < 8 {
+		return time.Time{}, fmt.Errorf("invalid OFX date %q", s)
+	}
+	date, err := time.Parse("20060102", s[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid OFX date %q", s)
+	}
+	return date, nil
+}
+
+/* Parses an OFX amount, which may use a comma as the decimal separator */
+func parseOFXAmount(s string) (float64, error) {
+	f, err := strconv.ParseFloat(strings.Replace(strings.TrimSpace(s), ",", ".", 1), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid OFX amount %q", s)
+	}
+	return f, nil
+}
+
+/*
+Parses an OFX or QFX statement. Bank, credit card and investment account cash
+transactions become records (with no category), and buys and sells of
+securities become investments, sells having a negative quantity.
+
+The FITID of each transaction (or check number if there's no FITID), prefixed
+with the account id, is kept as the ExtId so re-importing a statement doesn't
+add duplicates.
+*/
+func ParseOFX(r io.Reader) (Statement, error) {
+	var st Statement
+	root, err := parseOFXTree(r)
+	if err != nil {
+		return st, err
+	}
+
+	// securities are referred to by CUSIP/ISIN in transactions, map them to ticker codes
+	tickers := map[string]string{}
+	root.walk(func(n *ofxNode) {
+		if ticker := n.get("TICKER"); ticker != "" {
+			tickers[n.get("SECID", "UNIQUEID")] = ticker
+		}
+	}, "SECINFO")
+
+	var errs []error
+	root.walk(func(stmt *ofxNode) {
+		acct := stmt.get("BANKACCTFROM", "ACCTID") + stmt.get("CCACCTFROM", "ACCTID") + stmt.get("INVACCTFROM", "ACCTID")
+		extId := func(fitId, checkNum string) string {
+			switch {
+			case fitId != "":
+				return acct + "/" + fitId
+			case checkNum != "":
+				return acct + "/check/" + checkNum
+			}
+			return ""
+		}
+
+		stmt.walk(func(n *ofxNode) {
+			rec, err := ofxRecord(n)
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			rec.ExtId = extId(n.get("FITID"), n.get("CHECKNUM"))
+			st.Records = append(st.Records, rec)
+		}, "STMTTRN")
+
+		stmt.walk(func(n *ofxNode) {
+			inv, err := ofxInvestment(n, tickers)
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			inv.ExtId = extId(n.get("INVTRAN", "FITID"), "")
+			st.Investments = append(st.Investments, inv)
+		}, "INVBUY", "INVSELL")
+	}, "STMTRS", "CCSTMTRS", "INVSTMTRS")
+
+	if err := errors.Join(errs...); err != nil {
+		return Statement{}, err
+	}
+	return st, nil
+}
+
+/* Converts a STMTTRN element into a record */
+func ofxRecord(n *ofxNode) (backend.Record, error) {
+	var rec backend.Record
+	date, err := parseOFXDate(n.get("DTPOSTED"))
+	if err != nil {
+		return rec, err
+	}
+	amt, err := parseOFXAmount(n.get("TRNAMT"))
+	if err != nil {
+		return rec, err
+	}
+
+	desc := n.get("NAME")
+	if memo := n.get("MEMO"); desc == "" {
+		desc = memo
+	} else if memo != "" && !strings.Contains(desc, memo) {
+		desc += " " + memo
+	}
+	return backend.Record{Date: date, Desc: desc, Amt: int(math.Round(amt * 100))}, nil
+}
+
+/* Converts an INVBUY or INVSELL element into an investment */
+func ofxInvestment(n *ofxNode, tickers map[string]string) (backend.Investment, error) {
+	var inv backend.Investment
+	date, err := parseOFXDate(n.get("INVTRAN", "DTTRADE"))
+	if err != nil {
+		return inv, err
+	}
+	units, err := parseOFXAmount(n.get("UNITS"))
+	if err != nil {
+		return inv, err
+	}
+	price, err := parseOFXAmount(n.get("UNITPRICE"))
+	if err != nil {
+		return inv, err
+	}
+
+	// brokerage, both are optional
+	fee := 0.0
+	for _, name := range []string{"COMMISSION", "FEES"} {
+		if s := n.get(name); s != "" {
+			amt, err := parseOFXAmount(s)
+			if err != nil {
+				return inv, err
+			}
+			fee += math.Abs(amt)
+		}
+	}
+
+	secId := n.get("SECID", "UNIQUEID")
+	code, ok := tickers[secId]
+	if !ok {
+		code = secId
+	}
+	// units are negative for sells in most statements, but not all
+	qty := math.Abs(units)
+	if n.name == "INVSELL" {
+		qty = -qty
+	}
+	return backend.Investment{Date: date, Code: code, Unitprice: int(math.Round(price * 100)), Qty: float32(qty), Fee: int(math.Round(fee * 100))}, nil
+}
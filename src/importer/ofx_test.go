@@ -0,0 +1,110 @@
+package importer
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+func parseOFXFile(t *testing.T, path string) Statement {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	st, err := ParseOFX(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+func TestParseOFXBankStatement(t *testing.T) {
+	st := parseOFXFile(t, "testdata/bank.qfx")
+	checkRecords(t, st.Records, []backend.Record{
+		{Date: day("2026-10-01"), Desc: "COLES 0123 SYDNEY", Amt: -4250, ExtId: "12345678/202610010001"},
+		{Date: day("2026-10-02"), Desc: "SALARY ACME PTY LTD", Amt: 300000, ExtId: "12345678/202610020001"},
+		{Date: day("2026-10-03"), Desc: "RENT & STRATA", Amt: -150000, ExtId: "12345678/check/000123"},
+	})
+	if len(st.Investments) != 0 {
+		t.Errorf("unexpected investments %+v", st.Investments)
+	}
+}
+
+func TestParseOFXInvestmentStatement(t *testing.T) {
+	st := parseOFXFile(t, "testdata/broker.ofx")
+	checkRecords(t, st.Records, []backend.Record{
+		{Date: day("2026-09-20"), Desc: "Deposit", Amt: 100000, ExtId: "INV-99/D-1003"},
+	})
+
+	want := []backend.Investment{
+		{Date: day("2026-09-15"), Code: "IVV.AX", Unitprice: 5510, Qty: 10, Fee: 950, ExtId: "INV-99/B-1001"},
+		// no SECINFO for this security, the ISIN is used as the code
+		{Date: day("2026-10-01"), Code: "AU000000VAS1", Unitprice: 10126, Qty: -4, ExtId: "INV-99/S-1002"},
+	}
+	if len(st.Investments) != len(want) {
+		t.Fatalf("got %d investments, want %d: %+v", len(st.Investments), len(want), st.Investments)
+	}
+	for i := range want {
+		if got := st.Investments[i]; !got.Date.Equal(want[i].Date) || got.Code != want[i].Code ||
+			got.Unitprice != want[i].Unitprice || got.Qty != want[i].Qty || got.Fee != want[i].Fee || got.ExtId != want[i].ExtId {
+			t.Errorf("investment %d: got %+v, want %+v", i, got, want[i])
+		}
+	}
+}
+
+func TestParseOFXEmptyLeaf(t *testing.T) {
+	doc := `OFXHEADER:100
+DATA:OFXSGML
+
+<OFX>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<BANKACCTFROM><ACCTID>12345678</BANKACCTFROM>
+<BANKTRANLIST>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20261001
+<MEMO>
+<TRNAMT>-42.50
+<FITID>0001
+<NAME>COLES 0123 SYDNEY
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20261002
+<TRNAMT>3000.00
+<FITID>0002
+<NAME>SALARY ACME PTY LTD
+</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+	st, err := ParseOFX(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the fields after the empty memo are still the transaction's
+	checkRecords(t, st.Records, []backend.Record{
+		{Date: day("2026-10-01"), Desc: "COLES 0123 SYDNEY", Amt: -4250, ExtId: "12345678/0001"},
+		{Date: day("2026-10-02"), Desc: "SALARY ACME PTY LTD", Amt: 300000, ExtId: "12345678/0002"},
+	})
+}
+
+func TestParseOFXErrors(t *testing.T) {
+	tests := []struct{ name, doc string }{
+		{"not ofx", "Date,Amount\n2026-01-01,1.00\n"},
+		{"bad date", "<OFX><STMTRS><STMTTRN><DTPOSTED>2026<TRNAMT>1.00</STMTTRN></STMTRS></OFX>"},
+		{"bad amount", "<OFX><STMTRS><STMTTRN><DTPOSTED>20260101<TRNAMT>abc</STMTTRN></STMTRS></OFX>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseOFX(strings.NewReader(tt.doc)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
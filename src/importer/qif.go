@@ -0,0 +1,140 @@
+package importer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+// QIF account types holding bank-style transactions, other sections (investments, memorised transactions) are skipped
+var qifBankTypes = map[string]bool{"bank": true, "cash": true, "ccard": true, "oth a": true, "oth l": true}
+
+// quicken writes years after 2000 with an apostrophe, e.g. 1/02'26, and may pad with spaces
+var qifDateSeparators = regexp.MustCompile(`\s*['/.-]\s*`)
+
+var checkNumber = regexp.MustCompile(`^\d+$`)
+
+/* Parses a QIF date using a format like D/M/YYYY, accepting 2 or 4 digit years whatever the format */
+func parseQIFDate(s, format string) (time.Time, error) {
+	s = qifDateSeparators.ReplaceAllString(strings.TrimSpace(s), "/")
+	format = qifDateSeparators.ReplaceAllString(format, "/")
+
+	// the day and month may or may not be padded
+	if !strings.Contains(format, "MMM") {
+		format = strings.NewReplacer("DD", "D", "MM", "M").Replace(format)
+	}
+	layouts := []string{DateLayout(format)}
+	if strings.Contains(format, "YYYY") {
+		layouts = append(layouts, DateLayout(strings.Replace(format, "YYYY", "YY", 1)))
+	} else {
+		layouts = append(layouts, DateLayout(strings.Replace(format, "YY", "YYYY", 1)))
+	}
+	for _, layout := range layouts {
+		if date, err := time.Parse(layout, s); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("date %q doesn't match format %s", s, format)
+}
+
+/*
+Parses the bank, cash and credit card transactions in a QIF file. QIF dates
+have no standard order, so dateFormat gives it, e.g. D/M/YYYY or M/D/YY.
+
+The check number (N) is kept in the ExtId with the date and the account it's
+from, the name in the file's !Account header or account if there isn't one.
+The category (L) is kept as CatName unless it's a transfer to another account
+([Account Name]).
+*/
+func ParseQIF(r io.Reader, dateFormat, account string) ([]backend.Record, error) {
+	if dateFormat == "" {
+		return nil, errors.New("date format is required")
+	}
+
+	var recs []backend.Record
+	var rec backend.Record
+	var memo, checkNum string
+	hasAmt, inBank, inAccount := false, false, false
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r ")
+		if text == "" {
+			continue
+		}
+		if strings.HasPrefix(text, "!") {
+			header := strings.ToLower(text)
+			if strings.HasPrefix(header, "!type:") {
+				inBank = qifBankTypes[strings.TrimSpace(header[len("!type:"):])]
+			}
+			// the account the transactions after it are from, or a list of accounts, until the next header
+			inAccount = header == "!account"
+			if inAccount {
+				inBank = false
+			}
+			continue
+		}
+		if inAccount {
+			if text[0] == 'N' {
+				account = strings.TrimSpace(text[1:])
+			}
+			continue
+		}
+		if !inBank {
+			continue
+		}
+
+		field, value := text[0], strings.TrimSpace(text[1:])
+		var err error
+		switch field {
+		case 'D':
+			rec.Date, err = parseQIFDate(value, dateFormat)
+		case 'T', 'U':
+			rec.Amt, err = ParseAmount(value)
+			hasAmt = true
+		case 'P':
+			rec.Desc = value
+		case 'M':
+			memo = value
+		case 'N': // also used for transaction types such as EFT or ATM, only check numbers are unique
+			if checkNumber.MatchString(value) {
+				checkNum = value
+			}
+		case 'L':
+			if !strings.HasPrefix(value, "[") {
+				// subcategories are written as Category:Subcategory
+				rec.CatName = strings.SplitN(value, ":", 2)[0]
+			}
+		case '^': // end of transaction
+			if rec.Desc == "" {
+				rec.Desc = memo
+			} else if memo != "" && !strings.Contains(rec.Desc, memo) {
+				rec.Desc += " " + memo
+			}
+			if rec.Date.IsZero() || !hasAmt {
+				return nil, fmt.Errorf("line %d: transaction is missing a date or amount", line)
+			}
+			if checkNum != "" {
+				// numbers are only unique within an account, and are reused by later check books
+				rec.ExtId = account + "/check/" + checkNum + "/" + rec.Date.Format("20060102")
+			}
+			if rec.Amt != 0 {
+				recs = append(recs, rec)
+			}
+			rec, memo, checkNum, hasAmt = backend.Record{}, "", "", false
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return recs, nil
+}
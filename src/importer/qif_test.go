@@ -0,0 +1,117 @@
+package importer
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+func TestParseQIF(t *testing.T) {
+	f, err := os.Open("testdata/bank.qif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	recs, err := ParseQIF(f, "D/M/YYYY", "Cheque")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the account list and investment sections are skipped
+	checkRecords(t, recs, []backend.Record{
+		{Date: day("2026-10-01"), Desc: "COLES 0123 SYDNEY", Amt: -4250, CatName: "Groceries"},
+		{Date: day("2026-10-02"), Desc: "SALARY ACME PTY LTD", Amt: 300000, CatName: "Income"},
+		{Date: day("2026-10-03"), Desc: "RENT", Amt: -150000, ExtId: "Everyday/check/123/20261003"},
+		{Date: day("2026-10-04"), Desc: "Transfer to savings", Amt: -20000},
+	})
+}
+
+func TestParseQIFCheckNumbers(t *testing.T) {
+	doc := `!Account
+NEveryday
+TBank
+^
+!Type:Bank
+D1/10/26
+T-10.00
+N1001
+^
+!Account
+NBusiness
+TBank
+^
+!Type:Bank
+D1/10/26
+T-20.00
+N1001
+^
+D1/11/26
+T-30.00
+N1001
+^
+`
+	recs, err := ParseQIF(strings.NewReader(doc), "D/M/YY", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the same number in another account or check book is another check
+	checkRecords(t, recs, []backend.Record{
+		{Date: day("2026-10-01"), Amt: -1000, ExtId: "Everyday/check/1001/20261001"},
+		{Date: day("2026-10-01"), Amt: -2000, ExtId: "Business/check/1001/20261001"},
+		{Date: day("2026-11-01"), Amt: -3000, ExtId: "Business/check/1001/20261101"},
+	})
+
+	s, err := backend.SetupDb(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.InsertCategory(backend.Category{Name: "Bills"}); err != nil {
+		t.Fatal(err)
+	}
+	for i := range recs {
+		recs[i].CatId = 1
+	}
+	if n, err := s.InsertRecords(recs); err != nil || n != 3 {
+		t.Errorf("inserted %d records (%v), want all 3", n, err)
+	}
+
+	// without an !Account header the check is from the account imported into
+	recs, err = ParseQIF(strings.NewReader("!Type:Bank\nD1/10/26\nT-10.00\nN1001\n^\n"), "D/M/YY", "Savings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRecords(t, recs, []backend.Record{{Date: day("2026-10-01"), Amt: -1000, ExtId: "Savings/check/1001/20261001"}})
+}
+
+func TestParseQIFDates(t *testing.T) {
+	tests := []struct{ in, format, want string }{
+		{"1/10'26", "D/M/YYYY", "2026-10-01"},
+		{"01/10/2026", "DD/MM/YYYY", "2026-10-01"},
+		{"10/ 1/26", "M/D/YY", "2026-10-01"},
+		{"2026-10-01", "YYYY-MM-DD", "2026-10-01"},
+	}
+	for _, tt := range tests {
+		got, err := parseQIFDate(tt.in, tt.format)
+		if err != nil || !got.Equal(day(tt.want)) {
+			t.Errorf("parseQIFDate(%q, %q) = %v, %v, want %s", tt.in, tt.format, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseQIFErrors(t *testing.T) {
+	tests := []struct{ name, doc string }{
+		{"bad date", "!Type:Bank\nD31/31/26\nT1.00\n^\n"},
+		{"bad amount", "!Type:Bank\nD1/1/26\nTabc\n^\n"},
+		{"missing amount", "!Type:Bank\nD1/1/26\nPshop\n^\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseQIF(strings.NewReader(tt.doc), "D/M/YY", ""); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
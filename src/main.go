@@ -1,19 +1,54 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"time"
 
 	"github.com/shen-kit/finance-tracker/backend"
+	"github.com/shen-kit/finance-tracker/cli"
 	"github.com/shen-kit/finance-tracker/frontend"
 )
 
 func main() {
-	if len(os.Args) != 2 {
-		fmt.Println("Run using `$finance-tracker <path_to_db>`")
+	if len(os.Args) < 2 {
+		fmt.Print(cli.Usage)
 		os.Exit(0)
 	}
 
-	backend.SetupDb(os.Args[1])
-	frontend.CreateTUI()
+	if err := run(os.Args[1], os.Args[2:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if errors.Is(err, cli.ErrUsage) {
+			fmt.Fprint(os.Stderr, "\n"+cli.Usage)
+		}
+		os.Exit(1)
+	}
+}
+
+/* Opens the database at path, then runs the command in args, or the TUI if there isn't one */
+func run(path string, args []string, stdout, stderr io.Writer) error {
+	store, err := backend.SetupDb(path)
+	if err != nil {
+		return fmt.Errorf("Failed to open database: %w", err)
+	}
+	defer store.Close()
+
+	// create records for recurring rules which have fallen due, but not for commands which only read
+	var created []backend.Record
+	if len(args) == 0 || cli.Mutates(args) {
+		if created, err = store.MaterialiseRecurring(time.Now()); err != nil {
+			fmt.Fprintln(stderr, err)
+		}
+	}
+
+	if len(args) == 0 {
+		return frontend.CreateTUI(store, created)
+	}
+	// on stderr, so scripts reading stdout aren't affected
+	for _, rec := range created {
+		fmt.Fprintf(stderr, "created recurring record: %s %s %.2f\n", rec.Date.Format("2006-01-02"), rec.Desc, float64(rec.Amt)/100)
+	}
+	return cli.Run(store, args, stdout)
 }
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shen-kit/finance-tracker/backend"
+)
+
+func TestRecurringRecordsCreatedByCommands(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	s, err := backend.SetupDb(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.InsertCategory(backend.Category{Name: "Groceries"})
+	if err == nil {
+		rule := backend.RecurringRule{Desc: "shop", Amt: -100, CatId: 1, Freq: backend.Weekly, Start: time.Now().AddDate(0, 0, -15)}
+		err = s.InsertRecurringRule(rule)
+	}
+	s.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runCmd := func(args ...string) (string, string) {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		if err := run(path, args, &stdout, &stderr); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+		return stdout.String(), stderr.String()
+	}
+
+	// commands which only read don't create them
+	if out, msgs := runCmd("list", "records"); strings.Contains(out, "shop") || msgs != "" {
+		t.Errorf("list records created records:\n%s%s", out, msgs)
+	}
+	// add recurring lists the records it creates on stdout
+	if out, msgs := runCmd("add", "recurring"); strings.Count(out, "created recurring record: ") != 3 || msgs != "" {
+		t.Errorf("unexpected output from add recurring:\n%s%s", out, msgs)
+	}
+	if out, _ := runCmd("list", "records"); strings.Count(out, "shop") != 3 {
+		t.Errorf("want 3 records, got:\n%s", out)
+	}
+}